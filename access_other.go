@@ -0,0 +1,18 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+import "os"
+
+// Return why this process can't write to path, or nil if it can, as far
+// as its permission bits tell. On Windows, only files can be read-only.
+func canWrite(path string, dir bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !dir && info.Mode().Perm()&0200 == 0 {
+		return &os.PathError{Op: "access", Path: path, Err: os.ErrPermission}
+	}
+	return nil
+}
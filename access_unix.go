@@ -0,0 +1,23 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Return why this process can't write to path, or nil if it can. For a
+// directory, it must also be searchable to add or remove entries.
+func canWrite(path string, dir bool) error {
+	mode := uint32(unix.W_OK)
+	if dir {
+		mode |= unix.X_OK
+	}
+	err := unix.Access(path, mode)
+	if err != nil {
+		return &os.PathError{Op: "access", Path: path, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package shutil
+
+// Copy the POSIX access control lists of src to dst: its access ACL, and
+// if it's a directory, the default ACL that what is created inside it
+// inherits. An ACL that src doesn't have is removed from dst, so dst is
+// left with the permissions of its mode alone, as src is. Symbolic links
+// are followed.
+//
+// ACLs grant access to users and groups beyond a file's owner and group,
+// as on shared directories and trees exported with Samba, and are lost
+// when only the mode is copied. Only Linux is supported, where ACLs are
+// kept in extended attributes, and a NotSupportedError is returned
+// elsewhere.
+func CopyACL(src, dst string) error {
+	return copyACL(src, dst)
+}
@@ -0,0 +1,56 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// The extended attributes Linux keeps POSIX ACLs in.
+var aclXattrs = []string{"system.posix_acl_access", "system.posix_acl_default"}
+
+func copyACL(src, dst string) error {
+	for _, name := range aclXattrs {
+		value, err := getACL(src, name)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			err = unix.Setxattr(dst, name, value, 0)
+			if err != nil {
+				return &os.PathError{Op: "setxattr", Path: dst, Err: err}
+			}
+			continue
+		}
+		err = unix.Removexattr(dst, name)
+		if err != nil && !errors.Is(err, unix.ENODATA) && !errors.Is(err, unix.EOPNOTSUPP) {
+			return &os.PathError{Op: "removexattr", Path: dst, Err: err}
+		}
+	}
+	return nil
+}
+
+// Return the ACL of path kept in the extended attribute name, or nil if it
+// doesn't have one, including if its filesystem doesn't support ACLs.
+func getACL(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(path, name, nil)
+		if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.EOPNOTSUPP) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		buf := make([]byte, size)
+		size, err = unix.Getxattr(path, name, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// The ACL changed between the calls
+			continue
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		return buf[:size], nil
+	}
+}
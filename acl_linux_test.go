@@ -0,0 +1,72 @@
+package shutil
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+// Encode a POSIX ACL as Linux keeps it in an extended attribute, from
+// tags, permissions and IDs.
+func encodeACL(entries ...[3]uint32) []byte {
+	buf := make([]byte, 4+8*len(entries))
+	binary.LittleEndian.PutUint32(buf, 2)
+	for i, e := range entries {
+		entry := buf[4+8*i:]
+		binary.LittleEndian.PutUint16(entry, uint16(e[0]))
+		binary.LittleEndian.PutUint16(entry[2:], uint16(e[1]))
+		binary.LittleEndian.PutUint32(entry[4:], e[2])
+	}
+	return buf
+}
+
+func TestCopyACL(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	const (
+		userObj  = 0x01
+		user     = 0x02
+		groupObj = 0x04
+		mask     = 0x10
+		other    = 0x20
+		noID     = 0xffffffff
+	)
+	acl := encodeACL(
+		[3]uint32{userObj, 6, noID},
+		[3]uint32{user, 4, 1234},
+		[3]uint32{groupObj, 4, noID},
+		[3]uint32{mask, 4, noID},
+		[3]uint32{other, 4, noID},
+	)
+	src := makeTestPath("testdir")
+	err := unix.Setxattr(src, aclXattrs[0], acl, 0)
+	if errors.Is(err, unix.EOPNOTSUPP) {
+		t.Skip("ACLs not supported")
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(unix.Setxattr(src, aclXattrs[1], acl, 0)).To(Succeed())
+
+	dst := makeTestPath("testdir3")
+	g.Expect(os.Mkdir(dst, 0755)).To(Succeed())
+	g.Expect(CopyACL(src, dst)).To(Succeed())
+	for _, name := range aclXattrs {
+		value, err := getACL(dst, name)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(value).To(Equal(acl))
+	}
+
+	// A file without an ACL takes it away
+	g.Expect(CopyACL(makeTestPath("testfile"), dst)).To(Succeed())
+	g.Expect(getACL(dst, aclXattrs[0])).To(BeNil())
+
+	g.Expect(unix.Setxattr(makeTestPath("testdir/file1"), aclXattrs[0], acl, 0)).To(Succeed())
+	_, err = CopyContext(context.Background(), makeTestPath("testdir/file1"), makeTestPath("file2"), &CopyOptions{PreserveACLs: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(getACL(makeTestPath("file2"), aclXattrs[0])).To(Equal(acl))
+}
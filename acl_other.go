@@ -0,0 +1,7 @@
+//go:build !linux
+
+package shutil
+
+func copyACL(src, dst string) error {
+	return &NotSupportedError{"copy ACLs", dst}
+}
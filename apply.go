@@ -0,0 +1,392 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How often Apply() saves its checkpoint to the StateFile, besides when it
+// returns.
+var stateSaveInterval = time.Second
+
+// Options for Apply().
+type ApplyOptions struct {
+	// The number of files copied at once.
+	Parallel int
+
+	// The most files the copies may have open at once, which limits how
+	// many are copied at once. Zero is half the process's limit, where
+	// the platform has one, such as RLIMIT_NOFILE.
+	MaxOpenFiles int
+
+	// The order the files are copied in, which by default is the plan's.
+	// LargestFirst suits copying in parallel.
+	Order CopyOrder
+
+	// Passed to CopyContext() for each file. Directories and symbolic
+	// links are given their sources' times and owners as requested too.
+	CopyOptions *CopyOptions
+
+	// Called after each file has been copied.
+	Progress ProgressFunc
+
+	// Called with each change that is made, and each that fails.
+	Events EventFunc
+
+	// Skip files that are removed from the source after the plan was
+	// made, rather than failing, as CopyTreeOptions.SkipVanished does.
+	SkipVanished bool
+
+	// The checkpoint of an earlier, interrupted Apply() of the same plan.
+	// The actions it lists as done are skipped.
+	Resume *Checkpoint
+
+	// A file the checkpoint is saved to as the plan is applied, so that it
+	// can be resumed by another process, such as after a crash. If the
+	// file exists and Resume isn't set, the plan is resumed from it. It is
+	// removed once the whole plan is done.
+	StateFile string
+}
+
+// Reports whether Apply() should start copying the file of action a
+// before that of action b.
+type CopyOrder func(a, b Action) bool
+
+// A CopyOrder that copies the largest files first. When files are copied
+// in parallel, a large file then doesn't leave the copy waiting on it
+// alone at the end, and progress by bytes gives a steadier estimate of
+// the time left.
+func LargestFirst(a, b Action) bool {
+	return actionSize(a) > actionSize(b)
+}
+
+func actionSize(action Action) int64 {
+	if action.Info == nil {
+		return 0
+	}
+	return action.Info.Size()
+}
+
+// How far Apply() got through a plan.
+type Checkpoint struct {
+	// The indexes in Plan.Actions of the actions that are done, in order.
+	// A directory is only done once its metadata has been set, after
+	// everything inside it is done.
+	Done []int
+}
+
+// Report whether the action at index i of the plan is done.
+func (c Checkpoint) IsDone(i int) bool {
+	n := sort.SearchInts(c.Done, i)
+	return n < len(c.Done) && c.Done[n] == i
+}
+
+// What Apply() did.
+type ApplyResult struct {
+	// What was created by this call, not counting an earlier one being
+	// resumed.
+	TreeResult
+
+	// Where the plan got to, which can be passed back to Apply() to resume
+	// it if it failed.
+	Checkpoint Checkpoint
+}
+
+// Make the changes in plan, as made by PlanCopyTree() or PlanMove().
+// Directories and symbolic links are created first, in order, then the
+// files are copied, in parallel if requested, and the directories are
+// given their sources' metadata. Renames and removals come last, so that
+// a move only removes its source once the copy is complete.
+//
+// If Apply() fails or its context is cancelled, the checkpoint in the
+// result records what is done. Passing it back as the Resume option
+// carries on from there. Directories and symbolic links that an
+// interrupted call already created are accepted, and files are copied
+// over, so resuming is safe even if the checkpoint is a little behind.
+//
+// With the StateFile option, the checkpoint is also saved to a file as
+// Apply() goes, which a later Apply() of the same plan, perhaps by
+// another process, picks up. Files copied with the Verify option aren't
+// marked done until they have been verified, so they aren't read again.
+func Apply(ctx context.Context, plan Plan, options *ApplyOptions) (ApplyResult, error) {
+	if options == nil {
+		options = &ApplyOptions{}
+	}
+	a := &planApplier{ctx: ctx, options: options, plan: plan, done: map[int]bool{}}
+	if options.CopyOptions != nil {
+		a.copyOptions = *options.CopyOptions
+	}
+	a.copyOptions.PreserveOwner = a.copyOptions.PreserveOwner || plan.PreserveOwner
+
+	resume := options.Resume
+	if resume == nil && options.StateFile != "" {
+		checkpoint, err := LoadCheckpoint(options.StateFile, plan)
+		if err == nil {
+			resume = &checkpoint
+		} else if !os.IsNotExist(err) {
+			return ApplyResult{}, err
+		}
+	}
+	if resume != nil {
+		for _, i := range resume.Done {
+			a.done[i] = true
+		}
+	}
+	a.lastSave = time.Now()
+
+	err := a.apply(plan, resume != nil)
+	result := ApplyResult{TreeResult: a.result, Checkpoint: a.checkpoint()}
+	if options.StateFile != "" {
+		var stateErr error
+		if err == nil {
+			stateErr = os.Remove(options.StateFile)
+			if os.IsNotExist(stateErr) {
+				stateErr = nil
+			}
+		} else {
+			stateErr = SaveCheckpoint(options.StateFile, plan, result.Checkpoint)
+		}
+		if err == nil {
+			err = stateErr
+		}
+	}
+	return result, err
+}
+
+// The state of a single Apply() call.
+type planApplier struct {
+	ctx         context.Context
+	options     *ApplyOptions
+	plan        Plan
+	copyOptions CopyOptions
+
+	mu       sync.Mutex
+	done     map[int]bool
+	result   TreeResult
+	lastSave time.Time
+}
+
+func (a *planApplier) apply(plan Plan, resume bool) error {
+	var copies, dirs, others []int
+	for i, action := range plan.Actions {
+		if a.done[i] {
+			continue
+		}
+		if err := a.ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		switch action.Kind {
+		case ActionMkdir:
+			err = os.Mkdir(action.Dst, 0700)
+			if err == nil {
+				a.result.Dirs++
+				a.options.Events.send(Event{Kind: EventDirCreated, Src: action.Src, Dst: action.Dst})
+			} else if resume && os.IsExist(err) {
+				err = nil
+			} else if plan.DirsExistOK && os.IsExist(err) {
+				if dir, _ := isDirectory(action.Dst); dir {
+					err = nil
+				}
+			}
+			dirs = append(dirs, i)
+		case ActionSymlink:
+			err = a.symlink(action, resume)
+			if err == nil {
+				a.markDone(i)
+			}
+		case ActionCopy:
+			copies = append(copies, i)
+		default:
+			others = append(others, i)
+		}
+		if err != nil {
+			return a.fail(action, err)
+		}
+	}
+
+	err := a.copyFiles(plan, copies)
+	if err != nil {
+		return err
+	}
+
+	// Deepest first, as filling a directory changes its times
+	for n := len(dirs) - 1; n >= 0; n-- {
+		action := plan.Actions[dirs[n]]
+		err = os.Chmod(action.Dst, action.Info.Mode().Perm())
+		if err == nil {
+			var warnings []error
+			warnings, err = preserveMetadata(action.Src, action.Dst, action.Info, &a.copyOptions)
+			a.result.Warnings = append(a.result.Warnings, warnings...)
+		}
+		if err == nil && a.copyOptions.PreserveInodeFlags {
+			var warning *InodeFlagsWarning
+			warning, err = copyInodeFlags(action.Src, action.Dst)
+			if warning != nil {
+				a.result.Warnings = append(a.result.Warnings, warning)
+			}
+		}
+		if err != nil {
+			return a.fail(action, err)
+		}
+		a.markDone(dirs[n])
+	}
+
+	for _, i := range others {
+		action := plan.Actions[i]
+		event := Event{Src: action.Src, Dst: action.Dst}
+		switch action.Kind {
+		case ActionRename:
+			err = os.Rename(action.Src, action.Dst)
+			event.Kind = EventRenamed
+		case ActionRemove:
+			err = os.RemoveAll(action.Src)
+			event = Event{Kind: EventRemoved, Dst: action.Src}
+		}
+		if err != nil {
+			return a.fail(action, err)
+		}
+		a.options.Events.send(event)
+		a.markDone(i)
+	}
+	return nil
+}
+
+// Report that action failed with err, and return it.
+func (a *planApplier) fail(action Action, err error) error {
+	a.options.Events.send(Event{Kind: EventErrored, Src: action.Src, Dst: action.Dst, Err: err})
+	return err
+}
+
+func (a *planApplier) symlink(action Action, resume bool) error {
+	create := CreateLink
+	if a.plan.DirsExistOK {
+		create = replaceLink
+	}
+	err := create(action.Target, action.Dst)
+	created := err == nil
+	if created {
+		a.result.Symlinks++
+	} else if resume && os.IsExist(err) {
+		// Accept a link the call being resumed created
+		if target, readErr := os.Readlink(action.Dst); readErr == nil && target == action.Target {
+			err = nil
+		}
+	}
+	if err == nil {
+		var warnings []error
+		warnings, err = preserveMetadata(action.Src, action.Dst, action.Info, &a.copyOptions)
+		a.result.Warnings = append(a.result.Warnings, warnings...)
+	}
+	if err == nil && created {
+		a.options.Events.send(Event{Kind: EventSymlinkCreated, Src: action.Src, Dst: action.Dst})
+	}
+	return err
+}
+
+// Copy the files at the given indexes of the plan, in the order the
+// options ask for, stopping at the first error.
+func (a *planApplier) copyFiles(plan Plan, copies []int) error {
+	if a.options.Order != nil {
+		sort.SliceStable(copies, func(i, j int) bool {
+			return a.options.Order(plan.Actions[copies[i]], plan.Actions[copies[j]])
+		})
+	}
+	var (
+		firstErr error
+		progress = Progress{FilesTotal: len(copies)}
+		meter    = newProgressMeter()
+	)
+	for _, i := range copies {
+		progress.BytesTotal += actionSize(plan.Actions[i])
+	}
+	forEachParallel(len(copies), limitParallel(a.options.Parallel, a.options.MaxOpenFiles, fdsPerCopy), func(n int) {
+		a.mu.Lock()
+		stopped := firstErr != nil
+		a.mu.Unlock()
+		if stopped {
+			return
+		}
+		if err := a.ctx.Err(); err != nil {
+			a.mu.Lock()
+			firstErr = err
+			a.mu.Unlock()
+			return
+		}
+
+		action := plan.Actions[copies[n]]
+		copyOptions := a.copyOptions
+		copyOptions.FollowSymlinks = true
+		copied, err := CopyContext(a.ctx, action.Src, action.Dst, &copyOptions)
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.result.Bytes += copied.Bytes
+		a.result.Warnings = append(a.result.Warnings, copied.Warnings...)
+		switch {
+		case err == nil:
+			a.result.Files++
+			a.done[copies[n]] = true
+			a.saveState()
+			a.options.Events.send(Event{Kind: EventFileCopied, Src: action.Src, Dst: copied.Dst, Bytes: copied.Bytes, Digests: copied.Digests})
+		case a.options.SkipVanished && sourceVanished(action.Src, err):
+			a.result.Vanished = append(a.result.Vanished, action.Src)
+			a.done[copies[n]] = true
+			a.saveState()
+			a.options.Events.send(Event{Kind: EventSkipped, Src: action.Src, Dst: action.Dst, Reason: SkipVanished})
+			err = nil
+		default:
+			a.options.Events.send(Event{Kind: EventErrored, Src: action.Src, Dst: action.Dst, Err: err})
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		progress.Src = action.Src
+		progress.Dst = copied.Dst
+		progress.Err = err
+		progress.FilesDone++
+		progress.BytesDone += copied.Bytes
+		meter.update(&progress)
+		if a.options.Progress != nil {
+			a.options.Progress(progress)
+		}
+	})
+	return firstErr
+}
+
+func (a *planApplier) markDone(i int) {
+	a.mu.Lock()
+	a.done[i] = true
+	a.saveState()
+	a.mu.Unlock()
+}
+
+// Save the checkpoint to the StateFile if it hasn't been for a while. A
+// failure to save it isn't fatal, as the next save may succeed, and the
+// checkpoint is saved again when Apply() returns. Called with mu held.
+func (a *planApplier) saveState() {
+	if a.options.StateFile == "" || time.Since(a.lastSave) < stateSaveInterval {
+		return
+	}
+	if SaveCheckpoint(a.options.StateFile, a.plan, a.checkpointLocked()) == nil {
+		a.lastSave = time.Now()
+	}
+}
+
+func (a *planApplier) checkpoint() Checkpoint {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.checkpointLocked()
+}
+
+func (a *planApplier) checkpointLocked() Checkpoint {
+	done := make([]int, 0, len(a.done))
+	for i := range a.done {
+		done = append(done, i)
+	}
+	sort.Ints(done)
+	return Checkpoint{Done: done}
+}
@@ -0,0 +1,163 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestApply(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Chmod(makeTestPath("testdir"), 0750)).To(Succeed())
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+
+	plan, err := PlanCopyTree(src, dst, &CopyTreeOptions{Symlinks: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	result, err := Apply(context.Background(), plan, &ApplyOptions{Parallel: 2})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.TreeResult).To(Equal(TreeResult{Files: 2, Dirs: 1, Symlinks: 1, Bytes: 12}))
+	g.Expect(result.Checkpoint.Done).To(Equal([]int{0, 1, 2, 3}))
+
+	diff, err := TreesEqual(src, dst, &CompareOptions{Symlinks: true, Mode: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diff.Equal()).To(BeTrue())
+
+	plan, err = PlanMove(dst, makeTestPath("moved"))
+	g.Expect(err).NotTo(HaveOccurred())
+	result, err = Apply(context.Background(), plan, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Checkpoint.Done).To(Equal([]int{0}))
+	g.Expect(makeTestPath("moved/file1")).To(BeAnExistingFile())
+	g.Expect(dst).NotTo(BeADirectory())
+}
+
+func TestApplyLargestFirst(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.WriteFile(makeTestPath("testdir/large"), make([]byte, 100), 0644)).To(Succeed())
+	plan, err := PlanCopyTree(makeTestPath("testdir"), makeTestPath("out"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var copied []string
+	_, err = Apply(context.Background(), plan, &ApplyOptions{
+		Order:    LargestFirst,
+		Progress: func(p Progress) { copied = append(copied, filepath.Base(p.Src)) },
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(copied).To(Equal([]string{"large", "file1", "file2"}))
+}
+
+func TestApplySkipVanished(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	plan, err := PlanCopyTree(makeTestPath("testdir"), makeTestPath("out"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.Remove(makeTestPath("testdir/file1"))).To(Succeed())
+
+	result, err := Apply(context.Background(), plan, &ApplyOptions{SkipVanished: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.TreeResult).To(Equal(TreeResult{
+		Files:    1,
+		Dirs:     1,
+		Bytes:    6,
+		Vanished: []string{makeTestPath("testdir/file1")},
+	}))
+	g.Expect(result.Checkpoint.Done).To(HaveLen(3))
+}
+
+func TestApplyResume(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	info, err := os.Stat(makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	fileInfo, err := os.Stat(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	plan := Plan{Actions: []Action{
+		{Kind: ActionMkdir, Src: makeTestPath("testdir"), Dst: makeTestPath("out"), Info: info},
+		{Kind: ActionCopy, Src: makeTestPath("testfile"), Dst: makeTestPath("out/a"), Info: fileInfo},
+		{Kind: ActionCopy, Src: makeTestPath("missing"), Dst: makeTestPath("out/b"), Info: fileInfo},
+		{Kind: ActionSymlink, Dst: makeTestPath("out/link"), Target: "a", Info: fileInfo},
+	}}
+
+	result, err := Apply(context.Background(), plan, nil)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+	g.Expect(result.Files).To(Equal(1))
+	g.Expect(result.Checkpoint.Done).To(Equal([]int{1, 3}))
+	g.Expect(result.Checkpoint.IsDone(1)).To(BeTrue())
+	g.Expect(result.Checkpoint.IsDone(2)).To(BeFalse())
+
+	// Resuming only copies what failed, accepting what was created
+	g.Expect(os.WriteFile(makeTestPath("missing"), []byte("b"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("out/a"), []byte("changed"), 0644)).To(Succeed())
+	g.Expect(os.Remove(makeTestPath("out/link"))).To(Succeed())
+	g.Expect(os.Symlink("a", makeTestPath("out/link"))).To(Succeed())
+	checkpoint := result.Checkpoint
+	checkpoint.Done = checkpoint.Done[:1]
+	result, err = Apply(context.Background(), plan, &ApplyOptions{Resume: &checkpoint})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.TreeResult).To(Equal(TreeResult{Files: 1, Bytes: 1}))
+	g.Expect(result.Checkpoint.Done).To(Equal([]int{0, 1, 2, 3}))
+	g.Expect(os.ReadFile(makeTestPath("out/a"))).To(Equal([]byte("changed")))
+	g.Expect(os.ReadFile(makeTestPath("out/b"))).To(Equal([]byte("b")))
+
+	// Without a checkpoint, what exists isn't accepted
+	_, err = Apply(context.Background(), plan, nil)
+	g.Expect(os.IsExist(err)).To(BeTrue())
+}
+
+func TestApplyCancelled(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	plan, err := PlanCopyTree(makeTestPath("testdir"), makeTestPath("out"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := Apply(ctx, plan, nil)
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(result.Checkpoint.Done).To(BeEmpty())
+	g.Expect(makeTestPath("out")).NotTo(BeADirectory())
+}
+
+func TestApplyStateFile(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	info, err := os.Stat(makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	fileInfo, err := os.Stat(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	plan := Plan{Actions: []Action{
+		{Kind: ActionMkdir, Src: makeTestPath("testdir"), Dst: makeTestPath("out"), Info: info},
+		{Kind: ActionCopy, Src: makeTestPath("testfile"), Dst: makeTestPath("out/a"), Info: fileInfo},
+		{Kind: ActionCopy, Src: makeTestPath("missing"), Dst: makeTestPath("out/b"), Info: fileInfo},
+	}}
+	state := makeTestPath("state.json")
+
+	_, err = Apply(context.Background(), plan, &ApplyOptions{StateFile: state})
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+	checkpoint, err := LoadCheckpoint(state, plan)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(checkpoint.Done).To(Equal([]int{1}))
+
+	// A state file can't be used with another plan
+	_, err = LoadCheckpoint(state, Plan{Actions: plan.Actions[:2]})
+	g.Expect(err).To(BeAssignableToTypeOf(&StateMismatchError{}))
+
+	// Resuming picks up the state file, and removes it when done
+	g.Expect(os.WriteFile(makeTestPath("missing"), []byte("b"), 0644)).To(Succeed())
+	result, err := Apply(context.Background(), plan, &ApplyOptions{StateFile: state})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.TreeResult).To(Equal(TreeResult{Files: 1, Bytes: 1}))
+	g.Expect(state).NotTo(BeAnExistingFile())
+}
@@ -0,0 +1,402 @@
+package shutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Decides whether an entry of a tree is included in an archive. `name` is
+// the entry's path relative to the root of the tree, separated by slashes.
+// Leaving out a directory leaves out everything inside it too.
+type FilterFunc func(name string, info os.FileInfo) bool
+
+// Renames an entry of a tree as it is added to an archive. Returning ""
+// leaves the entry itself out, but not anything inside it.
+type TransformFunc func(name string) string
+
+// Options shared by the functions that stream a tree into an archive.
+type ArchiveOptions struct {
+	// Store symbolic links as links, rather than the files they point to.
+	Symlinks bool
+
+	// Called like CopyTreeOptions.Ignore for each directory of the tree.
+	Ignore IgnoreFunc
+
+	// Called for each entry that isn't ignored.
+	Filter FilterFunc
+
+	// Called for each entry that is included, to give its name in the
+	// archive.
+	Transform TransformFunc
+
+	// Store extended attributes, which only tar archives can hold.
+	Xattrs bool
+
+	// If set, used as the modification time of every entry instead of the
+	// file's, so that the archive doesn't depend on when the tree was
+	// written.
+	ModTime time.Time
+
+	// Make the archive depend only on the names, contents, modes and link
+	// targets of the entries, so the same tree always gives a byte for
+	// byte identical archive. Times are set to ModTime, or to the start of
+	// 1980 if that isn't set, owners are cleared and extended attributes
+	// are left out. Entries are always written in order of name within
+	// each directory.
+	Reproducible bool
+}
+
+// The modification time of the entries of reproducible archives without a
+// ModTime. It is the earliest time zip archives can represent.
+var reproducibleModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Return the modification time every entry should be given, if any.
+func (o *ArchiveOptions) fixedModTime() time.Time {
+	if o.ModTime.IsZero() && o.Reproducible {
+		return reproducibleModTime
+	}
+	return o.ModTime
+}
+
+// Options for MakeArchive().
+type MakeArchiveOptions struct {
+	ArchiveOptions
+
+	// Passed to the compressor of compressed formats.
+	Compress *CompressOptions
+}
+
+// Returned by MakeArchive() for a format it doesn't know.
+type UnknownFormatError struct {
+	Format string
+}
+
+func (e UnknownFormatError) Error() string {
+	return fmt.Sprintf("unknown archive format `%s`", e.Format)
+}
+
+func (e UnknownFormatError) Code() ErrorCode {
+	return CodeUnknownFormat
+}
+
+// An entry of a tree being archived.
+type archiveEntry struct {
+	// The name of the entry in the archive, separated by slashes.
+	name string
+	path string
+	info os.FileInfo
+	// The target of the entry, if it's a symbolic link being stored as one.
+	link string
+}
+
+// Write the tree rooted at src to w as a tar archive, without creating any
+// temporary files.
+//
+// Entries are named relative to src, which isn't itself included. Named
+// pipes and devices are stored, but sockets are left out as tar can't
+// represent them. The archive is finished when TarTree() returns, but w
+// isn't closed.
+func TarTree(src string, w io.Writer, options *ArchiveOptions) error {
+	if options == nil {
+		options = &ArchiveOptions{}
+	}
+	tw := tar.NewWriter(w)
+	err := walkArchive(src, options, func(entry archiveEntry) error {
+		return writeTarEntry(tw, entry, options)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// Write the tree rooted at src to w as a zip archive, like TarTree().
+//
+// Only directories, regular files and symbolic links are stored, as zip
+// can't represent other kinds of file. Unix modes, including whether an
+// entry is a symbolic link, are kept in the entries' external attributes.
+// Zip64 records are used as needed for files over 4GiB and archives of
+// more than 65535 entries.
+func ZipTree(src string, w io.Writer, options *ArchiveOptions) error {
+	if options == nil {
+		options = &ArchiveOptions{}
+	}
+	zw := zip.NewWriter(w)
+	err := walkArchive(src, options, func(entry archiveEntry) error {
+		return writeZipEntry(zw, entry, options)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Return the archive formats MakeArchive() and UnpackArchive() take,
+// sorted, like Python's get_archive_formats(). They include "tar" and
+// "zip", and a compressed tar format for each registered compressor,
+// although some compressors, such as "bz", can only be unpacked.
+func ArchiveFormats() []string {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	formats := []string{"tar", "zip"}
+	for name := range compressors {
+		formats = append(formats, name+"tar")
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Create an archive file holding the tree rootDir, and return its name,
+// which is baseName plus the extension for the format.
+//
+// The format is "tar", "zip", or the name of a registered compressor
+// followed by "tar" for a compressed tar archive, such as "gztar". The
+// archive is written to a temporary file that is renamed into place, so a
+// partial archive is never left behind.
+func MakeArchive(baseName, format, rootDir string, options *MakeArchiveOptions) (string, error) {
+	if options == nil {
+		options = &MakeArchiveOptions{}
+	}
+
+	var extension string
+	var write func(w io.Writer) error
+	switch {
+	case format == "zip":
+		extension = ".zip"
+		write = func(w io.Writer) error {
+			return ZipTree(rootDir, w, &options.ArchiveOptions)
+		}
+	case format == "tar":
+		extension = ".tar"
+		write = func(w io.Writer) error {
+			return TarTree(rootDir, w, &options.ArchiveOptions)
+		}
+	case strings.HasSuffix(format, "tar"):
+		compressor, err := LookupCompressor(strings.TrimSuffix(format, "tar"))
+		if err != nil {
+			return "", &UnknownFormatError{format}
+		}
+		extension = ".tar" + compressor.Extension()
+		write = func(w io.Writer) error {
+			cw, err := compressor.NewWriter(w, options.Compress)
+			if err != nil {
+				return err
+			}
+			err = TarTree(rootDir, cw, &options.ArchiveOptions)
+			closeErr := cw.Close()
+			if err != nil {
+				return err
+			}
+			return closeErr
+		}
+	default:
+		return "", &UnknownFormatError{format}
+	}
+
+	archiveName := baseName + extension
+	err := replaceAtomic(archiveName, func(tmp string) error {
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			return err
+		}
+		err = write(f)
+		closeErr := f.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		return os.Chmod(tmp, 0644)
+	})
+	if err != nil {
+		return "", err
+	}
+	return archiveName, nil
+}
+
+func writeTarEntry(tw *tar.Writer, entry archiveEntry, options *ArchiveOptions) error {
+	if IsSocket(entry.info) {
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(entry.info, entry.link)
+	if err != nil {
+		return err
+	}
+	header.Name = entry.name
+	if entry.info.IsDir() {
+		header.Name += "/"
+	}
+	if modTime := options.fixedModTime(); !modTime.IsZero() {
+		header.ModTime = modTime
+		header.AccessTime = time.Time{}
+		header.ChangeTime = time.Time{}
+	}
+	if options.Reproducible {
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+	} else if options.Xattrs {
+		err = addTarXattrs(header, entry.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	if entry.info.Mode().IsRegular() {
+		return copyFileTo(tw, entry.path)
+	}
+	return nil
+}
+
+// The prefix of the PAX records that hold extended attributes.
+const paxXattrPrefix = "SCHILY.xattr."
+
+// Record the extended attributes of the file at path in a tar header.
+func addTarXattrs(header *tar.Header, path string) error {
+	names, err := listXattrs(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		value, err := getXattr(path, name)
+		if err != nil {
+			return err
+		}
+		if header.PAXRecords == nil {
+			header.PAXRecords = map[string]string{}
+		}
+		header.PAXRecords[paxXattrPrefix+name] = string(value)
+	}
+	if header.PAXRecords != nil {
+		header.Format = tar.FormatPAX
+	}
+	return nil
+}
+
+func writeZipEntry(zw *zip.Writer, entry archiveEntry, options *ArchiveOptions) error {
+	mode := entry.info.Mode()
+	if !mode.IsRegular() && !mode.IsDir() && !IsSymlink(entry.info) {
+		return nil
+	}
+
+	header, err := zip.FileInfoHeader(entry.info)
+	if err != nil {
+		return err
+	}
+	header.Name = entry.name
+	if mode.IsDir() {
+		header.Name += "/"
+	} else {
+		header.Method = zip.Deflate
+	}
+	if modTime := options.fixedModTime(); !modTime.IsZero() {
+		header.Modified = modTime
+	}
+
+	fw, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	switch {
+	case IsSymlink(entry.info):
+		_, err = io.WriteString(fw, entry.link)
+		return err
+	case mode.IsRegular():
+		return copyFileTo(fw, entry.path)
+	}
+	return nil
+}
+
+// Copy the contents of the file at path to w.
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Call visit for each entry of the tree rooted at src that should be
+// archived, parents before their contents.
+func walkArchive(src string, options *ArchiveOptions, visit func(archiveEntry) error) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &NotADirectoryError{src}
+	}
+	return walkArchiveDir(src, "", options, visit)
+}
+
+func walkArchiveDir(dir, prefix string, options *ArchiveOptions, visit func(archiveEntry) error) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	ignoredNames := []string{}
+	if options.Ignore != nil {
+		ignoredNames = options.Ignore(dir, entries)
+	}
+
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			continue
+		}
+		name := prefix + entry.Name()
+		e := archiveEntry{name: name, path: filepath.Join(dir, entry.Name())}
+
+		e.info, err = os.Lstat(e.path)
+		if err != nil {
+			return err
+		}
+		if IsSymlink(e.info) {
+			if options.Symlinks {
+				e.link, err = os.Readlink(e.path)
+			} else {
+				e.info, err = os.Stat(e.path)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if options.Filter != nil && !options.Filter(name, e.info) {
+			continue
+		}
+		if options.Transform != nil {
+			e.name = options.Transform(name)
+		}
+		if e.name != "" {
+			err = visit(e)
+			if err != nil {
+				return err
+			}
+		}
+
+		if e.info.IsDir() {
+			err = walkArchiveDir(e.path, name+"/", options, visit)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package shutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// Read back a tar archive as a map of entry names to their contents, or
+// link targets for symbolic links.
+func readTar(g *WithT, r io.Reader) map[string]string {
+	entries := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		g.Expect(err).NotTo(HaveOccurred())
+		contents, err := ioutil.ReadAll(tr)
+		g.Expect(err).NotTo(HaveOccurred())
+		if header.Typeflag == tar.TypeSymlink {
+			contents = []byte("-> " + header.Linkname)
+		}
+		entries[header.Name] = string(contents)
+	}
+}
+
+func readZip(g *WithT, data []byte) map[string]string {
+	entries := map[string]string{}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, f := range zr.File {
+		r, err := f.Open()
+		g.Expect(err).NotTo(HaveOccurred())
+		contents, err := ioutil.ReadAll(r)
+		g.Expect(err).NotTo(HaveOccurred())
+		r.Close()
+		if f.Mode()&os.ModeSymlink != 0 {
+			contents = []byte("-> " + string(contents))
+		}
+		entries[f.Name] = string(contents)
+	}
+	return entries
+}
+
+func TestTarTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+
+	var buf bytes.Buffer
+	g.Expect(TarTree(testdir, &buf, nil)).To(Succeed())
+	g.Expect(readTar(g, &buf)).To(Equal(map[string]string{
+		"link":          "testfile\n",
+		"testdir/":      "",
+		"testdir/file1": "file1\n",
+		"testdir/file2": "file2\n",
+		"testfile":      "testfile\n",
+		"testfile2":     "testfile2\n",
+	}))
+
+	buf.Reset()
+	g.Expect(TarTree(testdir, &buf, &ArchiveOptions{Symlinks: true})).To(Succeed())
+	g.Expect(readTar(g, &buf)).To(HaveKeyWithValue("link", "-> testfile"))
+}
+
+func TestTarTreeFilterTransform(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	var buf bytes.Buffer
+	err := TarTree(testdir, &buf, &ArchiveOptions{
+		Ignore: func(dir string, entries []os.FileInfo) []string {
+			return []string{"file1"}
+		},
+		Filter: func(name string, info os.FileInfo) bool {
+			return name != "testfile2"
+		},
+		Transform: func(name string) string {
+			if name == "testdir" {
+				return ""
+			}
+			return "root/" + strings.ToUpper(name)
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTar(g, &buf)).To(Equal(map[string]string{
+		"root/TESTDIR/FILE2": "file2\n",
+		"root/TESTFILE":      "testfile\n",
+	}))
+}
+
+func TestTarTreeNotADirectory(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	err := TarTree(makeTestPath("testfile"), ioutil.Discard, nil)
+	g.Expect(err).To(MatchError(&NotADirectoryError{makeTestPath("testfile")}))
+}
+
+func TestZipTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+
+	var buf bytes.Buffer
+	err := ZipTree(testdir, &buf, &ArchiveOptions{
+		Symlinks: true,
+		Filter: func(name string, info os.FileInfo) bool {
+			return name != "testdir"
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readZip(g, buf.Bytes())).To(Equal(map[string]string{
+		"link":      "-> testfile",
+		"testfile":  "testfile\n",
+		"testfile2": "testfile2\n",
+	}))
+}
+
+func TestZipTreeModes(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Chmod(makeTestPath("testfile"), 0600)).To(Succeed())
+	g.Expect(os.Chmod(makeTestPath("testfile2"), 0755)).To(Succeed())
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+
+	var buf bytes.Buffer
+	g.Expect(ZipTree(testdir, &buf, &ArchiveOptions{Symlinks: true})).To(Succeed())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	g.Expect(err).NotTo(HaveOccurred())
+	modes := map[string]os.FileMode{}
+	for _, f := range zr.File {
+		modes[f.Name] = f.Mode()
+	}
+	g.Expect(modes).To(HaveKeyWithValue("testfile", os.FileMode(0600)))
+	g.Expect(modes).To(HaveKeyWithValue("testfile2", os.FileMode(0755)))
+	g.Expect(modes["link"] & os.ModeSymlink).NotTo(BeZero())
+	g.Expect(modes["testdir/"].IsDir()).To(BeTrue())
+}
+
+func TestZipTreeManyEntries(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	info, err := os.Stat(makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// More entries than fit in a zip without Zip64 records
+	const count = 1<<16 + 10
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < count; i++ {
+		entry := archiveEntry{name: fmt.Sprintf("dir%d", i), info: info}
+		g.Expect(writeZipEntry(zw, entry, &ArchiveOptions{})).To(Succeed())
+	}
+	g.Expect(zw.Close()).To(Succeed())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(zr.File).To(HaveLen(count))
+}
+
+func TestArchiveModTime(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	fixed := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	options := &ArchiveOptions{ModTime: fixed, Xattrs: true}
+
+	var tarBuf bytes.Buffer
+	g.Expect(TarTree(testdir, &tarBuf, options)).To(Succeed())
+	tr := tar.NewReader(&tarBuf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(header.ModTime.Equal(fixed)).To(BeTrue(), header.Name)
+	}
+
+	var zipBuf bytes.Buffer
+	g.Expect(ZipTree(testdir, &zipBuf, options)).To(Succeed())
+	first := zipBuf.Bytes()
+
+	now := time.Now()
+	g.Expect(os.Chtimes(makeTestPath("testfile"), now, now)).To(Succeed())
+	var again bytes.Buffer
+	g.Expect(ZipTree(testdir, &again, options)).To(Succeed())
+	g.Expect(again.Bytes()).To(Equal(first))
+
+	zr, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, f := range zr.File {
+		g.Expect(f.Modified.Equal(fixed)).To(BeTrue(), f.Name)
+	}
+}
+
+func TestTarTreeReproducible(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	options := &ArchiveOptions{Reproducible: true, Xattrs: true}
+	var first bytes.Buffer
+	g.Expect(TarTree(makeTestPath("testdir"), &first, options)).To(Succeed())
+
+	// The same tree written at a different time
+	later := time.Now().Add(time.Hour)
+	g.Expect(CopyTree(makeTestPath("testdir"), makeTestPath("copy"), nil)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("copy/file1"), later, later)).To(Succeed())
+	var second bytes.Buffer
+	g.Expect(TarTree(makeTestPath("copy"), &second, options)).To(Succeed())
+	g.Expect(second.Bytes()).To(Equal(first.Bytes()))
+
+	tr := tar.NewReader(&first)
+	header, err := tr.Next()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(header.ModTime.Equal(reproducibleModTime)).To(BeTrue())
+	g.Expect(header.Uid).To(BeZero())
+	g.Expect(header.Uname).To(BeEmpty())
+	g.Expect(header.PAXRecords).To(BeEmpty())
+}
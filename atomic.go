@@ -0,0 +1,33 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Atomically replace dst with a file prepared by fill.
+//
+// An empty temporary file is created next to dst and passed to fill by
+// name. If fill succeeds the temporary file is renamed over dst, so
+// readers see either the old file or the complete new one. On failure the
+// temporary file is removed and dst is untouched.
+func replaceAtomic(dst string, fill func(tmp string) error) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := tmpFile.Name()
+	err = tmpFile.Close()
+
+	if err == nil {
+		err = fill(tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package shutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// A change an operation made to the filesystem, as an AuditLog records it.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+
+	// The EventKind of the change, such as "file-copied" or "removed".
+	Op string `json:"op"`
+
+	// What was changed, and where it was copied or renamed from.
+	Path string `json:"path"`
+	Src  string `json:"src,omitempty"`
+
+	// The amount of data copied, for "file-copied".
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// What was at Path before the change, where the operation had looked,
+	// and what is there after it, unless it was removed.
+	Old *AuditMetadata `json:"old,omitempty"`
+	New *AuditMetadata `json:"new,omitempty"`
+}
+
+// The metadata of a file in an AuditRecord.
+type AuditMetadata struct {
+	// The FileKind of the file, such as "regular file".
+	Kind string `json:"kind"`
+
+	// The permission bits, in octal, such as "0644".
+	Mode string `json:"mode"`
+
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+
+	// The numeric owner and group, where the platform has them.
+	UID *int `json:"uid,omitempty"`
+	GID *int `json:"gid,omitempty"`
+
+	// What a symbolic link points to, after the change.
+	Target string `json:"target,omitempty"`
+}
+
+// Writes an AuditRecord, as a line of JSON, for each change an operation
+// makes to the filesystem, so that what a migration did can be accounted
+// for. It records the Events of operations, which are passed to it with
+// its Events() method, and only those that change something: skipped
+// entries and errors aren't recorded. The log can be shared by several
+// operations running at once.
+type AuditLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// Return an AuditLog that writes to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// Return an EventFunc that records each event in the log and then passes
+// it to next, if that's set.
+func (l *AuditLog) Events(next EventFunc) EventFunc {
+	return func(event Event) {
+		l.Record(event)
+		next.send(event)
+	}
+}
+
+// Record the change event describes, if it's one, with the metadata the
+// changed path has now.
+func (l *AuditLog) Record(event Event) {
+	switch event.Kind {
+	case EventDirCreated, EventFileCopied, EventSymlinkCreated, EventUpdated, EventRemoved, EventRenamed:
+	default:
+		return
+	}
+	record := AuditRecord{
+		Time:  time.Now().UTC(),
+		Op:    event.Kind.String(),
+		Path:  event.Dst,
+		Src:   event.Src,
+		Bytes: event.Bytes,
+	}
+	if event.Old != nil {
+		record.Old = auditMetadata(event.Old)
+	}
+	if event.Kind != EventRemoved {
+		if info, err := os.Lstat(event.Dst); err == nil {
+			record.New = auditMetadata(info)
+			if IsSymlink(info) {
+				record.New.Target, _ = os.Readlink(event.Dst)
+			}
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		l.fail(err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		_, l.err = l.w.Write(append(data, '\n'))
+	}
+}
+
+func (l *AuditLog) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// Return the first error writing the log, after which nothing more is
+// written, so that a log that is incomplete can be told apart.
+func (l *AuditLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Describe the file info describes. What a symbolic link points to is left
+// to the caller, as it may have changed since info was taken.
+func auditMetadata(info os.FileInfo) *AuditMetadata {
+	meta := &AuditMetadata{
+		Kind:    KindOf(info.Mode()).String(),
+		Mode:    fmt.Sprintf("%04o", info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime().UTC(),
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		meta.UID, meta.GID = &uid, &gid
+	}
+	return meta
+}
@@ -0,0 +1,75 @@
+package shutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestAuditLog(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	shutiltest.CreateTree(t, dst, shutiltest.Tree{
+		"file1": shutiltest.File("old contents\n"),
+		"stale": shutiltest.File("stale\n"),
+	})
+	var buf bytes.Buffer
+	log := NewAuditLog(&buf)
+	var events int
+	_, err := SyncTree(context.Background(), makeTestPath("testdir"), dst, &SyncTreeOptions{
+		Delete: true,
+		Events: log.Events(func(Event) { events++ }),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(log.Err()).NotTo(HaveOccurred())
+
+	records := map[string]AuditRecord{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record AuditRecord
+		g.Expect(json.Unmarshal(scanner.Bytes(), &record)).To(Succeed())
+		records[record.Path] = record
+	}
+	g.Expect(records).To(HaveLen(3))
+	g.Expect(events).To(BeNumerically(">=", 3))
+
+	copied := records[makeTestPath("out/file1")]
+	g.Expect(copied.Op).To(Equal("file-copied"))
+	g.Expect(copied.Src).To(Equal(makeTestPath("testdir/file1")))
+	g.Expect(copied.Old.Size).To(Equal(int64(len("old contents\n"))))
+	g.Expect(copied.New.Size).To(Equal(int64(len("file1\n"))))
+	g.Expect(copied.New.Kind).To(Equal("regular file"))
+	g.Expect(copied.Time).NotTo(BeZero())
+
+	removed := records[makeTestPath("out/stale")]
+	g.Expect(removed.Op).To(Equal("removed"))
+	g.Expect(removed.Old.Size).To(Equal(int64(len("stale\n"))))
+	g.Expect(removed.New).To(BeNil())
+
+	g.Expect(records[makeTestPath("out/file2")].Old).To(BeNil())
+
+	// Skipped entries aren't changes
+	buf.Reset()
+	_, err = SyncTree(context.Background(), makeTestPath("testdir"), dst, &SyncTreeOptions{Events: log.Events(nil)})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(buf.Len()).To(BeZero())
+
+	// The first error writing the log is kept
+	log = NewAuditLog(failingWriter{})
+	log.Record(Event{Kind: EventRemoved, Dst: dst})
+	g.Expect(log.Err()).To(MatchError("disk full"))
+}
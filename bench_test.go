@@ -0,0 +1,193 @@
+package shutil
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+)
+
+// Sizes of the benchmark trees, which can be scaled up to measure changes
+// that only show on large trees, e.g.
+//
+//	go test -run '^$' -bench . -count 10 -bench.files 10000 > new.txt
+//	benchstat old.txt new.txt
+var (
+	benchFiles    = flag.Int("bench.files", 1000, "number of small files in the small file benchmarks")
+	benchFileSize = flag.Int64("bench.filesize", 4<<10, "size of each small file in bytes")
+	benchHugeSize = flag.Int64("bench.hugesize", 64<<20, "size of the file in the huge file benchmarks")
+	benchDepth    = flag.Int("bench.depth", 64, "depth of the deep tree benchmarks")
+	benchSymlinks = flag.Int("bench.symlinks", 1000, "number of symbolic links in the symlink benchmarks")
+)
+
+// Create the tree described by spec once, returning its root and the
+// number of bytes of files in it.
+func benchTree(b *testing.B, spec shutiltest.TreeSpec) (string, int64) {
+	b.Helper()
+	root := filepath.Join(b.TempDir(), "src")
+	shutiltest.CreateTree(b, root, shutiltest.GenerateTree(spec))
+	_, files, _ := spec.Count()
+	return root, int64(files) * spec.FileSize
+}
+
+// Count the calls copies make to look up files, returning a function
+// that reports them per iteration as the "stats/op" metric.
+func countStats(b *testing.B) func() {
+	var calls int64
+	oldStat, oldLstat := statFile, lstatFile
+	statFile = func(name string) (os.FileInfo, error) {
+		atomic.AddInt64(&calls, 1)
+		return oldStat(name)
+	}
+	lstatFile = func(name string) (os.FileInfo, error) {
+		atomic.AddInt64(&calls, 1)
+		return oldLstat(name)
+	}
+	b.Cleanup(func() { statFile, lstatFile = oldStat, oldLstat })
+	return func() {
+		b.ReportMetric(float64(atomic.LoadInt64(&calls))/float64(b.N), "stats/op")
+	}
+}
+
+// Copy src with CopyTreeContext() b.N times, each to a fresh destination,
+// removing the copies outside of the timed part of the loop.
+func benchCopyTree(b *testing.B, src string, bytes int64, options *CopyTreeOptions) {
+	b.Helper()
+	dstDir := b.TempDir()
+	b.SetBytes(bytes)
+	b.ReportAllocs()
+	defer countStats(b)()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst := filepath.Join(dstDir, "dst")
+		_, err := CopyTreeContext(context.Background(), src, dst, options)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		if err := os.RemoveAll(dst); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+	}
+}
+
+func BenchmarkCopyTreeSmallFiles(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Files: *benchFiles, FileSize: *benchFileSize})
+	benchCopyTree(b, src, bytes, nil)
+}
+
+func BenchmarkCopyTreeSmallFilesIOUring(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Files: *benchFiles, FileSize: *benchFileSize})
+	benchCopyTree(b, src, bytes, &CopyTreeOptions{CopyOptions: &CopyOptions{Engine: EngineIOUring}})
+}
+
+// Without reading small files whole, for comparison.
+func BenchmarkCopyTreeSmallFilesChunked(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Files: *benchFiles, FileSize: *benchFileSize})
+	benchCopyTree(b, src, bytes, &CopyTreeOptions{CopyOptions: &CopyOptions{SmallFileSize: -1}})
+}
+
+func BenchmarkCopyTreeWideTree(b *testing.B) {
+	// Spread the files over 2 levels of 10 directories
+	spec := shutiltest.TreeSpec{Depth: 2, Fanout: 10, FileSize: *benchFileSize}
+	dirs, _, _ := spec.Count()
+	spec.Files = *benchFiles / (dirs + 1)
+	src, bytes := benchTree(b, spec)
+	benchCopyTree(b, src, bytes, nil)
+}
+
+func BenchmarkCopyTreeDeepTree(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Depth: *benchDepth, Fanout: 1, Files: 1, FileSize: *benchFileSize})
+	benchCopyTree(b, src, bytes, nil)
+}
+
+func BenchmarkCopyTreeSymlinks(b *testing.B) {
+	spec := shutiltest.TreeSpec{Files: 1, FileSize: *benchFileSize, Symlinks: *benchSymlinks}
+	src, _ := benchTree(b, spec)
+
+	b.Run("copy-links", func(b *testing.B) {
+		benchCopyTree(b, src, 0, &CopyTreeOptions{Symlinks: true})
+	})
+	b.Run("follow-links", func(b *testing.B) {
+		benchCopyTree(b, src, int64(spec.Symlinks+1)*spec.FileSize, nil)
+	})
+}
+
+func BenchmarkCopyFileMedium(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Files: 1, FileSize: 16 << 20})
+	src = filepath.Join(src, "file0")
+
+	for _, engine := range []struct {
+		name   string
+		engine CopyEngine
+	}{{"default", EngineDefault}, {"mmap", EngineMmap}} {
+		options := &CopyOptions{Engine: engine.engine, Verify: true}
+		b.Run(engine.name, func(b *testing.B) {
+			dst := filepath.Join(b.TempDir(), "dst")
+			b.SetBytes(bytes)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := CopyFileContext(context.Background(), src, dst, options)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCopyFileHuge(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Files: 1, FileSize: *benchHugeSize})
+	src = filepath.Join(src, "file0")
+
+	for _, bufferSize := range []int{0, 1 << 20} {
+		options := &CopyOptions{BufferSize: bufferSize}
+		name := "default-buffer"
+		if bufferSize != 0 {
+			name = "1MiB-buffer"
+		}
+		b.Run(name, func(b *testing.B) {
+			dst := filepath.Join(b.TempDir(), "dst")
+			b.SetBytes(bytes)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := CopyFileContext(context.Background(), src, dst, options)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCopyFilesParallel(b *testing.B) {
+	src, bytes := benchTree(b, shutiltest.TreeSpec{Files: *benchFiles, FileSize: *benchFileSize})
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, parallel := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("parallel=%d", parallel), func(b *testing.B) {
+			dstDir := b.TempDir()
+			pairs := make([]SrcDst, len(entries))
+			for i, entry := range entries {
+				pairs[i] = SrcDst{filepath.Join(src, entry.Name()), filepath.Join(dstDir, entry.Name())}
+			}
+			options := &CopyFilesOptions{Parallel: parallel}
+			b.SetBytes(bytes)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := CopyFiles(pairs, options); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
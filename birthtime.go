@@ -0,0 +1,60 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// What preserving times does with creation times. Only some platforms can
+// set them: macOS and Windows can, and FreeBSD and NetBSD can only move
+// them earlier. Linux records them on most filesystems, but they can't be
+// set.
+type BirthtimePolicy int
+
+const (
+	// Preserve creation times where the platform and filesystem can, and
+	// leave them alone elsewhere.
+	BirthtimeBestEffort BirthtimePolicy = iota
+	// Fail with a NotSupportedError where they can't be preserved.
+	BirthtimeRequire
+	// Leave them alone.
+	BirthtimeIgnore
+)
+
+// Returned by setBirthtime() where creation times can't be set.
+var errNoBirthtime = errors.New("creation times can't be set")
+
+// Return the creation time of the named file, without following it if it
+// is a symbolic link. The error is a NotSupportedError if the platform or
+// filesystem doesn't record it.
+func Birthtime(name string) (time.Time, error) {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	birth, ok := fileBirthtime(name, info)
+	if !ok {
+		return time.Time{}, &NotSupportedError{"birthtime", name}
+	}
+	return birth, nil
+}
+
+// Give dst the creation time of src, which srcInfo describes, as policy
+// says.
+func copyBirthtime(src, dst string, srcInfo os.FileInfo, policy BirthtimePolicy) error {
+	if policy == BirthtimeIgnore {
+		return nil
+	}
+	err := errNoBirthtime
+	if birth, ok := fileBirthtime(src, srcInfo); ok {
+		err = setBirthtime(dst, birth, IsSymlink(srcInfo))
+	}
+	if err == errNoBirthtime {
+		if policy == BirthtimeRequire {
+			return &NotSupportedError{"preserve creation time", dst}
+		}
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+//go:build freebsd || netbsd
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Return the creation time of the file at path, which info describes.
+func fileBirthtime(path string, info os.FileInfo) (time.Time, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Birthtimespec.Sec < 0 {
+		// Filesystems that don't record it report -1
+		return time.Time{}, false
+	}
+	return time.Unix(int64(stat.Birthtimespec.Sec), int64(stat.Birthtimespec.Nsec)), true
+}
+
+// Set the creation time of a file, without following it if it is a
+// symbolic link. There's no call for it, but giving a file a modification
+// time earlier than its creation time moves the creation time back too,
+// so it can only be made earlier.
+func setBirthtime(path string, birth time.Time, link bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	current, ok := fileBirthtime(path, info)
+	if !ok || birth.After(current) {
+		return errNoBirthtime
+	}
+	atime, mtime := fileTimes(info)
+	if !birth.Before(mtime) {
+		return nil
+	}
+	err = lutimes(path, atime, birth)
+	if err != nil {
+		return err
+	}
+	return lutimes(path, atime, mtime)
+}
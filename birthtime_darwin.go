@@ -0,0 +1,36 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Return the creation time of the file at path, which info describes.
+func fileBirthtime(path string, info os.FileInfo) (time.Time, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec), true
+}
+
+// Set the creation time of a file, without following it if it is a
+// symbolic link.
+func setBirthtime(path string, birth time.Time, link bool) error {
+	ts := unix.NsecToTimespec(birth.UnixNano())
+	attrs := unix.Attrlist{Bitmapcount: unix.ATTR_BIT_MAP_COUNT, Commonattr: unix.ATTR_CMN_CRTIME}
+	buf := (*[unsafe.Sizeof(ts)]byte)(unsafe.Pointer(&ts))[:]
+	err := unix.Setattrlist(path, &attrs, buf, unix.FSOPT_NOFOLLOW)
+	if errors.Is(err, unix.ENOTSUP) {
+		return errNoBirthtime
+	}
+	if err != nil {
+		return &os.PathError{Op: "setattrlist", Path: path, Err: err}
+	}
+	return nil
+}
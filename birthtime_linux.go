@@ -0,0 +1,28 @@
+package shutil
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Return the creation time of the file at path, which info describes, if
+// the filesystem records it.
+func fileBirthtime(path string, info os.FileInfo) (time.Time, bool) {
+	flags := 0
+	if IsSymlink(info) {
+		flags = unix.AT_SYMLINK_NOFOLLOW
+	}
+	var stat unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, flags, unix.STATX_BTIME, &stat)
+	if err != nil || stat.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Btime.Sec, int64(stat.Btime.Nsec)), true
+}
+
+// Linux has no way to set creation times.
+func setBirthtime(path string, birth time.Time, link bool) error {
+	return errNoBirthtime
+}
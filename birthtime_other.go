@@ -0,0 +1,17 @@
+//go:build !darwin && !freebsd && !linux && !netbsd && !windows
+
+package shutil
+
+import (
+	"os"
+	"time"
+)
+
+// Creation times aren't recorded on this platform.
+func fileBirthtime(path string, info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func setBirthtime(path string, birth time.Time, link bool) error {
+	return errNoBirthtime
+}
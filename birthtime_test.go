@@ -0,0 +1,52 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestBirthtime(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	birth, err := Birthtime(makeTestPath("testfile"))
+	var notSupported *NotSupportedError
+	if errors.As(err, &notSupported) {
+		t.Skipf("creation times not recorded: %v", err)
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(birth).To(BeTemporally("~", time.Now(), time.Minute))
+
+	_, err = Birthtime(makeTestPath("missing"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCopyBirthtime(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("testfile3")
+	_, err := CopyContext(context.Background(), src, dst, &CopyOptions{
+		PreserveTimes: true,
+		Birthtime:     BirthtimeRequire,
+	})
+	if runtime.GOOS == "linux" {
+		var notSupported *NotSupportedError
+		g.Expect(errors.As(err, &notSupported)).To(BeTrue())
+		g.Expect(notSupported.Path).To(Equal(dst))
+	} else {
+		g.Expect(err).NotTo(HaveOccurred())
+		srcBirth, err := Birthtime(src)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(Birthtime(dst)).To(Equal(srcBirth))
+	}
+
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{PreserveTimes: true})
+	g.Expect(err).NotTo(HaveOccurred())
+}
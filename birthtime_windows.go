@@ -0,0 +1,43 @@
+package shutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Return the creation time of the file at path, which info describes.
+func fileBirthtime(path string, info os.FileInfo) (time.Time, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
+
+// Set the creation time of a file, without following it if it is a
+// symbolic link.
+func setBirthtime(path string, birth time.Time, link bool) error {
+	pathp, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return &os.PathError{Op: "setbirthtime", Path: path, Err: err}
+	}
+	// Directories can only be opened with backup semantics
+	flags := uint32(syscall.FILE_FLAG_BACKUP_SEMANTICS)
+	if link {
+		flags |= syscall.FILE_FLAG_OPEN_REPARSE_POINT
+	}
+	h, err := syscall.CreateFile(pathp, syscall.FILE_WRITE_ATTRIBUTES,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, flags, 0)
+	if err != nil {
+		return &os.PathError{Op: "setbirthtime", Path: path, Err: err}
+	}
+	defer syscall.CloseHandle(h)
+	ft := syscall.NsecToFiletime(birth.UnixNano())
+	err = syscall.SetFileTime(h, &ft, nil, nil)
+	if err != nil {
+		return &os.PathError{Op: "setbirthtime", Path: path, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package shutil
+
+// How copies use the page cache, which by default keeps what was copied in
+// memory, pushing out what was there before.
+type CacheBehavior int
+
+const (
+	// Leave it to the platform.
+	CacheDefault CacheBehavior = iota
+	// Tell the platform sources are read from start to end, so it reads
+	// further ahead and drops what was read sooner.
+	CacheSequential
+	// As CacheSequential, and also drop large files from the cache once
+	// they're copied, so a huge copy doesn't push the working set of
+	// everything else out of it. The copies are written out first, which
+	// makes each copy slower.
+	CacheDrop
+)
+
+// Files smaller than this are copied through the page cache even with the
+// DirectIO option or CacheDrop, as caching them costs little.
+const largeFileSize = 1 << 20
@@ -0,0 +1,24 @@
+package shutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Tell the platform f is about to be read from start to end. It's only a
+// hint, so it doesn't matter if it fails.
+func adviseSequential(f *os.File) {
+	unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_SEQUENTIAL)
+}
+
+// Drop the data of the files from the page cache, writing out what hasn't
+// been written first, which the cache would keep otherwise.
+func dropCache(files ...*os.File) {
+	for _, f := range files {
+		fd := int(f.Fd())
+		unix.SyncFileRange(fd, 0, 0,
+			unix.SYNC_FILE_RANGE_WAIT_BEFORE|unix.SYNC_FILE_RANGE_WRITE|unix.SYNC_FILE_RANGE_WAIT_AFTER)
+		unix.Fadvise(fd, 0, 0, unix.FADV_DONTNEED)
+	}
+}
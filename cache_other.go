@@ -0,0 +1,10 @@
+//go:build !linux
+
+package shutil
+
+import "os"
+
+// The page cache can't be advised on this platform.
+func adviseSequential(f *os.File) {}
+
+func dropCache(files ...*os.File) {}
@@ -0,0 +1,25 @@
+package shutil
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// The extended attribute Linux keeps file capabilities in.
+const capabilityXattr = "security.capability"
+
+func copyCapabilities(src, dst string) error {
+	value, err := getXattr(src, capabilityXattr)
+	if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.EOPNOTSUPP) {
+		err = removeXattr(dst, capabilityXattr)
+		if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.EOPNOTSUPP) {
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return setXattr(dst, capabilityXattr, value)
+}
@@ -0,0 +1,54 @@
+package shutil
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+func TestPreserveCapabilities(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// cap_net_bind_service, permitted and effective, as a version 2
+	// vfs_cap_data
+	caps := make([]byte, 20)
+	binary.LittleEndian.PutUint32(caps, 0x02000001)
+	binary.LittleEndian.PutUint32(caps[4:], 1<<unix.CAP_NET_BIND_SERVICE)
+	src := makeTestPath("testfile")
+	err := unix.Setxattr(src, capabilityXattr, caps, 0)
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EPERM) {
+		t.Skip("can't set file capabilities")
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Changing the owner clears them, so they are copied after
+	info, err := os.Stat(src)
+	g.Expect(err).NotTo(HaveOccurred())
+	uid, gid, _ := fileOwner(info)
+	dst := makeTestPath("copied")
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{PreserveCapabilities: true, PreserveOwner: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(getXattr(dst, capabilityXattr)).To(Equal(caps))
+	g.Expect(os.Chown(dst, uid, gid)).To(Succeed())
+	_, err = getXattr(dst, capabilityXattr)
+	g.Expect(errors.Is(err, unix.ENODATA)).To(BeTrue())
+
+	// They're left behind otherwise
+	dst = makeTestPath("copied2")
+	_, err = CopyContext(context.Background(), src, dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = getXattr(dst, capabilityXattr)
+	g.Expect(errors.Is(err, unix.ENODATA)).To(BeTrue())
+
+	// A source without them takes them away
+	g.Expect(unix.Setxattr(dst, capabilityXattr, caps, 0)).To(Succeed())
+	g.Expect(copyCapabilities(makeTestPath("testfile2"), dst)).To(Succeed())
+	_, err = getXattr(dst, capabilityXattr)
+	g.Expect(errors.Is(err, unix.ENODATA)).To(BeTrue())
+}
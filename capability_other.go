@@ -0,0 +1,7 @@
+//go:build !linux
+
+package shutil
+
+func copyCapabilities(src, dst string) error {
+	return nil
+}
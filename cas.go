@@ -0,0 +1,105 @@
+package shutil
+
+import (
+	"context"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Returned by StorePath() and LinkFromStore() for a hash that isn't a
+// hex-encoded SHA-256 hash.
+type InvalidHashError struct {
+	Hash string
+}
+
+func (e InvalidHashError) Error() string {
+	return fmt.Sprintf("`%s` is not a SHA-256 hash", e.Hash)
+}
+
+func (e InvalidHashError) Code() ErrorCode {
+	return CodeInvalidHash
+}
+
+// Return where the file with the hex-encoded SHA-256 hash, as
+// StoreByHash() returns it, is kept in the content-addressed store
+// storeDir: in a directory named after the first two characters of the
+// hash, named after the rest of it, as git keeps its objects. An
+// InvalidHashError is returned if hash isn't a hex-encoded SHA-256 hash,
+// so it can't name anything outside the store.
+func StorePath(storeDir, hash string) (string, error) {
+	if len(hash) != 2*crypto.SHA256.Size() {
+		return "", &InvalidHashError{hash}
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", &InvalidHashError{hash}
+	}
+	return filepath.Join(storeDir, hash[:2], hash[2:]), nil
+}
+
+// Copy the file src into the content-addressed store storeDir, under its
+// SHA-256 hash, which is returned hex-encoded. The hash is taken as the
+// file is copied, so it's only read once, and the copy is only moved into
+// place once it's complete. If the store already has a file with that
+// hash, it's kept, so each distinct file is only stored once.
+//
+// Files in the store are read-only, as they're meant to be shared with
+// LinkFromStore(), and changing one would change every link to it.
+// Symbolic links are followed, and storeDir is created if it doesn't
+// exist.
+func StoreByHash(src, storeDir string) (string, error) {
+	err := os.MkdirAll(storeDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	tmpFile, err := os.CreateTemp(storeDir, ".tmp")
+	if err != nil {
+		return "", err
+	}
+	tmp := tmpFile.Name()
+	tmpFile.Close()
+	// Once it's been moved into place, there's nothing to remove
+	defer os.Remove(tmp)
+
+	result, err := CopyContext(context.Background(), src, tmp, &CopyOptions{
+		FollowSymlinks: true,
+		Hashes:         []crypto.Hash{crypto.SHA256},
+	})
+	if err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(result.Digests[crypto.SHA256])
+	stored, err := StorePath(storeDir, hash)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(stored); err == nil {
+		return hash, nil
+	}
+	err = os.MkdirAll(filepath.Dir(stored), 0755)
+	if err == nil {
+		err = os.Chmod(tmp, 0444)
+	}
+	if err == nil {
+		err = os.Rename(tmp, stored)
+	}
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Create dst as a hard link to the file with the hex-encoded SHA-256 hash
+// in the content-addressed store storeDir, which StoreByHash() put there,
+// so that trees can be put together from the store without copying any
+// data. Like os.Link(), dst mustn't exist, and has to be on the same
+// filesystem as the store.
+func LinkFromStore(storeDir, hash, dst string) error {
+	stored, err := StorePath(storeDir, hash)
+	if err != nil {
+		return err
+	}
+	return os.Link(stored, dst)
+}
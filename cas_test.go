@@ -0,0 +1,72 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestStoreByHash(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	store := makeTestPath("store")
+	data, err := os.ReadFile(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	hash, err := StoreByHash(makeTestPath("testfile"), store)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hash).To(Equal(sha256Hex(string(data))))
+	stored, err := StorePath(store, hash)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stored).To(Equal(filepath.Join(store, hash[:2], hash[2:])))
+	g.Expect(FilesEqual(makeTestPath("testfile"), stored, nil)).To(BeTrue())
+	info, err := os.Stat(stored)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0444)))
+
+	// Storing the same data again keeps what's stored, and leaves nothing
+	// else behind
+	g.Expect(os.WriteFile(makeTestPath("same"), data, 0644)).To(Succeed())
+	again, err := StoreByHash(makeTestPath("same"), store)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again).To(Equal(hash))
+	infoAgain, err := os.Stat(stored)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.SameFile(info, infoAgain)).To(BeTrue())
+	entries, err := os.ReadDir(store)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+
+	for _, invalid := range []string{"", "a", hash[1:], "zz" + hash[2:]} {
+		_, err = StorePath(store, invalid)
+		g.Expect(err).To(MatchError(&InvalidHashError{invalid}))
+	}
+}
+
+func TestLinkFromStore(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	store := makeTestPath("store")
+	hash, err := StoreByHash(makeTestPath("testfile"), store)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(os.Mkdir(makeTestPath("tree"), 0755)).To(Succeed())
+	for _, name := range []string{"a", "b"} {
+		g.Expect(LinkFromStore(store, hash, makeTestPath("tree/"+name))).To(Succeed())
+	}
+	a, err := os.Stat(makeTestPath("tree/a"))
+	g.Expect(err).NotTo(HaveOccurred())
+	b, err := os.Stat(makeTestPath("tree/b"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.SameFile(a, b)).To(BeTrue())
+	g.Expect(FilesEqual(makeTestPath("testfile"), makeTestPath("tree/a"), nil)).To(BeTrue())
+
+	var hashErr *InvalidHashError
+	g.Expect(errors.As(LinkFromStore(store, "../../etc/passwd", makeTestPath("c")), &hashErr)).To(BeTrue())
+	g.Expect(LinkFromStore(store, sha256Hex("missing"), makeTestPath("c"))).To(MatchError(os.ErrNotExist))
+}
@@ -0,0 +1,80 @@
+package shutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Returned when a state file was written for a different plan than the
+// one being applied, for example because the source has changed since.
+type StateMismatchError struct {
+	File string
+}
+
+func (e StateMismatchError) Error() string {
+	return fmt.Sprintf("`%s` is the state of a different plan", e.File)
+}
+
+func (e StateMismatchError) Code() ErrorCode {
+	return CodeStateMismatch
+}
+
+// What is written to a state file.
+type stateFile struct {
+	// Identifies the plan the checkpoint is for.
+	Plan string `json:"plan"`
+
+	Done []int `json:"done"`
+}
+
+// Write the checkpoint of plan to the state file at path, replacing it
+// atomically, so it can be read back by LoadCheckpoint() to resume the
+// plan after this process has exited.
+func SaveCheckpoint(path string, plan Plan, checkpoint Checkpoint) error {
+	data, err := json.Marshal(stateFile{Plan: planHash(plan), Done: checkpoint.Done})
+	if err != nil {
+		return err
+	}
+	return replaceAtomic(path, func(tmp string) error {
+		return os.WriteFile(tmp, data, 0644)
+	})
+}
+
+// Read the checkpoint of plan from the state file at path, as written by
+// SaveCheckpoint(). A StateMismatchError is returned if it was written for
+// any other plan, as its indexes would be meaningless.
+func LoadCheckpoint(path string, plan Plan) (Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	var state stateFile
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if state.Plan != planHash(plan) {
+		return Checkpoint{}, &StateMismatchError{path}
+	}
+	for _, i := range state.Done {
+		if i < 0 || i >= len(plan.Actions) {
+			return Checkpoint{}, &StateMismatchError{path}
+		}
+	}
+	sort.Ints(state.Done)
+	return Checkpoint{Done: state.Done}, nil
+}
+
+// Identify a plan by what its actions do, so a state file can't be used
+// to resume a different one.
+func planHash(plan Plan) string {
+	h := sha256.New()
+	for _, action := range plan.Actions {
+		fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\x00", action.Kind, action.Src, action.Dst, action.Target)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,465 @@
+// Command goshutil runs the operations of the shutil package from the
+// shell:
+//
+//	goshutil copy [flags] SRC DST
+//	goshutil copytree [flags] SRC DST
+//	goshutil move [flags] SRC DST
+//	goshutil rmtree [flags] PATH
+//	goshutil sync [flags] SRC DST
+//	goshutil archive [flags] BASENAME ROOT
+//	goshutil unpack [flags] ARCHIVE DST
+//
+// Run `goshutil COMMAND -h` for the flags of a command. Every command
+// takes -dry-run, to print what it would do without doing it, and -v, to
+// print each file as it is handled. The commands that copy or move take
+// -json, to print what they did as the JSON of a shutil.Report instead of
+// a summary, even when they fail, or -sarif, to print its errors and
+// warnings as SARIF for CI systems to annotate, and copy, copytree and
+// sync take -preset, to start from one of the shutil presets, such as
+// mirror. It exits with status 1 if the operation fails, and 2 if it is
+// used wrongly. Like rsync, copytree -skip-vanished exits with status 24
+// if files vanished from the source while they were being copied.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	shutil "github.com/gocardless/go-shutil"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// A subcommand, and the flags it takes.
+type command struct {
+	name  string
+	args  string
+	nargs int
+	flags func(c *cli, fs *flag.FlagSet)
+	run   func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"copy", "SRC DST", 2, (*cli).copyFlags, (*cli).copy},
+	{"copytree", "SRC DST", 2, (*cli).copyTreeFlags, (*cli).copyTree},
+	{"move", "SRC DST", 2, (*cli).jsonFlags, (*cli).move},
+	{"rmtree", "PATH", 1, (*cli).rmTreeFlags, (*cli).rmTree},
+	{"sync", "SRC DST", 2, (*cli).syncFlags, (*cli).sync},
+	{"archive", "BASENAME ROOT", 2, (*cli).archiveFlags, (*cli).archive},
+	{"unpack", "ARCHIVE DST", 2, nil, (*cli).unpack},
+}
+
+// The state of a single run of a command, with the values of its flags.
+type cli struct {
+	ctx    context.Context
+	stdout io.Writer
+
+	dryRun      bool
+	verbose     bool
+	json        bool
+	sarif       bool
+	ignore      stringList
+	ignoreFiles stringList
+	dirIgnore   string
+	parallel    int
+	verify      bool
+	symlinks    bool
+	vanished    bool
+	preserve    bool
+	delete      bool
+	force       bool
+	format      string
+	preset      string
+}
+
+// A flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Run the command line args, returning the exit status.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "goshutil: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	c := &cli{ctx: ctx, stdout: stdout}
+	fs := flag.NewFlagSet("goshutil "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: goshutil %s [flags] %s\n", cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&c.dryRun, "dry-run", false, "print what would be done without doing it")
+	fs.BoolVar(&c.verbose, "v", false, "print each file as it is handled")
+	if cmd.flags != nil {
+		cmd.flags(c, fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != cmd.nargs {
+		fs.Usage()
+		return 2
+	}
+
+	if err := cmd.run(c, fs.Args()); err != nil {
+		fmt.Fprintf(stderr, "goshutil %s: %s\n", cmd.name, err)
+		var vanished *vanishedError
+		if errors.As(err, &vanished) {
+			return 24
+		}
+		return 1
+	}
+	return 0
+}
+
+// Returned by a command that succeeded, except for files that vanished
+// from its source.
+type vanishedError struct {
+	files []string
+}
+
+func (e vanishedError) Error() string {
+	return fmt.Sprintf("%d files vanished before they could be copied", len(e.files))
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: goshutil COMMAND [flags] ARGS...")
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\n", cmd.name, cmd.args)
+	}
+}
+
+func (c *cli) jsonFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.json, "json", false, "print what was done as JSON")
+	fs.BoolVar(&c.sarif, "sarif", false, "print what went wrong as SARIF")
+}
+
+func (c *cli) presetFlags(fs *flag.FlagSet) {
+	var names []string
+	for _, preset := range shutil.Presets() {
+		names = append(names, preset.Name)
+	}
+	fs.StringVar(&c.preset, "preset", "", "start from a preset, which other flags add to: "+strings.Join(names, ", "))
+}
+
+func (c *cli) copyFlags(fs *flag.FlagSet) {
+	c.jsonFlags(fs)
+	c.presetFlags(fs)
+	fs.BoolVar(&c.verify, "verify", false, "read each copy back and compare it with its source")
+	fs.BoolVar(&c.preserve, "preserve", false, "preserve times, owners and extended attributes")
+}
+
+func (c *cli) copyTreeFlags(fs *flag.FlagSet) {
+	c.copyFlags(fs)
+	fs.BoolVar(&c.symlinks, "symlinks", false, "copy symbolic links as links, rather than what they point to")
+	c.ignoreFlags(fs)
+	fs.IntVar(&c.parallel, "parallel", 0, "copy up to this many files at once")
+	fs.BoolVar(&c.vanished, "skip-vanished", false, "skip files that are removed while they are being copied")
+}
+
+func (c *cli) ignoreFlags(fs *flag.FlagSet) {
+	fs.Var(&c.ignore, "ignore", "leave out names matching a pattern (can be repeated)")
+	fs.Var(&c.ignoreFiles, "ignore-file", "leave out what a gitignore-style file lists (can be repeated)")
+	fs.StringVar(&c.dirIgnore, "dir-ignore", "", "leave out what ignore files with this name, such as .gitignore, list in each directory")
+}
+
+func (c *cli) rmTreeFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.force, "force", false, "remove read-only entries too")
+}
+
+func (c *cli) syncFlags(fs *flag.FlagSet) {
+	c.jsonFlags(fs)
+	c.presetFlags(fs)
+	c.ignoreFlags(fs)
+	fs.BoolVar(&c.delete, "delete", false, "remove what isn't in SRC from DST")
+	fs.BoolVar(&c.preserve, "preserve", false, "compare and copy modes and times")
+}
+
+func (c *cli) archiveFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.format, "format", "gztar", "the archive format: tar, zip, gztar, or another registered compressor followed by tar")
+}
+
+// Combine the ignore flags into an IgnoreFunc, or nil if there are none.
+func (c *cli) ignoreFunc() (shutil.IgnoreFunc, error) {
+	var fns []shutil.IgnoreFunc
+	if len(c.ignore) > 0 {
+		fns = append(fns, shutil.IgnorePatterns(c.ignore...))
+	}
+	for _, name := range c.ignoreFiles {
+		fn, err := shutil.LoadIgnoreFile(name)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, fn)
+	}
+	if c.dirIgnore != "" {
+		fns = append(fns, shutil.IgnoreFilesNamed(c.dirIgnore))
+	}
+	if len(fns) == 0 {
+		return nil, nil
+	}
+	return shutil.CombineIgnore(fns...), nil
+}
+
+// Print an event of the operation, if -v was given.
+func (c *cli) event(event shutil.Event) {
+	if !c.verbose {
+		return
+	}
+	switch event.Kind {
+	case shutil.EventSkipped:
+		fmt.Fprintf(c.stdout, "%s %s (%s)\n", event.Kind, event.Src, event.Reason)
+	case shutil.EventRemoved:
+		fmt.Fprintf(c.stdout, "%s %s\n", event.Kind, event.Dst)
+	case shutil.EventErrored:
+		fmt.Fprintf(c.stdout, "%s %s: %s\n", event.Kind, event.Src, event.Err)
+	default:
+		fmt.Fprintf(c.stdout, "%s %s -> %s\n", event.Kind, event.Src, event.Dst)
+	}
+}
+
+func (c *cli) printPlan(plan shutil.Plan) {
+	for _, action := range plan.Actions {
+		switch action.Kind {
+		case shutil.ActionRemove:
+			fmt.Fprintf(c.stdout, "%s %s\n", action.Kind, action.Src)
+		case shutil.ActionSymlink:
+			fmt.Fprintf(c.stdout, "%s %s -> %s\n", action.Kind, action.Dst, action.Target)
+		default:
+			fmt.Fprintf(c.stdout, "%s %s -> %s\n", action.Kind, action.Src, action.Dst)
+		}
+	}
+}
+
+// Print the report of an Op that has run, as JSON if -json was given, its
+// problems as SARIF if -sarif was, or otherwise as summary if it
+// succeeded.
+func (c *cli) printReport(report shutil.Report, err error, summary string) error {
+	if c.sarif {
+		if sarifErr := shutil.WriteSARIF(c.stdout, report); sarifErr != nil {
+			return sarifErr
+		}
+	} else if c.json {
+		data, jsonErr := json.MarshalIndent(report, "", "  ")
+		if jsonErr != nil {
+			return jsonErr
+		}
+		fmt.Fprintf(c.stdout, "%s\n", data)
+	} else if err == nil && summary != "" {
+		fmt.Fprintln(c.stdout, summary)
+	}
+	return err
+}
+
+func treeSummary(report shutil.Report) string {
+	return fmt.Sprintf("%d files, %d directories, %d symlinks, %d bytes in %s",
+		report.Files, report.Dirs, report.Symlinks, report.Bytes, report.Duration)
+}
+
+// Apply the -preset flag to op, if it was given.
+func (c *cli) applyPreset(op *shutil.Op) error {
+	if c.preset == "" {
+		return nil
+	}
+	preset, err := shutil.LookupPreset(c.preset)
+	if err != nil {
+		return err
+	}
+	op.Preset(preset)
+	return nil
+}
+
+// Set up an Op with the flags common to copies.
+func (c *cli) newCopy(src, dst string) (*shutil.Op, error) {
+	op := shutil.NewCopy(src, dst).WithContext(c.ctx).Events(c.event)
+	if err := c.applyPreset(op); err != nil {
+		return nil, err
+	}
+	if c.verify {
+		op.Verify()
+	}
+	if c.preserve {
+		op.PreserveAll()
+	}
+	return op, nil
+}
+
+func (c *cli) copy(args []string) error {
+	if c.dryRun {
+		fmt.Fprintf(c.stdout, "copy %s -> %s\n", args[0], args[1])
+		return nil
+	}
+	op, err := c.newCopy(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	report, err := op.Run()
+	return c.printReport(report, err, "")
+}
+
+func (c *cli) copyTree(args []string) error {
+	ignore, err := c.ignoreFunc()
+	if err != nil {
+		return err
+	}
+	if c.dryRun {
+		plan, err := shutil.PlanCopyTree(args[0], args[1], &shutil.CopyTreeOptions{Symlinks: c.symlinks, Ignore: ignore})
+		if err != nil {
+			return err
+		}
+		c.printPlan(plan)
+		return nil
+	}
+
+	op, err := c.newCopy(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	op.Recursive().Ignore(ignore)
+	if c.parallel > 0 {
+		op.Parallel(c.parallel)
+	}
+	if c.symlinks {
+		op.Symlinks()
+	}
+	if c.vanished {
+		op.SkipVanished()
+	}
+	report, err := op.Run()
+	err = c.printReport(report, err, treeSummary(report))
+	if err == nil && len(report.Vanished) > 0 {
+		return &vanishedError{report.Vanished}
+	}
+	return err
+}
+
+func (c *cli) move(args []string) error {
+	if c.dryRun {
+		plan, err := shutil.PlanMove(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		c.printPlan(plan)
+		return nil
+	}
+	report, err := shutil.NewMove(args[0], args[1]).Events(c.event).Run()
+	return c.printReport(report, err, "")
+}
+
+func (c *cli) rmTree(args []string) error {
+	if c.dryRun {
+		fmt.Fprintf(c.stdout, "remove %s\n", args[0])
+		return nil
+	}
+	return shutil.RmTree(args[0], &shutil.RmTreeOptions{ForceWritable: c.force})
+}
+
+func (c *cli) sync(args []string) error {
+	ignore, err := c.ignoreFunc()
+	if err != nil {
+		return err
+	}
+	if c.dryRun {
+		return c.syncDryRun(args[0], args[1], ignore)
+	}
+
+	op := shutil.NewSync(args[0], args[1]).WithContext(c.ctx).Events(c.event).Ignore(ignore)
+	if err := c.applyPreset(op); err != nil {
+		return err
+	}
+	if c.delete {
+		op.Delete()
+	}
+	if c.preserve {
+		op.PreserveAll()
+	}
+	report, err := op.Run()
+	return c.printReport(report, err, fmt.Sprintf("%d copied, %d updated, %d unchanged, %d deleted, %d bytes in %s",
+		report.Files, report.Updated, report.Unchanged, report.Deleted, report.Bytes, report.Duration))
+}
+
+// Print what a sync would change, by comparing the trees.
+func (c *cli) syncDryRun(src, dst string, ignore shutil.IgnoreFunc) error {
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		fmt.Fprintf(c.stdout, "copy %s -> %s\n", src, dst)
+		return nil
+	}
+	diff, err := shutil.TreesEqual(src, dst, &shutil.CompareOptions{
+		Symlinks: true,
+		Mode:     c.preserve,
+		ModTime:  c.preserve,
+		Ignore:   ignore,
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range diff.OnlyInA {
+		fmt.Fprintf(c.stdout, "copy %s -> %s\n", filepath.Join(src, name), filepath.Join(dst, name))
+	}
+	for _, name := range diff.Differ {
+		fmt.Fprintf(c.stdout, "update %s -> %s\n", filepath.Join(src, name), filepath.Join(dst, name))
+	}
+	if c.delete {
+		for _, name := range diff.OnlyInB {
+			fmt.Fprintf(c.stdout, "remove %s\n", filepath.Join(dst, name))
+		}
+	}
+	return nil
+}
+
+func (c *cli) archive(args []string) error {
+	if c.dryRun {
+		fmt.Fprintf(c.stdout, "archive %s -> %s (%s)\n", args[1], args[0], c.format)
+		return nil
+	}
+	name, err := shutil.MakeArchive(args[0], c.format, args[1], nil)
+	if err == nil {
+		fmt.Fprintln(c.stdout, name)
+	}
+	return err
+}
+
+func (c *cli) unpack(args []string) error {
+	if c.dryRun {
+		fmt.Fprintf(c.stdout, "unpack %s -> %s\n", args[0], args[1])
+		return nil
+	}
+	return shutil.UnpackArchive(args[0], args[1], "", nil)
+}
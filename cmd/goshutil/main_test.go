@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	shutil "github.com/gocardless/go-shutil"
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+// Run goshutil with args, returning its exit status and output.
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(context.Background(), args, &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCopyTreeCommand(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	shutiltest.CreateTree(t, src, shutiltest.Tree{
+		"a":       shutiltest.File("a"),
+		"b.o":     shutiltest.File("b"),
+		"sub/c":   shutiltest.File("cc"),
+		"sub/d.o": shutiltest.File("d"),
+	})
+
+	status, stdout, _ := runCommand("copytree", "-dry-run", "-ignore", "*.o", src, dst)
+	g.Expect(status).To(Equal(0))
+	g.Expect(strings.Split(strings.TrimSpace(stdout), "\n")).To(Equal([]string{
+		"mkdir " + src + " -> " + dst,
+		"copy " + filepath.Join(src, "a") + " -> " + filepath.Join(dst, "a"),
+		"mkdir " + filepath.Join(src, "sub") + " -> " + filepath.Join(dst, "sub"),
+		"copy " + filepath.Join(src, "sub/c") + " -> " + filepath.Join(dst, "sub/c"),
+	}))
+	g.Expect(dst).NotTo(BeADirectory())
+
+	status, stdout, _ = runCommand("copytree", "-ignore", "*.o", "-parallel", "2", "-verify", src, dst)
+	g.Expect(status).To(Equal(0))
+	g.Expect(stdout).To(HavePrefix("2 files, 2 directories, 0 symlinks, 3 bytes in "))
+	g.Expect(filepath.Join(dst, "sub/c")).To(BeARegularFile())
+	g.Expect(filepath.Join(dst, "sub/d.o")).NotTo(BeAnExistingFile())
+
+	status, _, stderr := runCommand("copytree", src, dst)
+	g.Expect(status).To(Equal(1))
+	g.Expect(stderr).To(ContainSubstring("already exists"))
+
+	status, _, _ = runCommand("copytree", "-preset", "fast-deploy", src, dst)
+	g.Expect(status).To(Equal(0))
+	g.Expect(filepath.Join(dst, "sub/d.o")).To(BeARegularFile())
+
+	status, _, stderr = runCommand("copytree", "-preset", "turbo", src, dst)
+	g.Expect(status).To(Equal(1))
+	g.Expect(stderr).To(ContainSubstring("unknown preset `turbo`"))
+}
+
+func TestSyncCommand(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	shutiltest.CreateTree(t, src, shutiltest.Tree{"a": shutiltest.File("a"), "b": shutiltest.File("b")})
+	shutiltest.CreateTree(t, dst, shutiltest.Tree{"a": shutiltest.File("a"), "c": shutiltest.File("c")})
+
+	status, stdout, _ := runCommand("sync", "-dry-run", "-delete", src, dst)
+	g.Expect(status).To(Equal(0))
+	g.Expect(stdout).To(Equal("copy " + filepath.Join(src, "b") + " -> " + filepath.Join(dst, "b") + "\n" +
+		"remove " + filepath.Join(dst, "c") + "\n"))
+
+	status, stdout, _ = runCommand("sync", "-delete", "-v", src, dst)
+	g.Expect(status).To(Equal(0))
+	g.Expect(stdout).To(HavePrefix("skipped " + filepath.Join(src, "a") + " (unchanged)\n"))
+	g.Expect(stdout).To(ContainSubstring("1 copied, 0 updated, 1 unchanged, 1 deleted, 1 bytes in "))
+	g.Expect(shutil.TreesEqual(src, dst, nil)).To(HaveField("OnlyInB", BeEmpty()))
+}
+
+func TestArchiveCommands(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	shutiltest.CreateTree(t, src, shutiltest.Tree{"a": shutiltest.File("a"), "sub/b": shutiltest.File("b")})
+
+	for _, format := range []string{"gztar", "zip"} {
+		status, stdout, _ := runCommand("archive", "-format", format, filepath.Join(dir, format), src)
+		g.Expect(status).To(Equal(0))
+		archive := strings.TrimSpace(stdout)
+
+		dst := filepath.Join(dir, format+"-out")
+		status, _, _ = runCommand("unpack", archive, dst)
+		g.Expect(status).To(Equal(0))
+		diff, err := shutil.TreesEqual(src, dst, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(diff.Equal()).To(BeTrue())
+
+		status, _, _ = runCommand("rmtree", dst)
+		g.Expect(status).To(Equal(0))
+		g.Expect(dst).NotTo(BeADirectory())
+	}
+}
+
+func TestUsage(t *testing.T) {
+	g := NewWithT(t)
+
+	status, _, stderr := runCommand()
+	g.Expect(status).To(Equal(2))
+	g.Expect(stderr).To(ContainSubstring("copytree SRC DST"))
+
+	status, _, stderr = runCommand("frobnicate")
+	g.Expect(status).To(Equal(2))
+	g.Expect(stderr).To(ContainSubstring(`unknown command "frobnicate"`))
+
+	status, _, stderr = runCommand("move", "only-one")
+	g.Expect(status).To(Equal(2))
+	g.Expect(stderr).To(ContainSubstring("usage: goshutil move [flags] SRC DST"))
+}
+
+func TestJSONOutput(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	shutiltest.CreateTree(t, src, shutiltest.Tree{"a": shutiltest.File("a")})
+
+	status, stdout, _ := runCommand("copytree", "-json", src, filepath.Join(dir, "dst"))
+	g.Expect(status).To(Equal(0))
+	var report shutil.Report
+	g.Expect(json.Unmarshal([]byte(stdout), &report)).To(Succeed())
+	g.Expect(report.Op).To(Equal("copy"))
+	g.Expect(report.TreeResult).To(Equal(shutil.TreeResult{Files: 1, Dirs: 1, Bytes: 1}))
+
+	status, stdout, _ = runCommand("move", "-json", filepath.Join(dir, "missing"), filepath.Join(dir, "moved"))
+	g.Expect(status).To(Equal(1))
+	report = shutil.Report{}
+	g.Expect(json.Unmarshal([]byte(stdout), &report)).To(Succeed())
+	g.Expect(report.Errors).To(HaveLen(1))
+	g.Expect(report.Errors[0].Src).To(Equal(filepath.Join(dir, "missing")))
+}
+
+func TestSARIFOutput(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+
+	status, stdout, _ := runCommand("copy", "-sarif", filepath.Join(dir, "missing"), filepath.Join(dir, "dst"))
+	g.Expect(status).To(Equal(1))
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID string
+				Level  string
+			}
+		}
+	}
+	g.Expect(json.Unmarshal([]byte(stdout), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Runs).To(HaveLen(1))
+	g.Expect(log.Runs[0].Results).To(HaveLen(1))
+	g.Expect(log.Runs[0].Results[0].RuleID).To(Equal("not-exist"))
+	g.Expect(log.Runs[0].Results[0].Level).To(Equal("error"))
+}
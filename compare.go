@@ -0,0 +1,424 @@
+package shutil
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const compareChunkSize = 32 * 1024
+
+// Report whether a and b are the same file, following symbolic links, as
+// hard links or different names for the same file are. If either doesn't
+// exist they aren't, but any other error finding out is returned.
+func SamePath(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	bInfo, err := os.Stat(b)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return os.SameFile(aInfo, bInfo), nil
+}
+
+// Report whether files a and b have identical contents, following
+// symbolic links. Their sizes are compared first, and then they are read
+// a chunk at a time until they differ. Both must exist.
+func SameContent(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if os.SameFile(aInfo, bInfo) {
+		return true, nil
+	}
+	if aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+	return readersEqual(fa, fb)
+}
+
+// Report whether a and b read the same data, reading them a chunk at a
+// time until they differ.
+func readersEqual(a, b io.Reader) (bool, error) {
+	bufA := make([]byte, compareChunkSize)
+	bufB := make([]byte, compareChunkSize)
+	for {
+		nA, errA := io.ReadFull(a, bufA)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return false, errA
+		}
+		nB, errB := io.ReadFull(b, bufB)
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, errB
+		}
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		// A short read means the end of that file
+		if errA != nil || errB != nil {
+			return errA != nil && errB != nil, nil
+		}
+	}
+}
+
+// Options for FilesEqual() and TreesEqual(). By default only the kinds and
+// contents of files are compared.
+type CompareOptions struct {
+	// Compare symbolic links themselves, by their targets, rather than
+	// the files they point to.
+	Symlinks bool
+
+	// Also compare permission bits.
+	Mode bool
+
+	// Also compare modification times.
+	ModTime bool
+
+	// How far apart modification times can be and still match, for
+	// filesystems that store them less precisely, such as FAT with its
+	// two seconds.
+	ModifyWindow time.Duration
+
+	// Called like CopyTreeOptions.Ignore for each directory of both trees,
+	// leaving out the names it returns from the comparison.
+	Ignore IgnoreFunc
+}
+
+// Report whether files a and b are equal: they are the same kind of file
+// and, if they are regular files, have the same contents, or if they are
+// symbolic links being compared themselves, the same targets. The contents
+// are compared a chunk at a time, once their sizes are known to match.
+func FilesEqual(a, b string, options *CompareOptions) (bool, error) {
+	if options == nil {
+		options = &CompareOptions{}
+	}
+	aInfo, err := compareStat(a, options)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := compareStat(b, options)
+	if err != nil {
+		return false, err
+	}
+	return filesEqual(a, b, aInfo, bInfo, options)
+}
+
+func compareStat(name string, options *CompareOptions) (os.FileInfo, error) {
+	if options.Symlinks {
+		return os.Lstat(name)
+	}
+	return os.Stat(name)
+}
+
+func filesEqual(a, b string, aInfo, bInfo os.FileInfo, options *CompareOptions) (bool, error) {
+	if aInfo.Mode().Type() != bInfo.Mode().Type() {
+		return false, nil
+	}
+	if options.Mode && aInfo.Mode().Perm() != bInfo.Mode().Perm() {
+		return false, nil
+	}
+	if options.ModTime && !TimesEqual(aInfo.ModTime(), bInfo.ModTime(), options.ModifyWindow) {
+		return false, nil
+	}
+
+	switch {
+	case aInfo.Mode().IsRegular():
+		return SameContent(a, b)
+	case IsSymlink(aInfo):
+		aTarget, err := os.Readlink(a)
+		if err != nil {
+			return false, err
+		}
+		bTarget, err := os.Readlink(b)
+		if err != nil {
+			return false, err
+		}
+		return aTarget == bTarget, nil
+	}
+	return true, nil
+}
+
+// The differences between two trees found by TreesEqual(). Names are
+// relative to the roots of the trees, separated by slashes, and sorted.
+type TreeDiff struct {
+	// Names only in the first tree. The contents of directories aren't
+	// listed as well.
+	OnlyInA []string
+
+	// Names only in the second tree.
+	OnlyInB []string
+
+	// Names in both trees that aren't equal, as FilesEqual() decides.
+	Differ []string
+}
+
+// Report whether there are no differences.
+func (d TreeDiff) Equal() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.Differ) == 0
+}
+
+// Recursively compare the directory trees a and b, reporting every name
+// that is in only one of them, or whose files aren't equal as FilesEqual()
+// decides. Directories in both trees are compared like files, so only
+// differ in their modes or times, and then their entries are compared.
+//
+// Unless the Symlinks option is set, symbolic links are followed, so a
+// link to a directory is compared as that directory.
+func TreesEqual(a, b string, options *CompareOptions) (TreeDiff, error) {
+	if options == nil {
+		options = &CompareOptions{}
+	}
+	var diff TreeDiff
+	for _, root := range []string{a, b} {
+		info, err := os.Stat(root)
+		if err != nil {
+			return diff, err
+		}
+		if !info.IsDir() {
+			return diff, &NotADirectoryError{root}
+		}
+	}
+
+	err := compareDirs(a, b, "", options, &diff)
+	if err != nil {
+		return diff, err
+	}
+	sort.Strings(diff.OnlyInA)
+	sort.Strings(diff.OnlyInB)
+	sort.Strings(diff.Differ)
+	return diff, nil
+}
+
+// Compare the entries of directories a and b. `prefix` is their name
+// relative to the roots of the trees, plus a slash unless they are the
+// roots.
+func compareDirs(a, b, prefix string, options *CompareOptions, diff *TreeDiff) error {
+	aEntries, err := compareEntries(a, options)
+	if err != nil {
+		return err
+	}
+	bEntries, err := compareEntries(b, options)
+	if err != nil {
+		return err
+	}
+
+	for name, aInfo := range aEntries {
+		bInfo, ok := bEntries[name]
+		if !ok {
+			diff.OnlyInA = append(diff.OnlyInA, prefix+name)
+			continue
+		}
+		aPath := filepath.Join(a, name)
+		bPath := filepath.Join(b, name)
+		equal, err := filesEqual(aPath, bPath, aInfo, bInfo, options)
+		if err != nil {
+			return err
+		}
+		if !equal {
+			diff.Differ = append(diff.Differ, prefix+name)
+		}
+		if aInfo.IsDir() && bInfo.IsDir() {
+			err = compareDirs(aPath, bPath, path.Join(prefix, name)+"/", options, diff)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for name := range bEntries {
+		if _, ok := aEntries[name]; !ok {
+			diff.OnlyInB = append(diff.OnlyInB, prefix+name)
+		}
+	}
+	return nil
+}
+
+// Describe the entries of dir that aren't ignored, by name.
+func compareEntries(dir string, options *CompareOptions) (map[string]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ignoredNames := []string{}
+	if options.Ignore != nil {
+		ignoredNames = options.Ignore(dir, entries)
+	}
+
+	infos := map[string]os.FileInfo{}
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			continue
+		}
+		if IsSymlink(entry) && !options.Symlinks {
+			info, err := os.Stat(filepath.Join(dir, entry.Name()))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			// Dangling links can only be compared as links
+			if err == nil {
+				entry = info
+			}
+		}
+		infos[entry.Name()] = entry
+	}
+	return infos, nil
+}
+
+// How much of two files CmpFiles() compares. Every mode compares the kinds
+// of the files and their contents, or targets for symbolic links.
+type CompareMode int
+
+const (
+	// Compare only the kinds and contents of the files.
+	CompareContent CompareMode = iota
+	// Also compare permission bits.
+	CompareContentMode
+	// Also compare permission bits and modification times.
+	CompareContentModeTimes
+	// Also compare extended attributes.
+	CompareContentXattrs
+)
+
+// What differs between two files, as found by CmpFiles().
+type FileDiff struct {
+	// The files are different kinds of file, such as a regular file and
+	// a directory. Nothing else is compared if so.
+	Kind bool
+
+	// The contents of regular files or the targets of symbolic links
+	// differ.
+	Content bool
+
+	// The permission bits differ.
+	Mode bool
+
+	// The modification times differ.
+	ModTime bool
+
+	// The names of the extended attributes that differ or are only on one
+	// of the files, sorted.
+	Xattrs []string
+}
+
+// Report whether the files are the same as far as they were compared.
+func (d FileDiff) Equal() bool {
+	return !d.Kind && !d.Content && !d.Mode && !d.ModTime && len(d.Xattrs) == 0
+}
+
+// Compare files a and b as described by mode, reporting what differs.
+// Symbolic links aren't followed, so two links are compared by their
+// targets. Directories and special files only differ in their metadata.
+//
+// Modification times are compared exactly, so a copy can only match if
+// both filesystems store times as precisely as each other. SyncTree() and
+// FilesEqual() have a ModifyWindow option for when they don't.
+func CmpFiles(a, b string, mode CompareMode) (FileDiff, error) {
+	var diff FileDiff
+	aInfo, err := os.Lstat(a)
+	if err != nil {
+		return diff, err
+	}
+	bInfo, err := os.Lstat(b)
+	if err != nil {
+		return diff, err
+	}
+	return cmpFiles(a, b, aInfo, bInfo, mode, 0)
+}
+
+// Modification times match if they are no more than window apart.
+func cmpFiles(a, b string, aInfo, bInfo os.FileInfo, mode CompareMode, window time.Duration) (FileDiff, error) {
+	var diff FileDiff
+	if aInfo.Mode().Type() != bInfo.Mode().Type() {
+		diff.Kind = true
+		return diff, nil
+	}
+
+	same, err := filesEqual(a, b, aInfo, bInfo, &CompareOptions{Symlinks: true})
+	if err != nil {
+		return diff, err
+	}
+	diff.Content = !same
+
+	// Links don't have their own permission bits on most platforms
+	link := IsSymlink(aInfo)
+	switch mode {
+	case CompareContentModeTimes:
+		diff.ModTime = !link && !TimesEqual(aInfo.ModTime(), bInfo.ModTime(), window)
+		fallthrough
+	case CompareContentMode:
+		diff.Mode = !link && aInfo.Mode().Perm() != bInfo.Mode().Perm()
+	case CompareContentXattrs:
+		diff.Xattrs, err = diffXattrs(a, b)
+	}
+	return diff, err
+}
+
+// Return the names of the extended attributes that differ between files a
+// and b, or are only on one of them, sorted.
+func diffXattrs(a, b string) ([]string, error) {
+	aXattrs, err := readXattrs(a)
+	if err != nil {
+		return nil, err
+	}
+	bXattrs, err := readXattrs(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, aValue := range aXattrs {
+		if bValue, ok := bXattrs[name]; !ok || !bytes.Equal(aValue, bValue) {
+			names = append(names, name)
+		}
+	}
+	for name := range bXattrs {
+		if _, ok := aXattrs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Return the extended attributes of a file, by name, without following it
+// if it is a symbolic link.
+func readXattrs(path string) (map[string][]byte, error) {
+	names, err := listXattrs(path)
+	if err != nil {
+		return nil, err
+	}
+	xattrs := make(map[string][]byte, len(names))
+	for _, name := range names {
+		xattrs[name], err = getXattr(path, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return xattrs, nil
+}
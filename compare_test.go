@@ -0,0 +1,190 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestSamePath(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Link(makeTestPath("testfile"), makeTestPath("hardlink"))).To(Succeed())
+	g.Expect(os.Symlink("testfile", makeTestPath("symlink"))).To(Succeed())
+
+	g.Expect(SamePath(makeTestPath("testfile"), makeTestPath("testdir/../testfile"))).To(BeTrue())
+	g.Expect(SamePath(makeTestPath("testfile"), makeTestPath("hardlink"))).To(BeTrue())
+	g.Expect(SamePath(makeTestPath("symlink"), makeTestPath("testfile"))).To(BeTrue())
+	g.Expect(SamePath(makeTestPath("testfile"), makeTestPath("testfile2"))).To(BeFalse())
+	g.Expect(SamePath(makeTestPath("testfile"), makeTestPath("missing"))).To(BeFalse())
+	g.Expect(SamePath(makeTestPath("missing"), makeTestPath("missing"))).To(BeFalse())
+
+	long := makeTestPath(strings.Repeat("x", 1000))
+	_, err := SamePath(makeTestPath("testfile"), long)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSameContent(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.WriteFile(makeTestPath("copy"), []byte("testfile\n"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("other"), []byte("testfilf\n"), 0644)).To(Succeed())
+
+	g.Expect(SameContent(makeTestPath("testfile"), makeTestPath("copy"))).To(BeTrue())
+	g.Expect(SameContent(makeTestPath("testfile"), makeTestPath("testfile"))).To(BeTrue())
+	g.Expect(SameContent(makeTestPath("testfile"), makeTestPath("other"))).To(BeFalse())
+	g.Expect(SameContent(makeTestPath("testfile"), makeTestPath("testfile2"))).To(BeFalse())
+
+	_, err := SameContent(makeTestPath("testfile"), makeTestPath("missing"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestCopyFileStatError(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	long := makeTestPath(strings.Repeat("x", 1000))
+	err := CopyFile(makeTestPath("testfile"), long, false)
+	// The error comes from checking whether they're the same file, rather
+	// than from trying to copy anyway
+	var pathErr *os.PathError
+	g.Expect(errors.As(err, &pathErr)).To(BeTrue())
+	g.Expect(pathErr.Op).To(Equal("stat"))
+}
+
+func TestFilesEqual(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("copy")
+	g.Expect(Copy(src, dst, false)).To(Equal(dst))
+	g.Expect(FilesEqual(src, dst, nil)).To(BeTrue())
+	g.Expect(FilesEqual(src, makeTestPath("testdir"), nil)).To(BeFalse())
+
+	g.Expect(os.Chmod(dst, 0600)).To(Succeed())
+	g.Expect(FilesEqual(src, dst, nil)).To(BeTrue())
+	g.Expect(FilesEqual(src, dst, &CompareOptions{Mode: true})).To(BeFalse())
+
+	old := time.Now().Add(-time.Hour)
+	g.Expect(os.Chtimes(dst, old, old)).To(Succeed())
+	g.Expect(FilesEqual(src, dst, &CompareOptions{ModTime: true})).To(BeFalse())
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link1"))).To(Succeed())
+	g.Expect(os.Symlink("testfile", makeTestPath("link2"))).To(Succeed())
+	g.Expect(os.Symlink("copy", makeTestPath("link3"))).To(Succeed())
+	symlinks := &CompareOptions{Symlinks: true}
+	g.Expect(FilesEqual(makeTestPath("link1"), makeTestPath("link2"), symlinks)).To(BeTrue())
+	g.Expect(FilesEqual(makeTestPath("link1"), makeTestPath("link3"), symlinks)).To(BeFalse())
+	g.Expect(FilesEqual(makeTestPath("link1"), makeTestPath("link3"), nil)).To(BeTrue())
+	g.Expect(FilesEqual(makeTestPath("link1"), src, symlinks)).To(BeFalse())
+}
+
+func TestTreesEqual(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	a := makeTestPath("a")
+	b := makeTestPath("b")
+	shutiltest.CreateTree(t, a, shutiltest.Tree{
+		"same":        shutiltest.File("same"),
+		"changed":     shutiltest.File("old"),
+		"kind":        shutiltest.File("file"),
+		"gone/file":   shutiltest.File("gone"),
+		"dir/nested":  shutiltest.File("old"),
+		"dir/same":    shutiltest.File("same"),
+		"link":        shutiltest.Symlink("same"),
+		"ignored.tmp": shutiltest.File("a"),
+	})
+	shutiltest.CreateTree(t, b, shutiltest.Tree{
+		"same":        shutiltest.File("same"),
+		"changed":     shutiltest.File("new"),
+		"kind":        shutiltest.Dir(),
+		"new":         shutiltest.File("new"),
+		"dir/nested":  shutiltest.File("new"),
+		"dir/same":    shutiltest.File("same"),
+		"dir/new":     shutiltest.File("new"),
+		"link":        shutiltest.File("same"),
+		"ignored.tmp": shutiltest.File("b"),
+	})
+
+	ignore := func(dir string, entries []os.FileInfo) []string {
+		return []string{"ignored.tmp"}
+	}
+	options := &CompareOptions{Ignore: ignore}
+	diff, err := TreesEqual(a, b, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diff.Equal()).To(BeFalse())
+	g.Expect(diff).To(Equal(TreeDiff{
+		OnlyInA: []string{"gone"},
+		OnlyInB: []string{"dir/new", "new"},
+		Differ:  []string{"changed", "dir/nested", "kind"},
+	}))
+
+	options.Symlinks = true
+	diff, err = TreesEqual(a, b, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diff.Differ).To(ContainElement("link"))
+
+	diff, err = TreesEqual(makeTestPath("testdir"), makeTestPath("testdir"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diff.Equal()).To(BeTrue())
+
+	_, err = TreesEqual(a, makeTestPath("testfile"), nil)
+	g.Expect(err).To(MatchError(&NotADirectoryError{makeTestPath("testfile")}))
+}
+
+func TestCmpFiles(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("copy")
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{"copy": {Contents: "testfile\n", Mode: 0600}})
+
+	for _, mode := range []CompareMode{CompareContent, CompareContentXattrs} {
+		diff, err := CmpFiles(src, dst, mode)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(diff.Equal()).To(BeTrue())
+	}
+	g.Expect(CmpFiles(src, dst, CompareContentMode)).To(Equal(FileDiff{Mode: true}))
+
+	old := time.Now().Add(-time.Hour)
+	g.Expect(os.Chtimes(dst, old, old)).To(Succeed())
+	g.Expect(os.WriteFile(src, []byte("changed"), 0644)).To(Succeed())
+	g.Expect(CmpFiles(src, dst, CompareContentModeTimes)).To(Equal(FileDiff{Content: true, Mode: true, ModTime: true}))
+
+	g.Expect(CmpFiles(src, makeTestPath("testdir"), CompareContentModeTimes)).To(Equal(FileDiff{Kind: true}))
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link1"))).To(Succeed())
+	g.Expect(os.Symlink("copy", makeTestPath("link2"))).To(Succeed())
+	g.Expect(CmpFiles(makeTestPath("link1"), makeTestPath("link2"), CompareContentModeTimes)).To(Equal(FileDiff{Content: true}))
+
+	_, err := CmpFiles(src, makeTestPath("missing"), CompareContent)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestCmpFilesXattrs(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir/file1")
+	dst := makeTestPath("testdir/file2")
+	g.Expect(os.WriteFile(dst, []byte("file1\n"), 0644)).To(Succeed())
+	if err := setXattr(src, "user.a", []byte("1")); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	g.Expect(setXattr(src, "user.b", []byte("1"))).To(Succeed())
+	g.Expect(setXattr(dst, "user.b", []byte("2"))).To(Succeed())
+	g.Expect(setXattr(dst, "user.c", []byte("1"))).To(Succeed())
+
+	g.Expect(CmpFiles(src, dst, CompareContentXattrs)).To(Equal(FileDiff{Xattrs: []string{"user.a", "user.b", "user.c"}}))
+	g.Expect(CmpFiles(src, dst, CompareContent)).To(Equal(FileDiff{}))
+}
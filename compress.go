@@ -0,0 +1,270 @@
+package shutil
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sync"
+)
+
+// Options for compressing the data of an archive.
+type CompressOptions struct {
+	// The compression level, whose meaning depends on the compressor. nil
+	// means its default level, so that a level of 0, which for gzip is no
+	// compression, can be asked for.
+	Level *int
+
+	// How many blocks compressors that support it may compress at once.
+	// 0 means one per CPU.
+	Workers int
+}
+
+// Compresses and decompresses the data of archives.
+//
+// Only the codecs the standard library provides are built in: gzip, and
+// bzip2 for decompressing only. This package doesn't depend on any
+// third-party compression libraries, so there are no built-in
+// compressors for zstd, xz or lz4; implementations wrapping such a
+// library can be added with RegisterCompressor().
+type Compressor interface {
+	// The extension added to the names of compressed files, such as ".gz".
+	Extension() string
+
+	// Return a writer that compresses what is written to it into w. Closing
+	// it flushes everything to w, but doesn't close w.
+	NewWriter(w io.Writer, options *CompressOptions) (io.WriteCloser, error)
+
+	// Return a reader that decompresses what is read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Returned when a compressor can't be found, or can't compress.
+type UnsupportedCompressionError struct {
+	Name string
+}
+
+func (e UnsupportedCompressionError) Error() string {
+	return fmt.Sprintf("compression `%s` is not supported", e.Name)
+}
+
+func (e UnsupportedCompressionError) Code() ErrorCode {
+	return CodeUnknownFormat
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[string]Compressor{
+		"gz": GzipCompressor{},
+		"bz": bzip2Compressor{},
+	}
+)
+
+// Make a compressor available under name, replacing any already
+// registered. The compressor is used by MakeArchive() for the format
+// name + "tar", so registering "xz" provides "xztar". Only "gz" (gzip)
+// and "bz" (bzip2, which can only decompress) are registered by default.
+func RegisterCompressor(name string, c Compressor) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	compressors[name] = c
+}
+
+// Return the compressor registered under name.
+func LookupCompressor(name string) (Compressor, error) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	c, ok := compressors[name]
+	if !ok {
+		return nil, &UnsupportedCompressionError{name}
+	}
+	return c, nil
+}
+
+// Return the registered compressor whose Extension() is ext, such as
+// ".gz".
+func LookupCompressorByExtension(ext string) (Compressor, error) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	for _, c := range compressors {
+		if c.Extension() == ext {
+			return c, nil
+		}
+	}
+	return nil, &UnsupportedCompressionError{ext}
+}
+
+// Compresses with gzip, splitting large inputs into blocks that are
+// compressed in parallel. Each block is written as a separate gzip member,
+// which any gzip reader decompresses as one stream. The output depends only
+// on the data, the level and the block size, not on how many workers
+// there are.
+type GzipCompressor struct {
+	// The amount of data in each block. 0 means 1MiB.
+	BlockSize int
+}
+
+func (c GzipCompressor) Extension() string {
+	return ".gz"
+}
+
+func (c GzipCompressor) NewWriter(w io.Writer, options *CompressOptions) (io.WriteCloser, error) {
+	if options == nil {
+		options = &CompressOptions{}
+	}
+	level := gzip.DefaultCompression
+	if options.Level != nil {
+		level = *options.Level
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Check the level now, rather than when the first block is compressed
+	_, err := gzip.NewWriterLevel(ioutil.Discard, level)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := c.BlockSize
+	if blockSize <= 0 {
+		blockSize = 1 << 20
+	}
+	pw := &parallelGzipWriter{
+		level:     level,
+		blockSize: blockSize,
+		queue:     make(chan chan gzipBlock, workers),
+		done:      make(chan struct{}),
+	}
+	go pw.writeBlocks(w)
+	return pw, nil
+}
+
+func (c GzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// Decompresses bzip2 with compress/bzip2, which has no writer.
+type bzip2Compressor struct{}
+
+func (bzip2Compressor) Extension() string {
+	return ".bz2"
+}
+
+func (bzip2Compressor) NewWriter(w io.Writer, options *CompressOptions) (io.WriteCloser, error) {
+	return nil, &UnsupportedCompressionError{"bz"}
+}
+
+func (bzip2Compressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bzip2.NewReader(r)), nil
+}
+
+// A block compressed by a parallelGzipWriter.
+type gzipBlock struct {
+	data []byte
+	err  error
+}
+
+// Compresses blocks of data concurrently, writing them out in order.
+type parallelGzipWriter struct {
+	level     int
+	blockSize int
+	buf       []byte
+	written   bool
+	closed    bool
+
+	// Each block's result is delivered on its own channel, queued in the
+	// order the blocks must be written. The queue's capacity limits how
+	// many blocks are compressed at once.
+	queue chan chan gzipBlock
+	done  chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (pw *parallelGzipWriter) Write(p []byte) (int, error) {
+	if err := pw.error(); err != nil {
+		return 0, err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		space := pw.blockSize - len(pw.buf)
+		if space > len(p) {
+			space = len(p)
+		}
+		pw.buf = append(pw.buf, p[:space]...)
+		p = p[space:]
+		if len(pw.buf) == pw.blockSize {
+			pw.submit()
+		}
+	}
+	return n, nil
+}
+
+func (pw *parallelGzipWriter) Close() error {
+	if pw.closed {
+		return pw.error()
+	}
+	pw.closed = true
+	if len(pw.buf) > 0 || !pw.written {
+		pw.submit()
+	}
+	close(pw.queue)
+	<-pw.done
+	return pw.error()
+}
+
+// Start compressing the buffered data as a block.
+func (pw *parallelGzipWriter) submit() {
+	data := pw.buf
+	pw.buf = make([]byte, 0, pw.blockSize)
+	pw.written = true
+
+	result := make(chan gzipBlock, 1)
+	pw.queue <- result
+	go func() {
+		var buf bytes.Buffer
+		zw, err := gzip.NewWriterLevel(&buf, pw.level)
+		if err == nil {
+			_, err = zw.Write(data)
+		}
+		if err == nil {
+			err = zw.Close()
+		}
+		result <- gzipBlock{buf.Bytes(), err}
+	}()
+}
+
+// Write out the compressed blocks in order as they are finished.
+func (pw *parallelGzipWriter) writeBlocks(w io.Writer) {
+	defer close(pw.done)
+	for result := range pw.queue {
+		block := <-result
+		err := block.err
+		if err == nil && pw.error() == nil {
+			_, err = w.Write(block.data)
+		}
+		if err != nil {
+			pw.setError(err)
+		}
+	}
+}
+
+func (pw *parallelGzipWriter) error() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return pw.err
+}
+
+func (pw *parallelGzipWriter) setError(err error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if pw.err == nil {
+		pw.err = err
+	}
+}
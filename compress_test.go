@@ -0,0 +1,168 @@
+package shutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGzipCompressorParallel(t *testing.T) {
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	for _, workers := range []int{1, 4} {
+		var buf bytes.Buffer
+		w, err := GzipCompressor{BlockSize: 1000}.NewWriter(&buf, &CompressOptions{Workers: workers})
+		g.Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(data[:12345])
+		g.Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(data[12345:])
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(w.Close()).To(Succeed())
+
+		r, err := gzip.NewReader(&buf)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(ioutil.ReadAll(r)).To(Equal(data))
+	}
+}
+
+func TestGzipCompressorEmpty(t *testing.T) {
+	g := NewWithT(t)
+
+	var buf bytes.Buffer
+	w, err := GzipCompressor{}.NewWriter(&buf, &CompressOptions{Workers: 2})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.Close()).To(Succeed())
+
+	r, err := GzipCompressor{}.NewReader(&buf)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ioutil.ReadAll(r)).To(BeEmpty())
+}
+
+func TestGzipCompressorLevel(t *testing.T) {
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	compress := func(options *CompressOptions) []byte {
+		var buf bytes.Buffer
+		w, err := GzipCompressor{}.NewWriter(&buf, options)
+		g.Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(data)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(w.Close()).To(Succeed())
+
+		r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(ioutil.ReadAll(r)).To(Equal(data))
+		return buf.Bytes()
+	}
+
+	// A level of 0 stores the data rather than using the default
+	none := gzip.NoCompression
+	g.Expect(len(compress(&CompressOptions{Level: &none}))).To(BeNumerically(">", len(data)))
+	g.Expect(len(compress(nil))).To(BeNumerically("<", len(data)/10))
+
+	bad := 42
+	_, err := GzipCompressor{}.NewWriter(ioutil.Discard, &CompressOptions{Level: &bad})
+	g.Expect(err).To(HaveOccurred())
+}
+
+// Stores data as it is, to check that registered compressors are used.
+type identityCompressor struct{}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (identityCompressor) Extension() string { return ".id" }
+
+func (identityCompressor) NewWriter(w io.Writer, options *CompressOptions) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (identityCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(r), nil
+}
+
+func TestMakeArchive(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	name, err := MakeArchive(makeTestPath("archive"), "gztar", makeTestPath("testdir"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal(makeTestPath("archive.tar.gz")))
+
+	f, err := os.Open(name)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readTar(g, r)).To(Equal(map[string]string{
+		"file1": "file1\n",
+		"file2": "file2\n",
+	}))
+
+	name, err = MakeArchive(makeTestPath("archive"), "zip", makeTestPath("testdir"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	data, err := ioutil.ReadFile(name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(readZip(g, data)).To(HaveLen(2))
+}
+
+func TestMakeArchiveRegisteredCompressor(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	RegisterCompressor("id", identityCompressor{})
+	name, err := MakeArchive(makeTestPath("archive"), "idtar", makeTestPath("testdir"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal(makeTestPath("archive.tar.id")))
+
+	f, err := os.Open(name)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+	g.Expect(readTar(g, f)).To(HaveLen(2))
+}
+
+func TestMakeArchiveUnsupported(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	_, err := MakeArchive(makeTestPath("archive"), "rar", makeTestPath("testdir"), nil)
+	g.Expect(err).To(MatchError(&UnknownFormatError{"rar"}))
+
+	_, err = MakeArchive(makeTestPath("archive"), "bztar", makeTestPath("testdir"), nil)
+	g.Expect(err).To(MatchError(&UnsupportedCompressionError{"bz"}))
+	g.Expect(makeTestPath("archive.tar.bz2")).NotTo(BeAnExistingFile())
+}
+
+func TestMakeArchiveReproducible(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	var archives [][]byte
+	for _, workers := range []int{1, 3} {
+		name, err := MakeArchive(makeTestPath("archive"), "gztar", makeTestPath("testdir"), &MakeArchiveOptions{
+			ArchiveOptions: ArchiveOptions{Reproducible: true},
+			Compress:       &CompressOptions{Workers: workers},
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		data, err := ioutil.ReadFile(name)
+		g.Expect(err).NotTo(HaveOccurred())
+		archives = append(archives, data)
+	}
+	g.Expect(archives[1]).To(Equal(archives[0]))
+}
+
+func TestLookupCompressorByExtension(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(LookupCompressorByExtension(".gz")).To(Equal(GzipCompressor{}))
+	_, err := LookupCompressorByExtension(".xz")
+	g.Expect(err).To(MatchError(&UnsupportedCompressionError{".xz"}))
+}
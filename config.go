@@ -0,0 +1,396 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// Returned by the Options() methods of the configs for a field whose
+// value isn't valid.
+type ConfigError struct {
+	// The JSON name of the field, with those of the configs it's in, such
+	// as "copy.mode".
+	Field string
+	Value string
+	Err   error
+}
+
+func (e ConfigError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid %s `%s`: %s", e.Field, e.Value, e.Err)
+	}
+	return fmt.Sprintf("invalid %s `%s`", e.Field, e.Value)
+}
+
+func (e ConfigError) Code() ErrorCode {
+	return CodeInvalidConfig
+}
+
+func (e ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Decode data, which is JSON, into config, such as a *SyncConfig, failing
+// on fields the config doesn't have so that typos aren't ignored.
+func DecodeConfig(data []byte, config interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(config)
+}
+
+// CopyOptions in a form that can be read from a config file or an API
+// request, as JSON or, with a YAML library that uses the json tags, YAML.
+// Options() checks it and turns it into CopyOptions. The names of the
+// fields are those of CopyOptions, in snake case.
+type CopyConfig struct {
+	FollowSymlinks bool `json:"follow_symlinks,omitempty"`
+
+	// "fail", the default, "copy-link" or "skip".
+	DanglingSymlinks string `json:"dangling_symlinks,omitempty"`
+
+	PreserveTimes        bool `json:"preserve_times,omitempty"`
+	PreserveOwner        bool `json:"preserve_owner,omitempty"`
+	PreserveXattrs       bool `json:"preserve_xattrs,omitempty"`
+	PreserveACLs         bool `json:"preserve_acls,omitempty"`
+	PreserveInodeFlags   bool `json:"preserve_inode_flags,omitempty"`
+	PreserveCapabilities bool `json:"preserve_capabilities,omitempty"`
+	PreserveSELinux      bool `json:"preserve_selinux,omitempty"`
+	DegradePreservation  bool `json:"degrade_preservation,omitempty"`
+	NoAtime              bool `json:"no_atime,omitempty"`
+	RestoreSourceAtime   bool `json:"restore_source_atime,omitempty"`
+	Verify               bool `json:"verify,omitempty"`
+	BufferSize           int  `json:"buffer_size,omitempty"`
+	DirectIO             bool `json:"direct_io,omitempty"`
+	Preallocate          bool `json:"preallocate,omitempty"`
+
+	// "exact", the default, "at-least" or "off".
+	SizeCheck string `json:"size_check,omitempty"`
+
+	// "remove", the default, "keep" or "leave".
+	PartialFiles string `json:"partial_files,omitempty"`
+
+	// Durations as time.ParseDuration() takes them, such as "30s".
+	StallTimeout string `json:"stall_timeout,omitempty"`
+	FileTimeout  string `json:"file_timeout,omitempty"`
+
+	// A mode as chmod(1) takes it, which copies' modes are changed by, as
+	// ParseModeSpec() parses it. Empty keeps the sources' modes.
+	Mode string `json:"mode,omitempty"`
+}
+
+var danglingSymlinkPolicies = map[string]DanglingSymlinkPolicy{
+	"":          DanglingSymlinkFail,
+	"fail":      DanglingSymlinkFail,
+	"copy-link": DanglingSymlinkCopyLink,
+	"skip":      DanglingSymlinkSkip,
+}
+
+var sizeChecks = map[string]SizeCheck{
+	"":         SizeExact,
+	"exact":    SizeExact,
+	"at-least": SizeAtLeast,
+	"off":      SizeUnchecked,
+}
+
+var partialFilePolicies = map[string]PartialFilePolicy{
+	"":       PartialRemove,
+	"remove": PartialRemove,
+	"keep":   PartialKeep,
+	"leave":  PartialLeave,
+}
+
+// Check the config and return the CopyOptions it describes.
+func (c *CopyConfig) Options() (*CopyOptions, error) {
+	return c.options("")
+}
+
+// Build the options, naming fields with prefix in errors.
+func (c *CopyConfig) options(prefix string) (*CopyOptions, error) {
+	policy, ok := danglingSymlinkPolicies[c.DanglingSymlinks]
+	if !ok {
+		return nil, &ConfigError{prefix + "dangling_symlinks", c.DanglingSymlinks, nil}
+	}
+	sizeCheck, ok := sizeChecks[c.SizeCheck]
+	if !ok {
+		return nil, &ConfigError{prefix + "size_check", c.SizeCheck, nil}
+	}
+	partial, ok := partialFilePolicies[c.PartialFiles]
+	if !ok {
+		return nil, &ConfigError{prefix + "partial_files", c.PartialFiles, nil}
+	}
+	if c.BufferSize < 0 {
+		return nil, &ConfigError{prefix + "buffer_size", fmt.Sprint(c.BufferSize), nil}
+	}
+	options := &CopyOptions{
+		FollowSymlinks:       c.FollowSymlinks,
+		DanglingSymlinks:     policy,
+		PreserveTimes:        c.PreserveTimes,
+		PreserveOwner:        c.PreserveOwner,
+		PreserveXattrs:       c.PreserveXattrs,
+		PreserveACLs:         c.PreserveACLs,
+		PreserveInodeFlags:   c.PreserveInodeFlags,
+		PreserveCapabilities: c.PreserveCapabilities,
+		PreserveSELinux:      c.PreserveSELinux,
+		DegradePreservation:  c.DegradePreservation,
+		NoAtime:              c.NoAtime,
+		RestoreSourceAtime:   c.RestoreSourceAtime,
+		Verify:               c.Verify,
+		BufferSize:           c.BufferSize,
+		DirectIO:             c.DirectIO,
+		Preallocate:          c.Preallocate,
+		SizeCheck:            sizeCheck,
+		PartialFiles:         partial,
+	}
+	var err error
+	options.StallTimeout, err = parseConfigDuration(prefix+"stall_timeout", c.StallTimeout)
+	if err != nil {
+		return nil, err
+	}
+	options.FileTimeout, err = parseConfigDuration(prefix+"file_timeout", c.FileTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.Mode != "" {
+		spec, err := ParseModeSpec(c.Mode)
+		if err != nil {
+			return nil, &ConfigError{prefix + "mode", c.Mode, err}
+		}
+		options.ModeMapper = spec.Apply
+	}
+	return options, nil
+}
+
+// CopyTreeOptions in a form that can be read from a config file, like
+// CopyConfig.
+type CopyTreeConfig struct {
+	Symlinks               bool `json:"symlinks,omitempty"`
+	IgnoreDanglingSymlinks bool `json:"ignore_dangling_symlinks,omitempty"`
+
+	// Patterns of names to leave out, as IgnorePatterns() takes.
+	Ignore []string `json:"ignore,omitempty"`
+
+	// The name of the ignore files, such as ".gitignore", to look for in
+	// each directory, as IgnoreFilesNamed() takes.
+	IgnoreFilesNamed string `json:"ignore_files_named,omitempty"`
+
+	// The names of directories to skip, as SkipDirsNamed() takes.
+	SkipDirs []string `json:"skip_dirs,omitempty"`
+
+	// The types of filesystems to skip directories on, as SkipFilesystems()
+	// takes, where "virtual" stands for VirtualFilesystems.
+	SkipFilesystems []string `json:"skip_filesystems,omitempty"`
+
+	SkipVanished       bool `json:"skip_vanished,omitempty"`
+	ExcludeDestination bool `json:"exclude_destination,omitempty"`
+	DirsExistOK        bool `json:"dirs_exist_ok,omitempty"`
+
+	ExpectEmptyDestination bool `json:"expect_empty_destination,omitempty"`
+	VerifyBeforeOverwrite  bool `json:"verify_before_overwrite,omitempty"`
+
+	// "as-is", the default, "relative" or "absolute".
+	LinkStyle string `json:"link_style,omitempty"`
+
+	// How each file is copied.
+	Copy *CopyConfig `json:"copy,omitempty"`
+}
+
+var linkStyles = map[string]LinkStyle{
+	"":         LinksAsIs,
+	"as-is":    LinksAsIs,
+	"relative": LinksRelative,
+	"absolute": LinksAbsolute,
+}
+
+// Check the config and return the CopyTreeOptions it describes.
+func (c *CopyTreeConfig) Options() (*CopyTreeOptions, error) {
+	style, ok := linkStyles[c.LinkStyle]
+	if !ok {
+		return nil, &ConfigError{"link_style", c.LinkStyle, nil}
+	}
+	ignore, err := configIgnore(c.Ignore, c.IgnoreFilesNamed)
+	if err != nil {
+		return nil, err
+	}
+	options := &CopyTreeOptions{
+		Symlinks:               c.Symlinks,
+		IgnoreDanglingSymlinks: c.IgnoreDanglingSymlinks,
+		Ignore:                 ignore,
+		SkipVanished:           c.SkipVanished,
+		LinkStyle:              style,
+		ExcludeDestination:     c.ExcludeDestination,
+		DirsExistOK:            c.DirsExistOK,
+		ExpectEmptyDestination: c.ExpectEmptyDestination,
+		VerifyBeforeOverwrite:  c.VerifyBeforeOverwrite,
+	}
+	options.SkipDir = configSkipDir(c.SkipDirs, c.SkipFilesystems)
+	if c.Copy != nil {
+		options.CopyOptions, err = c.Copy.options("copy.")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// SyncTreeOptions in a form that can be read from a config file, like
+// CopyConfig.
+type SyncConfig struct {
+	// "content", the default, "content-mode", "content-mode-times" or
+	// "content-xattrs".
+	Compare string `json:"compare,omitempty"`
+
+	// A duration as time.ParseDuration() takes it, such as "2s".
+	ModifyWindow string `json:"modify_window,omitempty"`
+
+	Delete bool `json:"delete,omitempty"`
+
+	// Names to leave out, as for CopyTreeConfig.
+	Ignore           []string `json:"ignore,omitempty"`
+	IgnoreFilesNamed string   `json:"ignore_files_named,omitempty"`
+
+	// Lock the destination while syncing it.
+	Lock *LockConfig `json:"lock,omitempty"`
+}
+
+// LockOptions in a form that can be read from a config file, like
+// CopyConfig, with durations as for SyncConfig.ModifyWindow.
+type LockConfig struct {
+	Wait       string `json:"wait,omitempty"`
+	StaleAfter string `json:"stale_after,omitempty"`
+}
+
+var compareModes = map[string]CompareMode{
+	"":                   CompareContent,
+	"content":            CompareContent,
+	"content-mode":       CompareContentMode,
+	"content-mode-times": CompareContentModeTimes,
+	"content-xattrs":     CompareContentXattrs,
+}
+
+// Check the config and return the SyncTreeOptions it describes.
+func (c *SyncConfig) Options() (*SyncTreeOptions, error) {
+	compare, ok := compareModes[c.Compare]
+	if !ok {
+		return nil, &ConfigError{"compare", c.Compare, nil}
+	}
+	window, err := parseConfigDuration("modify_window", c.ModifyWindow)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := configIgnore(c.Ignore, c.IgnoreFilesNamed)
+	if err != nil {
+		return nil, err
+	}
+	options := &SyncTreeOptions{
+		Compare:      compare,
+		ModifyWindow: window,
+		Delete:       c.Delete,
+		Ignore:       ignore,
+	}
+	if c.Lock != nil {
+		options.Lock = &LockOptions{}
+		options.Lock.Wait, err = parseConfigDuration("lock.wait", c.Lock.Wait)
+		if err != nil {
+			return nil, err
+		}
+		options.Lock.StaleAfter, err = parseConfigDuration("lock.stale_after", c.Lock.StaleAfter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// MoveOptions in a form that can be read from a config file, like
+// CopyConfig.
+type MoveConfig struct {
+	// How files are copied when a move has to copy them, rather than
+	// renaming them, such as to another filesystem. By default they are
+	// copied as Copy() copies them.
+	Copy *CopyConfig `json:"copy,omitempty"`
+}
+
+// Check the config and return the MoveOptions it describes.
+func (c *MoveConfig) Options() (*MoveOptions, error) {
+	options := &MoveOptions{}
+	if c.Copy != nil {
+		copyOptions, err := c.Copy.options("copy.")
+		if err != nil {
+			return nil, err
+		}
+		options.CopyFunction = func(src, dst string, followSymlinks bool) (string, error) {
+			fileOptions := *copyOptions
+			fileOptions.FollowSymlinks = followSymlinks
+			result, err := CopyContext(context.Background(), src, dst, &fileOptions)
+			return result.Dst, err
+		}
+	}
+	return options, nil
+}
+
+// Parse the duration s of the field, which can be empty for zero, but not
+// negative.
+func parseConfigDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, &ConfigError{field, s, err}
+	}
+	return d, nil
+}
+
+// Combine the names of directories and the types of filesystems to skip
+// into a SkipDirFunc, or nil if there are neither.
+func configSkipDir(names, filesystems []string) SkipDirFunc {
+	var types []string
+	for _, fsType := range filesystems {
+		if fsType == "virtual" {
+			types = append(types, VirtualFilesystems...)
+		} else {
+			types = append(types, fsType)
+		}
+	}
+	switch {
+	case len(names) == 0 && len(types) == 0:
+		return nil
+	case len(types) == 0:
+		return SkipDirsNamed(names...)
+	case len(names) == 0:
+		return SkipFilesystems(types...)
+	}
+	named, onFilesystem := SkipDirsNamed(names...), SkipFilesystems(types...)
+	return func(path string, info os.FileInfo) bool {
+		return named(path, info) || onFilesystem(path, info)
+	}
+}
+
+// Combine ignore patterns and the name of ignore files into an IgnoreFunc,
+// or nil if there are neither, checking the patterns.
+func configIgnore(patterns []string, filesNamed string) (IgnoreFunc, error) {
+	var fns []IgnoreFunc
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, &ConfigError{"ignore", pattern, err}
+		}
+	}
+	if len(patterns) > 0 {
+		fns = append(fns, IgnorePatterns(patterns...))
+	}
+	if filesNamed != "" {
+		fns = append(fns, IgnoreFilesNamed(filesNamed))
+	}
+	switch len(fns) {
+	case 0:
+		return nil, nil
+	case 1:
+		return fns[0], nil
+	}
+	return CombineIgnore(fns...), nil
+}
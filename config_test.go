@@ -0,0 +1,113 @@
+package shutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyTreeConfig(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	var config CopyTreeConfig
+	g.Expect(DecodeConfig([]byte(`{
+		"symlinks": true,
+		"ignore": ["file2"],
+		"link_style": "relative",
+		"copy": {"preserve_times": true, "mode": "go-rwx", "partial_files": "keep", "stall_timeout": "30s"}
+	}`), &config)).To(Succeed())
+	options, err := config.Options()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(options.Symlinks).To(BeTrue())
+	g.Expect(options.LinkStyle).To(Equal(LinksRelative))
+	g.Expect(options.CopyOptions.PreserveTimes).To(BeTrue())
+	g.Expect(options.CopyOptions.PartialFiles).To(Equal(PartialKeep))
+	g.Expect(options.CopyOptions.StallTimeout).To(Equal(30 * time.Second))
+	g.Expect(options.SkipDir).To(BeNil())
+
+	skipping, err := (&CopyTreeConfig{SkipDirs: []string{"testdir"}, SkipFilesystems: []string{"virtual"}}).Options()
+	g.Expect(err).NotTo(HaveOccurred())
+	dirInfo, err := os.Stat(makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(skipping.SkipDir(makeTestPath("testdir"), dirInfo)).To(BeTrue())
+
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(makeTestPath("out/file2")).NotTo(BeAnExistingFile())
+	info, err := os.Stat(makeTestPath("out/file1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm() & 077).To(BeZero())
+
+	// Configs marshal back to what they were read from
+	data, err := json.Marshal(config)
+	g.Expect(err).NotTo(HaveOccurred())
+	var again CopyTreeConfig
+	g.Expect(DecodeConfig(data, &again)).To(Succeed())
+	g.Expect(again).To(Equal(config))
+}
+
+func TestSyncConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	var config SyncConfig
+	g.Expect(DecodeConfig([]byte(`{"compare": "content-mode-times", "modify_window": "2s", "delete": true, "lock": {"stale_after": "1h"}}`), &config)).To(Succeed())
+	options, err := config.Options()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(options.Compare).To(Equal(CompareContentModeTimes))
+	g.Expect(options.ModifyWindow).To(Equal(2 * time.Second))
+	g.Expect(options.Delete).To(BeTrue())
+	g.Expect(options.Ignore).To(BeNil())
+	g.Expect(options.Lock).To(Equal(&LockOptions{StaleAfter: time.Hour}))
+}
+
+func TestMoveConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	options, err := (&MoveConfig{}).Options()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(options.CopyFunction).To(BeNil())
+
+	options, err = (&MoveConfig{Copy: &CopyConfig{PreserveTimes: true}}).Options()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(options.CopyFunction).NotTo(BeNil())
+}
+
+func TestConfigErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	var config SyncConfig
+	g.Expect(DecodeConfig([]byte(`{"delte": true}`), &config)).NotTo(Succeed())
+
+	for _, test := range []struct {
+		options func() error
+		field   string
+	}{
+		{func() error { _, err := (&SyncConfig{Compare: "size"}).Options(); return err }, "compare"},
+		{func() error { _, err := (&SyncConfig{ModifyWindow: "-1s"}).Options(); return err }, "modify_window"},
+		{func() error { _, err := (&SyncConfig{Ignore: []string{"["}}).Options(); return err }, "ignore"},
+		{func() error { _, err := (&SyncConfig{Lock: &LockConfig{Wait: "-1s"}}).Options(); return err }, "lock.wait"},
+		{func() error { _, err := (&CopyTreeConfig{LinkStyle: "sideways"}).Options(); return err }, "link_style"},
+		{func() error {
+			_, err := (&CopyTreeConfig{Copy: &CopyConfig{Mode: "u+q"}}).Options()
+			return err
+		}, "copy.mode"},
+		{func() error {
+			_, err := (&MoveConfig{Copy: &CopyConfig{DanglingSymlinks: "follow"}}).Options()
+			return err
+		}, "copy.dangling_symlinks"},
+		{func() error { _, err := (&CopyConfig{BufferSize: -1}).Options(); return err }, "buffer_size"},
+		{func() error { _, err := (&CopyConfig{SizeCheck: "most"}).Options(); return err }, "size_check"},
+		{func() error { _, err := (&CopyConfig{StallTimeout: "soon"}).Options(); return err }, "stall_timeout"},
+		{func() error { _, err := (&CopyConfig{PartialFiles: "resume"}).Options(); return err }, "partial_files"},
+	} {
+		var configErr *ConfigError
+		g.Expect(errors.As(test.options(), &configErr)).To(BeTrue(), test.field)
+		g.Expect(configErr.Field).To(Equal(test.field))
+	}
+}
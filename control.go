@@ -0,0 +1,304 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Returned by the operations run with a Control's context once Stop() has
+// been called on it and they have finished the files they were copying.
+var ErrStopped = errors.New("operation stopped")
+
+// What a controlled operation is doing.
+type ControlState int
+
+const (
+	ControlRunning ControlState = iota
+	ControlPaused
+	// Cancel() was called, and the operation is stopping or has stopped.
+	ControlCancelled
+	// Stop() was called, and the operation is finishing the files it's
+	// copying.
+	ControlStopping
+	// The operation has finished, whether it succeeded or not.
+	ControlDone
+)
+
+func (s ControlState) String() string {
+	switch s {
+	case ControlRunning:
+		return "running"
+	case ControlPaused:
+		return "paused"
+	case ControlCancelled:
+		return "cancelled"
+	case ControlStopping:
+		return "stopping"
+	}
+	return "done"
+}
+
+// A snapshot of a controlled operation.
+type Status struct {
+	State ControlState
+
+	// The progress the operation last reported, which is zero until it
+	// has handled a file. Only operations started with Op.Start() or
+	// StartApply() report it.
+	Progress Progress
+}
+
+type controlKey struct{}
+
+// Pauses, resumes and cancels the operations run with the context
+// WithControl() returns, so that an interactive tool can free up disk
+// bandwidth for a while without restarting a large copy. A paused
+// operation stops before the next file, and part way through a file where
+// it's copied with reads and writes, as it is when its context can be
+// cancelled, but not during a clone or a copy with io_uring or mmap.
+//
+// Cancel() stops an operation at once, leaving the file it was part way
+// through copying as CopyOptions.PartialFiles says, whereas Stop() lets it
+// finish the files it's copying first, so that it doesn't leave a
+// truncated file behind.
+type Control struct {
+	// The context files are copied with, which only Cancel() cancels, and
+	// that the operation is run with, which Stop() cancels too
+	hard   context.Context
+	cancel context.CancelFunc
+	stop   context.CancelFunc
+
+	mu       sync.Mutex
+	state    ControlState
+	resumed  chan struct{} // closed unless paused
+	progress Progress
+}
+
+// Return a context for operations that control controls, along with
+// control.
+func WithControl(parent context.Context) (context.Context, *Control) {
+	c := &Control{resumed: make(chan struct{})}
+	close(c.resumed)
+	c.hard, c.cancel = context.WithCancel(context.WithValue(parent, controlKey{}, c))
+	soft, stop := context.WithCancel(c.hard)
+	c.stop = stop
+	return &stopContext{soft, c.hard}, c
+}
+
+// A context that is done once its Control is stopped or cancelled, and
+// whose error is ErrStopped if it was only stopped.
+type stopContext struct {
+	context.Context
+	hard context.Context
+}
+
+func (c *stopContext) Err() error {
+	if c.Context.Err() == nil {
+		return nil
+	}
+	if err := c.hard.Err(); err != nil {
+		return err
+	}
+	return ErrStopped
+}
+
+// Pause the operation at the next opportunity, until Resume(), Stop() or
+// Cancel() is called.
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == ControlRunning {
+		c.state = ControlPaused
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Carry on with a paused operation.
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == ControlPaused {
+		c.state = ControlRunning
+		close(c.resumed)
+	}
+}
+
+// Stop the operation, paused or not, by cancelling its context, so it
+// fails with context.Canceled. A file it was part way through copying is
+// dealt with as CopyOptions.PartialFiles says, which by default removes
+// it.
+func (c *Control) Cancel() {
+	c.mu.Lock()
+	if c.state != ControlDone {
+		c.state = ControlCancelled
+	}
+	c.mu.Unlock()
+	c.cancel()
+}
+
+// Let the operation finish the files it's copying, resuming it if it's
+// paused, and then stop it, so that it fails with ErrStopped. Cancel()
+// can still be called to stop it at once.
+func (c *Control) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case ControlPaused:
+		close(c.resumed)
+	case ControlRunning:
+	default:
+		return
+	}
+	c.state = ControlStopping
+	c.stop()
+}
+
+// Return what the operation is doing.
+func (c *Control) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Status{State: c.state, Progress: c.progress}
+}
+
+func (c *Control) setProgress(p Progress) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.progress = p
+}
+
+// Record that the operation has finished, and release its context.
+func (c *Control) finish() {
+	c.mu.Lock()
+	c.state = ControlDone
+	c.mu.Unlock()
+	c.cancel()
+}
+
+// Wait while the operation is paused, returning the context's error if
+// it is cancelled.
+func (c *Control) wait(ctx context.Context) error {
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+	select {
+	case <-resumed:
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Return the Control of the context, or nil if it hasn't one.
+func controlOf(ctx context.Context) *Control {
+	c, _ := ctx.Value(controlKey{}).(*Control)
+	return c
+}
+
+// Wait while an operation with the context is paused, returning the
+// context's error if it is cancelled, whether it has a Control or not.
+func waitIfPaused(ctx context.Context) error {
+	if c := controlOf(ctx); c != nil {
+		return c.wait(ctx)
+	}
+	return ctx.Err()
+}
+
+// Return the context to copy a file's data with, which for an operation
+// with a Control is only cancelled by Cancel(), so that the file is
+// finished when it's stopped.
+func fileContext(ctx context.Context) context.Context {
+	if c := controlOf(ctx); c != nil {
+		return c.hard
+	}
+	return ctx
+}
+
+// An Op started with Op.Start().
+type Handle struct {
+	*Control
+	done   chan struct{}
+	report Report
+	err    error
+}
+
+// Start running the operation in the background, returning a handle to
+// pause, resume or cancel it with, as well as to wait for it. The Op
+// shouldn't be changed once it has started.
+func (o *Op) Start() *Handle {
+	parent := o.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, control := WithControl(parent)
+	h := &Handle{Control: control, done: make(chan struct{})}
+	o.ctx = ctx
+	own := o.progress
+	o.progress = func(p Progress) {
+		control.setProgress(p)
+		if own != nil {
+			own(p)
+		}
+	}
+	go func() {
+		h.report, h.err = o.Run()
+		control.finish()
+		close(h.done)
+	}()
+	return h
+}
+
+// Return a channel that is closed when the operation has finished.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait for the operation to finish, and return what Run() would have.
+func (h *Handle) Wait() (Report, error) {
+	<-h.done
+	return h.report, h.err
+}
+
+// A plan being applied by StartApply().
+type ApplyHandle struct {
+	*Control
+	done   chan struct{}
+	result ApplyResult
+	err    error
+}
+
+// Start applying plan like Apply(), in the background, returning a handle
+// to pause, resume or cancel it with, as well as to wait for it.
+func StartApply(ctx context.Context, plan Plan, options *ApplyOptions) *ApplyHandle {
+	applyOptions := ApplyOptions{}
+	if options != nil {
+		applyOptions = *options
+	}
+	ctx, control := WithControl(ctx)
+	h := &ApplyHandle{Control: control, done: make(chan struct{})}
+	own := applyOptions.Progress
+	applyOptions.Progress = func(p Progress) {
+		control.setProgress(p)
+		if own != nil {
+			own(p)
+		}
+	}
+	go func() {
+		h.result, h.err = Apply(ctx, plan, &applyOptions)
+		control.finish()
+		close(h.done)
+	}()
+	return h
+}
+
+// Return a channel that is closed when the plan has been applied, or
+// applying it has failed.
+func (h *ApplyHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait for the plan to be applied, and return what Apply() would have.
+func (h *ApplyHandle) Wait() (ApplyResult, error) {
+	<-h.done
+	return h.result, h.err
+}
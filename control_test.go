@@ -0,0 +1,164 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestControlPause(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, control := WithControl(context.Background())
+	control.Pause()
+	g.Expect(control.Status().State).To(Equal(ControlPaused))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := CopyContext(ctx, makeTestPath("testfile"), makeTestPath("copy"), nil)
+		done <- err
+	}()
+	g.Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+	g.Expect(makeTestPath("copy")).NotTo(BeAnExistingFile())
+
+	control.Resume()
+	g.Expect(control.Status().State).To(Equal(ControlRunning))
+	g.Eventually(done).Should(Receive(BeNil()))
+	g.Expect(FilesEqual(makeTestPath("testfile"), makeTestPath("copy"), nil)).To(BeTrue())
+}
+
+func TestControlCancelWhilePaused(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, control := WithControl(context.Background())
+	control.Pause()
+	done := make(chan error, 1)
+	go func() {
+		_, err := CopyContext(ctx, makeTestPath("testfile"), makeTestPath("copy"), nil)
+		done <- err
+	}()
+	control.Cancel()
+	g.Eventually(done).Should(Receive(MatchError(context.Canceled)))
+	g.Expect(control.Status().State).To(Equal(ControlCancelled))
+
+	// Resuming a cancelled operation does nothing
+	control.Resume()
+	g.Expect(control.Status().State).To(Equal(ControlCancelled))
+}
+
+func TestOpStart(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	first := make(chan struct{})
+	paused := make(chan struct{})
+	op := NewCopy(makeTestPath("testdir"), makeTestPath("copy")).Recursive().Parallel(1).
+		Progress(func(p Progress) {
+			if p.FilesDone == 1 {
+				close(first)
+				<-paused
+			}
+		})
+	h := op.Start()
+	<-first
+	h.Pause()
+	close(paused)
+
+	g.Consistently(h.Done(), 50*time.Millisecond).ShouldNot(BeClosed())
+	status := h.Status()
+	g.Expect(status.State).To(Equal(ControlPaused))
+	g.Expect(status.Progress.FilesDone).To(Equal(1))
+
+	h.Resume()
+	report, err := h.Wait()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Files).To(Equal(2))
+	g.Expect(h.Status().State).To(Equal(ControlDone))
+	g.Expect(h.Status().Progress.FilesDone).To(Equal(2))
+}
+
+func TestStartApplyCancel(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	plan, err := PlanCopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("copy"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	first := make(chan struct{})
+	cancelled := make(chan struct{})
+	h := StartApply(context.Background(), plan, &ApplyOptions{
+		Parallel: 1,
+		Progress: func(p Progress) {
+			if p.FilesDone == 1 {
+				close(first)
+				<-cancelled
+			}
+		},
+	})
+	<-first
+	h.Cancel()
+	g.Expect(h.Status().State).To(Equal(ControlCancelled))
+	close(cancelled)
+	_, err = h.Wait()
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(h.Status().State).To(Equal(ControlDone))
+}
+
+func TestControlStateString(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(ControlRunning.String()).To(Equal("running"))
+	g.Expect(ControlPaused.String()).To(Equal("paused"))
+	g.Expect(ControlCancelled.String()).To(Equal("cancelled"))
+	g.Expect(ControlStopping.String()).To(Equal("stopping"))
+	g.Expect(ControlDone.String()).To(Equal("done"))
+}
+
+func TestControlStop(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("stop")
+	g.Expect(os.Mkdir(src, 0755)).To(Succeed())
+	data := bytes.Repeat([]byte("x"), 200<<10)
+	g.Expect(os.WriteFile(filepath.Join(src, "a"), data, 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(src, "b"), data, 0644)).To(Succeed())
+
+	// Copying each file takes about a second
+	dst := makeTestPath("copy")
+	h := NewCopy(src, dst).Recursive().Parallel(1).RateLimit(NewRateLimiter(100 << 10)).Start()
+	time.Sleep(50 * time.Millisecond)
+	h.Stop()
+	g.Expect(h.Status().State).To(Equal(ControlStopping))
+
+	_, err := h.Wait()
+	g.Expect(err).To(MatchError(ErrStopped))
+	g.Expect(FilesEqual(filepath.Join(src, "a"), filepath.Join(dst, "a"), nil)).To(BeTrue())
+	g.Expect(filepath.Join(dst, "b")).NotTo(BeAnExistingFile())
+}
+
+func TestControlCancelRemovesPartialFile(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("big")
+	g.Expect(os.WriteFile(src, bytes.Repeat([]byte("x"), 1<<20), 0644)).To(Succeed())
+	dst := makeTestPath("copy")
+
+	ctx, control := WithControl(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := CopyContext(ctx, src, dst, &CopyOptions{RateLimit: NewRateLimiter(64 << 10)})
+		done <- err
+	}()
+	g.Eventually(dst).Should(BeAnExistingFile())
+	control.Cancel()
+	g.Eventually(done).Should(Receive(MatchError(context.Canceled)))
+	g.Expect(dst).NotTo(BeAnExistingFile())
+}
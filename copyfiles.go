@@ -0,0 +1,138 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A single source and destination for CopyFiles.
+type SrcDst struct {
+	Src string
+	Dst string
+}
+
+type CopyFilesOptions struct {
+	FollowSymlinks bool
+	CopyFunction   CopyFunc
+
+	// Create any missing parent directories of each destination.
+	CreateParents bool
+
+	// The number of files copied at once. Zero or one copies the files
+	// sequentially, in order.
+	Parallel int
+
+	// The most files the copies may have open at once, which limits how
+	// many are copied at once. Zero is half the process's limit, where
+	// the platform has one, such as RLIMIT_NOFILE.
+	MaxOpenFiles int
+
+	// Stop at the first error instead of copying the remaining files.
+	FailFast bool
+
+	// Make read-only destination files writable before overwriting them.
+	ForceWritable bool
+
+	Progress ProgressFunc
+}
+
+// Copy an explicit list of files, each from its Src to its Dst, sharing
+// the same options. This is useful when the files to copy come from a
+// computed manifest rather than a directory root.
+//
+// Each pair is copied with the optional CopyFunction, which defaults to
+// Copy(), so a Dst that is an existing directory receives the file inside
+// it.
+//
+// By default every pair is attempted and the failures are returned
+// together in a MultiError of FileErrors. If FailFast is set, no new copies
+// are started once one has failed. The optional Progress callback is
+// called once for every pair that was attempted.
+func CopyFiles(pairs []SrcDst, options *CopyFilesOptions) error {
+	if options == nil {
+		options = &CopyFilesOptions{}
+	}
+	copyFunction := options.CopyFunction
+	if copyFunction == nil {
+		copyFunction = Copy
+	}
+
+	var (
+		mu       sync.Mutex
+		errs     []error
+		progress = Progress{FilesTotal: len(pairs)}
+		meter    = newProgressMeter()
+	)
+
+	forEachParallel(len(pairs), limitParallel(options.Parallel, options.MaxOpenFiles, fdsPerCopy), func(i int) {
+		mu.Lock()
+		stopped := options.FailFast && len(errs) > 0
+		mu.Unlock()
+		if stopped {
+			return
+		}
+
+		pair := pairs[i]
+		dst, size, err := copyOne(pair, copyFunction, options)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, &FileError{pair.Src, pair.Dst, err})
+		}
+		progress.Src = pair.Src
+		progress.Dst = dst
+		progress.Err = err
+		progress.FilesDone++
+		progress.BytesDone += size
+		meter.update(&progress)
+		if options.Progress != nil {
+			options.Progress(progress)
+		}
+	})
+
+	if len(errs) > 0 {
+		return &MultiError{errs}
+	}
+	return nil
+}
+
+// Copy a single pair for CopyFiles, returning the final destination and
+// the number of bytes it holds.
+func copyOne(pair SrcDst, copyFunction CopyFunc, options *CopyFilesOptions) (string, int64, error) {
+	if options.CreateParents {
+		err := os.MkdirAll(filepath.Dir(pair.Dst), 0777)
+		if err != nil {
+			return pair.Dst, 0, err
+		}
+	}
+
+	if options.ForceWritable {
+		target := pair.Dst
+		if isDir, _ := isDirectory(target); isDir {
+			target = filepath.Join(target, filepath.Base(pair.Src))
+		}
+		info, err := os.Lstat(target)
+		if err == nil && info.Mode().IsRegular() {
+			err = makeWritable(target)
+			if err != nil {
+				return pair.Dst, 0, err
+			}
+		}
+	}
+
+	dst, err := copyFunction(pair.Src, pair.Dst, options.FollowSymlinks)
+	if err != nil {
+		return dst, 0, err
+	}
+
+	dstInfo, err := os.Lstat(dst)
+	if err != nil {
+		return dst, 0, err
+	}
+	if !dstInfo.Mode().IsRegular() {
+		return dst, 0, nil
+	}
+	return dst, dstInfo.Size(), nil
+}
@@ -0,0 +1,85 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyFiles(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	pairs := []SrcDst{
+		{makeTestPath("testfile"), makeTestPath("out/a/testfile")},
+		{makeTestPath("testfile2"), makeTestPath("out/b/testfile2")},
+		{makeTestPath("testdir/file1"), makeTestPath("out/file1")},
+	}
+
+	var progress []Progress
+	g.Expect(CopyFiles(pairs, &CopyFilesOptions{
+		CreateParents: true,
+		Parallel:      2,
+		Progress:      func(p Progress) { progress = append(progress, p) },
+	})).To(Succeed())
+
+	for _, pair := range pairs {
+		g.Expect(FilesEqual(pair.Src, pair.Dst, nil)).To(BeTrue())
+	}
+	g.Expect(progress).To(HaveLen(3))
+	last := progress[len(progress)-1]
+	g.Expect(last.FilesDone).To(Equal(3))
+	g.Expect(last.FilesTotal).To(Equal(3))
+	g.Expect(last.BytesDone).To(Equal(int64(9 + 10 + 6)))
+}
+
+func TestCopyFilesAggregatesErrors(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	pairs := []SrcDst{
+		{makeTestPath("missing1"), makeTestPath("out1")},
+		{makeTestPath("testfile"), makeTestPath("out2")},
+		{makeTestPath("missing2"), makeTestPath("out3")},
+	}
+
+	err := CopyFiles(pairs, nil)
+	var multi *MultiError
+	g.Expect(errors.As(err, &multi)).To(BeTrue())
+	g.Expect(multi.Errors).To(HaveLen(2))
+
+	var fileErr *FileError
+	g.Expect(errors.As(multi.Errors[0], &fileErr)).To(BeTrue())
+	g.Expect(fileErr.Src).To(Equal(makeTestPath("missing1")))
+	g.Expect(os.IsNotExist(errors.Unwrap(fileErr))).To(BeTrue())
+
+	g.Expect(FilesEqual(makeTestPath("testfile"), makeTestPath("out2"), nil)).To(BeTrue())
+}
+
+func TestCopyFilesFailFast(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	pairs := []SrcDst{
+		{makeTestPath("missing"), makeTestPath("out1")},
+		{makeTestPath("testfile"), makeTestPath("out2")},
+	}
+
+	g.Expect(CopyFiles(pairs, &CopyFilesOptions{FailFast: true})).To(HaveOccurred())
+	_, err := os.Stat(makeTestPath("out2"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestCopyFilesForceWritable(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("testfile2")
+	g.Expect(os.Chmod(dst, 0444)).To(Succeed())
+
+	pairs := []SrcDst{{makeTestPath("testfile"), dst}}
+	g.Expect(CopyFiles(pairs, &CopyFilesOptions{ForceWritable: true})).To(Succeed())
+	g.Expect(FilesEqual(makeTestPath("testfile"), dst, nil)).To(BeTrue())
+}
@@ -0,0 +1,348 @@
+package shutil
+
+import (
+	"context"
+	"crypto"
+	"io"
+	"os"
+	"time"
+)
+
+// What to do when following a symbolic link whose target doesn't exist.
+type DanglingSymlinkPolicy int
+
+const (
+	// Return a DanglingSymlinkError.
+	DanglingSymlinkFail DanglingSymlinkPolicy = iota
+	// Copy the link itself, as if it wasn't being followed.
+	DanglingSymlinkCopyLink
+	// Copy nothing, and don't return an error.
+	DanglingSymlinkSkip
+)
+
+// How the amount of data copied from a file is checked against the size
+// it had when the copy began.
+type SizeCheck int
+
+const (
+	// Return a ShortCopyError unless they're the same.
+	SizeExact SizeCheck = iota
+	// Return a ShortCopyError if less was copied, but allow more, as it
+	// is for a log file that is appended to while it's copied.
+	SizeAtLeast
+	// Don't check the size.
+	SizeUnchecked
+)
+
+func (c SizeCheck) ok(copied, size int64) bool {
+	switch c {
+	case SizeAtLeast:
+		return copied >= size
+	case SizeUnchecked:
+		return true
+	}
+	return copied == size
+}
+
+// What is done with the part of a file that was written when copying its
+// data fails, or is cancelled, part way through.
+type PartialFilePolicy int
+
+const (
+	// Remove it, so that a truncated copy isn't mistaken for a whole one.
+	PartialRemove PartialFilePolicy = iota
+	// Rename it to the destination with PartialSuffix added, so that a
+	// copy can be resumed from it, replacing any file already there.
+	PartialKeep
+	// Leave it where it is.
+	PartialLeave
+)
+
+// Added to the destination of a partial copy with PartialKeep.
+const PartialSuffix = ".partial"
+
+// Options for copying a single file with a CopyFunc2.
+type CopyOptions struct {
+	// Copy the file a symbolic link points to, rather than the link.
+	FollowSymlinks bool
+
+	// What to do when FollowSymlinks is set but the target of the link
+	// doesn't exist.
+	DanglingSymlinks DanglingSymlinkPolicy
+
+	// Give the copy the same access and modification times as the source.
+	PreserveTimes bool
+
+	// What to do with the source's creation time when PreserveTimes is
+	// set. By default it is preserved where the platform can set it.
+	Birthtime BirthtimePolicy
+
+	// Give the copy the same owner and group as the source. This usually
+	// needs root privileges.
+	PreserveOwner bool
+
+	// Translates the owner and group of the source into those the copy is
+	// given when PreserveOwner is set, such as with an IDMap.
+	OwnerMapper OwnerMapper
+
+	// Give the copy the same extended attributes as the source. On macOS
+	// these hold Finder info, resource forks and quarantine flags, and the
+	// copy is given the source's hidden and nodump flags as well.
+	PreserveXattrs bool
+
+	// Give the copy the same POSIX ACLs as the source, as CopyACL() does.
+	// Where the platform keeps ACLs some other way, copies fail with a
+	// NotSupportedError. On Linux, PreserveXattrs copies them too.
+	PreserveACLs bool
+
+	// Give the copy the source's Linux inode flags, as chattr(1) sets,
+	// such as immutable, append-only and no copy-on-write. Flags that
+	// can't be set, such as immutable without the CAP_LINUX_IMMUTABLE
+	// capability, are reported as an InodeFlagsWarning. Other platforms
+	// don't have them.
+	PreserveInodeFlags bool
+
+	// Give the copy the source's Linux file capabilities, such as
+	// cap_net_bind_service on a server binary, which writing to a file or
+	// changing its owner clears. Setting them needs the CAP_SETFCAP
+	// capability. PreserveXattrs copies them too. Other platforms don't
+	// have them.
+	PreserveCapabilities bool
+
+	// Report metadata the preservation options ask for that can't be given
+	// to the copy, because its filesystem can't store it or the caller
+	// isn't allowed to set it, as a PreservationWarning rather than
+	// failing. Ownership without privileges and extended attributes on
+	// filesystems such as FAT are examples.
+	DegradePreservation bool
+
+	// Give the copy the source's SELinux security context, like
+	// `cp --preserve=context`. Relabelling files needs privileges, so
+	// failing to is reported as a warning. Only Linux has SELinux.
+	PreserveSELinux bool
+
+	// Called to decide the SELinux security context of each copy, with
+	// the source's if PreserveSELinux is set, like restorecon(8) after a
+	// copy. Failing to set the context it returns is a warning.
+	Relabel RelabelFunc
+
+	// Read the source without updating its access time, where the
+	// platform allows and the source is owned by the caller, so copying
+	// many files doesn't change their metadata. It's read as usual where
+	// not.
+	NoAtime bool
+
+	// Give the source back the access time it had before it was read,
+	// leaving its modification time alone, so copying it doesn't count as
+	// using it. If that isn't permitted, it's reported as a warning.
+	RestoreSourceAtime bool
+
+	// Read the source to its end, however large it is said to be, rather
+	// than failing if the size copied differs. Files in /proc and /sys
+	// need this, as they report a size of zero or a page.
+	SizeUnknown bool
+
+	// How the data copied from a file is checked against its size, which
+	// by default it has to match. Whatever the check, files that are
+	// cloned or copied with io_uring or mmap are copied to the size they
+	// had when the copy began.
+	SizeCheck SizeCheck
+
+	// Copy what is read from a character or block device, such as a disk
+	// or /dev/stdin, which have no size, as if SizeUnknown were set. It
+	// is read to its end, so the copy never ends for devices like
+	// /dev/zero unless the context is cancelled.
+	AllowSpecialSources bool
+
+	// Copy what is written to a named pipe until its writers close it,
+	// rather than returning a SpecialFileError, such as to capture the
+	// output of a command. Opening the pipe waits for a writer. As the
+	// pipe can't be read again, Verify compares the copy with a hash of
+	// the data taken as it was read.
+	ReadNamedPipes bool
+
+	// Read the copy back after writing it and compare it with the source,
+	// returning a VerifyError if they differ.
+	Verify bool
+
+	// The size of the buffer used to copy data, when the platform can't
+	// copy it directly between the files. Zero uses a default size.
+	BufferSize int
+
+	// Files smaller than this many bytes are read whole into memory and
+	// written out at once, which is quicker for trees of many small files.
+	// Zero uses a default of 128KiB, and a negative size turns it off. It
+	// only applies with EngineDefault, as other engines copy every file.
+	SmallFileSize int
+
+	// Copy large files with direct I/O, bypassing the page cache, so a
+	// bulk copy doesn't push everything else out of it. Where the
+	// platform or filesystem doesn't support it, files are copied as
+	// usual.
+	DirectIO bool
+
+	// How the data of files is copied.
+	Engine CopyEngine
+
+	// How copying uses the page cache.
+	Cache CacheBehavior
+
+	// Hashes to take of each file's data as it's copied, without reading
+	// it again, which are returned in CopyResult.Digests. Files are copied
+	// with reads and writes, rather than cloned, so the data can be
+	// hashed, and each hash's package has to be linked into the program,
+	// or an UnavailableHashError is returned.
+	Hashes []crypto.Hash
+
+	// Limits the rate file data is copied at. As the kernel's copies
+	// can't be slowed down, limited copies are made with reads and
+	// writes, other than clones, which copy no data, and copies from
+	// named pipes, which aren't limited.
+	RateLimit *RateLimiter
+
+	// Allocate the disk space for each copy before writing it, so a copy
+	// that won't fit fails straight away, and the copy is less
+	// fragmented. Copies of sparse files aren't sparse.
+	Preallocate bool
+
+	// Convert text files as the options say, such as to change their line
+	// endings, rather than copying them exactly. Text files are copied with
+	// reads and writes, and as their copies can differ from them, neither
+	// SizeCheck nor Verify apply to them.
+	Text *TextOptions
+
+	// Give up on a file with a StalledError if no data is read from it for
+	// this long, such as because it's on a hung NFS mount, rather than
+	// waiting for it indefinitely. A read that never returns is abandoned,
+	// along with the file. Zero turns this off. With this or FileTimeout
+	// set, files are copied with reads and writes, so that progress can be
+	// seen.
+	StallTimeout time.Duration
+
+	// Give up on a file with a StalledError if copying its data takes
+	// longer than this. Zero means no limit.
+	FileTimeout time.Duration
+
+	// What is done when the destination lacks a feature, for each feature
+	// that shouldn't be handled as it is by default. Symbolic links that
+	// can't be created are copied as what they point to when they're
+	// done without, and skipped if they're dangling. Extended attributes,
+	// and the ACLs and capabilities kept in them, that can't be preserved
+	// are left off. By default, those fail, unless DegradePreservation is
+	// set, which is the same as DowngradeWarn for extended attributes.
+	Downgrade map[Feature]DowngradePolicy
+
+	// What is done with a copy that fails, or is cancelled, part way
+	// through writing its data.
+	PartialFiles PartialFilePolicy
+
+	// Decides the mode of the copy from the source's mode, instead of it
+	// being copied. A ModeSpec's Apply method can be used to change modes
+	// as chmod would. Copy trees give it to directories too.
+	ModeMapper ModeMapper
+}
+
+// What a CopyFunc2 did.
+type CopyResult struct {
+	// Where the file was copied to, which isn't the destination that was
+	// passed in if that was a directory.
+	Dst string
+
+	// The amount of data copied.
+	Bytes int64
+
+	// The digests of the data copied for each of CopyOptions.Hashes.
+	Digests map[crypto.Hash][]byte
+
+	// Set if the copy is a symbolic link, rather than a copy of the data.
+	Symlink bool
+
+	// Set if nothing was copied, but that isn't an error.
+	Skipped bool
+
+	// What couldn't be preserved exactly, such as a TimePrecisionWarning,
+	// or at all, such as a PreservationWarning.
+	Warnings []error
+}
+
+// A function that copies a single file, like CopyFunc, but which can be
+// cancelled, given options and report what it did. CopyContext() is the
+// default.
+type CopyFunc2 func(ctx context.Context, src, dst string, options *CopyOptions) (CopyResult, error)
+
+// Adapt a CopyFunc to the CopyFunc2 signature. The context is only checked
+// before the copy starts, and only the FollowSymlinks option is passed on.
+// The size of the result is found afterwards.
+func AdaptCopyFunc(fn CopyFunc) CopyFunc2 {
+	return func(ctx context.Context, src, dst string, options *CopyOptions) (CopyResult, error) {
+		if options == nil {
+			options = &CopyOptions{}
+		}
+		if err := ctx.Err(); err != nil {
+			return CopyResult{Dst: dst}, err
+		}
+
+		dst, err := fn(src, dst, options.FollowSymlinks)
+		result := CopyResult{Dst: dst}
+		if err != nil {
+			return result, err
+		}
+
+		info, err := os.Lstat(dst)
+		if err == nil && info.Mode().IsRegular() {
+			result.Bytes = info.Size()
+		}
+		return result, nil
+	}
+}
+
+// Adapt a CopyFunc2 to the CopyFunc signature, so it can be used where an
+// old-style function is expected. It is given a background context and
+// default options.
+func AdaptCopyFunc2(fn CopyFunc2) CopyFunc {
+	return func(src, dst string, followSymlinks bool) (string, error) {
+		result, err := fn(context.Background(), src, dst, &CopyOptions{FollowSymlinks: followSymlinks})
+		return result.Dst, err
+	}
+}
+
+// Copy from src to dst, returning early with the context's error if it
+// gets cancelled.
+func copyData(ctx context.Context, dst io.Writer, src io.Reader, bufferSize int) (int64, error) {
+	// Only wrap the reader if we have to, as that stops os.File from
+	// copying directly between files
+	if ctx.Done() != nil {
+		src = &contextReader{ctx, controlOf(ctx), src}
+	}
+	if bufferSize > 0 {
+		return io.CopyBuffer(dst, src, make([]byte, bufferSize))
+	}
+	return io.Copy(dst, src)
+}
+
+// Deal with the partial copy dst, which has been closed, as policy says.
+func cleanUpPartial(dst string, policy PartialFilePolicy) {
+	switch policy {
+	case PartialRemove:
+		os.Remove(dst)
+	case PartialKeep:
+		os.Rename(dst, dst+PartialSuffix)
+	}
+}
+
+type contextReader struct {
+	ctx     context.Context
+	control *Control // nil unless the context has one
+	r       io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if r.control != nil {
+		if err := r.control.wait(r.ctx); err != nil {
+			return 0, err
+		}
+	} else if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,379 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyContext(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chtimes(src, old, old)).To(Succeed())
+
+	result, err := CopyContext(context.Background(), src, makeTestPath("testdir"), &CopyOptions{
+		PreserveTimes: true,
+		Verify:        true,
+		BufferSize:    4,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(CopyResult{Dst: makeTestPath("testdir/testfile"), Bytes: 9}))
+
+	info, err := os.Stat(result.Dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.ModTime()).To(Equal(old))
+}
+
+func TestCopyContextCancelled(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dst := makeTestPath("testfile3")
+	_, err := CopyContext(ctx, makeTestPath("testfile"), dst, nil)
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(dst).NotTo(BeAnExistingFile())
+}
+
+func TestCopyContextSizeUnknown(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := "/proc/self/status"
+	if _, err := os.Stat(src); err != nil {
+		t.Skip("no /proc")
+	}
+	dst := makeTestPath("status")
+	_, err := CopyContext(context.Background(), src, dst, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("copied")))
+
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{SizeUnknown: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(BeNumerically(">", 0))
+	g.Expect(os.ReadFile(dst)).To(ContainSubstring("Name:"))
+}
+
+func TestCopyContextSizeCheck(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// Files in /proc say they're empty, as if they've grown when read
+	src := "/proc/self/status"
+	if _, err := os.Stat(src); err != nil {
+		t.Skip("no /proc")
+	}
+	dst := makeTestPath("status")
+
+	_, err := CopyContext(context.Background(), src, dst, nil)
+	var sizeErr *ShortCopyError
+	g.Expect(errors.As(err, &sizeErr)).To(BeTrue())
+	g.Expect(sizeErr.Src).To(Equal(src))
+	g.Expect(sizeErr.Dst).To(Equal(dst))
+	g.Expect(sizeErr.Size).To(BeZero())
+	g.Expect(sizeErr.Copied).To(BeNumerically(">", 0))
+	g.Expect(DescribeErrors(err)[0].Src).To(Equal(src))
+
+	for _, check := range []SizeCheck{SizeAtLeast, SizeUnchecked} {
+		result, err := CopyContext(context.Background(), src, dst, &CopyOptions{SizeCheck: check})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Bytes).To(BeNumerically(">", 0))
+	}
+}
+
+func TestCopyContextPartialFiles(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// Files in /proc are larger than their size, so copying them fails
+	src := "/proc/self/status"
+	if _, err := os.Stat(src); err != nil {
+		t.Skip("no /proc")
+	}
+	dst := makeTestPath("status")
+
+	_, err := CopyContext(context.Background(), src, dst, nil)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(dst).NotTo(BeAnExistingFile())
+
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{PartialFiles: PartialKeep})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(dst).NotTo(BeAnExistingFile())
+	g.Expect(dst + PartialSuffix).To(BeAnExistingFile())
+
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{PartialFiles: PartialLeave})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(dst).To(BeAnExistingFile())
+}
+
+func TestAdaptCopyFunc(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	copy2 := AdaptCopyFunc(Copy)
+	g.Expect(copy2(context.Background(), makeTestPath("testfile2"), makeTestPath("testdir"), nil)).
+		To(Equal(CopyResult{Dst: makeTestPath("testdir/testfile2"), Bytes: 10}))
+
+	copy1 := AdaptCopyFunc2(CopyContext)
+	g.Expect(copy1(makeTestPath("testfile"), makeTestPath("testdir"), false)).
+		To(Equal(makeTestPath("testdir/testfile")))
+}
+
+func TestCopyTreeContext(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+
+	var seen []*CopyOptions
+	options := &CopyTreeOptions{
+		Symlinks:    true,
+		CopyOptions: &CopyOptions{Verify: true},
+		CopyFunction2: func(ctx context.Context, src, dst string, options *CopyOptions) (CopyResult, error) {
+			seen = append(seen, options)
+			return CopyContext(ctx, src, dst, options)
+		},
+	}
+	result, err := CopyTreeContext(context.Background(), testdir, makeTestPath("../_test_copy"), options)
+	t.Cleanup(func() { os.RemoveAll(makeTestPath("../_test_copy")) })
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(TreeResult{Files: 4, Dirs: 2, Symlinks: 1, Bytes: 9 + 10 + 6 + 6}))
+	g.Expect(seen).To(HaveLen(4))
+	g.Expect(seen[0].Verify).To(BeTrue())
+}
+
+func TestCopyTreeStatsOnce(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	var sources []string
+	oldStat, oldLstat := statFile, lstatFile
+	t.Cleanup(func() { statFile, lstatFile = oldStat, oldLstat })
+	lstatFile = func(name string) (os.FileInfo, error) {
+		sources = append(sources, name)
+		return oldLstat(name)
+	}
+	statFile = func(name string) (os.FileInfo, error) {
+		if !strings.HasPrefix(name, makeTestPath("testdir3")) {
+			sources = append(sources, name)
+		}
+		return oldStat(name)
+	}
+
+	// The directory entries are enough, so no file is looked up again
+	g.Expect(CopyTree(makeTestPath("testdir"), makeTestPath("testdir3"), nil)).To(Succeed())
+	g.Expect(sources).To(BeEmpty())
+	g.Expect(makeTestPath("testdir3/file1")).To(BeAnExistingFile())
+}
+
+func TestCopyTreeSkipVanished(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Mkdir(makeTestPath("testdir/sub"), 0755)).To(Succeed())
+	// Remove entries after their directory has been read
+	vanish := func(dir string, entries []os.FileInfo) []string {
+		if dir == makeTestPath("testdir") {
+			g.Expect(os.RemoveAll(makeTestPath("testdir/file1"))).To(Succeed())
+			g.Expect(os.RemoveAll(makeTestPath("testdir/sub"))).To(Succeed())
+		}
+		return nil
+	}
+
+	var events []Event
+	result, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("testdir3"), &CopyTreeOptions{
+		Ignore:       vanish,
+		SkipVanished: true,
+		Events:       func(e Event) { events = append(events, e) },
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Files).To(Equal(1))
+	g.Expect(result.Vanished).To(Equal([]string{makeTestPath("testdir/file1"), makeTestPath("testdir/sub")}))
+	g.Expect(events[1]).To(Equal(Event{
+		Kind:   EventSkipped,
+		Src:    makeTestPath("testdir/file1"),
+		Dst:    makeTestPath("testdir3/file1"),
+		Reason: SkipVanished,
+	}))
+
+	g.Expect(os.WriteFile(makeTestPath("testdir/file1"), nil, 0644)).To(Succeed())
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("testdir4"), &CopyTreeOptions{Ignore: vanish})
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestCopyTreeSkipDir(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	shutiltest.CreateTree(t, makeTestPath("src"), shutiltest.Tree{
+		"a":                    shutiltest.File("a"),
+		"node_modules/x/index": shutiltest.File("x"),
+		"sub/.git/HEAD":        shutiltest.File("ref"),
+		"sub/b":                shutiltest.File("b"),
+	})
+	// Skipped directories are never read
+	var read []string
+	options := &CopyTreeOptions{
+		SkipDir: SkipDirsNamed(".git", "node_modules"),
+		Ignore: func(dir string, entries []os.FileInfo) []string {
+			read = append(read, dir)
+			return nil
+		},
+	}
+	var events []Event
+	options.Events = func(e Event) { events = append(events, e) }
+	result, err := CopyTreeContext(context.Background(), makeTestPath("src"), makeTestPath("dst"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Files).To(Equal(2))
+	g.Expect(read).To(Equal([]string{makeTestPath("src"), makeTestPath("src/sub")}))
+	g.Expect(Glob(makeTestPath("dst/**"))).To(Equal([]string{
+		makeTestPath("dst/a"),
+		makeTestPath("dst/sub"),
+		makeTestPath("dst/sub/b"),
+	}))
+	g.Expect(events).To(ContainElement(Event{
+		Kind:   EventSkipped,
+		Src:    makeTestPath("src/node_modules"),
+		Dst:    makeTestPath("dst/node_modules"),
+		Reason: SkipSubtree,
+	}))
+
+	read = nil
+	plan, err := PlanCopyTree(makeTestPath("src"), makeTestPath("dst2"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan.Totals.Files).To(Equal(2))
+	g.Expect(read).To(Equal([]string{makeTestPath("src"), makeTestPath("src/sub")}))
+}
+
+func TestCopyTreePreserve(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	link := makeTestPath("testdir/link")
+	g.Expect(os.Symlink("file1", link)).To(Succeed())
+	g.Expect(lutimes(link, old, old)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir"), old, old)).To(Succeed())
+
+	dst := makeTestPath("testdir3")
+	g.Expect(CopyTree(makeTestPath("testdir"), dst, &CopyTreeOptions{
+		Symlinks:    true,
+		CopyOptions: &CopyOptions{PreserveTimes: true, PreserveOwner: true},
+	})).To(Succeed())
+
+	info, err := os.Lstat(makeTestPath("testdir3/link"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(IsSymlink(info)).To(BeTrue())
+	g.Expect(info.ModTime()).To(Equal(old))
+
+	info, err = os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.ModTime()).To(Equal(old))
+}
+
+func TestCopyFilePreserveOwner(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	if err := os.Chown(src, 1234, 5678); err != nil {
+		t.Skipf("can't change ownership: %s", err)
+	}
+
+	dst := makeTestPath("testfile3")
+	_, err := CopyFileContext(context.Background(), src, dst, &CopyOptions{PreserveOwner: true})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	info, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	uid, gid, _ := fileOwner(info)
+	g.Expect([]int{uid, gid}).To(Equal([]int{1234, 5678}))
+}
+
+func TestCopyPreserveXattrs(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	if err := setXattr(src, "user.a", []byte("1")); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	link := makeTestPath("link")
+	g.Expect(os.Symlink("testfile", link)).To(Succeed())
+
+	dst := makeTestPath("testfile3")
+	_, err := CopyContext(context.Background(), link, dst, &CopyOptions{FollowSymlinks: true, PreserveXattrs: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(getXattr(dst, "user.a")).To(Equal([]byte("1")))
+
+	dst = makeTestPath("testfile4")
+	_, err = CopyContext(context.Background(), src, dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(listXattrs(dst)).To(BeEmpty())
+}
+
+func TestCopyPreallocate(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("testfile3")
+	result, err := CopyContext(context.Background(), makeTestPath("testfile"), dst, &CopyOptions{Preallocate: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(9)))
+	g.Expect(os.ReadFile(dst)).To(Equal([]byte("testfile\n")))
+
+	g.Expect(os.WriteFile(makeTestPath("empty"), nil, 0644)).To(Succeed())
+	_, err = CopyContext(context.Background(), makeTestPath("empty"), dst, &CopyOptions{Preallocate: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(dst)).To(BeEmpty())
+}
+
+func TestCopyDanglingSymlink(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("dangling")
+	g.Expect(os.Symlink("missing", src)).To(Succeed())
+	options := &CopyOptions{FollowSymlinks: true}
+
+	_, err := CopyContext(context.Background(), src, makeTestPath("copy1"), options)
+	g.Expect(err).To(MatchError(&DanglingSymlinkError{src, "missing"}))
+	g.Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+
+	options.DanglingSymlinks = DanglingSymlinkSkip
+	result, err := CopyContext(context.Background(), src, makeTestPath("copy2"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Skipped).To(BeTrue())
+	_, err = os.Lstat(makeTestPath("copy2"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	options.DanglingSymlinks = DanglingSymlinkCopyLink
+	result, err = CopyContext(context.Background(), src, makeTestPath("copy3"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Symlink).To(BeTrue())
+	g.Expect(os.Readlink(makeTestPath("copy3"))).To(Equal("missing"))
+}
+
+func TestCopyFileRelativeSymlink(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir/link")
+	g.Expect(os.Symlink("../testfile", src)).To(Succeed())
+
+	g.Expect(CopyFile(src, makeTestPath("copy"), true)).To(Succeed())
+	g.Expect(os.ReadFile(makeTestPath("copy"))).To(Equal([]byte("testfile\n")))
+
+	g.Expect(CopyFile(src, makeTestPath("testdir/link2"), false)).To(Succeed())
+	g.Expect(os.Readlink(makeTestPath("testdir/link2"))).To(Equal("../testfile"))
+}
@@ -0,0 +1,36 @@
+package shutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+// Return how many files the process has open.
+func openFiles(t *testing.T) int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("can't count open files: %s", err)
+	}
+	return len(entries)
+}
+
+func TestCopyTreeDirsExistOKClosesDirs(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	tree := shutiltest.Tree{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		tree[name+"/file"] = shutiltest.File(name)
+	}
+	src := makeTestPath("src")
+	shutiltest.CreateTree(t, src, tree)
+	dst := makeTestPath("dst")
+	g.Expect(CopyTree(src, dst, nil)).To(Succeed())
+
+	before := openFiles(t)
+	g.Expect(CopyTree(src, dst, &CopyTreeOptions{DirsExistOK: true})).To(Succeed())
+	g.Expect(openFiles(t)).To(Equal(before))
+}
@@ -0,0 +1,50 @@
+package shutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The metadata a PreservationWarning is about.
+const (
+	MetadataOwner        = "owner"
+	MetadataXattrs       = "extended attributes"
+	MetadataFileFlags    = "file flags"
+	MetadataCapabilities = "capabilities"
+	MetadataACLs         = "ACLs"
+	MetadataSELinux      = "SELinux context"
+	MetadataTimes        = "times"
+)
+
+// A warning that metadata a preservation option asked for wasn't given
+// to the copy Path, because the destination can't store it or the caller
+// isn't allowed to set it, such as the owner when not running as root.
+type PreservationWarning struct {
+	Path string
+
+	// One of the Metadata constants.
+	Metadata string
+
+	Err error
+}
+
+func (w PreservationWarning) Error() string {
+	return fmt.Sprintf("could not preserve %s of `%s`: %s", w.Metadata, w.Path, w.Err)
+}
+
+func (w PreservationWarning) Code() ErrorCode {
+	return CodeNotPreserved
+}
+
+func (w PreservationWarning) Unwrap() error {
+	return w.Err
+}
+
+// Report whether err is because metadata can't be preserved where it's
+// being copied to, rather than something having gone wrong.
+func cannotPreserve(err error) bool {
+	var notSupported *NotSupportedError
+	return errors.Is(err, os.ErrPermission) || isNotSupportedErrno(err) ||
+		errors.Is(err, errNoXattrs) || errors.As(err, &notSupported)
+}
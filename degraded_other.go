@@ -0,0 +1,9 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+// Report whether err is the system saying the operation isn't supported,
+// which isn't checked for on this platform.
+func isNotSupportedErrno(err error) bool {
+	return false
+}
@@ -0,0 +1,61 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPreservationWarning(t *testing.T) {
+	g := NewWithT(t)
+
+	err := &os.PathError{Op: "chown", Path: "file", Err: syscall.EPERM}
+	w := &PreservationWarning{"file", MetadataOwner, err}
+	g.Expect(w.Error()).To(Equal("could not preserve owner of `file`: chown file: operation not permitted"))
+	g.Expect(errors.Is(w, os.ErrPermission)).To(BeTrue())
+	g.Expect(DescribeErrors(w)).To(Equal([]ErrorInfo{{Path: "file", Code: CodeNotPreserved, Message: w.Error()}}))
+
+	g.Expect(cannotPreserve(err)).To(BeTrue())
+	g.Expect(cannotPreserve(&NotSupportedError{"copy ACLs", "file"})).To(BeTrue())
+	g.Expect(cannotPreserve(&os.PathError{Op: "chown", Path: "file", Err: syscall.ENOENT})).To(BeFalse())
+}
+
+func TestCopyTreeDegradePreservation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("files don't have owners")
+	}
+	setup(t)
+	g := NewWithT(t)
+
+	// Only root can give files to someone else
+	owner := func(uid, gid int) (int, int) { return 0, 0 }
+	if os.Geteuid() == 0 {
+		owner = func(uid, gid int) (int, int) { return 1234, 1234 }
+	}
+	options := &CopyOptions{PreserveOwner: true, OwnerMapper: owner}
+	treeOptions := &CopyTreeOptions{CopyOptions: options}
+	dst := makeTestPath("testdir3")
+	if os.Geteuid() != 0 {
+		_, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), dst, treeOptions)
+		g.Expect(errors.Is(err, os.ErrPermission)).To(BeTrue())
+		g.Expect(os.RemoveAll(dst)).To(Succeed())
+	}
+
+	options.DegradePreservation = true
+	result, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), dst, treeOptions)
+	g.Expect(err).NotTo(HaveOccurred())
+	if os.Geteuid() == 0 {
+		g.Expect(result.Warnings).To(BeEmpty())
+		return
+	}
+	// Each file and directory is warned about
+	g.Expect(result.Warnings).To(HaveLen(result.Files + result.Dirs))
+	var w *PreservationWarning
+	g.Expect(errors.As(result.Warnings[0], &w)).To(BeTrue())
+	g.Expect(w.Metadata).To(Equal(MetadataOwner))
+}
@@ -0,0 +1,14 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Report whether err is the system saying the operation isn't supported,
+// such as by the filesystem.
+func isNotSupportedErrno(err error) bool {
+	return errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EOPNOTSUPP)
+}
@@ -0,0 +1,18 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCannotPreserveNotSupported(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(cannotPreserve(&os.PathError{Op: "setxattr", Path: "file", Err: syscall.ENOTSUP})).To(BeTrue())
+	g.Expect(cannotPreserve(&os.PathError{Op: "setxattr", Path: "file", Err: syscall.EOPNOTSUPP})).To(BeTrue())
+}
@@ -0,0 +1,133 @@
+package shutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Returned by CopyTreeContext() with the ExpectEmptyDestination or
+// VerifyBeforeOverwrite options when something other than the copy
+// creates, changes or removes an entry of the destination while it runs,
+// such as another deploy writing to the same directory.
+type ConcurrentModificationError struct {
+	Path string
+
+	// "created", "modified" or "removed".
+	Change string
+}
+
+func (e ConcurrentModificationError) Error() string {
+	return fmt.Sprintf("`%s` was %s by another writer during the copy", e.Path, e.Change)
+}
+
+func (e ConcurrentModificationError) Code() ErrorCode {
+	return CodeConcurrentModification
+}
+
+// Report whether the destination should be watched for other writers.
+func (o *CopyTreeOptions) checksDestination() bool {
+	return o.ExpectEmptyDestination || o.VerifyBeforeOverwrite
+}
+
+// Check that an existing destination is an empty directory, as the
+// ExpectEmptyDestination option requires.
+func checkEmptyDestination(dst string) error {
+	entries, err := ioutil.ReadDir(dst)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return &AlreadyExistsError{dst}
+	}
+	return nil
+}
+
+// Record the entries of the destination directory dst as they are before
+// anything is copied into it, which is nothing with ExpectEmptyDestination.
+func (t *treeCopier) watchDestination(dst string) error {
+	seen := map[string]os.FileInfo{}
+	if !t.options.ExpectEmptyDestination {
+		entries, err := ioutil.ReadDir(dst)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, entry := range entries {
+			seen[entry.Name()] = entry
+		}
+	}
+	if t.seen == nil {
+		t.seen = map[string]map[string]os.FileInfo{}
+	}
+	t.seen[dst] = seen
+	return nil
+}
+
+// Check that dstPath is as it was last seen, before it's written to.
+func (t *treeCopier) checkEntry(dstPath string) error {
+	seen := t.seen[filepath.Dir(dstPath)]
+	info, err := os.Lstat(dstPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return compareEntry(dstPath, seen[filepath.Base(dstPath)], info)
+}
+
+// Record dstPath as the copy left it.
+func (t *treeCopier) recordEntry(dstPath string) error {
+	seen := t.seen[filepath.Dir(dstPath)]
+	info, err := os.Lstat(dstPath)
+	switch {
+	case os.IsNotExist(err):
+		delete(seen, filepath.Base(dstPath))
+	case err != nil:
+		return err
+	default:
+		seen[filepath.Base(dstPath)] = info
+	}
+	return nil
+}
+
+// Check that the entries of dst are those that were seen as it was copied,
+// and forget them.
+func (t *treeCopier) verifyDestination(dst string) error {
+	seen := t.seen[dst]
+	delete(t.seen, dst)
+	entries, err := ioutil.ReadDir(dst)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dst, entry.Name())
+		if err := compareEntry(path, seen[entry.Name()], entry); err != nil {
+			return err
+		}
+		delete(seen, entry.Name())
+	}
+	for name := range seen {
+		return &ConcurrentModificationError{filepath.Join(dst, name), "removed"}
+	}
+	return nil
+}
+
+// Compare what was seen at path with what is there now, either of which
+// is nil if there was nothing there. Besides being the same file, it has
+// to have the same type, size and modification time.
+func compareEntry(path string, seen, now os.FileInfo) error {
+	switch {
+	case seen == nil && now == nil:
+		return nil
+	case seen == nil:
+		return &ConcurrentModificationError{path, "created"}
+	case now == nil:
+		return &ConcurrentModificationError{path, "removed"}
+	}
+	if !os.SameFile(seen, now) || seen.Mode() != now.Mode() || seen.Size() != now.Size() || !seen.ModTime().Equal(now.ModTime()) {
+		return &ConcurrentModificationError{path, "modified"}
+	}
+	return nil
+}
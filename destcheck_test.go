@@ -0,0 +1,101 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+// Copy files with CopyContext(), calling race before the one named name.
+func racingCopy(name string, race func(dst string)) CopyFunc2 {
+	return func(ctx context.Context, src, dst string, options *CopyOptions) (CopyResult, error) {
+		if filepath.Base(src) == name {
+			race(dst)
+		}
+		return CopyContext(ctx, src, dst, options)
+	}
+}
+
+func TestExpectEmptyDestination(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	g.Expect(os.Mkdir(dst, 0755)).To(Succeed())
+	_, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), dst, &CopyTreeOptions{ExpectEmptyDestination: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(makeTestPath("out/file2")).To(BeARegularFile())
+
+	// The destination has to be empty to start with
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), dst, &CopyTreeOptions{ExpectEmptyDestination: true})
+	g.Expect(err).To(MatchError(&AlreadyExistsError{dst}))
+
+	// Another writer adding a file is caught
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out2"), &CopyTreeOptions{
+		ExpectEmptyDestination: true,
+		CopyFunction2: racingCopy("file1", func(dst string) {
+			ioutil.WriteFile(filepath.Join(filepath.Dir(dst), "other"), []byte("other\n"), 0644)
+		}),
+	})
+	var modErr *ConcurrentModificationError
+	g.Expect(errors.As(err, &modErr)).To(BeTrue())
+	g.Expect(modErr.Path).To(Equal(makeTestPath("out2/other")))
+	g.Expect(modErr.Change).To(Equal("created"))
+	g.Expect(DescribeErrors(err)[0].Path).To(Equal(modErr.Path))
+
+	// As is one writing a file before the copy does
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out3"), &CopyTreeOptions{
+		ExpectEmptyDestination: true,
+		CopyFunction2: racingCopy("file1", func(dst string) {
+			ioutil.WriteFile(filepath.Join(filepath.Dir(dst), "file2"), []byte("other\n"), 0644)
+		}),
+	})
+	g.Expect(err).To(MatchError(&ConcurrentModificationError{makeTestPath("out3/file2"), "created"}))
+}
+
+func TestVerifyBeforeOverwrite(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+	shutiltest.CreateTree(t, dst, shutiltest.Tree{
+		"file1":     shutiltest.File("old\n"),
+		"file2":     shutiltest.File("old\n"),
+		"sub/file3": shutiltest.File("kept\n"),
+	})
+	options := &CopyTreeOptions{DirsExistOK: true, VerifyBeforeOverwrite: true}
+	_, err := CopyTreeContext(context.Background(), src, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ioutil.ReadFile(filepath.Join(dst, "file2"))).To(Equal([]byte("file2\n")))
+	g.Expect(filepath.Join(dst, "sub/file3")).To(BeARegularFile())
+
+	// A file changed after its directory was read isn't overwritten
+	options.CopyFunction2 = racingCopy("file1", func(dst string) {
+		later := time.Now().Add(time.Hour)
+		os.Chtimes(filepath.Join(filepath.Dir(dst), "file2"), later, later)
+	})
+	_, err = CopyTreeContext(context.Background(), src, dst, options)
+	g.Expect(err).To(MatchError(&ConcurrentModificationError{filepath.Join(dst, "file2"), "modified"}))
+
+	// Nor is one that was removed
+	options.CopyFunction2 = racingCopy("file1", func(dst string) {
+		os.Remove(filepath.Join(filepath.Dir(dst), "file2"))
+	})
+	_, err = CopyTreeContext(context.Background(), src, dst, options)
+	g.Expect(err).To(MatchError(&ConcurrentModificationError{filepath.Join(dst, "file2"), "removed"}))
+
+	// Entries the copy leaves alone are checked once it's done
+	options.CopyFunction2 = racingCopy("file2", func(dst string) {
+		os.RemoveAll(filepath.Join(filepath.Dir(dst), "sub"))
+	})
+	_, err = CopyTreeContext(context.Background(), src, dst, options)
+	g.Expect(err).To(MatchError(&ConcurrentModificationError{filepath.Join(dst, "sub"), "removed"}))
+}
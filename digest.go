@@ -0,0 +1,61 @@
+package shutil
+
+import (
+	"crypto"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Returned for a hash in CopyOptions.Hashes whose package isn't linked
+// into the program, such as crypto/sha512 for crypto.SHA512.
+type UnavailableHashError struct {
+	Hash crypto.Hash
+}
+
+func (e UnavailableHashError) Error() string {
+	return fmt.Sprintf("the %s hash is not available", e.Hash)
+}
+
+func (e UnavailableHashError) Code() ErrorCode {
+	return CodeUnavailableHash
+}
+
+// Hashes the data written to it with several hashes at once.
+type digester struct {
+	hashes []crypto.Hash
+	states []hash.Hash
+	w      io.Writer
+}
+
+// Return a digester for the hashes, or nil if there are none.
+func newDigester(hashes []crypto.Hash) (*digester, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	d := &digester{hashes: hashes}
+	var writers []io.Writer
+	for _, h := range hashes {
+		if !h.Available() {
+			return nil, &UnavailableHashError{h}
+		}
+		state := h.New()
+		d.states = append(d.states, state)
+		writers = append(writers, state)
+	}
+	d.w = io.MultiWriter(writers...)
+	return d, nil
+}
+
+func (d *digester) Write(p []byte) (int, error) {
+	return d.w.Write(p)
+}
+
+// Return the digest of each hash of the data written.
+func (d *digester) digests() map[crypto.Hash][]byte {
+	digests := make(map[crypto.Hash][]byte, len(d.hashes))
+	for i, h := range d.hashes {
+		digests[h] = d.states[i].Sum(nil)
+	}
+	return digests
+}
@@ -0,0 +1,71 @@
+package shutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyContextHashes(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	data, err := os.ReadFile(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	sha1Sum := sha1.Sum(data)
+
+	result, err := CopyContext(context.Background(), makeTestPath("testfile"), makeTestPath("copy"), &CopyOptions{
+		Hashes: []crypto.Hash{crypto.SHA256, crypto.SHA1},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Digests).To(HaveLen(2))
+	g.Expect(hex.EncodeToString(result.Digests[crypto.SHA256])).To(Equal(sha256Hex(string(data))))
+	g.Expect(result.Digests[crypto.SHA1]).To(Equal(sha1Sum[:]))
+	g.Expect(FilesEqual(makeTestPath("testfile"), makeTestPath("copy"), nil)).To(BeTrue())
+
+	// Without hashes, there are no digests
+	result, err = CopyContext(context.Background(), makeTestPath("testfile"), makeTestPath("copy"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Digests).To(BeNil())
+}
+
+func TestCopyTreeHashes(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	digests := map[string]string{}
+	_, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("copy"), &CopyTreeOptions{
+		CopyOptions: &CopyOptions{Hashes: []crypto.Hash{crypto.SHA256}},
+		Events: func(e Event) {
+			if e.Kind == EventFileCopied {
+				digests[e.Dst] = hex.EncodeToString(e.Digests[crypto.SHA256])
+			}
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(digests).To(HaveLen(2))
+	for dst, digest := range digests {
+		data, err := os.ReadFile(dst)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(digest).To(Equal(sha256Hex(string(data))), dst)
+	}
+}
+
+func TestCopyContextUnavailableHash(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	_, err := CopyContext(context.Background(), makeTestPath("testfile"), makeTestPath("copy"), &CopyOptions{
+		Hashes: []crypto.Hash{crypto.MD4},
+	})
+	var hashErr *UnavailableHashError
+	g.Expect(errors.As(err, &hashErr)).To(BeTrue())
+	g.Expect(hashErr.Hash).To(Equal(crypto.MD4))
+	g.Expect(makeTestPath("copy")).NotTo(BeAnExistingFile())
+}
@@ -0,0 +1,6 @@
+package shutil
+
+import "errors"
+
+// Returned by copyDirect() when files can't be copied with direct I/O.
+var errNoDirectIO = errors.New("direct I/O is not supported")
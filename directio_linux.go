@@ -0,0 +1,98 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// What O_DIRECT needs buffers, offsets and lengths to be multiples
+	// of. Devices need at most their logical block size, which is no
+	// more than this.
+	directIOAlign = 4096
+
+	directIOBufferSize = 1 << 20
+)
+
+// Copy the data of src to dst with O_DIRECT, bypassing the page cache. Both
+// files must be at their start. If the filesystem of either doesn't
+// support it, nothing is copied and errNoDirectIO is returned.
+func copyDirect(ctx context.Context, dst, src *os.File) (int64, error) {
+	if setDirect(src, true) != nil {
+		return 0, errNoDirectIO
+	}
+	defer setDirect(src, false)
+	if setDirect(dst, true) != nil {
+		return 0, errNoDirectIO
+	}
+	dstDirect := true
+	defer func() {
+		if dstDirect {
+			setDirect(dst, false)
+		}
+	}()
+
+	buf := alignedBuffer(directIOBufferSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			// Only the end of the file can be a partial block, which
+			// has to be written through the cache
+			if n%directIOAlign != 0 && dstDirect {
+				if err := setDirect(dst, false); err != nil {
+					return written, err
+				}
+				dstDirect = false
+			}
+			m, writeErr := dst.Write(buf[:n])
+			written += int64(m)
+			if writeErr != nil {
+				return written, writeErr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// Turn O_DIRECT on or off for an open file.
+func setDirect(f *os.File, direct bool) error {
+	fd := int(f.Fd())
+	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFL, 0)
+	if err != nil {
+		return &os.PathError{Op: "fcntl", Path: f.Name(), Err: err}
+	}
+	if direct {
+		flags |= unix.O_DIRECT
+	} else {
+		flags &^= unix.O_DIRECT
+	}
+	_, err = unix.FcntlInt(uintptr(fd), unix.F_SETFL, flags)
+	if err != nil {
+		return &os.PathError{Op: "fcntl", Path: f.Name(), Err: err}
+	}
+	return nil
+}
+
+// Return a buffer of size bytes whose start is aligned for O_DIRECT.
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directIOAlign)
+	offset := 0
+	if rem := int(uintptr(unsafe.Pointer(&buf[0])) % directIOAlign); rem != 0 {
+		offset = directIOAlign - rem
+	}
+	return buf[offset : offset+size]
+}
@@ -0,0 +1,46 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"unsafe"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyDirect(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), directIOBufferSize/8)
+	data = append(data, "tail"...)
+	src := makeTestPath("large")
+	g.Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+
+	fsrc, err := os.Open(src)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer fsrc.Close()
+	fdst, err := os.Create(makeTestPath("large2"))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer fdst.Close()
+
+	n, err := copyDirect(context.Background(), fdst, fsrc)
+	if err == errNoDirectIO {
+		t.Skip("direct I/O not supported")
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(int64(len(data))))
+	g.Expect(os.ReadFile(makeTestPath("large2"))).To(Equal(data))
+}
+
+func TestAlignedBuffer(t *testing.T) {
+	g := NewWithT(t)
+
+	for i := 0; i < 4; i++ {
+		buf := alignedBuffer(directIOAlign * 2)
+		g.Expect(buf).To(HaveLen(directIOAlign * 2))
+		g.Expect(uintptr(unsafe.Pointer(&buf[0])) % directIOAlign).To(BeZero())
+	}
+}
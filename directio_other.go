@@ -0,0 +1,13 @@
+//go:build !linux
+
+package shutil
+
+import (
+	"context"
+	"os"
+)
+
+// There's no O_DIRECT on this platform.
+func copyDirect(ctx context.Context, dst, src *os.File) (int64, error) {
+	return 0, errNoDirectIO
+}
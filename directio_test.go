@@ -0,0 +1,42 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyDirectIO(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// Not a multiple of the alignment, so the end is written through the
+	// cache
+	data := bytes.Repeat([]byte("0123456789abcdef"), largeFileSize/4)
+	data = append(data, "tail"...)
+	src := makeTestPath("large")
+	g.Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+
+	dst := makeTestPath("large2")
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{DirectIO: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(len(data))))
+	g.Expect(os.ReadFile(dst)).To(Equal(data))
+}
+
+func TestCopyCacheDrop(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), largeFileSize/8)
+	src := makeTestPath("large")
+	g.Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+
+	dst := makeTestPath("large2")
+	_, err := CopyContext(context.Background(), src, dst, &CopyOptions{Cache: CacheDrop})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(dst)).To(Equal(data))
+}
@@ -0,0 +1,133 @@
+package shutil
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Return an os.FileInfo for entry, for passing what os.ReadDir() or
+// filepath.WalkDir() found to the functions that take one. Name() and
+// IsDir() come from the entry, and the rest from entry.Info() the first
+// time any of it is needed, so filters that only look at names don't stat
+// anything. If the entry's file has gone by then, Mode() only has the type
+// bits, and Size() and ModTime() are zero.
+func EntryInfo(entry fs.DirEntry) os.FileInfo {
+	return &entryInfo{entry: entry}
+}
+
+type entryInfo struct {
+	entry fs.DirEntry
+	once  sync.Once
+	info  os.FileInfo
+}
+
+func (e *entryInfo) load() os.FileInfo {
+	e.once.Do(func() {
+		e.info, _ = e.entry.Info()
+	})
+	return e.info
+}
+
+func (e *entryInfo) Name() string { return e.entry.Name() }
+func (e *entryInfo) IsDir() bool  { return e.entry.IsDir() }
+
+func (e *entryInfo) Mode() os.FileMode {
+	if info := e.load(); info != nil {
+		return info.Mode()
+	}
+	return e.entry.Type()
+}
+
+func (e *entryInfo) Size() int64 {
+	if info := e.load(); info != nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (e *entryInfo) ModTime() time.Time {
+	if info := e.load(); info != nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func (e *entryInfo) Sys() interface{} {
+	if info := e.load(); info != nil {
+		return info.Sys()
+	}
+	return nil
+}
+
+// Call the IgnoreFunc fn for the entries of dir that os.ReadDir() returns,
+// without stating them up front.
+func IgnoreEntries(fn IgnoreFunc, dir string, entries []fs.DirEntry) []string {
+	infos := make([]os.FileInfo, len(entries))
+	for i, entry := range entries {
+		infos[i] = EntryInfo(entry)
+	}
+	return fn(dir, infos)
+}
+
+// Call the FilterFunc fn for an entry that os.ReadDir() or
+// filepath.WalkDir() returns, without stating it up front.
+func FilterEntry(fn FilterFunc, name string, entry fs.DirEntry) bool {
+	return fn(name, EntryInfo(entry))
+}
+
+// Copy the mode bits from src to dst like CopyModeResult(), given what
+// os.Lstat() returns for src, so callers that already have it don't stat
+// it again, or nil to have it stated. Use EntryInfo() to pass an
+// fs.DirEntry.
+func CopyModeInfo(src, dst string, srcInfo os.FileInfo, followSymlinks bool) (CopyResult, error) {
+	result := CopyResult{Dst: dst}
+	_, err := copyModeInfo(src, dst, srcInfo, followSymlinks, &result)
+	return result, err
+}
+
+// Copy the mode bits and times from src to dst like CopyStatResult(),
+// given what os.Lstat() returns for src, as CopyModeInfo() is.
+func CopyStatInfo(src, dst string, srcInfo os.FileInfo, followSymlinks bool) (CopyResult, error) {
+	result := CopyResult{Dst: dst}
+	srcInfo, err := copyModeInfo(src, dst, srcInfo, followSymlinks, &result)
+	if err != nil {
+		return result, err
+	}
+	warnings, err := preserveMetadata(src, dst, srcInfo, &CopyOptions{PreserveTimes: true})
+	result.Warnings = append(result.Warnings, warnings...)
+	return result, err
+}
+
+// Copy the mode bits from src, whose os.Lstat() is srcInfo, or nil if the
+// caller doesn't have it, to dst. Returns the information about the file
+// whose mode was copied, which is the file src links to if the link was
+// followed.
+func copyModeInfo(src, dst string, srcInfo os.FileInfo, followSymlinks bool, result *CopyResult) (os.FileInfo, error) {
+	var err error
+	if srcInfo == nil {
+		srcInfo, err = os.Lstat(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if IsSymlink(srcInfo) {
+		if !followSymlinks {
+			dstInfo, err := os.Lstat(dst)
+			if err != nil {
+				return nil, err
+			}
+			if IsSymlink(dstInfo) {
+				return srcInfo, copyLinkMode(dst, srcInfo, result)
+			}
+		}
+		// Atleast one is not a symlink, get the actual file stats
+		srcInfo, err = os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return srcInfo, os.Chmod(dst, srcInfo.Mode())
+}
@@ -0,0 +1,88 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestEntryInfo(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	entries, err := os.ReadDir(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, entry := range entries {
+		want, err := os.Lstat(filepath.Join(testdir, entry.Name()))
+		g.Expect(err).NotTo(HaveOccurred())
+		info := EntryInfo(entry)
+		g.Expect(info.Name()).To(Equal(want.Name()))
+		g.Expect(info.IsDir()).To(Equal(want.IsDir()))
+		g.Expect(info.Mode()).To(Equal(want.Mode()))
+		g.Expect(info.Size()).To(Equal(want.Size()))
+		g.Expect(info.ModTime()).To(Equal(want.ModTime()))
+	}
+}
+
+func TestEntryInfoRemoved(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	entries, err := os.ReadDir(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.RemoveAll(testdir)).To(Succeed())
+	for _, entry := range entries {
+		info := EntryInfo(entry)
+		g.Expect(info.Mode()).To(Equal(entry.Type()))
+		g.Expect(info.Size()).To(BeZero())
+	}
+}
+
+func TestIgnoreEntries(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	entries, err := os.ReadDir(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	ignored := IgnoreEntries(IgnorePatterns("testfile*"), testdir, entries)
+	sort.Strings(ignored)
+	g.Expect(ignored).To(Equal([]string{"testfile", "testfile2"}))
+
+	for _, entry := range entries {
+		g.Expect(FilterEntry(func(name string, info os.FileInfo) bool {
+			return info.IsDir()
+		}, entry.Name(), entry)).To(Equal(entry.IsDir()))
+	}
+}
+
+func TestCopyStatInfo(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("testfile2")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chmod(src, 0604)).To(Succeed())
+	g.Expect(os.Chtimes(src, old, old)).To(Succeed())
+	srcInfo, err := os.Lstat(src)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = CopyModeInfo(src, dst, srcInfo, true)
+	g.Expect(err).NotTo(HaveOccurred())
+	info, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0604)))
+	g.Expect(info.ModTime()).NotTo(Equal(old))
+
+	g.Expect(os.Chmod(dst, 0644)).To(Succeed())
+	_, err = CopyStatInfo(src, dst, srcInfo, true)
+	g.Expect(err).NotTo(HaveOccurred())
+	info, err = os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0604)))
+	g.Expect(info.ModTime()).To(Equal(old))
+}
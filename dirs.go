@@ -0,0 +1,141 @@
+package shutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Recursively create a directory and any missing parents, like Python's
+// os.makedirs().
+//
+// Unlike os.MkdirAll(), every directory that gets created is given exactly
+// `mode`, regardless of the umask. Directories that already exist are left
+// as they are.
+//
+// If the directory already exists an AlreadyExistsError is returned,
+// unless existOK is set. It is always returned if `path` exists but isn't
+// a directory.
+//
+// All the missing directories are created before any of them is given
+// `mode`, which is applied from `path` upwards, so a mode without the
+// owner's write and search bits, such as 0555, doesn't stop the rest of
+// the tree being created.
+func MakeDirs(path string, mode os.FileMode, existOK bool) error {
+	var created []string
+	err := makeDirs(filepath.Clean(path), existOK, &created)
+	if err != nil {
+		return err
+	}
+	for i := len(created) - 1; i >= 0; i-- {
+		err = os.Chmod(created[i], mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create path and any missing parents with mode 0700, adding each
+// directory that gets created to `created`, parents first.
+func makeDirs(path string, existOK bool, created *[]string) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		if !existOK || !info.IsDir() {
+			return &AlreadyExistsError{path}
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	parent := filepath.Dir(path)
+	if parent != path {
+		err = makeDirs(parent, true, created)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Mkdir(path, 0700)
+	if os.IsExist(err) {
+		// Somebody else created it first
+		if isDir, _ := isDirectory(path); isDir && existOK {
+			return nil
+		}
+		return &AlreadyExistsError{path}
+	} else if err != nil {
+		return err
+	}
+	*created = append(*created, path)
+	return nil
+}
+
+type CleanDirOptions struct {
+	Ignore IgnoreFunc
+}
+
+// Remove everything inside a directory, but keep the directory itself, so
+// its mode, ownership and ACLs are untouched. This is useful for emptying
+// a directory that is a mount point or is shared with another process.
+//
+// The optional Ignore function works as it does for CopyTree(): it is
+// called with each directory being cleaned and its entries, and returns
+// the names which should be kept. Subdirectories are cleaned the same way
+// and are only removed if nothing was kept inside them.
+func CleanDir(path string, options *CleanDirOptions) error {
+	if options == nil {
+		options = &CleanDirOptions{}
+	}
+
+	isDir, err := isDirectory(path)
+	if err != nil {
+		return err
+	}
+	if !isDir {
+		return &NotADirectoryError{path}
+	}
+
+	_, err = cleanDir(path, options)
+	return err
+}
+
+// Clean a directory, reporting whether it is now empty.
+func cleanDir(path string, options *CleanDirOptions) (bool, error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+
+	ignoredNames := []string{}
+	if options.Ignore != nil {
+		ignoredNames = options.Ignore(path, entries)
+	}
+
+	empty := true
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			empty = false
+			continue
+		}
+		entryPath := filepath.Join(path, entry.Name())
+
+		if entry.IsDir() && options.Ignore != nil {
+			entryEmpty, err := cleanDir(entryPath, options)
+			if err != nil {
+				return false, err
+			}
+			if !entryEmpty {
+				empty = false
+				continue
+			}
+		}
+
+		err = os.RemoveAll(entryPath)
+		if err != nil {
+			return false, err
+		}
+	}
+	return empty, nil
+}
@@ -0,0 +1,95 @@
+package shutil
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMakeDirs(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// The umask would normally remove the group and other write bits
+	g.Expect(MakeDirs(makeTestPath("a/b/c"), 0777, false)).To(Succeed())
+	for _, dir := range []string{"a", "a/b", "a/b/c"} {
+		info, err := os.Stat(makeTestPath(dir))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0777)))
+	}
+
+	// Existing directories keep their mode
+	info, _ := os.Stat(testdir)
+	g.Expect(MakeDirs(makeTestPath("testdir/new"), 0700, false)).To(Succeed())
+	after, _ := os.Stat(testdir)
+	g.Expect(after.Mode()).To(Equal(info.Mode()))
+}
+
+func TestMakeDirsReadOnly(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dirs := []string{"a", "a/b", "a/b/c"}
+	t.Cleanup(func() {
+		for _, dir := range dirs {
+			os.Chmod(makeTestPath(dir), 0755)
+		}
+	})
+	// Each parent is only made read-only once its children exist
+	g.Expect(MakeDirs(makeTestPath("a/b/c"), 0555, false)).To(Succeed())
+	for _, dir := range dirs {
+		info, err := os.Stat(makeTestPath(dir))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0555)))
+	}
+}
+
+func TestMakeDirsExists(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dir := makeTestPath("testdir")
+	g.Expect(MakeDirs(dir, 0755, false)).Should(MatchError(&AlreadyExistsError{dir}))
+	g.Expect(MakeDirs(dir, 0755, true)).To(Succeed())
+
+	file := makeTestPath("testfile")
+	g.Expect(MakeDirs(file, 0755, true)).Should(MatchError(&AlreadyExistsError{file}))
+}
+
+func TestCleanDir(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Chmod(testdir, 0700)).To(Succeed())
+	g.Expect(CleanDir(testdir, nil)).To(Succeed())
+
+	info, err := os.Stat(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+	g.Expect(ioutil.ReadDir(testdir)).To(BeEmpty())
+}
+
+func TestCleanDirIgnore(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	keep := func(src string, entries []os.FileInfo) []string {
+		return []string{"file1", "testfile2"}
+	}
+	g.Expect(CleanDir(testdir, &CleanDirOptions{Ignore: keep})).To(Succeed())
+
+	g.Expect(makeTestPath("testfile2")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("testdir/file1")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("testfile")).NotTo(BeAnExistingFile())
+	g.Expect(makeTestPath("testdir/file2")).NotTo(BeAnExistingFile())
+}
+
+func TestCleanDirNotADirectory(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	file := makeTestPath("testfile")
+	g.Expect(CleanDir(file, nil)).Should(MatchError(&NotADirectoryError{file}))
+}
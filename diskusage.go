@@ -0,0 +1,118 @@
+package shutil
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// How much space a filesystem has, in bytes, like the result of Python's
+// shutil.disk_usage().
+type Usage struct {
+	Total uint64
+	Used  uint64
+
+	// The space available to this process, which unlike Total - Used
+	// doesn't include any reserved for root.
+	Free uint64
+}
+
+// Return how much space the filesystem that path is on has.
+func DiskUsage(path string) (Usage, error) {
+	return diskUsage(path)
+}
+
+// Return the total size of the regular files in the tree at path, or the
+// size of path if it's a file. Symbolic links aren't followed or counted.
+func TreeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// How much space to leave free on a filesystem after copying to it. The
+// larger of the two is used.
+type Headroom struct {
+	// A number of bytes.
+	Bytes uint64
+
+	// A fraction of the filesystem's total size, such as 0.05 for 5%.
+	Fraction float64
+}
+
+// Returned by EnsureSpace() when there isn't enough space for a copy.
+type InsufficientSpaceError struct {
+	Path string
+
+	// The size of the copy plus the headroom.
+	Required uint64
+
+	// The space available.
+	Available uint64
+}
+
+func (e InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("not enough space for `%s`: %d bytes required, %d available", e.Path, e.Required, e.Available)
+}
+
+func (e InsufficientSpaceError) Code() ErrorCode {
+	return CodeInsufficientSpace
+}
+
+// Check that there is space to copy src to dst, returning an
+// InsufficientSpaceError if not, so that a copy can fail early rather
+// than part way through. Copies that may not fit should leave room for
+// everything else using the filesystem, so the headroom is required to be
+// left free as well.
+//
+// dst doesn't need to exist yet. The space files take up on dst is
+// estimated by their sizes, so copies of many small files need more
+// headroom, and copies of sparse files less.
+func EnsureSpace(src, dst string, headroom Headroom) error {
+	size, err := TreeSize(src)
+	if err != nil {
+		return err
+	}
+	return ensureSpace(size, dst, headroom)
+}
+
+// Check that there is space to make the changes in plan, as made for an
+// operation writing to dst, like EnsureSpace(). The plan's totals are
+// used, so the tree isn't read again.
+func EnsurePlanSpace(dst string, plan Plan, headroom Headroom) error {
+	return ensureSpace(plan.Totals.Bytes, dst, headroom)
+}
+
+// Check that there is space for size bytes in dst, and the headroom.
+func ensureSpace(size int64, dst string, headroom Headroom) error {
+	_, dir, err := existingAncestor(dst)
+	if err != nil {
+		return err
+	}
+	usage, err := DiskUsage(dir)
+	if err != nil {
+		return err
+	}
+
+	reserve := headroom.Bytes
+	if fraction := uint64(headroom.Fraction * float64(usage.Total)); fraction > reserve {
+		reserve = fraction
+	}
+	if reserve <= usage.Free && uint64(size) <= usage.Free-reserve {
+		return nil
+	}
+	required := uint64(size) + reserve
+	if required < reserve {
+		required = math.MaxUint64
+	}
+	return &InsufficientSpaceError{dst, required, usage.Free}
+}
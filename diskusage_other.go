@@ -0,0 +1,7 @@
+//go:build !darwin && !freebsd && !linux && !windows
+
+package shutil
+
+func diskUsage(path string) (Usage, error) {
+	return Usage{}, &NotSupportedError{"statfs", path}
+}
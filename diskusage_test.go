@@ -0,0 +1,84 @@
+package shutil
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiskUsage(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	usage, err := DiskUsage(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(usage.Total).To(BeNumerically(">", 0))
+	g.Expect(usage.Used).To(BeNumerically("<=", usage.Total))
+	g.Expect(usage.Free).To(BeNumerically("<=", usage.Total-usage.Used))
+}
+
+func TestTreeSize(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{
+		"big":  shutiltest.Sparse(1 << 20),
+		"link": shutiltest.Symlink("big"),
+	})
+	g.Expect(TreeSize(testdir)).To(Equal(int64(1<<20 + 31)))
+	g.Expect(TreeSize(makeTestPath("testfile"))).To(Equal(int64(9)))
+
+	_, err := TreeSize(makeTestPath("missing"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEnsureSpace(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out/nested")
+	g.Expect(EnsureSpace(makeTestPath("testdir"), dst, Headroom{})).To(Succeed())
+	g.Expect(EnsureSpace(makeTestPath("testdir"), dst, Headroom{Fraction: 0.0001})).To(Succeed())
+
+	usage, err := DiskUsage(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	size := uint64(12)
+
+	err = EnsureSpace(makeTestPath("testdir"), dst, Headroom{Bytes: usage.Free})
+	var spaceErr *InsufficientSpaceError
+	g.Expect(err).To(BeAssignableToTypeOf(spaceErr))
+	spaceErr = err.(*InsufficientSpaceError)
+	g.Expect(spaceErr.Path).To(Equal(dst))
+	// Something else may be using the filesystem at the same time
+	g.Expect(spaceErr.Required).To(Equal(usage.Free + size))
+	g.Expect(spaceErr.Available).To(BeNumerically("~", usage.Free, 1<<30))
+
+	err = EnsureSpace(makeTestPath("testdir"), dst, Headroom{Fraction: 1})
+	g.Expect(err).To(BeAssignableToTypeOf(spaceErr))
+
+	g.Expect(EnsureSpace(makeTestPath("testdir"), dst, Headroom{Bytes: math.MaxUint64})).NotTo(Succeed())
+}
+
+func TestEnsurePlanSpace(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	plan, err := PlanCopyTree(makeTestPath("testdir"), dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(EnsurePlanSpace(dst, plan, Headroom{})).To(Succeed())
+
+	usage, err := DiskUsage(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	err = EnsurePlanSpace(dst, plan, Headroom{Bytes: usage.Free})
+	var spaceErr *InsufficientSpaceError
+	g.Expect(errors.As(err, &spaceErr)).To(BeTrue())
+	g.Expect(spaceErr.Required).To(Equal(usage.Free + 12))
+
+	_, err = NewCopy(makeTestPath("testdir"), dst).Recursive().EnsureSpace(Headroom{Bytes: usage.Free}).Run()
+	g.Expect(errors.As(err, &spaceErr)).To(BeTrue())
+	g.Expect(dst).NotTo(BeADirectory())
+}
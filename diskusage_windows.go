@@ -0,0 +1,22 @@
+package shutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func diskUsage(path string) (Usage, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return Usage{}, err
+	}
+	var usage Usage
+	var totalFree uint64
+	err = windows.GetDiskFreeSpaceEx(name, &usage.Free, &usage.Total, &totalFree)
+	if err != nil {
+		return Usage{}, &os.PathError{Op: "GetDiskFreeSpaceEx", Path: path, Err: err}
+	}
+	usage.Used = usage.Total - totalFree
+	return usage, nil
+}
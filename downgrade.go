@@ -0,0 +1,146 @@
+package shutil
+
+import (
+	"fmt"
+)
+
+// What is done when the destination of a copy lacks a Feature that the
+// options ask for.
+type DowngradePolicy int
+
+const (
+	// Fail with an UnsupportedFeatureError.
+	DowngradeFail DowngradePolicy = iota
+	// Do without the feature, and report a warning.
+	DowngradeWarn
+	// Do without the feature quietly.
+	DowngradeSilently
+)
+
+// Returned when the destination lacks a feature whose DowngradePolicy is
+// DowngradeFail.
+type UnsupportedFeatureError struct {
+	Path    string
+	Feature Feature
+
+	// Why it's thought to be unsupported, or nil if a DestinationReport
+	// said so.
+	Err error
+}
+
+func (e UnsupportedFeatureError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("`%s` does not support %s: %s", e.Path, e.Feature, e.Err)
+	}
+	return fmt.Sprintf("`%s` does not support %s", e.Path, e.Feature)
+}
+
+func (e UnsupportedFeatureError) Code() ErrorCode {
+	return CodeUnsupportedFeature
+}
+
+func (e UnsupportedFeatureError) Unwrap() error {
+	return e.Err
+}
+
+// A warning that the copy Path was made without a feature its destination
+// lacks, as DowngradeWarn asks for, such as a symbolic link being copied
+// as the file it points to.
+type DowngradeWarning struct {
+	Path    string
+	Feature Feature
+	Err     error
+}
+
+func (w DowngradeWarning) Error() string {
+	return fmt.Sprintf("copied `%s` without %s: %s", w.Path, w.Feature, w.Err)
+}
+
+func (w DowngradeWarning) Code() ErrorCode {
+	return CodeDowngraded
+}
+
+func (w DowngradeWarning) Unwrap() error {
+	return w.Err
+}
+
+// The features that preserving each kind of metadata needs, where it
+// needs one that CopyOptions.Downgrade can be given a policy for.
+var metadataFeatures = map[string]Feature{
+	MetadataXattrs:       FeatureXattrs,
+	MetadataCapabilities: FeatureXattrs,
+	MetadataACLs:         FeatureXattrs,
+}
+
+// Return the policy for the feature, and whether the options give one.
+func (o *CopyOptions) downgradePolicy(feature Feature) (DowngradePolicy, bool) {
+	policy, ok := o.Downgrade[feature]
+	return policy, ok
+}
+
+// Return the policy for the feature, with what the options do by default
+// when they don't give one: metadata that can't be preserved is only
+// done without with DegradePreservation, and nothing else is.
+func (o *CopyOptions) effectiveDowngradePolicy(feature Feature) DowngradePolicy {
+	if policy, ok := o.downgradePolicy(feature); ok {
+		return policy
+	}
+	if feature == FeatureXattrs && o.DegradePreservation {
+		return DowngradeWarn
+	}
+	return DowngradeFail
+}
+
+// Check that the destination the report describes has the features that
+// copying a tree with the options needs, or that their policies let the
+// copy do without them, returning an UnsupportedFeatureError for the
+// first that isn't, so that a copy can be rejected before it starts.
+func (r DestinationReport) Check(options *CopyTreeOptions) error {
+	copyOptions := options.CopyOptions
+	if copyOptions == nil {
+		copyOptions = &CopyOptions{}
+	}
+	for _, feature := range r.Missing(options) {
+		if copyOptions.effectiveDowngradePolicy(feature) == DowngradeFail {
+			return &UnsupportedFeatureError{r.Dir, feature, nil}
+		}
+	}
+	return nil
+}
+
+// Returned by copySymlink() when a link can't be created, as the
+// destination can't have them, and the options say to do without them.
+type symlinksUnsupported struct {
+	err error
+}
+
+func (e *symlinksUnsupported) Error() string {
+	return e.err.Error()
+}
+
+// Return the warnings to report for copying the link as dst without
+// symbolic links.
+func (e *symlinksUnsupported) warnings(dst string, options *CopyOptions) []error {
+	if options.effectiveDowngradePolicy(FeatureSymlinks) != DowngradeWarn {
+		return nil
+	}
+	return []error{&DowngradeWarning{dst, FeatureSymlinks, e.err}}
+}
+
+// Return what to do about err, from creating the symbolic link dst: an
+// UnsupportedFeatureError or a symlinksUnsupported if the destination
+// can't have links and the options give a policy for that, and err
+// otherwise.
+func downgradeSymlink(dst string, err error, options *CopyOptions) error {
+	if !cannotPreserve(err) {
+		return err
+	}
+	policy, ok := options.downgradePolicy(FeatureSymlinks)
+	switch {
+	case !ok:
+		return err
+	case policy == DowngradeFail:
+		return &UnsupportedFeatureError{dst, FeatureSymlinks, err}
+	}
+	return &symlinksUnsupported{err}
+}
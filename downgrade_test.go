@@ -0,0 +1,59 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDowngradeSymlink(t *testing.T) {
+	g := NewWithT(t)
+
+	linkErr := &os.LinkError{Op: "symlink", Old: "target", New: "dst", Err: os.ErrPermission}
+	g.Expect(downgradeSymlink("dst", linkErr, &CopyOptions{})).To(Equal(linkErr))
+
+	// Errors that aren't because links aren't supported aren't changed
+	notExist := &os.LinkError{Op: "symlink", Old: "target", New: "dst", Err: os.ErrNotExist}
+	options := &CopyOptions{Downgrade: map[Feature]DowngradePolicy{FeatureSymlinks: DowngradeWarn}}
+	g.Expect(downgradeSymlink("dst", notExist, options)).To(Equal(notExist))
+
+	err := downgradeSymlink("dst", linkErr, options)
+	var unsupported *symlinksUnsupported
+	g.Expect(errors.As(err, &unsupported)).To(BeTrue())
+	g.Expect(unsupported.warnings("dst", options)).To(Equal([]error{&DowngradeWarning{"dst", FeatureSymlinks, linkErr}}))
+
+	options.Downgrade[FeatureSymlinks] = DowngradeSilently
+	g.Expect(unsupported.warnings("dst", options)).To(BeEmpty())
+
+	options.Downgrade[FeatureSymlinks] = DowngradeFail
+	err = downgradeSymlink("dst", linkErr, options)
+	var featureErr *UnsupportedFeatureError
+	g.Expect(errors.As(err, &featureErr)).To(BeTrue())
+	g.Expect(featureErr.Feature).To(Equal(FeatureSymlinks))
+	g.Expect(err).To(MatchError(os.ErrPermission))
+}
+
+func TestDestinationReportCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	report := DestinationReport{Dir: "dst"}
+	g.Expect(report.Check(&CopyTreeOptions{})).To(Succeed())
+
+	options := &CopyTreeOptions{Symlinks: true}
+	var featureErr *UnsupportedFeatureError
+	g.Expect(errors.As(report.Check(options), &featureErr)).To(BeTrue())
+	g.Expect(featureErr).To(Equal(&UnsupportedFeatureError{"dst", FeatureSymlinks, nil}))
+
+	options.CopyOptions = &CopyOptions{
+		PreserveXattrs: true,
+		Downgrade:      map[Feature]DowngradePolicy{FeatureSymlinks: DowngradeSilently},
+	}
+	g.Expect(errors.As(report.Check(options), &featureErr)).To(BeTrue())
+	g.Expect(featureErr.Feature).To(Equal(FeatureXattrs))
+
+	// Degrading preservation does without extended attributes
+	options.CopyOptions.DegradePreservation = true
+	g.Expect(report.Check(options)).To(Succeed())
+}
@@ -0,0 +1,236 @@
+package shutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// What FindDuplicates() does with the duplicates it finds.
+type DuplicateAction int
+
+const (
+	// Only report them.
+	DuplicatesReport DuplicateAction = iota
+	// Replace every file of a cluster but the first with a hard link to
+	// the first.
+	DuplicatesHardlink
+	// Remove every file of a cluster but the first.
+	DuplicatesDelete
+)
+
+// Options for FindDuplicates().
+type FindDuplicatesOptions struct {
+	// Leave out files smaller than this. Empty files are always left out.
+	MinSize int64
+
+	// Called like CopyTreeOptions.Ignore for each directory. Ignored
+	// names are left out, and ignored directories aren't looked into.
+	Ignore IgnoreFunc
+
+	// The number of files hashed at once.
+	Parallel int
+
+	// The most files hashing may have open at once, as for
+	// ApplyOptions.MaxOpenFiles.
+	MaxOpenFiles int
+
+	// What to do with the duplicates.
+	Action DuplicateAction
+}
+
+// Files with identical contents.
+type DuplicateCluster struct {
+	// The size of each file.
+	Size int64 `json:"size"`
+
+	// The files, sorted. Hard links to the same file only appear once.
+	Paths []string `json:"paths"`
+}
+
+// What FindDuplicates() found.
+type DuplicatesResult struct {
+	Clusters []DuplicateCluster `json:"clusters"`
+
+	// The space that all but one file of each cluster take up, which
+	// the Hardlink and Delete actions free.
+	Bytes int64 `json:"bytes"`
+}
+
+// Find the regular files in the tree root with identical contents, and
+// then act on them as the Action option says. Files are grouped by size
+// first, so only those of the same size are read, and then compared by
+// their SHA-256 hashes. Symbolic links aren't followed.
+//
+// The clusters are sorted by path, and the first file of each cluster is
+// the one that is kept. Replacing a file with a hard link is atomic, but
+// gives it the first file's metadata.
+func FindDuplicates(ctx context.Context, root string, options *FindDuplicatesOptions) (DuplicatesResult, error) {
+	if options == nil {
+		options = &FindDuplicatesOptions{}
+	}
+	var result DuplicatesResult
+	bySize := map[int64][]string{}
+	err := walkDuplicates(root, options, bySize)
+	if err != nil {
+		return result, err
+	}
+
+	var sizes []int64
+	for size, paths := range bySize {
+		if len(paths) > 1 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	for _, size := range sizes {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		clusters, err := clusterByHash(ctx, distinctFiles(bySize[size]), limitParallel(options.Parallel, options.MaxOpenFiles, 1))
+		if err != nil {
+			return result, err
+		}
+		for _, paths := range clusters {
+			result.Clusters = append(result.Clusters, DuplicateCluster{Size: size, Paths: paths})
+			result.Bytes += size * int64(len(paths)-1)
+		}
+	}
+	sort.Slice(result.Clusters, func(i, j int) bool {
+		return result.Clusters[i].Paths[0] < result.Clusters[j].Paths[0]
+	})
+
+	for _, cluster := range result.Clusters {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		for _, path := range cluster.Paths[1:] {
+			switch options.Action {
+			case DuplicatesHardlink:
+				err = replaceAtomic(path, func(tmp string) error {
+					err := os.Remove(tmp)
+					if err != nil {
+						return err
+					}
+					return os.Link(cluster.Paths[0], tmp)
+				})
+			case DuplicatesDelete:
+				err = os.Remove(path)
+			}
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// Group the regular files under dir by size.
+func walkDuplicates(dir string, options *FindDuplicatesOptions, bySize map[int64][]string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	ignoredNames := []string{}
+	if options.Ignore != nil {
+		ignoredNames = options.Ignore(dir, entries)
+	}
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			err = walkDuplicates(path, options, bySize)
+			if err != nil {
+				return err
+			}
+		case entry.Mode().IsRegular() && entry.Size() > 0 && entry.Size() >= options.MinSize:
+			bySize[entry.Size()] = append(bySize[entry.Size()], path)
+		}
+	}
+	return nil
+}
+
+// Leave out all but the first of the paths that are hard links to the
+// same file.
+func distinctFiles(paths []string) []string {
+	var distinct []string
+	var infos []os.FileInfo
+outer:
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil {
+			for _, seen := range infos {
+				if os.SameFile(info, seen) {
+					continue outer
+				}
+			}
+			infos = append(infos, info)
+		}
+		distinct = append(distinct, path)
+	}
+	return distinct
+}
+
+// Hash the files, which all have the same size, returning the groups of
+// more than one file with the same hash, sorted.
+func clusterByHash(ctx context.Context, paths []string, parallel int) ([][]string, error) {
+	if len(paths) < 2 {
+		return nil, nil
+	}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		byHash   = map[[sha256.Size]byte][]string{}
+	)
+	forEachParallel(len(paths), parallel, func(i int) {
+		var sum [sha256.Size]byte
+		err := ctx.Err()
+		if err == nil {
+			sum, err = hashFile(paths[i])
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		byHash[sum] = append(byHash[sum], paths[i])
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var clusters [][]string
+	for _, group := range byHash {
+		if len(group) > 1 {
+			sort.Strings(group)
+			clusters = append(clusters, group)
+		}
+	}
+	return clusters, nil
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
@@ -0,0 +1,56 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	shutiltest.CreateTree(t, makeTestPath("tree"), shutiltest.Tree{
+		"a":       shutiltest.File("same"),
+		"sub/b":   shutiltest.File("same"),
+		"sub/c":   shutiltest.File("diff"),
+		"d":       shutiltest.File("other contents"),
+		"e":       shutiltest.File("other contents"),
+		"empty1":  shutiltest.File(""),
+		"empty2":  shutiltest.File(""),
+		"ignored": shutiltest.File("same"),
+		"link":    shutiltest.Symlink("a"),
+	})
+	g.Expect(os.Link(makeTestPath("tree/a"), makeTestPath("tree/hardlink"))).To(Succeed())
+
+	options := &FindDuplicatesOptions{Parallel: 2, Ignore: IgnorePatterns("ignored")}
+	result, err := FindDuplicates(context.Background(), makeTestPath("tree"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(DuplicatesResult{
+		Clusters: []DuplicateCluster{
+			{Size: 4, Paths: []string{makeTestPath("tree/a"), makeTestPath("tree/sub/b")}},
+			{Size: 14, Paths: []string{makeTestPath("tree/d"), makeTestPath("tree/e")}},
+		},
+		Bytes: 18,
+	}))
+
+	options.MinSize = 5
+	options.Action = DuplicatesHardlink
+	result, err = FindDuplicates(context.Background(), makeTestPath("tree"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Clusters).To(HaveLen(1))
+	g.Expect(SamePath(makeTestPath("tree/d"), makeTestPath("tree/e"))).To(BeTrue())
+
+	// Hard links are no longer duplicates
+	options.MinSize = 0
+	options.Action = DuplicatesDelete
+	result, err = FindDuplicates(context.Background(), makeTestPath("tree"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(4)))
+	g.Expect(makeTestPath("tree/a")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("tree/sub/b")).NotTo(BeAnExistingFile())
+	g.Expect(makeTestPath("tree/ignored")).To(BeAnExistingFile())
+}
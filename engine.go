@@ -0,0 +1,91 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// How the data of files is copied. Engines other than EngineDefault are
+// used for every file, however small, as CopyOptions.SmallFileSize only
+// applies to EngineDefault.
+type CopyEngine int
+
+const (
+	// Copy within the kernel where the platform can, such as with
+	// copy_file_range(2), and with reads and writes otherwise.
+	EngineDefault CopyEngine = iota
+	// Keep many reads and writes of each file in flight at once with
+	// Linux's io_uring, which suits fast NVMe drives. Where io_uring
+	// isn't available, EngineDefault is used.
+	EngineIOUring
+	// Map each file into memory and write the copy from the map, which
+	// can be quicker for files of a few megabytes. With the Verify
+	// option, the map is hashed to check the copy against, rather than
+	// the source being read again. Files over 1GiB, and those that can't
+	// be mapped, are copied as with EngineDefault.
+	EngineMmap
+)
+
+// Returned by copyIOUring() when io_uring isn't available.
+var errNoIOUring = errors.New("io_uring is not available")
+
+// Copy the data of src, which is size bytes long, or -1 if that isn't
+// known, to dst in the way the options ask for, reading small files
+// whole, and falling back to copyData() where the platform or filesystem
+// can't. If the copy is to be verified and a hash of the data could be
+// taken as it was copied, that is returned for verifyHash(). The data
+// is written to tee as well, if it's set, which takes reads and writes.
+func copyFileData(ctx context.Context, dst, src *os.File, size int64, tee io.Writer, options *CopyOptions) (int64, []byte, error) {
+	if options.RateLimit != nil || tee != nil {
+		var r io.Reader = src
+		if options.RateLimit != nil {
+			r = &rateLimitedReader{ctx, r, options.RateLimit}
+		}
+		if tee != nil {
+			r = io.TeeReader(r, tee)
+		}
+		n, err := copyData(ctx, dst, r, options.BufferSize)
+		return n, nil, err
+	}
+	if size > 0 && size < smallFileSize(options) {
+		n, err := copySmall(ctx, dst, src, size)
+		return n, nil, err
+	}
+	if options.DirectIO && size >= largeFileSize {
+		n, err := copyDirect(ctx, dst, src)
+		if err != errNoDirectIO {
+			return n, nil, err
+		}
+	}
+	switch {
+	case options.Engine == EngineIOUring && size >= 0:
+		n, err := copyIOUring(ctx, dst, src, size)
+		if err != errNoIOUring {
+			return n, nil, err
+		}
+	case options.Engine == EngineMmap && size > 0 && size <= mmapMaxSize:
+		n, sum, err := copyMmap(ctx, dst, src, size, options.Verify)
+		if err != errNoMmap {
+			return n, sum, err
+		}
+	}
+	n, err := copyData(ctx, dst, src, options.BufferSize)
+	return n, nil, err
+}
+
+// Check that the copy dst of src has the SHA-256 hash sum, which was taken
+// of the data read from src as it was copied, returning a VerifyError if
+// not.
+func verifyHash(src, dst string, sum []byte) error {
+	dstSum, err := hashFile(dst)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, dstSum[:]) {
+		return &VerifyError{src, dst}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// What kind of error an error of this package is, so that callers can
+// switch on CodeOf(err) rather than trying errors.As() with each of the
+// error types. Every error type of the package has a Code() method
+// returning its code.
+type ErrorCode int
+
+const (
+	// An error that isn't one of this package's, nor one of the standard
+	// errors that have codes.
+	CodeUnknown ErrorCode = iota
+	CodeSameFile
+	CodeSpecialFile
+	CodeNotADirectory
+	CodeAlreadyExists
+	CodeCrossDevice
+	CodeDanglingSymlink
+	// A copy or move into the source itself.
+	CodeIntoSelf
+	// A copy that was read back didn't match its source.
+	CodeChecksumMismatch
+	CodeShortCopy
+	CodeInsufficientSpace
+	CodeStalled
+	CodeConcurrentModification
+	CodeLocked
+	// The platform or filesystem can't do something asked of it.
+	CodeNotSupported
+	// The destination lacks a feature, such as symbolic links.
+	CodeUnsupportedFeature
+	CodeImmutable
+	CodeUnsafePath
+	// An archive or compression format that isn't registered.
+	CodeUnknownFormat
+	CodeUnknownPreset
+	// A hash that isn't valid, or whose package isn't linked in.
+	CodeInvalidHash
+	CodeUnavailableHash
+	CodeStateMismatch
+	CodeInvalidConfig
+	// A mode or owner that can't be parsed.
+	CodeInvalidSpec
+	CodeMissingVariable
+	CodeInvalidTemplateName
+
+	// Metadata that couldn't be preserved, which is a warning.
+	CodeNotPreserved
+	// A feature the destination lacks that was done without, which is a
+	// warning.
+	CodeDowngraded
+	// Times that were preserved less precisely, which is a warning.
+	CodeTimePrecision
+	// Inode flags that couldn't be set, which is a warning.
+	CodeInodeFlags
+
+	// The standard errors, from the os and context packages, and
+	// ErrStopped.
+	CodeNotExist
+	CodeExist
+	CodePermission
+	CodeCancelled
+	CodeTimeout
+	CodeStopped
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	CodeSameFile:               "same-file",
+	CodeSpecialFile:            "special-file",
+	CodeNotADirectory:          "not-a-directory",
+	CodeAlreadyExists:          "already-exists",
+	CodeCrossDevice:            "cross-device",
+	CodeDanglingSymlink:        "dangling-symlink",
+	CodeIntoSelf:               "into-self",
+	CodeChecksumMismatch:       "checksum-mismatch",
+	CodeShortCopy:              "short-copy",
+	CodeInsufficientSpace:      "insufficient-space",
+	CodeStalled:                "stalled",
+	CodeConcurrentModification: "concurrent-modification",
+	CodeLocked:                 "locked",
+	CodeNotSupported:           "not-supported",
+	CodeUnsupportedFeature:     "unsupported-feature",
+	CodeImmutable:              "immutable",
+	CodeUnsafePath:             "unsafe-path",
+	CodeUnknownFormat:          "unknown-format",
+	CodeUnknownPreset:          "unknown-preset",
+	CodeInvalidHash:            "invalid-hash",
+	CodeUnavailableHash:        "unavailable-hash",
+	CodeStateMismatch:          "state-mismatch",
+	CodeInvalidConfig:          "invalid-config",
+	CodeInvalidSpec:            "invalid-spec",
+	CodeMissingVariable:        "missing-variable",
+	CodeInvalidTemplateName:    "invalid-template-name",
+	CodeNotPreserved:           "not-preserved",
+	CodeDowngraded:             "downgraded",
+	CodeTimePrecision:          "time-precision",
+	CodeInodeFlags:             "inode-flags",
+	CodeNotExist:               "not-exist",
+	CodeExist:                  "exist",
+	CodePermission:             "permission",
+	CodeCancelled:              "cancelled",
+	CodeTimeout:                "timeout",
+	CodeStopped:                "stopped",
+}
+
+// Return the name of the code, such as "already-exists", which is what
+// it's marshalled as.
+func (c ErrorCode) String() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (c ErrorCode) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *ErrorCode) UnmarshalText(text []byte) error {
+	for code, name := range errorCodeNames {
+		if name == string(text) {
+			*c = code
+			return nil
+		}
+	}
+	if string(text) == "unknown" {
+		*c = CodeUnknown
+		return nil
+	}
+	return fmt.Errorf("unknown error code `%s`", text)
+}
+
+// Return the code of err: that of the first error with a Code() method
+// that err is or wraps, or else of the standard error it is, or
+// CodeUnknown. Errors that wrap others, such as FileError, have the code
+// of what they wrap.
+func CodeOf(err error) ErrorCode {
+	var coded interface{ Code() ErrorCode }
+	switch {
+	case err == nil:
+		return CodeUnknown
+	case errors.As(err, &coded):
+		return coded.Code()
+	case errors.Is(err, ErrStopped):
+		return CodeStopped
+	case errors.Is(err, context.Canceled):
+		return CodeCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, os.ErrNotExist):
+		return CodeNotExist
+	case errors.Is(err, os.ErrExist):
+		return CodeExist
+	case errors.Is(err, os.ErrPermission):
+		return CodePermission
+	}
+	return CodeUnknown
+}
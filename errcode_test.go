@@ -0,0 +1,64 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCodeOf(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	notExist := &os.PathError{Op: "open", Path: "a", Err: os.ErrNotExist}
+	for _, test := range []struct {
+		err  error
+		code ErrorCode
+	}{
+		{nil, CodeUnknown},
+		{errors.New("oops"), CodeUnknown},
+		{&AlreadyExistsError{"a"}, CodeAlreadyExists},
+		{fmt.Errorf("copying: %w", &VerifyError{"a", "b"}), CodeChecksumMismatch},
+		{&FileError{"a", "b", &InsufficientSpaceError{Path: "b"}}, CodeInsufficientSpace},
+		{&FileError{"a", "b", notExist}, CodeNotExist},
+		{&DanglingSymlinkError{"a", "b"}, CodeDanglingSymlink},
+		{&MoveError{"a", "b", "copy", false, &CrossDeviceError{"a", "b", nil}}, CodeCrossDevice},
+		{&MultiError{[]error{&SpecialFileError{"a", nil}, notExist}}, CodeSpecialFile},
+		{&PreservationWarning{"a", MetadataOwner, os.ErrPermission}, CodeNotPreserved},
+		{&os.PathError{Op: "chown", Path: "a", Err: os.ErrPermission}, CodePermission},
+		{context.Canceled, CodeCancelled},
+		{ErrStopped, CodeStopped},
+	} {
+		g.Expect(CodeOf(test.err)).To(Equal(test.code), fmt.Sprint(test.err))
+	}
+
+	err := CopyFile(makeTestPath("testfile"), makeTestPath("testfile"), false)
+	g.Expect(CodeOf(err)).To(Equal(CodeSameFile))
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testfile"), makeTestPath("out"), nil)
+	g.Expect(CodeOf(err)).To(Equal(CodeNotADirectory))
+}
+
+func TestErrorCodeText(t *testing.T) {
+	g := NewWithT(t)
+
+	names := map[string]bool{}
+	for code := CodeUnknown + 1; code <= CodeStopped; code++ {
+		text, err := code.MarshalText()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(string(text)).NotTo(Equal("unknown"), fmt.Sprint(int(code)))
+		g.Expect(names).NotTo(HaveKey(string(text)))
+		names[string(text)] = true
+
+		var decoded ErrorCode
+		g.Expect(decoded.UnmarshalText(text)).To(Succeed())
+		g.Expect(decoded).To(Equal(code))
+	}
+	g.Expect(CodeAlreadyExists.String()).To(Equal("already-exists"))
+
+	var decoded ErrorCode
+	g.Expect(decoded.UnmarshalText([]byte("sideways"))).NotTo(Succeed())
+}
@@ -0,0 +1,75 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+)
+
+// A description of an error that other programs can read, such as in the
+// JSON of a Report.
+type ErrorInfo struct {
+	// The source and destination of the file being copied or moved, where
+	// the error records them.
+	Src string `json:"src,omitempty"`
+	Dst string `json:"dst,omitempty"`
+
+	// The file a system call failed on, or whose metadata wasn't preserved,
+	// where the error records it.
+	Path string `json:"path,omitempty"`
+
+	// What kind of error it is, as CodeOf() says, which doesn't change
+	// with the wording of the message. It's marshalled as its name, such
+	// as "already-exists", and left out if it's CodeUnknown.
+	Code ErrorCode `json:"code,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// Describe err, with each error of a MultiError described separately.
+// Nil is described as no errors.
+func DescribeErrors(err error) []ErrorInfo {
+	if err == nil {
+		return nil
+	}
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		var infos []ErrorInfo
+		for _, err := range multi.Errors {
+			infos = append(infos, DescribeErrors(err)...)
+		}
+		return infos
+	}
+
+	info := ErrorInfo{Code: CodeOf(err), Message: err.Error()}
+	var (
+		fileErr *FileError
+		moveErr *MoveError
+		sizeErr *ShortCopyError
+		pathErr *os.PathError
+		linkErr *os.LinkError
+		warning *PreservationWarning
+		modErr  *ConcurrentModificationError
+		lockErr *LockedError
+	)
+	switch {
+	case errors.As(err, &fileErr):
+		info.Src, info.Dst = fileErr.Src, fileErr.Dst
+	case errors.As(err, &moveErr):
+		info.Src, info.Dst = moveErr.Src, moveErr.Dst
+	case errors.As(err, &sizeErr):
+		info.Src, info.Dst = sizeErr.Src, sizeErr.Dst
+	case errors.As(err, &linkErr):
+		info.Src, info.Dst = linkErr.Old, linkErr.New
+	}
+	switch {
+	case errors.As(err, &warning):
+		info.Path = warning.Path
+	case errors.As(err, &modErr):
+		info.Path = modErr.Path
+	case errors.As(err, &lockErr):
+		info.Path = lockErr.Path
+	case errors.As(err, &pathErr):
+		info.Path = pathErr.Path
+	}
+	return []ErrorInfo{info}
+}
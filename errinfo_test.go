@@ -0,0 +1,50 @@
+package shutil
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDescribeErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	pathErr := &os.PathError{Op: "open", Path: "a/b", Err: os.ErrNotExist}
+	g.Expect(DescribeErrors(nil)).To(BeNil())
+	g.Expect(DescribeErrors(errors.New("oops"))).To(Equal([]ErrorInfo{{Message: "oops"}}))
+	g.Expect(DescribeErrors(&MultiError{[]error{
+		&FileError{"a/b", "c/b", pathErr},
+		&MoveError{"d", "e", "rename", false, errors.New("oops")},
+	}})).To(Equal([]ErrorInfo{
+		{Src: "a/b", Dst: "c/b", Path: "a/b", Code: CodeNotExist, Message: "`a/b` -> `c/b`: open a/b: file does not exist"},
+		{Src: "d", Dst: "e", Message: "Cannot move `d` to `e`: rename: oops"},
+	}))
+}
+
+func TestReportJSON(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	report, err := NewCopy(makeTestPath("missing"), makeTestPath("out")).Run()
+	g.Expect(err).To(HaveOccurred())
+	data, err := json.Marshal(report)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var decoded map[string]interface{}
+	g.Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+	g.Expect(decoded).To(HaveKeyWithValue("op", "copy"))
+	g.Expect(decoded).To(HaveKeyWithValue("files", 0.0))
+	g.Expect(decoded).To(HaveKey("duration"))
+	g.Expect(decoded["errors"]).To(ConsistOf(And(
+		HaveKeyWithValue("path", makeTestPath("missing")),
+		HaveKeyWithValue("code", "not-exist"),
+	)))
+
+	var roundTrip Report
+	g.Expect(json.Unmarshal(data, &roundTrip)).To(Succeed())
+	g.Expect(roundTrip.Errors).To(Equal(report.Errors))
+	g.Expect(roundTrip.Duration).To(Equal(report.Duration))
+}
@@ -0,0 +1,103 @@
+package shutil
+
+import (
+	"crypto"
+	"os"
+)
+
+// The kinds of thing an Event reports.
+type EventKind int
+
+const (
+	// The directory Dst was created.
+	EventDirCreated EventKind = iota
+	// The file Src was copied to Dst. Bytes is the amount of data copied.
+	EventFileCopied
+	// The symbolic link Dst was created, as a copy of Src.
+	EventSymlinkCreated
+	// The metadata of Dst was updated to match Src.
+	EventUpdated
+	// Dst was removed.
+	EventRemoved
+	// Src was renamed to Dst.
+	EventRenamed
+	// Src was left alone, for the given Reason.
+	EventSkipped
+	// Handling Src failed with Err.
+	EventErrored
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventDirCreated:
+		return "dir-created"
+	case EventFileCopied:
+		return "file-copied"
+	case EventSymlinkCreated:
+		return "symlink-created"
+	case EventUpdated:
+		return "updated"
+	case EventRemoved:
+		return "removed"
+	case EventRenamed:
+		return "renamed"
+	case EventSkipped:
+		return "skipped"
+	case EventErrored:
+		return "errored"
+	}
+	return "unknown"
+}
+
+// The reasons an EventSkipped is sent.
+const (
+	// The entry was left out by an IgnoreFunc.
+	SkipIgnored = "ignored"
+	// The entry is a symbolic link whose target doesn't exist.
+	SkipDanglingSymlink = "dangling symlink"
+	// The entry is a kind of file the operation doesn't copy.
+	SkipSpecialFile = "special file"
+	// The destination already matched.
+	SkipUnchanged = "unchanged"
+	// The entry was removed from the source while it was being copied.
+	SkipVanished = "vanished"
+	// The entry is a directory a SkipDirFunc skipped, with its contents.
+	SkipSubtree = "skipped subtree"
+)
+
+// Something a tree operation did to a single entry.
+type Event struct {
+	Kind EventKind
+	Src  string
+	Dst  string
+
+	// The amount of data copied, for EventFileCopied.
+	Bytes int64
+
+	// The digests of the data copied, for EventFileCopied, where
+	// CopyOptions.Hashes asks for them.
+	Digests map[crypto.Hash][]byte
+
+	// Why the entry was skipped, for EventSkipped. One of the Skip
+	// constants.
+	Reason string
+
+	// What went wrong, for EventErrored.
+	Err error
+
+	// What Dst was before it was copied over, updated or removed, where
+	// the operation had looked at it already, or nil.
+	Old os.FileInfo
+}
+
+// Called with each Event of a tree operation, in the order they happen.
+// Calls are never made concurrently, even when the operation itself runs
+// in parallel.
+type EventFunc func(Event)
+
+// Call fn with event, if it's set.
+func (fn EventFunc) send(event Event) {
+	if fn != nil {
+		fn(event)
+	}
+}
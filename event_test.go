@@ -0,0 +1,82 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// Collect events as "kind src -> dst", with the reason of skipped ones.
+type eventLog []string
+
+func (l *eventLog) record(event Event) {
+	entry := event.Kind.String() + " " + event.Src + " -> " + event.Dst
+	if event.Reason != "" {
+		entry += " (" + event.Reason + ")"
+	}
+	*l = append(*l, entry)
+}
+
+func TestCopyTreeEvents(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Symlink("missing", makeTestPath("testdir/zdangling"))).To(Succeed())
+	var events eventLog
+	_, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out"), &CopyTreeOptions{
+		IgnoreDanglingSymlinks: true,
+		Ignore:                 func(string, []os.FileInfo) []string { return []string{"file2"} },
+		Events:                 events.record,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal(eventLog{
+		"dir-created _test/testdir -> _test/out",
+		"file-copied _test/testdir/file1 -> _test/out/file1",
+		"skipped _test/testdir/file2 -> _test/out/file2 (ignored)",
+		"symlink-created _test/testdir/link -> _test/out/link",
+		"skipped _test/testdir/zdangling -> _test/out/zdangling (dangling symlink)",
+	}))
+
+	events = nil
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out"), &CopyTreeOptions{
+		Events: events.record,
+	})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(events).To(Equal(eventLog{"errored _test/testdir -> _test/out"}))
+}
+
+func TestSyncTreeEvents(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(CopyTree(makeTestPath("testdir"), makeTestPath("out"), nil)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("testdir/file2"), []byte("changed\n"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("out/extra"), nil, 0644)).To(Succeed())
+
+	var events eventLog
+	_, err := SyncTree(context.Background(), makeTestPath("testdir"), makeTestPath("out"), &SyncTreeOptions{
+		Delete: true,
+		Events: events.record,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal(eventLog{
+		"skipped _test/testdir/file1 -> _test/out/file1 (unchanged)",
+		"file-copied _test/testdir/file2 -> _test/out/file2",
+		"removed  -> _test/out/extra",
+	}))
+}
+
+func TestApplyEvents(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	plan, err := PlanMove(makeTestPath("testdir"), makeTestPath("moved"))
+	g.Expect(err).NotTo(HaveOccurred())
+	var events eventLog
+	_, err = Apply(context.Background(), plan, &ApplyOptions{Events: events.record})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(events).To(Equal(eventLog{"renamed _test/testdir -> _test/moved"}))
+}
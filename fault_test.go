@@ -0,0 +1,50 @@
+package shutil_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	shutil "github.com/gocardless/go-shutil"
+	"github.com/gocardless/go-shutil/faultfs"
+	"github.com/gocardless/go-shutil/memfs"
+	. "github.com/onsi/gomega"
+)
+
+var tree = map[string]string{
+	"a":     "a",
+	"b/c":   "c",
+	"b/d":   "d",
+	"b/e/f": "f",
+}
+
+func TestCopyTreeFSCreateFault(t *testing.T) {
+	g := NewWithT(t)
+	src := memfs.New()
+	g.Expect(memfs.BuildTree(src, "src", tree)).To(Succeed())
+
+	dst := faultfs.New(memfs.New(), faultfs.Fault{Op: faultfs.Create, Nth: 3})
+	result, err := shutil.CopyTreeFS(context.Background(), src, "src", dst, "dst", nil)
+	g.Expect(errors.Is(err, os.ErrPermission)).To(BeTrue())
+	g.Expect(err).To(MatchError(ContainSubstring("dst/b/d")))
+	g.Expect(result.Files).To(Equal(2))
+}
+
+func TestCopyTreeFSShortWrite(t *testing.T) {
+	g := NewWithT(t)
+	src := memfs.New()
+	g.Expect(memfs.BuildTree(src, "src", tree)).To(Succeed())
+
+	dst := faultfs.New(memfs.New(), faultfs.Fault{Op: faultfs.Write, Pattern: "dst/b/*", Err: io.ErrShortWrite})
+	_, err := shutil.CopyTreeFS(context.Background(), src, "src", dst, "dst", nil)
+	g.Expect(errors.Is(err, io.ErrShortWrite)).To(BeTrue())
+}
+
+// Return a filesystem holding just the test tree.
+func memfsTree(g *WithT) shutil.FS {
+	fsys := memfs.New()
+	g.Expect(memfs.BuildTree(fsys, "", tree)).To(Succeed())
+	return fsys
+}
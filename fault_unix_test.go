@@ -0,0 +1,41 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	shutil "github.com/gocardless/go-shutil"
+	"github.com/gocardless/go-shutil/faultfs"
+	"github.com/gocardless/go-shutil/memfs"
+	. "github.com/onsi/gomega"
+)
+
+func TestMoveFSCrossDeviceFallback(t *testing.T) {
+	g := NewWithT(t)
+	fsys := faultfs.New(memfs.New(), faultfs.Fault{Op: faultfs.Rename, Err: syscall.EXDEV})
+	g.Expect(memfs.BuildTree(fsys, "src", tree)).To(Succeed())
+
+	g.Expect(shutil.MoveFS(context.Background(), fsys, "src", fsys, "dst")).To(Succeed())
+	g.Expect(memfs.ReadTree(fsys, "dst")).To(HaveKeyWithValue("b/e/f", "f"))
+	_, err := fsys.Lstat("src")
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestMoveFSCopyFaultKeepsSource(t *testing.T) {
+	g := NewWithT(t)
+	fsys := faultfs.New(memfs.New(),
+		faultfs.Fault{Op: faultfs.Rename, Err: syscall.EXDEV},
+		faultfs.Fault{Op: faultfs.Write, Pattern: "dst/b/e/f"},
+	)
+	g.Expect(memfs.BuildTree(fsys, "src", tree)).To(Succeed())
+
+	err := shutil.MoveFS(context.Background(), fsys, "src", fsys, "dst")
+	g.Expect(errors.Is(err, os.ErrPermission)).To(BeTrue())
+	fsys.Reset()
+	memfs.AssertTreesEqual(t, fsys, "src", memfsTree(g), "")
+}
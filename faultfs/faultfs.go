@@ -0,0 +1,265 @@
+// A shutil.FS that injects errors into the calls made on it, so that
+// error handling and recovery can be tested deterministically.
+package faultfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	shutil "github.com/gocardless/go-shutil"
+)
+
+// An operation that can be made to fail.
+type Op string
+
+const (
+	Open     Op = "open"
+	Create   Op = "create"
+	Mkdir    Op = "mkdir"
+	Lstat    Op = "lstat"
+	ReadDir  Op = "readdir"
+	Readlink Op = "readlink"
+	Symlink  Op = "symlink"
+	Chmod    Op = "chmod"
+	Chtimes  Op = "chtimes"
+	Remove   Op = "remove"
+	Rename   Op = "rename"
+	// Writing to a file returned by Create.
+	Write Op = "write"
+)
+
+// An error to inject. For example, to fail the third file created with a
+// permission error, renames as if they crossed devices, or writes part way
+// through:
+//
+//	faultfs.Fault{Op: faultfs.Create, Nth: 3}
+//	faultfs.Fault{Op: faultfs.Rename, Err: syscall.EXDEV}
+//	faultfs.Fault{Op: faultfs.Write, Written: 10, Err: io.ErrShortWrite}
+type Fault struct {
+	Op Op
+
+	// If set, only calls on names matching this path.Match() pattern are
+	// affected. Renames match the old name, and writes the name of the
+	// file.
+	Pattern string
+
+	// Fail only the Nth affected call, counting from 1, rather than all
+	// of them.
+	Nth int
+
+	// The error to fail with, os.ErrPermission by default. It is wrapped
+	// in an *os.PathError, or an *os.LinkError for renames and links.
+	Err error
+
+	// For writes, how much of the data is written before failing.
+	Written int
+}
+
+type fault struct {
+	Fault
+	calls int
+}
+
+// Wraps another FS, injecting faults into the calls made on it. It is safe
+// for concurrent use if the wrapped FS is.
+type FS struct {
+	fsys shutil.FS
+
+	mu     sync.Mutex
+	faults []*fault
+}
+
+// Return an FS that passes calls on to fsys, or the local filesystem if
+// it's nil, apart from those failed by faults.
+func New(fsys shutil.FS, faults ...Fault) *FS {
+	if fsys == nil {
+		fsys = shutil.OSFS{}
+	}
+	f := &FS{fsys: fsys}
+	f.Inject(faults...)
+	return f
+}
+
+// Add faults to those already being injected.
+func (f *FS) Inject(faults ...Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, injected := range faults {
+		f.faults = append(f.faults, &fault{Fault: injected})
+	}
+}
+
+// Stop injecting any faults.
+func (f *FS) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+// Return the fault a call should fail with, if any.
+func (f *FS) check(op Op, name string) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var failed *Fault
+	for _, active := range f.faults {
+		if active.Op != op {
+			continue
+		}
+		if active.Pattern != "" {
+			if ok, _ := path.Match(active.Pattern, name); !ok {
+				continue
+			}
+		}
+		active.calls++
+		if failed == nil && (active.Nth == 0 || active.calls == active.Nth) {
+			failed = &active.Fault
+		}
+	}
+	return failed
+}
+
+func (f *FS) fail(op Op, name string) error {
+	fault := f.check(op, name)
+	if fault == nil {
+		return nil
+	}
+	return &os.PathError{Op: string(op), Path: name, Err: faultError(fault)}
+}
+
+func (f *FS) failLink(op Op, oldname, newname, name string) error {
+	fault := f.check(op, name)
+	if fault == nil {
+		return nil
+	}
+	return &os.LinkError{Op: string(op), Old: oldname, New: newname, Err: faultError(fault)}
+}
+
+func faultError(fault *Fault) error {
+	if fault.Err == nil {
+		return os.ErrPermission
+	}
+	return fault.Err
+}
+
+func (f *FS) Open(name string) (io.ReadCloser, error) {
+	if err := f.fail(Open, name); err != nil {
+		return nil, err
+	}
+	return f.fsys.Open(name)
+}
+
+func (f *FS) Create(name string, mode os.FileMode) (io.WriteCloser, error) {
+	if err := f.fail(Create, name); err != nil {
+		return nil, err
+	}
+	w, err := f.fsys.Create(name, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &writer{WriteCloser: w, fs: f, name: name}, nil
+}
+
+func (f *FS) Mkdir(name string, mode os.FileMode) error {
+	if err := f.fail(Mkdir, name); err != nil {
+		return err
+	}
+	return f.fsys.Mkdir(name, mode)
+}
+
+func (f *FS) Lstat(name string) (os.FileInfo, error) {
+	if err := f.fail(Lstat, name); err != nil {
+		return nil, err
+	}
+	return f.fsys.Lstat(name)
+}
+
+func (f *FS) ReadDir(name string) ([]os.FileInfo, error) {
+	if err := f.fail(ReadDir, name); err != nil {
+		return nil, err
+	}
+	return f.fsys.ReadDir(name)
+}
+
+func (f *FS) Readlink(name string) (string, error) {
+	if err := f.fail(Readlink, name); err != nil {
+		return "", err
+	}
+	return f.fsys.Readlink(name)
+}
+
+func (f *FS) Symlink(oldname, newname string) error {
+	if err := f.failLink(Symlink, oldname, newname, newname); err != nil {
+		return err
+	}
+	return f.fsys.Symlink(oldname, newname)
+}
+
+func (f *FS) Chmod(name string, mode os.FileMode) error {
+	if err := f.fail(Chmod, name); err != nil {
+		return err
+	}
+	return f.fsys.Chmod(name, mode)
+}
+
+func (f *FS) Chtimes(name string, atime, mtime time.Time) error {
+	if err := f.fail(Chtimes, name); err != nil {
+		return err
+	}
+	return f.fsys.Chtimes(name, atime, mtime)
+}
+
+func (f *FS) Remove(name string) error {
+	if err := f.fail(Remove, name); err != nil {
+		return err
+	}
+	return f.fsys.Remove(name)
+}
+
+// Rename within the wrapped FS, which fails with a
+// shutil.NotSupportedError unless it's a shutil.RenameFS.
+func (f *FS) Rename(oldname, newname string) error {
+	if err := f.failLink(Rename, oldname, newname, oldname); err != nil {
+		return err
+	}
+	renamer, ok := f.fsys.(shutil.RenameFS)
+	if !ok {
+		return &shutil.NotSupportedError{Op: "rename", Path: oldname}
+	}
+	return renamer.Rename(oldname, newname)
+}
+
+// Injects faults into writes to a file.
+type writer struct {
+	io.WriteCloser
+	fs   *FS
+	name string
+}
+
+// Abort the underlying writer, if it can be, or else close it.
+func (w *writer) Abort(err error) error {
+	if aborter, ok := w.WriteCloser.(shutil.AbortWriter); ok {
+		return aborter.Abort(err)
+	}
+	return w.WriteCloser.Close()
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	fault := w.fs.check(Write, w.name)
+	if fault == nil {
+		return w.WriteCloser.Write(p)
+	}
+
+	written := fault.Written
+	if written > len(p) {
+		written = len(p)
+	}
+	n, err := w.WriteCloser.Write(p[:written])
+	if err != nil {
+		return n, err
+	}
+	return n, &os.PathError{Op: string(Write), Path: w.name, Err: faultError(fault)}
+}
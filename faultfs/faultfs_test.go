@@ -0,0 +1,69 @@
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/gocardless/go-shutil/memfs"
+	. "github.com/onsi/gomega"
+)
+
+func TestNth(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New(memfs.New(), Fault{Op: Mkdir, Nth: 2})
+
+	g.Expect(fsys.Mkdir("a", 0755)).To(Succeed())
+	err := fsys.Mkdir("b", 0755)
+	g.Expect(err).To(MatchError(&os.PathError{Op: "mkdir", Path: "b", Err: os.ErrPermission}))
+	g.Expect(fsys.Mkdir("b", 0755)).To(Succeed())
+}
+
+// Errors to inject, which stand in for ENOSPC and EXDEV, as not every
+// platform has them.
+var (
+	errNoSpace     = errors.New("no space left on device")
+	errCrossDevice = errors.New("invalid cross-device link")
+)
+
+func TestPattern(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New(memfs.New(), Fault{Op: Create, Pattern: "*.log", Err: errNoSpace})
+
+	_, err := fsys.Create("file.txt", 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = fsys.Create("file.log", 0644)
+	g.Expect(errors.Is(err, errNoSpace)).To(BeTrue())
+
+	fsys.Reset()
+	_, err = fsys.Create("file.log", 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestShortWrite(t *testing.T) {
+	g := NewWithT(t)
+	inner := memfs.New()
+	fsys := New(inner, Fault{Op: Write, Nth: 2, Written: 2, Err: io.ErrShortWrite})
+
+	w, err := fsys.Create("file", 0644)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.Write([]byte("abc"))).To(Equal(3))
+	n, err := w.Write([]byte("def"))
+	g.Expect(n).To(Equal(2))
+	g.Expect(errors.Is(err, io.ErrShortWrite)).To(BeTrue())
+	g.Expect(w.Close()).To(Succeed())
+
+	info, err := inner.Lstat("file")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Size()).To(Equal(int64(5)))
+}
+
+func TestRename(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New(memfs.New(), Fault{Op: Rename, Err: errCrossDevice})
+	g.Expect(fsys.Mkdir("a", 0755)).To(Succeed())
+
+	err := fsys.Rename("a", "b")
+	g.Expect(err).To(MatchError(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: errCrossDevice}))
+}
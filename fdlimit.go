@@ -0,0 +1,26 @@
+package shutil
+
+// The most files a single copy has open at once: its source and
+// destination, and both again when it's verified.
+const fdsPerCopy = 4
+
+// Return how many of jobs, each of which keeps fdsPerJob files open, can
+// run at once without having more than maxOpenFiles open, and at most
+// parallel of them. A maxOpenFiles of zero is half the process's limit,
+// leaving the rest for everything else, or no limit if the platform
+// doesn't have one. At least one job always runs.
+func limitParallel(parallel, maxOpenFiles, fdsPerJob int) int {
+	if maxOpenFiles <= 0 {
+		maxOpenFiles = openFileLimit() / 2
+	}
+	if maxOpenFiles <= 0 {
+		return parallel
+	}
+	if limit := maxOpenFiles / fdsPerJob; parallel > limit {
+		parallel = limit
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	return parallel
+}
@@ -0,0 +1,9 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+// The number of files this process may have open, or zero if it isn't
+// limited.
+func openFileLimit() int {
+	return 0
+}
@@ -0,0 +1,20 @@
+package shutil
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLimitParallel(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(limitParallel(8, 100, fdsPerCopy)).To(Equal(8))
+	g.Expect(limitParallel(8, 16, fdsPerCopy)).To(Equal(4))
+	g.Expect(limitParallel(8, 2, fdsPerCopy)).To(Equal(1))
+	g.Expect(limitParallel(0, 2, fdsPerCopy)).To(Equal(1))
+
+	if limit := openFileLimit(); limit > 0 {
+		g.Expect(limitParallel(1<<20, 0, 1)).To(Equal(limit / 2))
+	}
+}
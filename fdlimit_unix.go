@@ -0,0 +1,16 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import "golang.org/x/sys/unix"
+
+// The number of files this process may have open, or zero if it isn't
+// limited.
+func openFileLimit() int {
+	var rlimit unix.Rlimit
+	err := unix.Getrlimit(unix.RLIMIT_NOFILE, &rlimit)
+	if err != nil || rlimit.Cur == unix.RLIM_INFINITY || rlimit.Cur > 1<<30 {
+		return 0
+	}
+	return int(rlimit.Cur)
+}
@@ -0,0 +1,91 @@
+package shutil
+
+import (
+	"io/fs"
+	"os"
+)
+
+// The kinds of file, as the type bits of their modes tell them apart.
+type FileKind int
+
+const (
+	KindRegular FileKind = iota
+	KindDir
+	KindSymlink
+	KindNamedPipe
+	KindSocket
+	KindBlockDevice
+	KindCharDevice
+	// Anything else, such as what os.ModeIrregular marks.
+	KindIrregular
+)
+
+func (k FileKind) String() string {
+	switch k {
+	case KindRegular:
+		return "regular file"
+	case KindDir:
+		return "directory"
+	case KindSymlink:
+		return "symbolic link"
+	case KindNamedPipe:
+		return "named pipe"
+	case KindSocket:
+		return "socket"
+	case KindBlockDevice:
+		return "block device"
+	case KindCharDevice:
+		return "character device"
+	}
+	return "irregular file"
+}
+
+// Return the kind of file whose mode is mode. Only the type bits are
+// looked at, so the mode can come from fs.DirEntry.Type().
+func KindOf(mode os.FileMode) FileKind {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return KindSymlink
+	case mode.IsDir():
+		return KindDir
+	case mode&os.ModeNamedPipe != 0:
+		return KindNamedPipe
+	case mode&os.ModeSocket != 0:
+		return KindSocket
+	case mode&os.ModeCharDevice != 0:
+		return KindCharDevice
+	case mode&os.ModeDevice != 0:
+		return KindBlockDevice
+	case mode.IsRegular():
+		return KindRegular
+	}
+	return KindIrregular
+}
+
+// Return the kind of file an entry of a directory is, without the stat
+// its Info() can take.
+func EntryKind(entry fs.DirEntry) FileKind {
+	return KindOf(entry.Type())
+}
+
+func IsRegular(fi os.FileInfo) bool {
+	return KindOf(fi.Mode()) == KindRegular
+}
+
+func IsDir(fi os.FileInfo) bool {
+	return KindOf(fi.Mode()) == KindDir
+}
+
+func IsFIFO(fi os.FileInfo) bool {
+	return KindOf(fi.Mode()) == KindNamedPipe
+}
+
+func IsSocket(fi os.FileInfo) bool {
+	return KindOf(fi.Mode()) == KindSocket
+}
+
+// Report whether fi describes a block or character device.
+func IsDevice(fi os.FileInfo) bool {
+	kind := KindOf(fi.Mode())
+	return kind == KindBlockDevice || kind == KindCharDevice
+}
@@ -0,0 +1,69 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+)
+
+func TestKindOf(t *testing.T) {
+	g := NewWithT(t)
+
+	for mode, kind := range map[os.FileMode]FileKind{
+		0644:                                 KindRegular,
+		os.ModeDir | 0755:                    KindDir,
+		os.ModeSymlink | 0777:                KindSymlink,
+		os.ModeNamedPipe | 0600:              KindNamedPipe,
+		os.ModeSocket | 0755:                 KindSocket,
+		os.ModeDevice | 0660:                 KindBlockDevice,
+		os.ModeDevice | os.ModeCharDevice:    KindCharDevice,
+		os.ModeIrregular:                     KindIrregular,
+		os.ModeSetuid | os.ModeSticky | 0755: KindRegular,
+	} {
+		g.Expect(KindOf(mode)).To(Equal(kind), mode.String())
+	}
+	g.Expect(KindCharDevice.String()).To(Equal("character device"))
+}
+
+func TestFileKindHelpers(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	info, err := os.Stat(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(IsRegular(info)).To(BeTrue())
+	g.Expect(IsDir(info)).To(BeFalse())
+
+	entries, err := os.ReadDir(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	kinds := map[string]FileKind{}
+	for _, entry := range entries {
+		kinds[entry.Name()] = EntryKind(entry)
+	}
+	g.Expect(kinds).To(HaveKeyWithValue("testdir", KindDir))
+	g.Expect(kinds).To(HaveKeyWithValue("testfile", KindRegular))
+
+	info, err = os.Stat(os.DevNull)
+	if err == nil && runtime.GOOS != "windows" {
+		g.Expect(IsDevice(info)).To(BeTrue())
+	}
+}
+
+func TestFileKindFifo(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	shutiltest.CreateTree(t, dir, shutiltest.Tree{"fifo": shutiltest.Fifo()})
+
+	info, err := os.Lstat(filepath.Join(dir, "fifo"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(KindOf(info.Mode())).To(Equal(KindNamedPipe))
+	g.Expect(IsFIFO(info)).To(BeTrue())
+	g.Expect(IsSocket(info)).To(BeFalse())
+	g.Expect(IsDevice(info)).To(BeFalse())
+	g.Expect(IsRegular(info)).To(BeFalse())
+}
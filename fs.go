@@ -0,0 +1,393 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// A filesystem that trees can be copied from and to, so that they can be
+// read from or written to somewhere other than local disk, such as a host
+// reached over SFTP or SMB. Names are separated by slashes.
+//
+// This is the minimal set of operations needed to copy a tree with local
+// semantics. Filesystems that can't support one, such as symbolic links,
+// should return a NotSupportedError.
+type FS interface {
+	// Open the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+
+	// Create or truncate the named file for writing.
+	Create(name string, mode os.FileMode) (io.WriteCloser, error)
+
+	// Create the named directory, returning an error that satisfies
+	// os.IsExist() if it already exists.
+	Mkdir(name string, mode os.FileMode) error
+
+	// Describe the named file without following it if it's a symbolic
+	// link.
+	Lstat(name string) (os.FileInfo, error)
+
+	// Describe the entries of the named directory, sorted by name, like
+	// Lstat().
+	ReadDir(name string) ([]os.FileInfo, error)
+
+	// Return the target of the named symbolic link.
+	Readlink(name string) (string, error)
+
+	// Create newname as a symbolic link to oldname.
+	Symlink(oldname, newname string) error
+
+	// Change the permission bits of the named file.
+	Chmod(name string, mode os.FileMode) error
+
+	// Change the access and modification times of the named file.
+	Chtimes(name string, atime, mtime time.Time) error
+
+	// Remove the named file or empty directory.
+	Remove(name string) error
+}
+
+// Implemented by filesystems that can rename files within themselves,
+// which MoveFS() uses in preference to copying.
+type RenameFS interface {
+	FS
+
+	// Rename oldname to newname, replacing newname if it's a file.
+	Rename(oldname, newname string) error
+}
+
+// Implemented by the writers an FS's Create() returns when the file is
+// only replaced once the writer is closed, such as an object being
+// uploaded, so that a copy that fails part way through can leave what was
+// there before alone.
+type AbortWriter interface {
+	io.WriteCloser
+
+	// Give up writing the file because of err, discarding what has been
+	// written.
+	Abort(err error) error
+}
+
+// Returned by an FS for an operation it can't support.
+type NotSupportedError struct {
+	Op   string
+	Path string
+}
+
+func (e NotSupportedError) Error() string {
+	return fmt.Sprintf("%s `%s`: operation not supported", e.Op, e.Path)
+}
+
+func (e NotSupportedError) Code() ErrorCode {
+	return CodeNotSupported
+}
+
+// The local filesystem.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+func (OSFS) Create(name string, mode os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(filepath.FromSlash(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+}
+
+func (OSFS) Mkdir(name string, mode os.FileMode) error {
+	return os.Mkdir(filepath.FromSlash(name), mode)
+}
+
+func (OSFS) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(filepath.FromSlash(name))
+}
+
+func (OSFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(filepath.FromSlash(name))
+}
+
+func (OSFS) Readlink(name string) (string, error) {
+	return os.Readlink(filepath.FromSlash(name))
+}
+
+func (OSFS) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, filepath.FromSlash(newname))
+}
+
+func (OSFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(filepath.FromSlash(name), mode)
+}
+
+func (OSFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(filepath.FromSlash(name), atime, mtime)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(filepath.FromSlash(name))
+}
+
+func (OSFS) Rename(oldname, newname string) error {
+	return os.Rename(filepath.FromSlash(oldname), filepath.FromSlash(newname))
+}
+
+// Options for CopyTreeFS().
+type CopyTreeFSOptions struct {
+	// Called like CopyTreeOptions.Ignore for each directory of the tree.
+	Ignore IgnoreFunc
+
+	// Called with each entry's name relative to src, leaving out those it
+	// returns false for.
+	Filter FilterFunc
+
+	// Called after each file has been copied.
+	Progress ProgressFunc
+
+	// Recreate symbolic links in dst, rather than leaving them out.
+	Symlinks bool
+
+	// Give files and directories the same modification times as in src.
+	PreserveTimes bool
+}
+
+// Recursively copy the directory tree src of srcFS to dst in dstFS, like
+// CopyTreeContext().
+//
+// Unlike CopyTree(), dst may already exist, in which case the tree is
+// merged into it. Files and directories are given the same permission
+// bits as in src. Symbolic links are only copied if the Symlinks option
+// is set, as links can't be followed through an FS, and other kinds of
+// file are left out.
+func CopyTreeFS(ctx context.Context, srcFS FS, src string, dstFS FS, dst string, options *CopyTreeFSOptions) (TreeResult, error) {
+	if options == nil {
+		options = &CopyTreeFSOptions{}
+	}
+
+	info, err := srcFS.Lstat(src)
+	if err != nil {
+		return TreeResult{}, err
+	}
+	if !info.IsDir() {
+		return TreeResult{}, &NotADirectoryError{src}
+	}
+
+	c := &fsTreeCopier{ctx: ctx, srcFS: srcFS, dstFS: dstFS, options: options, meter: newProgressMeter()}
+	err = c.copyDir(src, dst, "", info)
+	return c.result, err
+}
+
+// The state of a single CopyTreeFS() call.
+type fsTreeCopier struct {
+	ctx     context.Context
+	srcFS   FS
+	dstFS   FS
+	options *CopyTreeFSOptions
+	result  TreeResult
+	meter   *progressMeter
+}
+
+// Copy the directory src to dst. `prefix` is src's name relative to
+// the root of the tree, plus a slash unless src is the root.
+func (c *fsTreeCopier) copyDir(src, dst, prefix string, info os.FileInfo) error {
+	err := c.dstFS.Mkdir(dst, 0700)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	c.result.Dirs++
+
+	err = c.copyEntries(src, dst, prefix)
+	if err != nil {
+		return err
+	}
+	// Copying the entries changes the directory's times and may need
+	// permissions it won't have, so it's done last
+	return c.copyMetadata(dst, info)
+}
+
+func (c *fsTreeCopier) copyEntries(src, dst, prefix string) error {
+	entries, err := c.srcFS.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	ignoredNames := []string{}
+	if c.options.Ignore != nil {
+		ignoredNames = c.options.Ignore(src, entries)
+	}
+
+	for _, entry := range entries {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		if stringInSlice(entry.Name(), ignoredNames) {
+			continue
+		}
+		name := prefix + entry.Name()
+		if c.options.Filter != nil && !c.options.Filter(name, entry) {
+			continue
+		}
+
+		srcPath := path.Join(src, entry.Name())
+		dstPath := path.Join(dst, entry.Name())
+		switch {
+		case entry.IsDir():
+			err = c.copyDir(srcPath, dstPath, name+"/", entry)
+		case entry.Mode().IsRegular():
+			err = c.copyFile(srcPath, dstPath, entry)
+		case IsSymlink(entry) && c.options.Symlinks:
+			err = c.copySymlink(srcPath, dstPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fsTreeCopier) copyFile(src, dst string, info os.FileInfo) error {
+	n, err := c.copyData(src, dst, info)
+	c.result.Bytes += n
+	if err == nil {
+		err = c.copyMetadata(dst, info)
+	}
+	if err == nil {
+		c.result.Files++
+	}
+	if c.options.Progress != nil {
+		progress := Progress{
+			Src:       src,
+			Dst:       dst,
+			Err:       err,
+			FilesDone: c.result.Files,
+			BytesDone: c.result.Bytes,
+		}
+		c.meter.update(&progress)
+		c.options.Progress(progress)
+	}
+	return err
+}
+
+func (c *fsTreeCopier) copyData(src, dst string, info os.FileInfo) (int64, error) {
+	r, err := c.srcFS.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	w, err := c.dstFS.Create(dst, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	n, err := copyData(c.ctx, w, r, 0)
+	if err != nil {
+		if aborter, ok := w.(AbortWriter); ok {
+			aborter.Abort(err)
+		} else {
+			w.Close()
+		}
+		return n, err
+	}
+	return n, w.Close()
+}
+
+func (c *fsTreeCopier) copySymlink(src, dst string) error {
+	target, err := c.srcFS.Readlink(src)
+	if err != nil {
+		return err
+	}
+	err = c.dstFS.Symlink(target, dst)
+	if err != nil {
+		return err
+	}
+	c.result.Symlinks++
+	return nil
+}
+
+// Give dst the permission bits, and optionally the times, of info.
+func (c *fsTreeCopier) copyMetadata(dst string, info os.FileInfo) error {
+	err := c.dstFS.Chmod(dst, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if c.options.PreserveTimes {
+		return c.dstFS.Chtimes(dst, info.ModTime(), info.ModTime())
+	}
+	return nil
+}
+
+// Recursively move a file or directory src of srcFS to dst in dstFS, like
+// Move(), except that dst is always the new name and must not exist.
+//
+// If both filesystems are the same RenameFS, src is renamed. Otherwise, or
+// if the rename is across devices or not supported, src is copied with its
+// symbolic links, modes and times and then removed.
+func MoveFS(ctx context.Context, srcFS FS, src string, dstFS FS, dst string) error {
+	info, err := srcFS.Lstat(src)
+	if err != nil {
+		return err
+	}
+	_, err = dstFS.Lstat(dst)
+	if err == nil {
+		return &AlreadyExistsError{dst}
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if renamer, ok := srcFS.(RenameFS); ok && sameFS(srcFS, dstFS) {
+		err = renamer.Rename(src, dst)
+		var notSupported *NotSupportedError
+		if err == nil || !(isCrossDevice(err) || errors.As(err, &notSupported)) {
+			return err
+		}
+	}
+
+	options := &CopyTreeFSOptions{Symlinks: true, PreserveTimes: true}
+	c := &fsTreeCopier{ctx: ctx, srcFS: srcFS, dstFS: dstFS, options: options, meter: newProgressMeter()}
+	switch {
+	case info.IsDir():
+		err = c.copyDir(src, dst, "", info)
+	case IsSymlink(info):
+		err = c.copySymlink(src, dst)
+	case info.Mode().IsRegular():
+		err = c.copyFile(src, dst, info)
+	default:
+		return &SpecialFileError{src, info}
+	}
+	if err != nil {
+		return err
+	}
+	return removeAllFS(srcFS, src)
+}
+
+// Report whether two filesystems are the same one.
+func sameFS(a, b FS) bool {
+	// Comparing interfaces panics if their values can't be compared
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() && a == b
+}
+
+// Remove name from fsys, along with everything inside it.
+func removeAllFS(fsys FS, name string) error {
+	info, err := fsys.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := fsys.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			err = removeAllFS(fsys, path.Join(name, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return fsys.Remove(name)
+}
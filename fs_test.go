@@ -0,0 +1,114 @@
+package shutil
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyTreeFS(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+	g.Expect(os.MkdirAll(makeTestPath("out/testdir"), 0755)).To(Succeed())
+
+	var copied []string
+	result, err := CopyTreeFS(context.Background(), OSFS{}, testdir, OSFS{}, makeTestPath("out"), &CopyTreeFSOptions{
+		Ignore: func(dir string, entries []os.FileInfo) []string {
+			return []string{"out"}
+		},
+		Filter: func(name string, info os.FileInfo) bool {
+			return name != "testdir/file2"
+		},
+		Progress: func(p Progress) {
+			g.Expect(p.Err).NotTo(HaveOccurred())
+			copied = append(copied, p.Src)
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(TreeResult{Files: 3, Dirs: 2, Bytes: 25}))
+	g.Expect(copied).To(Equal([]string{
+		makeTestPath("testdir/file1"),
+		makeTestPath("testfile"),
+		makeTestPath("testfile2"),
+	}))
+
+	g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("out/testdir/file1"), nil)).To(BeTrue())
+	g.Expect(makeTestPath("out/testdir/file2")).NotTo(BeAnExistingFile())
+	_, err = os.Lstat(makeTestPath("out/link"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestCopyTreeFSNotADirectory(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	_, err := CopyTreeFS(context.Background(), OSFS{}, src, OSFS{}, makeTestPath("out"), nil)
+	g.Expect(err).To(MatchError(&NotADirectoryError{src}))
+}
+
+func TestCopyTreeFSCancelled(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := CopyTreeFS(ctx, OSFS{}, makeTestPath("testdir"), OSFS{}, makeTestPath("out"), nil)
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	entries, err := ioutil.ReadDir(makeTestPath("out"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+}
+
+func TestCopyTreeFSSymlinksAndMetadata(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Chmod(makeTestPath("testdir/file1"), 0600)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir/file1"), old, old)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir"), old, old)).To(Succeed())
+
+	result, err := CopyTreeFS(context.Background(), OSFS{}, makeTestPath("testdir"), OSFS{}, makeTestPath("out"), &CopyTreeFSOptions{
+		Symlinks:      true,
+		PreserveTimes: true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Symlinks).To(Equal(1))
+	g.Expect(os.Readlink(makeTestPath("out/link"))).To(Equal("file1"))
+
+	info, err := os.Stat(makeTestPath("out/file1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	g.Expect(info.ModTime()).To(Equal(old))
+
+	info, err = os.Stat(makeTestPath("out"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.ModTime()).To(Equal(old))
+}
+
+func TestMoveFS(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(MoveFS(context.Background(), OSFS{}, makeTestPath("testdir"), OSFS{}, makeTestPath("moved"))).To(Succeed())
+	g.Expect(makeTestPath("moved/file1")).To(BeARegularFile())
+	g.Expect(makeTestPath("testdir")).NotTo(BeADirectory())
+
+	store := newMemoryObjectStore()
+	bucket := ObjectStoreFS{Store: store}
+	g.Expect(MoveFS(context.Background(), OSFS{}, makeTestPath("moved"), bucket, "tree")).To(Succeed())
+	g.Expect(store.objects).To(HaveKey("tree/file1"))
+	g.Expect(makeTestPath("moved")).NotTo(BeADirectory())
+
+	err := MoveFS(context.Background(), OSFS{}, makeTestPath("testfile"), OSFS{}, makeTestPath("testfile2"))
+	g.Expect(err).To(MatchError(&AlreadyExistsError{makeTestPath("testfile2")}))
+}
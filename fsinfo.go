@@ -0,0 +1,203 @@
+package shutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var errNoReflink = errors.New("reflinks are not supported on this platform")
+
+// Describes the filesystem a path is on, so that callers can decide how
+// to copy to or from it.
+type FSInfo struct {
+	// The name of the filesystem type, such as "ext4", "btrfs" or "apfs",
+	// or "" if it isn't known.
+	Type string
+
+	// The size of the filesystem's blocks, or 0 if it isn't known.
+	BlockSize int64
+
+	// Whether files can be cloned, sharing their data until one of them
+	// is changed.
+	Reflink bool
+
+	// Whether files can have extended attributes.
+	Xattrs bool
+
+	// Whether files can have holes that take up no space.
+	Sparse bool
+
+	// Whether names that differ only in case are different files.
+	CaseSensitive bool
+}
+
+// Describe the filesystem that path is on. Path must be an existing
+// directory, or a file in one, that the caller can create files in, as
+// most of the features are detected by trying them out on temporary
+// files, which are removed again.
+func FilesystemInfo(path string) (FSInfo, error) {
+	var info FSInfo
+	dir, err := probeDir(path)
+	if err != nil {
+		return info, err
+	}
+	info.Type, info.BlockSize, err = statfs(dir)
+	if err != nil {
+		return info, err
+	}
+
+	if info.Reflink, err = probeReflink(dir); err != nil {
+		return info, err
+	}
+	if info.Xattrs, err = probeXattrs(dir); err != nil {
+		return info, err
+	}
+	if info.Sparse, err = probeSparse(dir, info.BlockSize); err != nil {
+		return info, err
+	}
+	if info.CaseSensitive, err = probeCaseSensitive(dir); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// The types of filesystems whose files are made up by the kernel, rather
+// than stored, such as "proc" and "sysfs", which a backup of a whole
+// system would leave out along with network filesystems and "tmpfs".
+var VirtualFilesystems = []string{
+	"autofs", "binfmt_misc", "bpf", "cgroup", "cgroup2", "configfs",
+	"debugfs", "devfs", "devpts", "efivarfs", "hugetlbfs", "mqueue", "nsfs",
+	"proc", "procfs", "pstore", "securityfs", "sysfs", "tracefs",
+}
+
+// Return a SkipDirFunc that skips the directories on filesystems of any
+// of the given types, as FSInfo.Type names them, such as "proc", "tmpfs"
+// or "nfs", so a copy of / doesn't have to list where they're mounted.
+// The filesystem of each device is only looked up once. Directories whose
+// filesystem can't be found out, including on platforms without statfs,
+// aren't skipped.
+func SkipFilesystems(types ...string) SkipDirFunc {
+	var mu sync.Mutex
+	devices := map[uint64]string{}
+	return func(path string, info os.FileInfo) bool {
+		dev, ok := fileDevice(info)
+		if ok {
+			mu.Lock()
+			fsType, seen := devices[dev]
+			mu.Unlock()
+			if seen {
+				return stringInSlice(fsType, types)
+			}
+		}
+		fsType, _, err := statfs(path)
+		if err != nil {
+			return false
+		}
+		if ok {
+			mu.Lock()
+			devices[dev] = fsType
+			mu.Unlock()
+		}
+		return stringInSlice(fsType, types)
+	}
+}
+
+// Report whether files on the same filesystem as path can be cloned, by
+// trying to clone a temporary file. Copies are made this way where
+// possible already, so this is only needed to choose between strategies
+// in advance.
+func SupportsReflink(path string) (bool, error) {
+	dir, err := probeDir(path)
+	if err != nil {
+		return false, err
+	}
+	return probeReflink(dir)
+}
+
+// Return the directory to create temporary files in to find out about
+// the filesystem path is on.
+func probeDir(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return filepath.Dir(path), nil
+	}
+	return path, nil
+}
+
+// Create a temporary file in dir holding data, returning it open.
+func probeFile(dir string, data []byte) (*os.File, error) {
+	f, err := ioutil.TempFile(dir, ".shutil-probe-")
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		closeAndRemove(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+func closeAndRemove(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+func probeXattrs(dir string) (bool, error) {
+	f, err := probeFile(dir, nil)
+	if err != nil {
+		return false, err
+	}
+	defer closeAndRemove(f)
+	// Any error means that the attribute can't be set, which may be
+	// because of security policy rather than the filesystem, but either
+	// way attributes can't be copied
+	return setXattr(f.Name(), "user.shutil.probe", []byte("1")) == nil, nil
+}
+
+func probeSparse(dir string, blockSize int64) (bool, error) {
+	if blockSize <= 0 {
+		blockSize = 4096
+	}
+	size := 16 * blockSize
+
+	f, err := probeFile(dir, nil)
+	if err != nil {
+		return false, err
+	}
+	defer closeAndRemove(f)
+	err = f.Truncate(size)
+	if err != nil {
+		return false, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	allocated, ok := allocatedBytes(fi)
+	return ok && allocated < size, nil
+}
+
+func probeCaseSensitive(dir string) (bool, error) {
+	f, err := probeFile(dir, nil)
+	if err != nil {
+		return false, err
+	}
+	defer closeAndRemove(f)
+
+	// The random part of the name is digits, so the prefix is what
+	// changes case
+	name := filepath.Base(f.Name())
+	_, err = os.Lstat(filepath.Join(dir, strings.ToUpper(name)))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	return false, err
+}
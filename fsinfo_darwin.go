@@ -0,0 +1,53 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Return the type and block size of the filesystem path is on.
+func statfs(path string) (string, int64, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return "", 0, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return strings.TrimRight(string(st.Fstypename[:]), "\x00"), int64(st.Bsize), nil
+}
+
+// clonefile(2) creates its destination, so it can't be used on a file
+// that's already open.
+func cloneFile(dst, src *os.File) error {
+	return errNoReflink
+}
+
+func probeReflink(dir string) (bool, error) {
+	src, err := probeFile(dir, []byte("shutil"))
+	if err != nil {
+		return false, err
+	}
+	defer closeAndRemove(src)
+
+	dst := filepath.Join(dir, filepath.Base(src.Name())+"-clone")
+	if unix.Clonefile(src.Name(), dst, unix.CLONE_NOFOLLOW) != nil {
+		return false, nil
+	}
+	return true, os.Remove(dst)
+}
+
+func diskUsage(path string) (Usage, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return Usage{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	size := uint64(st.Bsize)
+	return Usage{
+		Total: st.Blocks * size,
+		Used:  (st.Blocks - st.Bfree) * size,
+		Free:  st.Bavail * size,
+	}, nil
+}
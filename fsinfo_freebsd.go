@@ -0,0 +1,45 @@
+package shutil
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Return the type and block size of the filesystem path is on.
+func statfs(path string) (string, int64, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return "", 0, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return strings.TrimRight(string(st.Fstypename[:]), "\x00"), int64(st.Bsize), nil
+}
+
+func cloneFile(dst, src *os.File) error {
+	return errNoReflink
+}
+
+func probeReflink(dir string) (bool, error) {
+	return false, nil
+}
+
+func diskUsage(path string) (Usage, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return Usage{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	size := uint64(st.Bsize)
+	usage := Usage{
+		Total: st.Blocks * size,
+		Used:  (st.Blocks - st.Bfree) * size,
+	}
+	// Available blocks go negative when the space reserved for root is
+	// being used
+	if st.Bavail > 0 {
+		usage.Free = uint64(st.Bavail) * size
+	}
+	return usage, nil
+}
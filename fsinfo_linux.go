@@ -0,0 +1,101 @@
+package shutil
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Names of common filesystems, by the magic number statfs(2) reports.
+var filesystemTypes = map[uint32]string{
+	unix.BTRFS_SUPER_MAGIC:     "btrfs",
+	unix.CIFS_SUPER_MAGIC:      "cifs",
+	unix.EXFAT_SUPER_MAGIC:     "exfat",
+	unix.EXT4_SUPER_MAGIC:      "ext4",
+	unix.F2FS_SUPER_MAGIC:      "f2fs",
+	unix.FUSE_SUPER_MAGIC:      "fuse",
+	unix.MSDOS_SUPER_MAGIC:     "vfat",
+	unix.NFS_SUPER_MAGIC:       "nfs",
+	unix.OCFS2_SUPER_MAGIC:     "ocfs2",
+	unix.OVERLAYFS_SUPER_MAGIC: "overlay",
+	unix.RAMFS_MAGIC:           "ramfs",
+	unix.SMB2_SUPER_MAGIC:      "smb2",
+	unix.SQUASHFS_MAGIC:        "squashfs",
+	unix.TMPFS_MAGIC:           "tmpfs",
+	unix.XFS_SUPER_MAGIC:       "xfs",
+	0xca451a4e:                 "bcachefs",
+	0x2fc12fc1:                 "zfs",
+
+	// Virtual filesystems, which VirtualFilesystems lists
+	unix.AUTOFS_SUPER_MAGIC:  "autofs",
+	unix.BINFMTFS_MAGIC:      "binfmt_misc",
+	unix.BPF_FS_MAGIC:        "bpf",
+	unix.CGROUP_SUPER_MAGIC:  "cgroup",
+	unix.CGROUP2_SUPER_MAGIC: "cgroup2",
+	unix.DEBUGFS_MAGIC:       "debugfs",
+	unix.DEVPTS_SUPER_MAGIC:  "devpts",
+	unix.EFIVARFS_MAGIC:      "efivarfs",
+	unix.HUGETLBFS_MAGIC:     "hugetlbfs",
+	unix.NSFS_MAGIC:          "nsfs",
+	unix.PROC_SUPER_MAGIC:    "proc",
+	unix.PSTOREFS_MAGIC:      "pstore",
+	unix.SECURITYFS_MAGIC:    "securityfs",
+	unix.SYSFS_MAGIC:         "sysfs",
+	unix.TRACEFS_MAGIC:       "tracefs",
+	0x19800202:               "mqueue",
+	0x62656570:               "configfs",
+}
+
+// Return the type and block size of the filesystem path is on. ext2 and
+// ext3 share ext4's magic number, so are all reported as "ext4".
+func statfs(path string) (string, int64, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return "", 0, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return filesystemTypes[uint32(st.Type)], int64(st.Bsize), nil
+}
+
+// Make dst share src's data, as cp --reflink does.
+func cloneFile(dst, src *os.File) error {
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
+
+func probeReflink(dir string) (bool, error) {
+	src, err := probeFile(dir, []byte("shutil"))
+	if err != nil {
+		return false, err
+	}
+	defer closeAndRemove(src)
+	dst, err := probeFile(dir, nil)
+	if err != nil {
+		return false, err
+	}
+	defer closeAndRemove(dst)
+
+	// Cloning only needs to work, so why it didn't doesn't matter
+	if cloneFile(dst, src) != nil {
+		return false, nil
+	}
+	data, err := io.ReadAll(io.NewSectionReader(dst, 0, 16))
+	return err == nil && string(data) == "shutil", nil
+}
+
+func diskUsage(path string) (Usage, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return Usage{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	size := uint64(st.Frsize)
+	if size == 0 {
+		size = uint64(st.Bsize)
+	}
+	return Usage{
+		Total: uint64(st.Blocks) * size,
+		Used:  (uint64(st.Blocks) - uint64(st.Bfree)) * size,
+		Free:  uint64(st.Bavail) * size,
+	}, nil
+}
@@ -0,0 +1,19 @@
+//go:build !darwin && !freebsd && !linux
+
+package shutil
+
+import "os"
+
+// Return the type and block size of the filesystem path is on, which
+// aren't known on this platform.
+func statfs(path string) (string, int64, error) {
+	return "", 0, nil
+}
+
+func cloneFile(dst, src *os.File) error {
+	return errNoReflink
+}
+
+func probeReflink(dir string) (bool, error) {
+	return false, nil
+}
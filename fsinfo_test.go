@@ -0,0 +1,92 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestFilesystemInfo(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	info, err := FilesystemInfo(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" || runtime.GOOS == "freebsd" {
+		g.Expect(info.BlockSize).To(BeNumerically(">", 0))
+	}
+	if runtime.GOOS == "linux" {
+		g.Expect(info.CaseSensitive).To(BeTrue())
+	}
+
+	// Only the files that were already there are left
+	entries, err := os.ReadDir(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(3))
+
+	reflink, err := SupportsReflink(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reflink).To(Equal(info.Reflink))
+}
+
+func TestFilesystemInfoTmpfs(t *testing.T) {
+	if _, err := os.Stat("/dev/shm"); err != nil || runtime.GOOS != "linux" {
+		t.Skip("needs /dev/shm")
+	}
+	g := NewWithT(t)
+
+	info, err := FilesystemInfo("/dev/shm")
+	if os.IsPermission(err) {
+		t.Skip(err)
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Type).To(Equal("tmpfs"))
+	g.Expect(info.Sparse).To(BeTrue())
+	g.Expect(info.Reflink).To(BeFalse())
+}
+
+func TestFilesystemInfoMissing(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := FilesystemInfo(makeTestPath("missing"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestSkipFilesystems(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	info, err := FilesystemInfo(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	if info.Type == "" {
+		t.Skip("filesystem type unknown")
+	}
+
+	shutiltest.CreateTree(t, makeTestPath("src"), shutiltest.Tree{
+		"file":     shutiltest.File("file\n"),
+		"dir/file": shutiltest.File("file\n"),
+	})
+
+	_, err = CopyTreeContext(context.Background(), makeTestPath("src"), makeTestPath("out"), &CopyTreeOptions{
+		SkipDir: SkipFilesystems("proc", info.Type),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(makeTestPath("out/file")).To(BeARegularFile())
+	g.Expect(makeTestPath("out/dir")).NotTo(BeADirectory())
+
+	_, err = CopyTreeContext(context.Background(), makeTestPath("src"), makeTestPath("out2"), &CopyTreeOptions{
+		SkipDir: SkipFilesystems(VirtualFilesystems...),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(makeTestPath("out2/dir/file")).To(BeARegularFile())
+
+	if runtime.GOOS == "linux" {
+		proc, err := os.Stat("/proc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(SkipFilesystems(VirtualFilesystems...)("/proc", proc)).To(BeTrue())
+	}
+}
@@ -0,0 +1,220 @@
+package shutil
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type CopyGlobOptions struct {
+	CopyFilesOptions
+
+	// Copy every match directly into the destination directory instead
+	// of recreating its path relative to the pattern's base.
+	Flatten bool
+}
+
+// Return the paths matching a glob pattern, in lexical order.
+//
+// Patterns use the syntax of path.Match with `/` as the separator, plus
+// a `**` path segment which matches zero or more directories, so
+// `src/**/*.proto` matches every .proto file anywhere under `src`.
+// Symbolic links to directories are not followed.
+//
+// Only the directories that could hold a match are read, so `src/*.proto`
+// doesn't read the subdirectories of `src`, and errors reading paths that
+// can't match are ignored.
+func Glob(pattern string) ([]string, error) {
+	base, segments, err := splitGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err = filepath.Walk(base, func(p string, info os.FileInfo, err error) error {
+		if p == base {
+			if err != nil && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, relErr := filepath.Rel(base, p)
+		if relErr != nil {
+			return relErr
+		}
+		name := strings.Split(filepath.ToSlash(rel), "/")
+		matched := matchSegments(segments, name)
+		within := matchWithin(segments, name)
+		if err != nil {
+			if matched || within {
+				return err
+			}
+			return nil
+		}
+		if matched {
+			matches = append(matches, p)
+		}
+		if info.IsDir() && !within {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// Copy every file matching a glob pattern into dstDir. This is the Go
+// equivalent of "cp src/**/*.proto out/".
+//
+// The pattern syntax is the one accepted by Glob(). Each file keeps its
+// path relative to the longest leading part of the pattern that contains
+// no wildcards, so `src/**/*.proto` copies `src/a/b.proto` to
+// `dstDir/a/b.proto`. If Flatten is set it is copied to `dstDir/b.proto`
+// instead, and two matches with the same name result in an
+// AlreadyExistsError before anything is copied.
+//
+// Directories that match are not copied themselves. The files are copied
+// with CopyFiles(), creating any missing directories under dstDir, so
+// the remaining options behave as they do there. A pattern that matches
+// nothing is not an error.
+func CopyGlob(pattern, dstDir string, options *CopyGlobOptions) error {
+	if options == nil {
+		options = &CopyGlobOptions{}
+	}
+
+	base, _, err := splitGlob(pattern)
+	if err != nil {
+		return err
+	}
+	matches, err := Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	pairs := []SrcDst{}
+	seen := map[string]bool{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err == nil && info.IsDir() {
+			continue
+		}
+
+		var dst string
+		if options.Flatten {
+			dst = filepath.Join(dstDir, filepath.Base(match))
+		} else {
+			rel, err := filepath.Rel(base, match)
+			if err != nil {
+				return err
+			}
+			dst = filepath.Join(dstDir, rel)
+		}
+		if seen[dst] {
+			return &AlreadyExistsError{dst}
+		}
+		seen[dst] = true
+		pairs = append(pairs, SrcDst{match, dst})
+	}
+
+	copyOptions := options.CopyFilesOptions
+	copyOptions.CreateParents = true
+	return CopyFiles(pairs, &copyOptions)
+}
+
+// Split a pattern into the directory to start walking from and the
+// remaining pattern segments, which are checked for syntax errors.
+func splitGlob(pattern string) (string, []string, error) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	literal := 0
+	for literal < len(segments)-1 && !hasMeta(segments[literal]) {
+		literal++
+	}
+	for _, segment := range segments[literal:] {
+		if _, err := path.Match(segment, ""); err != nil {
+			return "", nil, err
+		}
+	}
+
+	base := filepath.FromSlash(strings.Join(segments[:literal], "/"))
+	if base == "" {
+		if literal > 0 {
+			base = string(os.PathSeparator)
+		} else {
+			base = "."
+		}
+	}
+	return base, segments[literal:], nil
+}
+
+func hasMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+// Match the segments of a relative path against pattern segments, where a
+// `**` segment matches any number of path segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// Report whether paths inside the directory whose relative path has the
+// segments name could match the pattern segments: a `**` segment is
+// reached before name runs out, or every segment of name matches and
+// there are pattern segments left over.
+func matchWithin(pattern, name []string) bool {
+	for ; len(name) > 0; name = name[1:] {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+	}
+	return len(pattern) > 0
+}
+
+// Return an IgnoreFunc that ignores the entries whose names match any of
+// the patterns, like Python's shutil.ignore_patterns(). The patterns use
+// the syntax of path.Match, and are matched against names, not paths.
+func IgnorePatterns(patterns ...string) IgnoreFunc {
+	return func(dir string, entries []os.FileInfo) []string {
+		var ignored []string
+		for _, entry := range entries {
+			for _, pattern := range patterns {
+				if ok, _ := path.Match(pattern, entry.Name()); ok {
+					ignored = append(ignored, entry.Name())
+					break
+				}
+			}
+		}
+		return ignored
+	}
+}
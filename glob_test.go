@@ -0,0 +1,132 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestGlob(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(Glob(makeTestPath("**/file*"))).To(Equal([]string{
+		makeTestPath("testdir/file1"),
+		makeTestPath("testdir/file2"),
+	}))
+	g.Expect(Glob(makeTestPath("testfile*"))).To(Equal([]string{
+		makeTestPath("testfile"),
+		makeTestPath("testfile2"),
+	}))
+	g.Expect(Glob(makeTestPath("missing/**"))).To(BeEmpty())
+
+	_, err := Glob(makeTestPath("[*"))
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func TestCopyGlob(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	g.Expect(CopyGlob(makeTestPath("**/*1"), dst, nil)).To(Succeed())
+	g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("out/testdir/file1"), nil)).To(BeTrue())
+	g.Expect(Glob(makeTestPath("out/**"))).To(HaveLen(2))
+}
+
+func TestCopyGlobFlatten(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	g.Expect(CopyGlob(makeTestPath("**/file?"), dst, &CopyGlobOptions{Flatten: true})).To(Succeed())
+	g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("out/file1"), nil)).To(BeTrue())
+	g.Expect(FilesEqual(makeTestPath("testdir/file2"), makeTestPath("out/file2"), nil)).To(BeTrue())
+
+	// out/file1 and testdir/file1 would both end up at out/file1
+	g.Expect(CopyGlob(makeTestPath("**/file1"), dst, &CopyGlobOptions{Flatten: true})).
+		Should(MatchError(&AlreadyExistsError{makeTestPath("out/file1")}))
+}
+
+func TestGlobPrunes(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read unreadable directories")
+	}
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("src")
+	shutiltest.CreateTree(t, src, shutiltest.Tree{
+		"a.proto":                shutiltest.File(""),
+		"deep/locked/b.proto":    shutiltest.File(""),
+		"other/locked/c.proto":   shutiltest.File(""),
+		"proto/locked/d/e.proto": shutiltest.File(""),
+		"proto/open/f.proto":     shutiltest.File(""),
+	})
+	for _, dir := range []string{"deep/locked", "other/locked", "proto/locked"} {
+		locked := filepath.Join(src, dir)
+		g.Expect(os.Chmod(locked, 0)).To(Succeed())
+		t.Cleanup(func() { os.Chmod(locked, 0755) })
+	}
+
+	// Directories deeper than the pattern, or whose names don't match,
+	// aren't read
+	g.Expect(Glob(filepath.Join(src, "*.proto"))).To(Equal([]string{filepath.Join(src, "a.proto")}))
+	g.Expect(Glob(filepath.Join(src, "pro*/open/*.proto"))).To(Equal([]string{filepath.Join(src, "proto/open/f.proto")}))
+
+	// But ones that could hold a match are
+	_, err := Glob(filepath.Join(src, "**/*.proto"))
+	g.Expect(os.IsPermission(err)).To(BeTrue())
+	_, err = Glob(filepath.Join(src, "proto/*/d/*.proto"))
+	g.Expect(os.IsPermission(err)).To(BeTrue())
+}
+
+// Private function tests
+
+func TestMatchSegments(t *testing.T) {
+	g := NewWithT(t)
+
+	match := func(pattern, name string) bool {
+		return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+	}
+
+	g.Expect(match("**/*.proto", "a.proto")).To(BeTrue())
+	g.Expect(match("**/*.proto", "a/b/c.proto")).To(BeTrue())
+	g.Expect(match("a/**/b", "a/b")).To(BeTrue())
+	g.Expect(match("a/**/b", "a/x/y/b")).To(BeTrue())
+	g.Expect(match("a/**", "a/x/y")).To(BeTrue())
+	g.Expect(match("*.proto", "a/b.proto")).To(BeFalse())
+	g.Expect(match("a/**/b", "a/x/c")).To(BeFalse())
+}
+
+func TestMatchWithin(t *testing.T) {
+	g := NewWithT(t)
+
+	within := func(pattern, name string) bool {
+		return matchWithin(strings.Split(pattern, "/"), strings.Split(name, "/"))
+	}
+
+	g.Expect(within("*.proto", "a")).To(BeFalse())
+	g.Expect(within("a*/*.proto", "ab")).To(BeTrue())
+	g.Expect(within("a*/*.proto", "b")).To(BeFalse())
+	g.Expect(within("a*/*.proto", "ab/c")).To(BeFalse())
+	g.Expect(within("a/**", "a/x/y")).To(BeTrue())
+	g.Expect(within("**/*.proto", "x")).To(BeTrue())
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// The destination is outside the tree being copied
+	defer os.RemoveAll("_out")
+	err := CopyTree(testdir, "_out", &CopyTreeOptions{
+		Ignore: IgnorePatterns("*2", "testdir/file1"),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(Glob("_out/**")).To(Equal([]string{"_out/testdir", "_out/testdir/file1", "_out/testfile"}))
+}
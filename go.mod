@@ -2,7 +2,10 @@ module github.com/gocardless/go-shutil
 
 go 1.17
 
-require github.com/onsi/gomega v1.18.1
+require (
+	github.com/onsi/gomega v1.18.1
+	golang.org/x/sys v0.13.0
+)
 
 require (
 	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781 // indirect
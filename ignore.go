@@ -0,0 +1,204 @@
+package shutil
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A line of an ignore file.
+type ignoreRule struct {
+	// The pattern, split into segments as matchSegments() expects.
+	segments []string
+
+	// The pattern started with "!", so it un-ignores what it matches.
+	negate bool
+
+	// The pattern ended with "/", so it only matches directories.
+	dirOnly bool
+}
+
+// The rules of an ignore file, which apply to the tree under dir.
+type ignoreRules struct {
+	dir   string
+	rules []ignoreRule
+}
+
+// Read the rules of the ignore file at name, whose paths are relative to
+// the directory it is in.
+func readIgnoreFile(name string) (*ignoreRules, error) {
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := &ignoreRules{dir: dir}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rules.rules = append(rules.rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// Parse a line of an ignore file, reporting false if it holds no pattern.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A pattern with no slash but at the end matches at any depth. One
+	// with a slash anywhere else is relative to the ignore file.
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+	rule.segments = strings.Split(line, "/")
+	for _, segment := range rule.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return rule, false
+		}
+	}
+	return rule, true
+}
+
+// Decide whether the entry at rel, relative to the rules' directory and
+// separated by slashes, is ignored. The last rule that matches wins;
+// ignored is left as it is if none do.
+func (r *ignoreRules) match(rel string, isDir bool, ignored bool) bool {
+	segments := strings.Split(rel, "/")
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(rule.segments, segments) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// Return the names of the entries of dir that the rules ignore, applying
+// each set of rules in turn.
+func ignoredEntries(dir string, entries []os.FileInfo, ruleSets []*ignoreRules) []string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	var ignored []string
+	for _, entry := range entries {
+		name := filepath.Join(absDir, entry.Name())
+		isIgnored := false
+		for _, rules := range ruleSets {
+			rel, err := filepath.Rel(rules.dir, name)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			isIgnored = rules.match(filepath.ToSlash(rel), entry.IsDir(), isIgnored)
+		}
+		if isIgnored {
+			ignored = append(ignored, entry.Name())
+		}
+	}
+	return ignored
+}
+
+// Return an IgnoreFunc that ignores what the ignore file at name does,
+// using the syntax of .gitignore files: one pattern per line, which
+// ignores what it matches, and can start with "!" to un-ignore it again
+// instead, or end with "/" to only match directories. A pattern only
+// matches at any depth if the only slash in it is at the end; otherwise
+// it's relative to the directory the ignore file is in. "**" matches any
+// number of directories. Blank lines and those starting with "#" are
+// skipped.
+//
+// As a directory that is ignored isn't looked into, what is inside it
+// can't be un-ignored, just as with git.
+func LoadIgnoreFile(name string) (IgnoreFunc, error) {
+	rules, err := readIgnoreFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(dir string, entries []os.FileInfo) []string {
+		return ignoredEntries(dir, entries, []*ignoreRules{rules})
+	}, nil
+}
+
+// Return an IgnoreFunc that looks for an ignore file called name, such as
+// ".gitignore", in each directory of the tree it is used with, like
+// rsync's --filter=':- .gitignore'. Each file's patterns apply to the
+// tree under the directory it is in, using the syntax of
+// LoadIgnoreFile(), and a file deeper in the tree takes precedence over
+// those above it. Ignore files that can't be read are treated as empty.
+//
+// The IgnoreFunc remembers each directory it has been called for, so it
+// should only be used for a single operation at a time.
+func IgnoreFilesNamed(name string) IgnoreFunc {
+	var mu sync.Mutex
+	seen := map[string]*ignoreRules{}
+	return func(dir string, entries []os.FileInfo) []string {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil
+		}
+		rules, err := readIgnoreFile(filepath.Join(absDir, name))
+		if err != nil {
+			rules = &ignoreRules{dir: absDir}
+		}
+
+		mu.Lock()
+		seen[absDir] = rules
+		// The directories above this one in the tree have been seen first
+		ruleSets := []*ignoreRules{rules}
+		for d := filepath.Dir(absDir); d != filepath.Dir(d); d = filepath.Dir(d) {
+			parent, ok := seen[d]
+			if !ok {
+				break
+			}
+			ruleSets = append([]*ignoreRules{parent}, ruleSets...)
+		}
+		mu.Unlock()
+		return ignoredEntries(dir, entries, ruleSets)
+	}
+}
+
+// Return an IgnoreFunc that ignores the names any of fns ignore.
+func CombineIgnore(fns ...IgnoreFunc) IgnoreFunc {
+	return func(dir string, entries []os.FileInfo) []string {
+		var ignored []string
+		for _, fn := range fns {
+			if fn != nil {
+				ignored = append(ignored, fn(dir, entries)...)
+			}
+		}
+		return ignored
+	}
+}
@@ -0,0 +1,70 @@
+package shutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestLoadIgnoreFile(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	shutiltest.CreateTree(t, makeTestPath("src"), shutiltest.Tree{
+		".shutilignore": shutiltest.File("# build output\n*.o\n!keep.o\n/top\nbuild/\ndocs/**/*.tmp\n"),
+		"a.o":           shutiltest.File(""),
+		"keep.o":        shutiltest.File(""),
+		"top":           shutiltest.File(""),
+		"sub/top":       shutiltest.File(""),
+		"sub/b.o":       shutiltest.File(""),
+		"sub/build/c":   shutiltest.File(""),
+		"build":         shutiltest.File(""),
+		"docs/x/y.tmp":  shutiltest.File(""),
+		"docs/y.tmp":    shutiltest.File(""),
+	})
+	ignore, err := LoadIgnoreFile(makeTestPath("src/.shutilignore"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(CopyTree(makeTestPath("src"), makeTestPath("dst"), &CopyTreeOptions{Ignore: ignore})).To(Succeed())
+	g.Expect(Glob(makeTestPath("dst/**"))).To(Equal([]string{
+		makeTestPath("dst/.shutilignore"),
+		makeTestPath("dst/build"),
+		makeTestPath("dst/docs"),
+		makeTestPath("dst/docs/x"),
+		makeTestPath("dst/keep.o"),
+		makeTestPath("dst/sub"),
+		makeTestPath("dst/sub/top"),
+	}))
+
+	_, err = LoadIgnoreFile(makeTestPath("missing"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestIgnoreFilesNamed(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	shutiltest.CreateTree(t, makeTestPath("src"), shutiltest.Tree{
+		".gitignore":         shutiltest.File("*.log\n"),
+		"a.log":              shutiltest.File(""),
+		"sub/.gitignore":     shutiltest.File("!keep.log\nlocal\n"),
+		"sub/keep.log":       shutiltest.File(""),
+		"sub/b.log":          shutiltest.File(""),
+		"sub/local":          shutiltest.File(""),
+		"sub/deeper/c.log":   shutiltest.File(""),
+		"sub/deeper/local":   shutiltest.File(""),
+		"sub/deeper/keep.md": shutiltest.File(""),
+		"local":              shutiltest.File(""),
+	})
+	g.Expect(CopyTree(makeTestPath("src"), makeTestPath("dst"), &CopyTreeOptions{
+		Ignore: CombineIgnore(IgnoreFilesNamed(".gitignore"), IgnorePatterns(".gitignore")),
+	})).To(Succeed())
+	g.Expect(Glob(makeTestPath("dst/**"))).To(Equal([]string{
+		makeTestPath("dst/local"),
+		makeTestPath("dst/sub"),
+		makeTestPath("dst/sub/deeper"),
+		makeTestPath("dst/sub/deeper/keep.md"),
+		makeTestPath("dst/sub/keep.log"),
+	}))
+}
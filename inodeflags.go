@@ -0,0 +1,71 @@
+package shutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reports that a copy couldn't be given some of its source's inode
+// flags, such as immutable without the CAP_LINUX_IMMUTABLE capability, or
+// flags its filesystem doesn't have.
+type InodeFlagsWarning struct {
+	Path string
+
+	// The flags that weren't set or cleared, as FS_IOC_GETFLAGS gives
+	// them.
+	Flags int
+
+	Err error
+}
+
+func (w InodeFlagsWarning) Error() string {
+	return fmt.Sprintf("could not set inode flags %s of `%s`: %s", inodeFlagLetters(w.Flags), w.Path, w.Err)
+}
+
+func (w InodeFlagsWarning) Code() ErrorCode {
+	return CodeInodeFlags
+}
+
+func (w InodeFlagsWarning) Unwrap() error {
+	return w.Err
+}
+
+// The letters lsattr(1) shows the inode flags that are copied as.
+var inodeFlagNames = []struct {
+	flag   int
+	letter byte
+}{
+	{0x00000001, 's'},
+	{0x00000002, 'u'},
+	{0x00000004, 'c'},
+	{0x00000008, 'S'},
+	{0x00000010, 'i'},
+	{0x00000020, 'a'},
+	{0x00000040, 'd'},
+	{0x00000080, 'A'},
+	{0x00004000, 'j'},
+	{0x00008000, 't'},
+	{0x00010000, 'D'},
+	{0x00020000, 'T'},
+	{0x00800000, 'C'},
+	{0x20000000, 'P'},
+}
+
+// The flags that are copied.
+var copiedInodeFlags = func() int {
+	var flags int
+	for _, name := range inodeFlagNames {
+		flags |= name.flag
+	}
+	return flags
+}()
+
+func inodeFlagLetters(flags int) string {
+	var b strings.Builder
+	for _, name := range inodeFlagNames {
+		if flags&name.flag != 0 {
+			b.WriteByte(name.letter)
+		}
+	}
+	return b.String()
+}
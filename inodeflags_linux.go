@@ -0,0 +1,97 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Inode flags from linux/fs.h
+const (
+	fsImmutableFlag = 0x00000010
+	fsAppendFlag    = 0x00000020
+	fsNoCOWFlag     = 0x00800000
+)
+
+// Return the inode flags of a file, as shown by lsattr(1).
+func getInodeFlags(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return unix.IoctlGetInt(int(f.Fd()), unix.FS_IOC_GETFLAGS)
+}
+
+// Report whether the immutable or append-only attributes of a file are
+// set, either of which stop it being removed or renamed.
+func isImmutable(path string) (bool, error) {
+	flags, err := getInodeFlags(path)
+	if err != nil {
+		return false, err
+	}
+	return flags&(fsImmutableFlag|fsAppendFlag) != 0, nil
+}
+
+// Give the new, empty copy dst the no-copy-on-write flag if src has it,
+// which only takes effect before any data is written. Anything that goes
+// wrong is left for copyInodeFlags() to report.
+func copyNoCOWFlag(dst, src *os.File) {
+	flags, err := unix.IoctlGetInt(int(src.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil || flags&fsNoCOWFlag == 0 {
+		return
+	}
+	dstFlags, err := unix.IoctlGetInt(int(dst.Fd()), unix.FS_IOC_GETFLAGS)
+	if err == nil {
+		unix.IoctlSetPointerInt(int(dst.Fd()), unix.FS_IOC_SETFLAGS, dstFlags|fsNoCOWFlag)
+	}
+}
+
+// Give dst the inode flags of src that chattr(1) can set. As immutable
+// and append-only stop dst being changed, this comes after everything
+// else. Flags that can't be set are returned as an InodeFlagsWarning,
+// after setting the rest, and a source on a filesystem without inode
+// flags is taken as having none.
+func copyInodeFlags(src, dst string) (*InodeFlagsWarning, error) {
+	srcFlags, err := getInodeFlags(src)
+	if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) {
+		srcFlags, err = 0, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(dst, os.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dstFlags, err := unix.IoctlGetInt(int(f.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		if srcFlags&copiedInodeFlags == 0 {
+			return nil, nil
+		}
+		return &InodeFlagsWarning{dst, srcFlags & copiedInodeFlags, err}, nil
+	}
+
+	wanted := dstFlags&^copiedInodeFlags | srcFlags&copiedInodeFlags
+	if wanted == dstFlags {
+		return nil, nil
+	}
+	err = unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, wanted)
+	if err == nil {
+		return nil, nil
+	}
+	// Setting immutable and append-only needs a capability, so try
+	// without them
+	privileged := fsImmutableFlag | fsAppendFlag
+	if (wanted^dstFlags)&^privileged != 0 {
+		retryErr := unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, wanted&^privileged|dstFlags&privileged)
+		if retryErr == nil {
+			return &InodeFlagsWarning{dst, (wanted ^ dstFlags) & privileged, err}, nil
+		}
+	}
+	return &InodeFlagsWarning{dst, wanted ^ dstFlags, err}, nil
+}
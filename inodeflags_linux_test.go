@@ -0,0 +1,63 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+// Set inode flags of path, skipping the test if its filesystem doesn't
+// have them.
+func setInodeFlags(t *testing.T, path string, flags int) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, flags)
+	if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) {
+		t.Skip("inode flags not supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyInodeFlags(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	const noDump = 0x00000040
+	src := makeTestPath("testfile")
+	flags, err := getInodeFlags(src)
+	g.Expect(err).NotTo(HaveOccurred())
+	setInodeFlags(t, src, flags|noDump|fsAppendFlag)
+	t.Cleanup(func() { setInodeFlags(t, src, flags) })
+
+	dst := makeTestPath("testfile3")
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{PreserveInodeFlags: true, PreserveTimes: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	dstFlags, err := getInodeFlags(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dstFlags & noDump).NotTo(BeZero())
+	if os.Geteuid() == 0 {
+		g.Expect(result.Warnings).To(BeEmpty())
+		g.Expect(dstFlags & fsAppendFlag).NotTo(BeZero())
+		setInodeFlags(t, dst, dstFlags&^fsAppendFlag)
+	} else {
+		g.Expect(result.Warnings).To(ConsistOf(&InodeFlagsWarning{dst, fsAppendFlag, unix.EPERM}))
+	}
+}
+
+func TestInodeFlagsWarning(t *testing.T) {
+	g := NewWithT(t)
+
+	w := InodeFlagsWarning{"file", fsImmutableFlag | fsNoCOWFlag, unix.EPERM}
+	g.Expect(w.Error()).To(Equal("could not set inode flags iC of `file`: operation not permitted"))
+	g.Expect(errors.Is(w, unix.EPERM)).To(BeTrue())
+}
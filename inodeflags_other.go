@@ -0,0 +1,17 @@
+//go:build !linux
+
+package shutil
+
+import "os"
+
+// Report whether the immutable or append-only attributes of a file are
+// set, either of which stop it being removed or renamed.
+func isImmutable(path string) (bool, error) {
+	return false, nil
+}
+
+func copyNoCOWFlag(dst, src *os.File) {}
+
+func copyInodeFlags(src, dst string) (*InodeFlagsWarning, error) {
+	return nil, nil
+}
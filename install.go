@@ -0,0 +1,147 @@
+package shutil
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+type InstallOptions struct {
+	// The user and group to give the installed file, as names or numeric
+	// ids. Empty values leave them as they are.
+	Owner string
+	Group string
+
+	// Strip the symbol table from the installed file with StripProgram,
+	// which defaults to "strip".
+	Strip        bool
+	StripProgram string
+
+	// Leave the destination alone if it already has the same contents,
+	// mode and ownership as the file would be installed with. This has
+	// no effect when stripping.
+	Compare bool
+}
+
+// Install a file, in the style of the Unix install(1) program. Return the
+// file's destination.
+//
+// The destination may be a directory, in which case the file is installed
+// inside it. Any missing parent directories are created. The file is
+// copied to a temporary file next to the destination, given exactly the
+// requested mode (regardless of the umask) and ownership, optionally
+// stripped, and then renamed into place, so the destination is never seen
+// half written.
+//
+// If Compare is set and the destination already matches, it isn't
+// touched at all, which preserves its modification time.
+func Install(src, dst string, mode os.FileMode, options *InstallOptions) (string, error) {
+	if options == nil {
+		options = &InstallOptions{}
+	}
+
+	if isDir, _ := isDirectory(dst); isDir {
+		dst = filepath.Join(dst, filepath.Base(src))
+	}
+
+	uid, gid, err := lookupOwner(options.Owner, options.Group)
+	if err != nil {
+		return dst, err
+	}
+
+	if options.Compare && !options.Strip {
+		same, err := installedMatches(src, dst, mode, uid, gid)
+		if err != nil {
+			return dst, err
+		}
+		if same {
+			return dst, nil
+		}
+	}
+
+	err = MakeDirs(filepath.Dir(dst), 0755, true)
+	if err != nil {
+		return dst, err
+	}
+
+	err = replaceAtomic(dst, func(tmp string) error {
+		if err := CopyFile(src, tmp, true); err != nil {
+			return err
+		}
+		if options.Strip {
+			program := options.StripProgram
+			if program == "" {
+				program = "strip"
+			}
+			if err := exec.Command(program, tmp).Run(); err != nil {
+				return err
+			}
+		}
+		if uid != -1 || gid != -1 {
+			if err := os.Chown(tmp, uid, gid); err != nil {
+				return err
+			}
+		}
+		return os.Chmod(tmp, mode)
+	})
+	return dst, err
+}
+
+// Resolve user and group names or ids to numeric ids, using -1 for those
+// not given.
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+	if owner != "" {
+		id, err := strconv.Atoi(owner)
+		if err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return -1, -1, err
+			}
+			id, err = strconv.Atoi(u.Uid)
+			if err != nil {
+				return -1, -1, err
+			}
+		}
+		uid = id
+	}
+	if group != "" {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, err
+			}
+			id, err = strconv.Atoi(g.Gid)
+			if err != nil {
+				return -1, -1, err
+			}
+		}
+		gid = id
+	}
+	return uid, gid, nil
+}
+
+// Report whether dst already looks exactly like src installed with the
+// given mode and ownership.
+func installedMatches(src, dst string, mode os.FileMode, uid, gid int) (bool, error) {
+	dstInfo, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	modeBits := os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	if !dstInfo.Mode().IsRegular() || dstInfo.Mode()&modeBits != mode&modeBits {
+		return false, nil
+	}
+
+	dstUid, dstGid, ok := fileOwner(dstInfo)
+	if ok && ((uid != -1 && uid != dstUid) || (gid != -1 && gid != dstGid)) {
+		return false, nil
+	}
+
+	return SameContent(src, dst)
+}
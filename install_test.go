@@ -0,0 +1,86 @@
+package shutil
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestInstall(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("usr/local/bin/testfile")
+
+	g.Expect(Install(src, dst, 0751, nil)).To(Equal(dst))
+	g.Expect(FilesEqual(src, dst, nil)).To(BeTrue())
+
+	info, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0751)))
+
+	// Installing into a directory puts the file inside it
+	g.Expect(Install(makeTestPath("testfile2"), makeTestPath("usr/local/bin"), 0644, nil)).
+		To(Equal(makeTestPath("usr/local/bin/testfile2")))
+}
+
+func TestInstallOwner(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("installed")
+	_, err := Install(makeTestPath("testfile"), dst, 0644, &InstallOptions{
+		Owner: strconv.Itoa(os.Getuid()),
+		Group: strconv.Itoa(os.Getgid()),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = Install(makeTestPath("testfile"), dst, 0644, &InstallOptions{Owner: "no-such-user-exists"})
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func TestInstallCompare(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("installed")
+	g.Expect(Install(src, dst, 0644, nil)).To(Equal(dst))
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chtimes(dst, old, old)).To(Succeed())
+
+	// Identical, so left alone
+	g.Expect(Install(src, dst, 0644, &InstallOptions{Compare: true})).To(Equal(dst))
+	info, _ := os.Stat(dst)
+	g.Expect(info.ModTime()).To(Equal(old))
+
+	// The mode differs, so it is reinstalled
+	g.Expect(Install(src, dst, 0600, &InstallOptions{Compare: true})).To(Equal(dst))
+	info, _ = os.Stat(dst)
+	g.Expect(info.ModTime()).NotTo(Equal(old))
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+}
+
+func TestInstallStrip(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("installed")
+	_, err := Install(makeTestPath("testfile"), dst, 0755, &InstallOptions{Strip: true, StripProgram: "false"})
+	g.Expect(err).Should(HaveOccurred())
+
+	// A failed install leaves nothing behind
+	entries, _ := os.ReadDir(testdir)
+	for _, entry := range entries {
+		g.Expect(entry.Name()).NotTo(HavePrefix(".installed"))
+	}
+	_, err = os.Stat(dst)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	g.Expect(Install(makeTestPath("testfile"), dst, 0755, &InstallOptions{Strip: true, StripProgram: "true"})).To(Equal(dst))
+}
@@ -0,0 +1,298 @@
+package shutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// From linux/io_uring.h
+const (
+	ioringOffSQRing = 0
+	ioringOffSQEs   = 0x10000000
+
+	ioringFeatSingleMmap = 1 << 0
+	ioringFeatRWCurPos   = 1 << 3
+
+	ioringOpRead  = 22
+	ioringOpWrite = 23
+
+	iosqeIOLink = 1 << 2
+
+	ioringEnterGetEvents = 1 << 0
+)
+
+const (
+	// The number of chunks of a file in flight at once, each of which
+	// takes a read and a write.
+	uringSlots     = 8
+	uringChunkSize = 128 << 10
+
+	// The number of rings kept for reuse, as setting one up takes
+	// several system calls.
+	uringPoolSize = 16
+)
+
+type uringSQOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type uringCQOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type uringParams struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFd uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  uringSQOffsets
+	cqOff                                                                  uringCQOffsets
+}
+
+type uringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	addr3       uint64
+	_           uint64
+}
+
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// An io_uring instance with buffers for uringSlots chunks.
+type uring struct {
+	fd      int
+	ringMem []byte
+	sqeMem  []byte
+	bufMem  []byte
+
+	sqHead, sqTail *uint32
+	sqMask         uint32
+	sqArray        []uint32
+	sqes           []uringSQE
+
+	cqHead, cqTail *uint32
+	cqMask         uint32
+	cqes           []uringCQE
+}
+
+// Rings that aren't in use.
+var uringPool = make(chan *uring, uringPoolSize)
+
+// Set up an io_uring, returning errNoIOUring if the kernel doesn't have it
+// or it's disabled.
+func newUring() (*uring, error) {
+	var params uringParams
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, 2*uringSlots, uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return nil, errNoIOUring
+	}
+	r := &uring{fd: int(fd)}
+	// Reading and writing at offsets came with the same kernel as the
+	// current position feature
+	if params.features&ioringFeatSingleMmap == 0 || params.features&ioringFeatRWCurPos == 0 {
+		r.close()
+		return nil, errNoIOUring
+	}
+
+	sqSize := params.sqOff.array + params.sqEntries*4
+	cqSize := params.cqOff.cqes + params.cqEntries*uint32(unsafe.Sizeof(uringCQE{}))
+	if cqSize > sqSize {
+		sqSize = cqSize
+	}
+	var err error
+	r.ringMem, err = unix.Mmap(r.fd, ioringOffSQRing, int(sqSize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err == nil {
+		r.sqeMem, err = unix.Mmap(r.fd, ioringOffSQEs, int(params.sqEntries)*int(unsafe.Sizeof(uringSQE{})),
+			unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	}
+	if err == nil {
+		r.bufMem, err = unix.Mmap(-1, 0, uringSlots*uringChunkSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANON)
+	}
+	if err != nil {
+		r.close()
+		return nil, &os.SyscallError{Syscall: "mmap", Err: err}
+	}
+
+	r.sqHead = r.ringUint32(params.sqOff.head)
+	r.sqTail = r.ringUint32(params.sqOff.tail)
+	r.sqMask = *r.ringUint32(params.sqOff.ringMask)
+	r.sqArray = unsafe.Slice(r.ringUint32(params.sqOff.array), params.sqEntries)
+	r.sqes = unsafe.Slice((*uringSQE)(unsafe.Pointer(&r.sqeMem[0])), params.sqEntries)
+	r.cqHead = r.ringUint32(params.cqOff.head)
+	r.cqTail = r.ringUint32(params.cqOff.tail)
+	r.cqMask = *r.ringUint32(params.cqOff.ringMask)
+	r.cqes = unsafe.Slice((*uringCQE)(unsafe.Pointer(&r.ringMem[params.cqOff.cqes])), params.cqEntries)
+	return r, nil
+}
+
+func (r *uring) ringUint32(offset uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&r.ringMem[offset]))
+}
+
+func (r *uring) close() {
+	for _, mem := range [][]byte{r.bufMem, r.sqeMem, r.ringMem} {
+		if mem != nil {
+			unix.Munmap(mem)
+		}
+	}
+	unix.Close(r.fd)
+}
+
+// Take a ring from the pool, or set up a new one.
+func getUring() (*uring, error) {
+	select {
+	case r := <-uringPool:
+		return r, nil
+	default:
+		return newUring()
+	}
+}
+
+// Return an idle ring to the pool, closing it if the pool is full.
+func putUring(r *uring) {
+	select {
+	case uringPool <- r:
+	default:
+		r.close()
+	}
+}
+
+// Add a request to the submission queue, which has room for it.
+func (r *uring) push(sqe uringSQE) {
+	tail := *r.sqTail
+	i := tail & r.sqMask
+	r.sqes[i] = sqe
+	r.sqArray[i] = i
+	atomic.StoreUint32(r.sqTail, tail+1)
+}
+
+// Submit the queued requests and wait for at least one to complete.
+func (r *uring) enter() error {
+	for {
+		pending := *r.sqTail - atomic.LoadUint32(r.sqHead)
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(pending), 1, ioringEnterGetEvents, 0, 0)
+		if errno == unix.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return &os.SyscallError{Syscall: "io_uring_enter", Err: errno}
+		}
+		return nil
+	}
+}
+
+// Call fn with each completed request.
+func (r *uring) reap(fn func(cqe uringCQE)) {
+	head := *r.cqHead
+	for tail := atomic.LoadUint32(r.cqTail); head != tail; head++ {
+		fn(r.cqes[head&r.cqMask])
+	}
+	atomic.StoreUint32(r.cqHead, head)
+}
+
+func (r *uring) chunk(slot int) []byte {
+	return r.bufMem[slot*uringChunkSize : (slot+1)*uringChunkSize]
+}
+
+// Copy the first size bytes of src to dst with io_uring, reading each chunk
+// into a buffer and writing it out as a linked request, with several chunks
+// in flight at once. Returns errNoIOUring if io_uring isn't available.
+func copyIOUring(ctx context.Context, dst, src *os.File, size int64) (int64, error) {
+	r, err := getUring()
+	if err != nil {
+		return 0, err
+	}
+	srcFd, dstFd := int32(src.Fd()), int32(dst.Fd())
+
+	var (
+		next, written int64
+		lengths       [uringSlots]uint32
+		free          []int
+		inFlight      int
+	)
+	for slot := 0; slot < uringSlots; slot++ {
+		free = append(free, slot)
+	}
+	// Only the first error is returned, once everything in flight is done
+	fail := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	for {
+		for err == nil && next < size && len(free) > 0 {
+			slot := free[len(free)-1]
+			free = free[:len(free)-1]
+			n := size - next
+			if n > uringChunkSize {
+				n = uringChunkSize
+			}
+			lengths[slot] = uint32(n)
+			addr := uint64(uintptr(unsafe.Pointer(&r.chunk(slot)[0])))
+			// Both halves are on the ring's slot, the read's user data
+			// being even and the write's odd. A short or failed read
+			// cancels the write linked to it.
+			r.push(uringSQE{opcode: ioringOpRead, flags: iosqeIOLink, fd: srcFd, off: uint64(next),
+				addr: addr, len: uint32(n), userData: uint64(slot) << 1})
+			r.push(uringSQE{opcode: ioringOpWrite, fd: dstFd, off: uint64(next),
+				addr: addr, len: uint32(n), userData: uint64(slot)<<1 | 1})
+			next += n
+			inFlight++
+		}
+		if inFlight == 0 {
+			break
+		}
+
+		if enterErr := r.enter(); enterErr != nil {
+			// The ring may still have requests using its buffers
+			// in flight, so it can't be reused
+			r.close()
+			return written, enterErr
+		}
+		r.reap(func(cqe uringCQE) {
+			slot := int(cqe.userData >> 1)
+			op, file := "read", src
+			if cqe.userData&1 == 1 {
+				op, file = "write", dst
+			}
+			switch {
+			case op == "write" && cqe.res == -int32(unix.ECANCELED):
+				// The read it was linked to failed, which is reported
+			case cqe.res < 0:
+				fail(&os.PathError{Op: op, Path: file.Name(), Err: unix.Errno(-cqe.res)})
+			case uint32(cqe.res) != lengths[slot]:
+				fail(fmt.Errorf("%s: short %s of %d/%d bytes", file.Name(), op, cqe.res, lengths[slot]))
+			case op == "write":
+				written += int64(cqe.res)
+			}
+			if op == "write" {
+				free = append(free, slot)
+				inFlight--
+			}
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	putUring(r)
+	return written, err
+}
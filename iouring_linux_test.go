@@ -0,0 +1,74 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// Copy data with copyIOUring(), skipping the test if io_uring isn't
+// available.
+func testCopyIOUring(t *testing.T, ctx context.Context, data []byte) (int64, error) {
+	t.Helper()
+	src := makeTestPath("src")
+	NewWithT(t).Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+	fsrc, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsrc.Close()
+	fdst, err := os.Create(makeTestPath("dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fdst.Close()
+
+	n, err := copyIOUring(ctx, fdst, fsrc, int64(len(data)))
+	if err == errNoIOUring {
+		t.Skip("io_uring not available")
+	}
+	return n, err
+}
+
+func TestCopyIOUring(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// Enough chunks that every slot is used more than once
+	data := bytes.Repeat([]byte("0123456789abcdef"), 3*uringSlots*uringChunkSize/16)
+	data = append(data, "tail"...)
+	n, err := testCopyIOUring(t, context.Background(), data)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(int64(len(data))))
+	g.Expect(os.ReadFile(makeTestPath("dst"))).To(Equal(data))
+
+	n, err = testCopyIOUring(t, context.Background(), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(BeZero())
+}
+
+func TestCopyIOUringCancelled(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	data := bytes.Repeat([]byte("x"), 4*uringSlots*uringChunkSize)
+	n, err := testCopyIOUring(t, ctx, data)
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(n).To(BeNumerically("<", len(data)))
+}
+
+func TestCopyEngineIOUring(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("testfile3")
+	result, err := CopyContext(context.Background(), makeTestPath("testfile"), dst, &CopyOptions{Engine: EngineIOUring})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(9)))
+	g.Expect(os.ReadFile(dst)).To(Equal([]byte("testfile\n")))
+}
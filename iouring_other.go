@@ -0,0 +1,13 @@
+//go:build !linux
+
+package shutil
+
+import (
+	"context"
+	"os"
+)
+
+// There's no io_uring on this platform.
+func copyIOUring(ctx context.Context, dst, src *os.File, size int64) (int64, error) {
+	return 0, errNoIOUring
+}
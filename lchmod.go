@@ -0,0 +1,18 @@
+package shutil
+
+import "fmt"
+
+// A warning that Op, such as changing the mode of a symbolic link, wasn't
+// done to Path because the platform can't do it.
+type UnsupportedWarning struct {
+	Op   string
+	Path string
+}
+
+func (w UnsupportedWarning) Error() string {
+	return fmt.Sprintf("%s `%s`: not supported on this platform", w.Op, w.Path)
+}
+
+func (w UnsupportedWarning) Code() ErrorCode {
+	return CodeNotSupported
+}
@@ -0,0 +1,20 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+import "os"
+
+// Change the mode of the named file like os.Chmod(), but if it's a
+// symbolic link, change the mode of the link rather than of what it points
+// to, as lchmod(2) does. This platform can't, so changing a link's mode
+// fails with a NotSupportedError.
+func Lchmod(name string, mode os.FileMode) error {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if IsSymlink(info) {
+		return &NotSupportedError{"lchmod", name}
+	}
+	return os.Chmod(name, mode)
+}
@@ -0,0 +1,48 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestLchmod(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(Lchmod(makeTestPath("testfile"), 0640)).To(Succeed())
+	info, err := os.Stat(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+	g.Expect(os.Symlink("testfile2", makeTestPath("link2"))).To(Succeed())
+	err = Lchmod(makeTestPath("link"), 0700)
+	var notSupported *NotSupportedError
+	result, copyErr := CopyModeResult(makeTestPath("link"), makeTestPath("link2"), false)
+	g.Expect(copyErr).NotTo(HaveOccurred())
+	switch runtime.GOOS {
+	case "linux", "windows":
+		g.Expect(errors.As(err, &notSupported)).To(BeTrue())
+		g.Expect(result.Warnings).To(Equal([]error{&UnsupportedWarning{"lchmod", makeTestPath("link2")}}))
+	default:
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.Warnings).To(BeEmpty())
+		info, err := os.Lstat(makeTestPath("link2"))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+	}
+	// The file the link points to is left alone
+	info, err = os.Stat(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+
+	result, err = CopyStatResult(makeTestPath("link"), makeTestPath("link2"), false)
+	g.Expect(err).NotTo(HaveOccurred())
+	if runtime.GOOS == "linux" {
+		g.Expect(result.Warnings).To(ContainElement(&UnsupportedWarning{"lchmod", makeTestPath("link2")}))
+	}
+}
@@ -0,0 +1,30 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Change the mode of the named file like os.Chmod(), but if it's a
+// symbolic link, change the mode of the link rather than of what it points
+// to, as lchmod(2) does. Only the BSDs and macOS give links modes of their
+// own, so elsewhere changing a link's mode fails with a
+// NotSupportedError.
+func Lchmod(name string, mode os.FileMode) error {
+	err := unix.Fchmodat(unix.AT_FDCWD, name, unixModeBits(mode), unix.AT_SYMLINK_NOFOLLOW)
+	if errors.Is(err, unix.EOPNOTSUPP) {
+		// Linux can't follow the flag even for files that aren't links
+		if info, statErr := os.Lstat(name); statErr == nil && !IsSymlink(info) {
+			return os.Chmod(name, mode)
+		}
+		return &NotSupportedError{"lchmod", name}
+	}
+	if err != nil {
+		return &os.PathError{Op: "lchmod", Path: name, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package shutil
+
+import "os"
+
+// Create linkPath as a symbolic link to target, like os.Symlink(), but
+// where the platform restricts who can create symbolic links, fall back
+// to a link that can be created instead. On Windows, creating symbolic
+// links needs administrator rights or developer mode, so without them a
+// link to a directory is created as a junction, which points at the
+// directory's absolute path. A link to a file still fails.
+func CreateLink(target, linkPath string) error {
+	return createLink(target, linkPath)
+}
+
+// Create linkPath like CreateLink(), first removing what's already there
+// unless it's a directory.
+func replaceLink(target, linkPath string) error {
+	err := CreateLink(target, linkPath)
+	if !os.IsExist(err) {
+		return err
+	}
+	if info, statErr := os.Lstat(linkPath); statErr == nil && info.IsDir() {
+		return err
+	}
+	removeErr := os.Remove(linkPath)
+	if removeErr != nil {
+		return removeErr
+	}
+	return CreateLink(target, linkPath)
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package shutil
+
+import "os"
+
+func createLink(target, linkPath string) error {
+	return os.Symlink(target, linkPath)
+}
@@ -0,0 +1,25 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCreateLink(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(CreateLink("testdir", makeTestPath("dirlink"))).To(Succeed())
+	entries, err := os.ReadDir(makeTestPath("dirlink"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).NotTo(BeEmpty())
+
+	g.Expect(CreateLink(filepath.Join("testdir", "file1"), makeTestPath("filelink"))).To(Succeed())
+	g.Expect(os.ReadFile(makeTestPath("filelink"))).To(Equal([]byte("file1\n")))
+
+	err = CreateLink("testdir", makeTestPath("dirlink"))
+	g.Expect(os.IsExist(err)).To(BeTrue())
+}
@@ -0,0 +1,75 @@
+package shutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+func createLink(target, linkPath string) error {
+	err := os.Symlink(target, linkPath)
+	if !errors.Is(err, windows.ERROR_PRIVILEGE_NOT_HELD) {
+		return err
+	}
+	abs, absErr := AbsoluteLink(target, linkPath)
+	if absErr != nil {
+		return err
+	}
+	if info, statErr := os.Stat(abs); statErr != nil || !info.IsDir() {
+		return err
+	}
+	return createJunction(abs, linkPath)
+}
+
+// Create linkPath as a junction to the directory target, an absolute path.
+func createJunction(target, linkPath string) error {
+	err := os.Mkdir(linkPath, 0777)
+	if err != nil {
+		return err
+	}
+	err = setJunction(target, linkPath)
+	if err != nil {
+		os.Remove(linkPath)
+		return &os.LinkError{Op: "junction", Old: target, New: linkPath, Err: err}
+	}
+	return nil
+}
+
+// Make the empty directory linkPath a junction to target, by giving it a
+// mount point reparse point.
+func setJunction(target, linkPath string) error {
+	// The reparse data holds the NT path of the target and the path to
+	// show for it, each terminated by a NUL
+	substitute := utf16.Encode([]rune(`\??\` + filepath.Clean(target)))
+	printName := utf16.Encode([]rune(filepath.Clean(target)))
+	paths := make([]uint16, 0, len(substitute)+len(printName)+2)
+	paths = append(append(append(paths, substitute...), 0), append(printName, 0)...)
+
+	buf := make([]byte, 16+2*len(paths))
+	binary.LittleEndian.PutUint32(buf, windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(8+2*len(paths)))
+	binary.LittleEndian.PutUint16(buf[8:], 0)
+	binary.LittleEndian.PutUint16(buf[10:], uint16(2*len(substitute)))
+	binary.LittleEndian.PutUint16(buf[12:], uint16(2*(len(substitute)+1)))
+	binary.LittleEndian.PutUint16(buf[14:], uint16(2*len(printName)))
+	for i, c := range paths {
+		binary.LittleEndian.PutUint16(buf[16+2*i:], c)
+	}
+
+	name, err := windows.UTF16PtrFromString(linkPath)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	var returned uint32
+	return windows.DeviceIoControl(h, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
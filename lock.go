@@ -0,0 +1,190 @@
+package shutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The name of the lock file AcquireLock() creates in a directory.
+const LockFileName = ".shutil.lock"
+
+// What a lock file records about the process that holds it.
+type LockInfo struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// Options for AcquireLock().
+type LockOptions struct {
+	// How long to keep trying to acquire a lock that another process
+	// holds before failing with a LockedError. Zero fails straight away.
+	Wait time.Duration
+
+	// Treat a lock file that hasn't been touched for this long as stale,
+	// as its holder has hung or its host has gone away. The holder
+	// touches it every half of this while it holds it. Zero only treats
+	// locks as stale whose holder was on this host and has exited.
+	StaleAfter time.Duration
+}
+
+// Returned by AcquireLock() when another process holds the lock.
+type LockedError struct {
+	Path string
+
+	// What the lock file says about its holder, which is zero if it
+	// couldn't be read, such as when it has only just been created.
+	Holder LockInfo
+}
+
+func (e LockedError) Error() string {
+	if e.Holder.PID == 0 {
+		return fmt.Sprintf("`%s` is locked", e.Path)
+	}
+	return fmt.Sprintf("`%s` is locked by process %d on %s since %s", e.Path, e.Holder.PID, e.Holder.Host, e.Holder.Acquired.Format(time.RFC3339))
+}
+
+func (e LockedError) Code() ErrorCode {
+	return CodeLocked
+}
+
+// An advisory lock on a directory, which only keeps out those that take
+// the lock too, such as two syncs to the same destination.
+type Lock struct {
+	path string
+	stop chan struct{}
+	once sync.Once
+}
+
+// Lock the directory dir, which must exist, by creating LockFileName in
+// it, so that two operations on the same tree don't interleave. A lock
+// that is stale, as LockOptions.StaleAfter says, or whose holder was on
+// this host and has exited without releasing it, is removed and taken
+// over.
+func AcquireLock(dir string, options *LockOptions) (*Lock, error) {
+	if options == nil {
+		options = &LockOptions{}
+	}
+	path := filepath.Join(dir, LockFileName)
+	host, _ := os.Hostname()
+	info := LockInfo{PID: os.Getpid(), Host: host, Acquired: time.Now().UTC()}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(options.Wait)
+	for {
+		err := createLockFile(path, data)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		locked, err := removeStaleLock(path, host, options.StaleAfter)
+		if err != nil {
+			return nil, err
+		}
+		if locked == nil {
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return nil, locked
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	lock := &Lock{path: path, stop: make(chan struct{})}
+	if options.StaleAfter > 0 {
+		go lock.touch(options.StaleAfter / 2)
+	}
+	return lock, nil
+}
+
+// How often a held lock is checked while waiting for it.
+var lockPollInterval = 100 * time.Millisecond
+
+// Create the lock file at path with data, failing if it exists. A lock
+// file that can't be written in full is removed again.
+func createLockFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// Remove the lock file at path if it's stale, returning nil so that it can
+// be created again, or return a LockedError if it's held. The lock file
+// is checked again just before it's removed, so that one another process
+// has just taken over isn't removed along with it.
+func removeStaleLock(path, host string, staleAfter time.Duration) (*LockedError, error) {
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var holder LockInfo
+	if data, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(data, &holder)
+	}
+
+	stale := staleAfter > 0 && time.Since(stat.ModTime()) > staleAfter
+	if holder.PID != 0 && holder.Host == host && !processRunning(holder.PID) {
+		stale = true
+	}
+	if !stale {
+		return &LockedError{path, holder}, nil
+	}
+
+	again, err := os.Stat(path)
+	if err == nil && os.SameFile(stat, again) && again.ModTime().Equal(stat.ModTime()) {
+		err = os.Remove(path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// Touch the lock file every interval, until it's released, so that it
+// isn't taken to be stale.
+func (l *Lock) touch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Release the lock by removing its lock file. Releasing it again does
+// nothing.
+func (l *Lock) Release() error {
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		err = os.Remove(l.path)
+	})
+	return err
+}
@@ -0,0 +1,113 @@
+package shutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// Write a lock file into dir as if holder held it.
+func writeLockFile(t *testing.T, dir string, holder LockInfo) string {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, LockFileName)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAcquireLock(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	lock, err := AcquireLock(testdir, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(makeTestPath(LockFileName)).To(BeARegularFile())
+
+	_, err = AcquireLock(testdir, nil)
+	var locked *LockedError
+	g.Expect(errors.As(err, &locked)).To(BeTrue())
+	g.Expect(locked.Path).To(Equal(makeTestPath(LockFileName)))
+	g.Expect(locked.Holder.PID).To(Equal(os.Getpid()))
+
+	g.Expect(lock.Release()).To(Succeed())
+	g.Expect(lock.Release()).To(Succeed())
+	g.Expect(makeTestPath(LockFileName)).NotTo(BeAnExistingFile())
+
+	// Waiting for a lock gets it once it's released
+	lock, err = AcquireLock(testdir, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	time.AfterFunc(200*time.Millisecond, func() { lock.Release() })
+	again, err := AcquireLock(testdir, &LockOptions{Wait: 10 * time.Second})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again.Release()).To(Succeed())
+}
+
+func TestAcquireLockStale(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// A lock whose holder on this host has exited is taken over
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	g.Expect(cmd.Run()).To(Succeed())
+	host, _ := os.Hostname()
+	writeLockFile(t, testdir, LockInfo{PID: cmd.Process.Pid, Host: host})
+	lock, err := AcquireLock(testdir, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(lock.Release()).To(Succeed())
+
+	// A lock held on another host is only stale once it's old enough
+	path := writeLockFile(t, testdir, LockInfo{PID: 1, Host: "elsewhere"})
+	_, err = AcquireLock(testdir, &LockOptions{StaleAfter: time.Minute})
+	g.Expect(err).To(BeAssignableToTypeOf(&LockedError{}))
+	hourAgo := time.Now().Add(-time.Hour)
+	g.Expect(os.Chtimes(path, hourAgo, hourAgo)).To(Succeed())
+	lock, err = AcquireLock(testdir, &LockOptions{StaleAfter: time.Minute})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(lock.Release()).To(Succeed())
+}
+
+func TestSyncTreeLock(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+	options := &SyncTreeOptions{Delete: true, Lock: &LockOptions{}}
+	_, err := SyncTree(context.Background(), src, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(filepath.Join(dst, "file1")).To(BeARegularFile())
+	g.Expect(filepath.Join(dst, LockFileName)).NotTo(BeAnExistingFile())
+	srcInfo, err := os.Stat(src)
+	g.Expect(err).NotTo(HaveOccurred())
+	dstInfo, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dstInfo.Mode()).To(Equal(srcInfo.Mode()))
+
+	// Another sync holding the lock keeps this one out
+	lock, err := AcquireLock(dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.Remove(filepath.Join(dst, "file1"))).To(Succeed())
+	_, err = SyncTree(context.Background(), src, dst, options)
+	g.Expect(err).To(BeAssignableToTypeOf(&LockedError{}))
+	g.Expect(filepath.Join(dst, "file1")).NotTo(BeAnExistingFile())
+
+	// The lock file isn't deleted by the sync that holds it
+	g.Expect(lock.Release()).To(Succeed())
+	writeLockFile(t, src, LockInfo{})
+	_, err = SyncTree(context.Background(), src, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(filepath.Join(dst, "file1")).To(BeARegularFile())
+	g.Expect(filepath.Join(dst, LockFileName)).NotTo(BeAnExistingFile())
+}
@@ -0,0 +1,42 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+import (
+	"os"
+	"time"
+)
+
+// Set the access and modification times of a file, without following it
+// if it is a symbolic link. The times of symbolic links themselves can't
+// be set on this platform, so they are left alone.
+func lutimes(path string, atime, mtime time.Time) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if IsSymlink(info) {
+		return nil
+	}
+	return chtimes(path, atime, mtime)
+}
+
+// Set the access and modification times of a file, leaving either alone
+// if it is the zero time. There's no call for that on this platform, so
+// the time left alone is read first.
+func chtimes(path string, atime, mtime time.Time) error {
+	if atime.IsZero() || mtime.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		currentAtime, currentMtime := fileTimes(info)
+		if atime.IsZero() {
+			atime = currentAtime
+		}
+		if mtime.IsZero() {
+			mtime = currentMtime
+		}
+	}
+	return os.Chtimes(path, atime, mtime)
+}
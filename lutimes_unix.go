@@ -0,0 +1,43 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Set the access and modification times of a file, without following it
+// if it is a symbolic link.
+func lutimes(path string, atime, mtime time.Time) error {
+	return utimensat("lutimes", path, atime, mtime, unix.AT_SYMLINK_NOFOLLOW)
+}
+
+// Set the access and modification times of a file, like os.Chtimes(), but
+// to the nanosecond for any time, including those before 1970 or too far
+// from it to be counted in nanoseconds.
+func chtimes(path string, atime, mtime time.Time) error {
+	return utimensat("chtimes", path, atime, mtime, 0)
+}
+
+func utimensat(op, path string, atime, mtime time.Time, flags int) error {
+	ts := make([]unix.Timespec, 2)
+	var err error
+	for i, t := range []time.Time{atime, mtime} {
+		if t.IsZero() {
+			ts[i] = unix.Timespec{Nsec: utimeOmit}
+			continue
+		}
+		ts[i], err = unix.TimeToTimespec(t)
+		if err != nil {
+			return &os.PathError{Op: op, Path: path, Err: err}
+		}
+	}
+	err = unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, flags)
+	if err != nil {
+		return &os.PathError{Op: op, Path: path, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package shutil
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Write a manifest of the regular files at root, which is a directory
+// tree or a single file, to w: a line for each file with its SHA-256 hash
+// and its path, sorted by path. Paths are relative to the directory root
+// is in, so they start with root's name, and are separated by slashes.
+// This is the format of sha256sum(1), so running `sha256sum -c` on the
+// manifest in that directory checks every file. Symbolic links aren't
+// followed, and other kinds of file are left out. The context is checked
+// before each file is read.
+func WriteManifest(ctx context.Context, root string, w io.Writer) error {
+	base := filepath.Dir(root)
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !IsRegular(info) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), filepath.ToSlash(rel))
+		return err
+	})
+}
+
+// Write the manifest of root, as WriteManifest() does, to the file name,
+// which is replaced atomically.
+func writeManifestFile(ctx context.Context, root, name string) error {
+	return replaceAtomic(name, func(tmp string) error {
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			return err
+		}
+		err = WriteManifest(ctx, root, f)
+		closeErr := f.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		return os.Chmod(tmp, 0644)
+	})
+}
@@ -0,0 +1,37 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestWriteManifest(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	var buf bytes.Buffer
+	g.Expect(WriteManifest(context.Background(), makeTestPath("testdir"), &buf)).To(Succeed())
+	g.Expect(buf.String()).To(Equal(
+		sha256Hex("file1\n") + "  testdir/file1\n" +
+			sha256Hex("file2\n") + "  testdir/file2\n"))
+
+	buf.Reset()
+	g.Expect(WriteManifest(context.Background(), makeTestPath("testfile"), &buf)).To(Succeed())
+	g.Expect(buf.String()).To(Equal(sha256Hex("testfile\n") + "  testfile\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.Expect(WriteManifest(ctx, makeTestPath("testdir"), &buf)).To(MatchError(context.Canceled))
+}
@@ -0,0 +1,192 @@
+package shutil
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Decides which entries of a tree are ignored, one directory at a time,
+// so that what depends on a directory's path is worked out once for all
+// of its entries rather than for each of them. Implementations can be
+// plugged into any operation that takes an IgnoreFunc with
+// MatcherIgnore(), which is worth doing for trees with hundreds of
+// thousands of entries.
+type Matcher interface {
+	// Report whether the entry called name, of the directory the Matcher
+	// is for, is ignored.
+	Match(name string, isDir bool) bool
+
+	// Return the Matcher for the entries of the subdirectory called name,
+	// which isn't ignored.
+	Dir(name string) Matcher
+}
+
+// Return an IgnoreFunc that ignores what m does, m being the Matcher for
+// the first directory the IgnoreFunc is called with. The Matcher of each
+// directory is kept, so that of a subdirectory is got from its parent's
+// with Dir(). Directories that aren't in the tree have nothing ignored.
+//
+// Like IgnoreFilesNamed(), the IgnoreFunc should only be used for a
+// single operation at a time.
+func MatcherIgnore(m Matcher) IgnoreFunc {
+	var mu sync.Mutex
+	var matchers map[string]Matcher
+	return func(dir string, entries []os.FileInfo) []string {
+		dir = filepath.Clean(dir)
+		mu.Lock()
+		dm, ok := matchers[dir]
+		if matchers == nil {
+			dm, ok = m, true
+			matchers = map[string]Matcher{dir: m}
+		} else if !ok {
+			if parent, found := matchers[filepath.Dir(dir)]; found {
+				dm, ok = parent.Dir(filepath.Base(dir)), true
+				matchers[dir] = dm
+			}
+		}
+		mu.Unlock()
+		if !ok {
+			return nil
+		}
+
+		var ignored []string
+		for _, entry := range entries {
+			if dm.Match(entry.Name(), entry.IsDir()) {
+				ignored = append(ignored, entry.Name())
+			}
+		}
+		return ignored
+	}
+}
+
+// Compile patterns with the syntax of the lines of an ignore file, as
+// LoadIgnoreFile() reads them, into a Matcher for the root directory of
+// a tree, which the patterns are relative to. Patterns that can't be
+// parsed are skipped.
+//
+// Rather than matching each pattern against the whole path of every entry,
+// the Matcher of each directory keeps how far along each pattern the
+// directory's path has got, and only matches the names of its entries
+// against what is left.
+func CompileIgnore(patterns ...string) Matcher {
+	m := &patternMatcher{}
+	for _, pattern := range patterns {
+		rule, ok := parseIgnoreRule(pattern)
+		if !ok {
+			continue
+		}
+		compiled := compiledRule{ignoreRule: rule, literal: make([]bool, len(rule.segments))}
+		for i, segment := range rule.segments {
+			compiled.literal[i] = !hasMeta(segment)
+		}
+		m.states = append(m.states, matchState{len(m.rules), 0})
+		m.rules = append(m.rules, compiled)
+	}
+	return m
+}
+
+// An ignoreRule, with which of its segments have no wildcards, so they
+// can be compared rather than matched.
+type compiledRule struct {
+	ignoreRule
+	literal []bool
+}
+
+// How far along a rule the path of a directory has got: the index of the
+// next segment of the rule to match.
+type matchState struct {
+	rule int
+	pos  int
+}
+
+// The Matcher CompileIgnore() returns, for a single directory.
+type patternMatcher struct {
+	rules []compiledRule
+
+	// In the order of the rules, with none repeated.
+	states []matchState
+}
+
+func (m *patternMatcher) Match(name string, isDir bool) bool {
+	ignored := false
+	var next []int
+	for i := 0; i < len(m.states); {
+		// The states are grouped by rule, and the last rule to match wins
+		r := m.states[i].rule
+		rule := &m.rules[r]
+		next = next[:0]
+		for ; i < len(m.states) && m.states[i].rule == r; i++ {
+			next = rule.step(m.states[i].pos, name, next)
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		for _, pos := range next {
+			if rule.matchesRest(pos) {
+				ignored = !rule.negate
+				break
+			}
+		}
+	}
+	return ignored
+}
+
+func (m *patternMatcher) Dir(name string) Matcher {
+	child := &patternMatcher{rules: m.rules}
+	var next []int
+	for i := 0; i < len(m.states); {
+		r := m.states[i].rule
+		next = next[:0]
+		for ; i < len(m.states) && m.states[i].rule == r; i++ {
+			next = m.rules[r].step(m.states[i].pos, name, next)
+		}
+		for _, pos := range next {
+			// A rule that has been used up can't match anything inside
+			if pos < len(m.rules[r].segments) {
+				child.states = append(child.states, matchState{r, pos})
+			}
+		}
+	}
+	return child
+}
+
+// Append to next the positions in the rule that matching name at pos
+// leads to, leaving out those already there.
+func (r *compiledRule) step(pos int, name string, next []int) []int {
+	for pos < len(r.segments) {
+		if r.segments[pos] != "**" {
+			if r.literal[pos] && r.segments[pos] == name {
+				return appendPos(next, pos+1)
+			}
+			if ok, _ := path.Match(r.segments[pos], name); ok && !r.literal[pos] {
+				return appendPos(next, pos+1)
+			}
+			return next
+		}
+		// "**" can take the name, and stay where it is, or match nothing
+		next = appendPos(next, pos)
+		pos++
+	}
+	return next
+}
+
+// Report whether the segments of the rule from pos on match nothing.
+func (r *compiledRule) matchesRest(pos int) bool {
+	for _, segment := range r.segments[pos:] {
+		if segment != "**" {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPos(positions []int, pos int) []int {
+	for _, p := range positions {
+		if p == pos {
+			return positions
+		}
+	}
+	return append(positions, pos)
+}
@@ -0,0 +1,73 @@
+package shutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestCompileIgnore(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	patterns := []string{"# build output", "*.o", "!keep.o", "/top", "build/", "docs/**/*.tmp", "a/**", "[bad"}
+	tree := shutiltest.Tree{
+		"a.o":          shutiltest.File(""),
+		"keep.o":       shutiltest.File(""),
+		"top":          shutiltest.File(""),
+		"sub/top":      shutiltest.File(""),
+		"sub/b.o":      shutiltest.File(""),
+		"sub/build/c":  shutiltest.File(""),
+		"build":        shutiltest.File(""),
+		"docs/x/y.tmp": shutiltest.File(""),
+		"docs/y.tmp":   shutiltest.File(""),
+		"docs/y.md":    shutiltest.File(""),
+		"a/b/c":        shutiltest.File(""),
+		"b/a/c":        shutiltest.File(""),
+	}
+	shutiltest.CreateTree(t, makeTestPath("src"), tree)
+	g.Expect(CopyTree(makeTestPath("src"), makeTestPath("dst"), &CopyTreeOptions{
+		Ignore: MatcherIgnore(CompileIgnore(patterns...)),
+	})).To(Succeed())
+	g.Expect(Glob(makeTestPath("dst/**"))).To(Equal([]string{
+		makeTestPath("dst/b"),
+		makeTestPath("dst/b/a"),
+		makeTestPath("dst/b/a/c"),
+		makeTestPath("dst/build"),
+		makeTestPath("dst/docs"),
+		makeTestPath("dst/docs/x"),
+		makeTestPath("dst/docs/y.md"),
+		makeTestPath("dst/keep.o"),
+		makeTestPath("dst/sub"),
+		makeTestPath("dst/sub/top"),
+	}))
+
+	// It ignores what an ignore file at the root of the tree does
+	var ignoreFile string
+	for _, pattern := range patterns {
+		ignoreFile += pattern + "\n"
+	}
+	tree[".shutilignore"] = shutiltest.File(ignoreFile)
+	shutiltest.CreateTree(t, makeTestPath("src2"), tree)
+	loaded, err := LoadIgnoreFile(makeTestPath("src2/.shutilignore"))
+	g.Expect(err).NotTo(HaveOccurred())
+	compiled := MatcherIgnore(CompileIgnore(patterns...))
+	g.Expect(filepath.Walk(makeTestPath("src2"), func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		entries, err := ioutil.ReadDir(path)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(compiled(path, entries)).To(Equal(loaded(path, entries)), path)
+		return nil
+	})).To(Succeed())
+
+	// Directories outside the tree have nothing ignored
+	entries, err := ioutil.ReadDir(makeTestPath("src"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(compiled(makeTestPath("src"), entries)).To(BeEmpty())
+}
@@ -0,0 +1,370 @@
+// An in-memory filesystem implementing shutil.FS, so that code that copies
+// and moves trees can be tested without touching the real filesystem.
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// How many symbolic links are followed before giving up.
+const maxSymlinks = 40
+
+// The errors wrapped in the *os.PathError returned when following a name
+// meets more than maxSymlinks symbolic links, and when removing a
+// directory that isn't empty. They're used rather than ELOOP and
+// ENOTEMPTY, which not every platform has.
+var (
+	ErrTooManySymlinks = errors.New("too many levels of symbolic links")
+	ErrNotEmpty        = errors.New("directory not empty")
+)
+
+// An in-memory filesystem. Names are separated by slashes and are all
+// relative to the root, whether or not they start with a slash. Symbolic
+// links are followed when they are the last element of a name, but not
+// part way through one. It is safe for concurrent use.
+type FS struct {
+	mu    sync.Mutex
+	files map[string]*file
+	now   func() time.Time
+}
+
+type file struct {
+	mode    os.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+}
+
+// Return an empty filesystem, holding only its root directory.
+func New() *FS {
+	fsys := &FS{files: map[string]*file{}, now: time.Now}
+	fsys.files[""] = &file{mode: os.ModeDir | 0755, modTime: fsys.now()}
+	return fsys
+}
+
+// Return the key of the named file in the map.
+func clean(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func pathError(op, name string, err error) error {
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// Look up a file without following it if it's a symbolic link.
+func (fsys *FS) lookup(op, name string) (string, *file, error) {
+	key := clean(name)
+	f, ok := fsys.files[key]
+	if !ok {
+		return key, nil, pathError(op, name, os.ErrNotExist)
+	}
+	return key, f, nil
+}
+
+// Look up a file, following symbolic links.
+func (fsys *FS) resolve(op, name string) (string, *file, error) {
+	key := clean(name)
+	for i := 0; i < maxSymlinks; i++ {
+		f, ok := fsys.files[key]
+		if !ok {
+			return key, nil, pathError(op, name, os.ErrNotExist)
+		}
+		if f.mode&os.ModeSymlink == 0 {
+			return key, f, nil
+		}
+		if path.IsAbs(f.target) {
+			key = clean(f.target)
+		} else {
+			key = clean(path.Join(path.Dir(key), f.target))
+		}
+	}
+	return key, nil, pathError(op, name, ErrTooManySymlinks)
+}
+
+// Check that a file can be created as key, returning why not otherwise.
+func (fsys *FS) checkParent(key string) error {
+	if key == "" {
+		return os.ErrExist
+	}
+	parent, ok := fsys.files[path.Dir("/" + key)[1:]]
+	if !ok {
+		return os.ErrNotExist
+	}
+	if !parent.mode.IsDir() {
+		return syscall.ENOTDIR
+	}
+	return nil
+}
+
+func (fsys *FS) Open(name string) (io.ReadCloser, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	_, f, err := fsys.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.mode.IsDir() {
+		return nil, pathError("open", name, syscall.EISDIR)
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func (fsys *FS) Create(name string, mode os.FileMode) (io.WriteCloser, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	key, f, err := fsys.resolve("create", name)
+	if os.IsNotExist(err) {
+		err = fsys.checkParent(key)
+		if err != nil {
+			return nil, pathError("create", name, err)
+		}
+		f = &file{mode: mode.Perm()}
+		fsys.files[key] = f
+	} else if err != nil {
+		return nil, err
+	} else if f.mode.IsDir() {
+		return nil, pathError("create", name, syscall.EISDIR)
+	}
+	f.data = nil
+	f.modTime = fsys.now()
+	return &writer{fsys: fsys, f: f}, nil
+}
+
+func (fsys *FS) Mkdir(name string, mode os.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	key := clean(name)
+	if _, ok := fsys.files[key]; ok {
+		return pathError("mkdir", name, os.ErrExist)
+	}
+	err := fsys.checkParent(key)
+	if err != nil {
+		return pathError("mkdir", name, err)
+	}
+	fsys.files[key] = &file{mode: os.ModeDir | mode.Perm(), modTime: fsys.now()}
+	return nil
+}
+
+func (fsys *FS) Lstat(name string) (os.FileInfo, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	key, f, err := fsys.lookup("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfo(key, f), nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]os.FileInfo, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	key, f, err := fsys.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.mode.IsDir() {
+		return nil, pathError("readdir", name, syscall.ENOTDIR)
+	}
+
+	prefix := key + "/"
+	if key == "" {
+		prefix = ""
+	}
+	var entries []os.FileInfo
+	for childKey, child := range fsys.files {
+		if childKey != "" && strings.HasPrefix(childKey, prefix) && !strings.Contains(childKey[len(prefix):], "/") {
+			entries = append(entries, newFileInfo(childKey, child))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (fsys *FS) Readlink(name string) (string, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	_, f, err := fsys.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if f.mode&os.ModeSymlink == 0 {
+		return "", pathError("readlink", name, syscall.EINVAL)
+	}
+	return f.target, nil
+}
+
+func (fsys *FS) Symlink(oldname, newname string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	key := clean(newname)
+	if _, ok := fsys.files[key]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	err := fsys.checkParent(key)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	fsys.files[key] = &file{mode: os.ModeSymlink | 0777, target: oldname, modTime: fsys.now()}
+	return nil
+}
+
+func (fsys *FS) Chmod(name string, mode os.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	_, f, err := fsys.resolve("chmod", name)
+	if err != nil {
+		return err
+	}
+	f.mode = f.mode&os.ModeType | mode.Perm()
+	return nil
+}
+
+func (fsys *FS) Chtimes(name string, atime, mtime time.Time) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	_, f, err := fsys.resolve("chtimes", name)
+	if err != nil {
+		return err
+	}
+	f.modTime = mtime
+	return nil
+}
+
+func (fsys *FS) Remove(name string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	key, f, err := fsys.lookup("remove", name)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return pathError("remove", name, syscall.EBUSY)
+	}
+	if f.mode.IsDir() && fsys.hasChildren(key) {
+		return pathError("remove", name, ErrNotEmpty)
+	}
+	delete(fsys.files, key)
+	return nil
+}
+
+// Rename oldname to newname, along with everything inside it. Like
+// os.Rename(), a file at newname is replaced, but a directory isn't.
+func (fsys *FS) Rename(oldname, newname string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	linkError := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	oldKey, f, err := fsys.lookup("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newKey := clean(newname)
+	if oldKey == newKey {
+		return nil
+	}
+	if oldKey == "" || strings.HasPrefix(newKey, oldKey+"/") {
+		return linkError(syscall.EINVAL)
+	}
+	if existing, ok := fsys.files[newKey]; ok {
+		if existing.mode.IsDir() && (!f.mode.IsDir() || fsys.hasChildren(newKey)) {
+			return linkError(syscall.EEXIST)
+		}
+		if !existing.mode.IsDir() && f.mode.IsDir() {
+			return linkError(syscall.ENOTDIR)
+		}
+	} else if err := fsys.checkParent(newKey); err != nil {
+		return linkError(err)
+	}
+
+	moved := map[string]*file{newKey: f}
+	for key, child := range fsys.files {
+		if strings.HasPrefix(key, oldKey+"/") {
+			moved[newKey+key[len(oldKey):]] = child
+			delete(fsys.files, key)
+		}
+	}
+	delete(fsys.files, oldKey)
+	for key, child := range moved {
+		fsys.files[key] = child
+	}
+	return nil
+}
+
+func (fsys *FS) hasChildren(key string) bool {
+	prefix := key + "/"
+	if key == "" {
+		prefix = ""
+	}
+	for childKey := range fsys.files {
+		if childKey != key && strings.HasPrefix(childKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Appends what is written to it to a file.
+type writer struct {
+	fsys *FS
+	f    *file
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.fsys.mu.Lock()
+	defer w.fsys.mu.Unlock()
+	w.f.data = append(w.f.data, p...)
+	w.f.modTime = w.fsys.now()
+	return len(p), nil
+}
+
+func (w *writer) Close() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// Describe a file as it is now.
+func newFileInfo(key string, f *file) fileInfo {
+	name := path.Base(key)
+	if key == "" {
+		name = "/"
+	}
+	size := int64(len(f.data))
+	if f.mode&os.ModeSymlink != 0 {
+		size = int64(len(f.target))
+	}
+	return fileInfo{name: name, size: size, mode: f.mode, modTime: f.modTime}
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,130 @@
+package memfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	shutil "github.com/gocardless/go-shutil"
+	. "github.com/onsi/gomega"
+)
+
+var _ shutil.RenameFS = New()
+
+func TestFS(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New()
+
+	g.Expect(fsys.Mkdir("dir", 0750)).To(Succeed())
+	g.Expect(os.IsExist(fsys.Mkdir("/dir", 0750))).To(BeTrue())
+	g.Expect(os.IsNotExist(fsys.Mkdir("missing/dir", 0750))).To(BeTrue())
+
+	w, err := fsys.Create("dir/file", 0600)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = w.Write([]byte("contents"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.Close()).To(Succeed())
+	g.Expect(fsys.Symlink("file", "dir/link")).To(Succeed())
+
+	r, err := fsys.Open("dir/link")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ioutil.ReadAll(r)).To(Equal([]byte("contents")))
+
+	info, err := fsys.Lstat("dir/link")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(shutil.IsSymlink(info)).To(BeTrue())
+	g.Expect(fsys.Readlink("dir/link")).To(Equal("file"))
+
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.Expect(fsys.Chmod("dir/link", 0644)).To(Succeed())
+	g.Expect(fsys.Chtimes("dir/file", old, old)).To(Succeed())
+	info, err = fsys.Lstat("dir/file")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode()).To(Equal(os.FileMode(0644)))
+	g.Expect(info.ModTime()).To(Equal(old))
+	g.Expect(info.Size()).To(Equal(int64(8)))
+
+	entries, err := fsys.ReadDir("dir")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+	g.Expect(entries[0].Name()).To(Equal("file"))
+	g.Expect(entries[1].Name()).To(Equal("link"))
+
+	g.Expect(errors.Is(fsys.Remove("dir"), ErrNotEmpty)).To(BeTrue())
+	g.Expect(fsys.Remove("dir/link")).To(Succeed())
+	g.Expect(fsys.Remove("dir/file")).To(Succeed())
+	g.Expect(fsys.Remove("dir")).To(Succeed())
+	_, err = fsys.Lstat("dir")
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	g.Expect(fsys.Symlink("loop", "loop")).To(Succeed())
+	_, err = fsys.Open("loop")
+	g.Expect(errors.Is(err, ErrTooManySymlinks)).To(BeTrue())
+}
+
+func TestRename(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New()
+	g.Expect(BuildTree(fsys, "", map[string]string{
+		"a/b/file": "data",
+		"c/":       "",
+		"d/file":   "data",
+	})).To(Succeed())
+
+	g.Expect(fsys.Rename("a", "c/a")).To(Succeed())
+	g.Expect(ReadTree(fsys, "c")).To(Equal(map[string]string{
+		"a/":       "",
+		"a/b/":     "",
+		"a/b/file": "data",
+	}))
+	g.Expect(fsys.Rename("c", "c/a/inside")).NotTo(Succeed())
+	g.Expect(fsys.Rename("c", "d")).NotTo(Succeed())
+}
+
+func TestCopyTreeFS(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New()
+	g.Expect(BuildTree(fsys, "src", map[string]string{
+		"empty/":   "",
+		"dir/file": "contents",
+		"dir/link": SymlinkPrefix + "file",
+	})).To(Succeed())
+
+	result, err := shutil.CopyTreeFS(context.Background(), fsys, "src", fsys, "dst", &shutil.CopyTreeFSOptions{Symlinks: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(shutil.TreeResult{Files: 1, Dirs: 3, Symlinks: 1, Bytes: 8}))
+	AssertTreesEqual(t, fsys, "src", fsys, "dst")
+}
+
+func TestMoveFS(t *testing.T) {
+	g := NewWithT(t)
+	src := New()
+	tree := map[string]string{
+		"dir/file": "contents",
+		"dir/link": SymlinkPrefix + "file",
+	}
+	g.Expect(BuildTree(src, "", tree)).To(Succeed())
+
+	// Within one filesystem the tree is renamed
+	g.Expect(shutil.MoveFS(context.Background(), src, "dir", src, "moved")).To(Succeed())
+	g.Expect(ReadTree(src, "")).To(Equal(map[string]string{
+		"moved/":     "",
+		"moved/file": "contents",
+		"moved/link": SymlinkPrefix + "file",
+	}))
+
+	// Between them it is copied and removed
+	dst := New()
+	g.Expect(shutil.MoveFS(context.Background(), src, "moved", dst, "dir")).To(Succeed())
+	g.Expect(ReadTree(src, "")).To(BeEmpty())
+	g.Expect(ReadTree(dst, "dir")).To(Equal(map[string]string{
+		"file": "contents",
+		"link": SymlinkPrefix + "file",
+	}))
+
+	err := shutil.MoveFS(context.Background(), dst, "dir/file", dst, "dir/link")
+	g.Expect(err).To(MatchError(&shutil.AlreadyExistsError{Dst: "dir/link"}))
+}
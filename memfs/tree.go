@@ -0,0 +1,188 @@
+package memfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	shutil "github.com/gocardless/go-shutil"
+)
+
+// Marks a value of a tree map as the target of a symbolic link.
+const SymlinkPrefix = "-> "
+
+// Create the files described by tree below root in fsys, which can be any
+// shutil.FS. Keys are names relative to root, separated by slashes. Keys
+// ending in a slash are directories, values starting with SymlinkPrefix
+// are symbolic links to the rest of the value, and anything else is a file
+// with the value as its contents. Missing parent directories are created.
+//
+//	BuildTree(fsys, "src", map[string]string{
+//	    "empty/":   "",
+//	    "dir/file": "contents",
+//	    "dir/link": memfs.SymlinkPrefix + "file",
+//	})
+func BuildTree(fsys shutil.FS, root string, tree map[string]string) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	err := mkdirAll(fsys, root)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		value := tree[name]
+		full := path.Join(root, name)
+		err = mkdirAll(fsys, path.Dir(full))
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case strings.HasSuffix(name, "/"):
+			err = mkdirAll(fsys, full)
+		case strings.HasPrefix(value, SymlinkPrefix):
+			err = fsys.Symlink(strings.TrimPrefix(value, SymlinkPrefix), full)
+		default:
+			err = writeFile(fsys, full, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Describe the tree below root in fsys like the map passed to BuildTree().
+// Every directory is included, not just empty ones.
+func ReadTree(fsys shutil.FS, root string) (map[string]string, error) {
+	tree := map[string]string{}
+	err := readTree(fsys, root, "", tree)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func readTree(fsys shutil.FS, dir, prefix string, tree map[string]string) error {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := prefix + entry.Name()
+		full := path.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			tree[name+"/"] = ""
+			err = readTree(fsys, full, name+"/", tree)
+		case shutil.IsSymlink(entry):
+			var target string
+			target, err = fsys.Readlink(full)
+			tree[name] = SymlinkPrefix + target
+		default:
+			var contents string
+			contents, err = readFile(fsys, full)
+			tree[name] = contents
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fail the test unless the trees below aRoot in a and bRoot in b hold the
+// same directories, files and symbolic links, reporting each difference.
+func AssertTreesEqual(t testing.TB, a shutil.FS, aRoot string, b shutil.FS, bRoot string) {
+	t.Helper()
+
+	aTree, err := ReadTree(a, aRoot)
+	if err != nil {
+		t.Fatalf("reading %s: %v", aRoot, err)
+	}
+	bTree, err := ReadTree(b, bRoot)
+	if err != nil {
+		t.Fatalf("reading %s: %v", bRoot, err)
+	}
+
+	for _, diff := range diffTrees(aTree, bTree) {
+		t.Error(diff)
+	}
+}
+
+// Describe the differences between two trees, in order of name.
+func diffTrees(a, b map[string]string) []string {
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []string
+	for _, name := range names {
+		aValue, inA := a[name]
+		bValue, inB := b[name]
+		switch {
+		case !inA:
+			diffs = append(diffs, fmt.Sprintf("%s: only in second tree", name))
+		case !inB:
+			diffs = append(diffs, fmt.Sprintf("%s: only in first tree", name))
+		case aValue != bValue:
+			diffs = append(diffs, fmt.Sprintf("%s: %q != %q", name, aValue, bValue))
+		}
+	}
+	return diffs
+}
+
+func mkdirAll(fsys shutil.FS, name string) error {
+	if name == "." || name == "/" || name == "" {
+		return nil
+	}
+	info, err := fsys.Lstat(name)
+	if err == nil {
+		if !info.IsDir() {
+			return &shutil.NotADirectoryError{Src: name}
+		}
+		return nil
+	}
+	err = mkdirAll(fsys, path.Dir(name))
+	if err != nil {
+		return err
+	}
+	return fsys.Mkdir(name, 0755)
+}
+
+func writeFile(fsys shutil.FS, name, contents string) error {
+	w, err := fsys.Create(name, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(contents))
+	closeErr := w.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+func readFile(fsys shutil.FS, name string) (string, error) {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	return string(data), err
+}
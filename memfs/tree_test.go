@@ -0,0 +1,40 @@
+package memfs
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestBuildTree(t *testing.T) {
+	g := NewWithT(t)
+	fsys := New()
+
+	g.Expect(BuildTree(fsys, "root", map[string]string{
+		"a/b/c":  "c",
+		"empty/": "",
+		"link":   SymlinkPrefix + "a/b/c",
+	})).To(Succeed())
+	g.Expect(ReadTree(fsys, "root")).To(Equal(map[string]string{
+		"a/":     "",
+		"a/b/":   "",
+		"a/b/c":  "c",
+		"empty/": "",
+		"link":   SymlinkPrefix + "a/b/c",
+	}))
+
+	g.Expect(BuildTree(fsys, "root", map[string]string{"a/b/c/d": ""})).NotTo(Succeed())
+}
+
+func TestDiffTrees(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(diffTrees(
+		map[string]string{"same": "x", "changed": "a", "first": ""},
+		map[string]string{"same": "x", "changed": "b", "second": ""},
+	)).To(Equal([]string{
+		`changed: "a" != "b"`,
+		"first: only in first tree",
+		"second: only in second tree",
+	}))
+}
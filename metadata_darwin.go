@@ -0,0 +1,41 @@
+//go:build darwin
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// The file flags Finder copies along with a file's extended attributes.
+// Flags such as immutable and append-only are left alone, as they would
+// stop the copy being changed afterwards.
+const finderFileFlags = unix.UF_HIDDEN | unix.UF_NODUMP
+
+// Give dst the hidden and nodump flags of the file described by srcInfo,
+// as copyfile(3) does. Symbolic links are left alone.
+func copyFileFlags(dst string, srcInfo os.FileInfo) error {
+	srcStat, ok := srcInfo.Sys().(*syscall.Stat_t)
+	if !ok || IsSymlink(srcInfo) {
+		return nil
+	}
+	dstInfo, err := os.Lstat(dst)
+	if err != nil {
+		return err
+	}
+	dstStat, ok := dstInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	flags := dstStat.Flags&^finderFileFlags | srcStat.Flags&finderFileFlags
+	if flags == dstStat.Flags {
+		return nil
+	}
+	err = unix.Chflags(dst, int(flags))
+	if err != nil {
+		return &os.PathError{Op: "chflags", Path: dst, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package shutil
+
+import "os"
+
+func copyFileFlags(dst string, srcInfo os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build aix || darwin || dragonfly || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import "golang.org/x/sys/unix"
+
+func mknod(path string, mode uint32, dev uint64) error {
+	return unix.Mknod(path, mode, int(dev))
+}
@@ -0,0 +1,10 @@
+package shutil
+
+import "errors"
+
+// The largest file copied with EngineMmap, so that mapping a file doesn't
+// take up too much of the address space.
+const mmapMaxSize = 1 << 30
+
+// Returned by copyMmap() when files can't be mapped.
+var errNoMmap = errors.New("memory-mapped files are not available")
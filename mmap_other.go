@@ -0,0 +1,12 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+import (
+	"context"
+	"os"
+)
+
+func copyMmap(ctx context.Context, dst, src *os.File, size int64, hash bool) (int64, []byte, error) {
+	return 0, nil, errNoMmap
+}
@@ -0,0 +1,49 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyMmap(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), largeFileSize/8)
+	src := makeTestPath("medium")
+	g.Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+
+	dst := makeTestPath("medium2")
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{Engine: EngineMmap, Verify: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(len(data))))
+	g.Expect(os.ReadFile(dst)).To(Equal(data))
+}
+
+func TestCopyMmapHash(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src, err := os.Open(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer src.Close()
+	dst, err := os.Create(makeTestPath("testfile3"))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer dst.Close()
+
+	n, sum, err := copyMmap(context.Background(), dst, src, 9, true)
+	if err == errNoMmap {
+		t.Skip("memory-mapped files not available")
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(int64(9)))
+	want := sha256.Sum256([]byte("testfile\n"))
+	g.Expect(sum).To(Equal(want[:]))
+	g.Expect(verifyHash(src.Name(), dst.Name(), sum)).To(Succeed())
+	g.Expect(verifyHash(src.Name(), makeTestPath("testfile2"), sum)).To(MatchError(&VerifyError{src.Name(), makeTestPath("testfile2")}))
+}
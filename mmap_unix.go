@@ -0,0 +1,60 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"golang.org/x/sys/unix"
+)
+
+// How much of a mapped file is written at once, between checks of the
+// context.
+const mmapChunkSize = 8 << 20
+
+// Copy the first size bytes of src to dst by mapping src into memory and
+// writing from the map, which saves copying the data into a buffer. With
+// hash set, the map is hashed too, which is cheaper than reading the
+// source again to verify the copy. Returns errNoMmap if src can't be
+// mapped.
+func copyMmap(ctx context.Context, dst, src *os.File, size int64, hash bool) (n int64, sum []byte, err error) {
+	data, err := unix.Mmap(int(src.Fd()), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return 0, nil, errNoMmap
+	}
+	defer unix.Munmap(data)
+	unix.Madvise(data, unix.MADV_SEQUENTIAL)
+
+	// Reading past the end of a source that is truncated while it's
+	// mapped faults, which is recovered from as an error
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("%s: truncated while being copied", src.Name())
+		}
+	}()
+
+	for n < size {
+		if err := ctx.Err(); err != nil {
+			return n, nil, err
+		}
+		end := n + mmapChunkSize
+		if end > size {
+			end = size
+		}
+		written, err := dst.Write(data[n:end])
+		n += int64(written)
+		if err != nil {
+			return n, nil, err
+		}
+	}
+	if hash {
+		h := sha256.Sum256(data)
+		sum = h[:]
+	}
+	return n, sum, nil
+}
@@ -0,0 +1,253 @@
+package shutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Returned by ParseModeSpec() for a mode it can't parse.
+type ModeSpecError struct {
+	Spec string
+}
+
+func (e ModeSpecError) Error() string {
+	return fmt.Sprintf("invalid mode `%s`", e.Spec)
+}
+
+func (e ModeSpecError) Code() ErrorCode {
+	return CodeInvalidSpec
+}
+
+// Decides the mode to give a copy of a file whose mode is mode. Only the
+// permission, setuid, setgid and sticky bits of the result are used.
+type ModeMapper func(mode os.FileMode) os.FileMode
+
+// A change to file modes, as chmod(1) takes, parsed by ParseModeSpec().
+type ModeSpec struct {
+	// The mode to set, if the spec was octal.
+	octal    uint32
+	isOctal  bool
+	clauses  []modeClause
+	original string
+}
+
+// A comma separated part of a symbolic mode, such as "go-w".
+type modeClause struct {
+	// The permission bits of the classes the clause applies to.
+	who uint32
+	ops []modeOp
+}
+
+// An operator of a clause and what follows it, such as "-w".
+type modeOp struct {
+	op byte
+	// The letters of the permissions, such as "rwX", or of the class to
+	// copy them from, such as "u".
+	perms string
+}
+
+// The permission bits of each class, and all of them.
+const (
+	modeUser  = 04700
+	modeGroup = 02070
+	modeOther = 01007
+	modeAll   = modeUser | modeGroup | modeOther
+)
+
+// Parse a mode as chmod(1) takes it: either octal, such as "0755", or
+// symbolic, such as "u+rwX,go-w" or "a=r,u+w". Symbolic modes are
+// comma separated clauses of who they apply to (any of "ugoa", where none
+// means all), and one or more operators ("+", "-" or "="), each followed
+// by permissions (any of "rwxXst") or the class to copy them from (one of
+// "ugo"). Unlike chmod, the umask is never applied.
+func ParseModeSpec(spec string) (ModeSpec, error) {
+	s := ModeSpec{original: spec}
+	if spec != "" && strings.Trim(spec, "01234567") == "" {
+		mode, err := strconv.ParseUint(spec, 8, 32)
+		if err != nil || mode > 07777 {
+			return s, &ModeSpecError{spec}
+		}
+		s.octal, s.isOctal = uint32(mode), true
+		return s, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		var clause modeClause
+		i := 0
+		for ; i < len(part) && strings.IndexByte("ugoa", part[i]) >= 0; i++ {
+			switch part[i] {
+			case 'u':
+				clause.who |= modeUser
+			case 'g':
+				clause.who |= modeGroup
+			case 'o':
+				clause.who |= modeOther
+			case 'a':
+				clause.who |= modeAll
+			}
+		}
+		if clause.who == 0 {
+			clause.who = modeAll
+		}
+		if i == len(part) {
+			return s, &ModeSpecError{spec}
+		}
+		for i < len(part) {
+			op := modeOp{op: part[i]}
+			if strings.IndexByte("+-=", op.op) < 0 {
+				return s, &ModeSpecError{spec}
+			}
+			i++
+			start := i
+			for ; i < len(part) && strings.IndexByte("+-=", part[i]) < 0; i++ {
+			}
+			op.perms = part[start:i]
+			if !validModePerms(op.perms) {
+				return s, &ModeSpecError{spec}
+			}
+			clause.ops = append(clause.ops, op)
+		}
+		s.clauses = append(s.clauses, clause)
+	}
+	return s, nil
+}
+
+func validModePerms(perms string) bool {
+	if len(perms) == 1 && strings.IndexByte("ugo", perms[0]) >= 0 {
+		return true
+	}
+	return strings.Trim(perms, "rwxXst") == ""
+}
+
+func (s ModeSpec) String() string {
+	return s.original
+}
+
+// Return what applying the spec to mode gives, as chmod would. The type
+// bits of mode are kept, and decide whether "X" applies to a directory.
+// This is a ModeMapper.
+func (s ModeSpec) Apply(mode os.FileMode) os.FileMode {
+	bits := unixModeBits(mode)
+	if s.isOctal {
+		// Like chmod, an octal mode doesn't clear a directory's setuid
+		// and setgid bits unless it has five digits
+		if mode.IsDir() && len(s.original) < 5 {
+			bits = s.octal | bits&06000
+		} else {
+			bits = s.octal
+		}
+		return fromUnixModeBits(mode, bits)
+	}
+
+	for _, clause := range s.clauses {
+		for _, op := range clause.ops {
+			perms := op.bits(bits, clause.who, mode.IsDir())
+			switch op.op {
+			case '+':
+				bits |= perms
+			case '-':
+				bits &^= perms
+			case '=':
+				// Like chmod, "=" leaves a directory's setuid and setgid
+				// bits alone unless they are mentioned
+				keep := clause.who
+				if mode.IsDir() {
+					keep &^= 06000
+				}
+				bits = bits&^keep | perms
+			}
+		}
+	}
+	return fromUnixModeBits(mode, bits)
+}
+
+// The bits op's permissions stand for, in the classes who, given the
+// current bits of the file.
+func (op modeOp) bits(current, who uint32, isDir bool) uint32 {
+	var perms uint32
+	switch op.perms {
+	case "u":
+		perms = (current >> 6 & 7) * 0111
+		return perms & who & 0777
+	case "g":
+		perms = (current >> 3 & 7) * 0111
+		return perms & who & 0777
+	case "o":
+		perms = (current & 7) * 0111
+		return perms & who & 0777
+	}
+	for _, c := range op.perms {
+		switch c {
+		case 'r':
+			perms |= 0444
+		case 'w':
+			perms |= 0222
+		case 'x':
+			perms |= 0111
+		case 'X':
+			if isDir || current&0111 != 0 {
+				perms |= 0111
+			}
+		case 's':
+			perms |= 06000
+		case 't':
+			perms |= 01000
+		}
+	}
+	return perms & who
+}
+
+func unixModeBits(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+// Replace the permission, setuid, setgid and sticky bits of mode with
+// bits.
+func fromUnixModeBits(mode os.FileMode, bits uint32) os.FileMode {
+	mode &^= os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	mode |= os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// The bits of a mode that os.Chmod() changes.
+func chmodBits(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// Change the mode of every file and directory in the tree root, including
+// root, as spec says, like `chmod -R`. Symbolic links aren't followed or
+// changed. Directories are changed before their contents, so a spec that
+// takes away permission to read them fails.
+func ChmodTree(root string, spec ModeSpec) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if IsSymlink(info) {
+			return nil
+		}
+		return os.Chmod(path, chmodBits(spec.Apply(info.Mode())))
+	})
+}
@@ -0,0 +1,80 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseModeSpec(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, test := range []struct {
+		spec string
+		mode os.FileMode
+		want os.FileMode
+	}{
+		{"755", 0600, 0755},
+		{"0640", os.ModeDir | os.ModeSetgid | 0777, os.ModeDir | os.ModeSetgid | 0640},
+		{"00640", os.ModeDir | os.ModeSetgid | 0777, os.ModeDir | 0640},
+		{"u+rwX,go-w", 0444, 0644},
+		{"u+rwX,go-w", os.ModeDir | 0466, os.ModeDir | 0744},
+		{"a+X", 0644, 0644},
+		{"a+X", 0744, 0755},
+		{"+x", 0600, 0711},
+		{"go=u-w", 0750, 0755 &^ 0022},
+		{"o=", 0777, 0770},
+		{"a=r,u+w", 0777, 0644},
+		{"u+s,g+s,+t", 0755, os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0755},
+		{"ug-s", os.ModeSetuid | os.ModeSetgid | 0755, 0755},
+		{"g=", os.ModeDir | os.ModeSetgid | 0775, os.ModeDir | os.ModeSetgid | 0705},
+	} {
+		spec, err := ParseModeSpec(test.spec)
+		g.Expect(err).NotTo(HaveOccurred(), test.spec)
+		g.Expect(spec.Apply(test.mode)).To(Equal(test.want), test.spec)
+	}
+
+	for _, spec := range []string{"", "u", "u+q", "x+r", "u+r,", "8", "77777", "u+ug"} {
+		_, err := ParseModeSpec(spec)
+		g.Expect(err).To(MatchError(&ModeSpecError{spec}), spec)
+	}
+}
+
+func TestChmodTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	spec, err := ParseModeSpec("go-rwx,u+X")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ChmodTree(makeTestPath("testdir"), spec)).To(Succeed())
+
+	info, err := os.Stat(makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+	info, err = os.Stat(makeTestPath("testdir/file1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+}
+
+func TestCopyModeMapper(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	spec, err := ParseModeSpec("a-w,u+w,o=")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.Chmod(makeTestPath("testdir/file1"), 0666)).To(Succeed())
+	g.Expect(os.Chmod(makeTestPath("testdir"), 0777)).To(Succeed())
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out"), &CopyTreeOptions{
+		CopyOptions: &CopyOptions{ModeMapper: spec.Apply},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for path, want := range map[string]os.FileMode{"out": 0750, "out/file1": 0640} {
+		info, err := os.Stat(makeTestPath(path))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(want), path)
+	}
+}
@@ -0,0 +1,169 @@
+package shutil
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+// Return a temporary directory on a different device to testdir, so that
+// renames between them fail with EXDEV.
+func crossDeviceDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("/dev/shm", "shutil")
+	if err != nil {
+		t.Skipf("no tmpfs to move across devices to: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testInfo, err := os.Stat(testdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirInfo.Sys().(*syscall.Stat_t).Dev == testInfo.Sys().(*syscall.Stat_t).Dev {
+		t.Skip("/dev/shm is on the same device as the tests")
+	}
+	return dir
+}
+
+func TestMoveCrossDevice(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	g.Expect(Move(makeTestPath("testfile"), other, nil)).To(Equal(filepath.Join(other, "testfile")))
+	g.Expect(makeTestPath("testfile")).NotTo(BeAnExistingFile())
+
+	g.Expect(Move(makeTestPath("testdir"), other, nil)).To(Equal(filepath.Join(other, "testdir")))
+	g.Expect(filepath.Join(other, "testdir/file1")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("testdir")).NotTo(BeADirectory())
+
+	link := makeTestPath("link")
+	g.Expect(os.Symlink("testfile2", link)).To(Succeed())
+	g.Expect(Move(link, other, nil)).To(Equal(filepath.Join(other, "link")))
+	g.Expect(os.Readlink(filepath.Join(other, "link"))).To(Equal("testfile2"))
+}
+
+func TestMoveCrossDeviceKeepsTimes(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	// Files are copied with Copy2() by default, like Python's move()
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chtimes(makeTestPath("testfile"), old, old)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir/file1"), old, old)).To(Succeed())
+	g.Expect(Move(makeTestPath("testfile"), other, nil)).To(Equal(filepath.Join(other, "testfile")))
+	g.Expect(Move(makeTestPath("testdir"), other, nil)).To(Equal(filepath.Join(other, "testdir")))
+
+	for _, name := range []string{"testfile", "testdir/file1"} {
+		info, err := os.Stat(filepath.Join(other, name))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.ModTime()).To(BeTemporally("==", old), name)
+	}
+}
+
+func TestMoveUniqueCrossDevice(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	link := makeTestPath("link")
+	g.Expect(os.Symlink("testfile2", link)).To(Succeed())
+	g.Expect(MoveUnique(link, other, nil)).To(Equal(filepath.Join(other, "link")))
+	g.Expect(os.Readlink(filepath.Join(other, "link"))).To(Equal("testfile2"))
+
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{"fifo": {Mode: os.ModeNamedPipe | 0640}})
+	g.Expect(MoveUnique(makeTestPath("fifo"), other, nil)).To(Equal(filepath.Join(other, "fifo")))
+	info, err := os.Lstat(filepath.Join(other, "fifo"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode()).To(Equal(os.ModeNamedPipe | 0640))
+
+	entries, err := os.ReadDir(other)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+}
+
+func TestMoveCrossDeviceNamedPipe(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{"fifo": {Mode: os.ModeNamedPipe | 0640}})
+	fifo := makeTestPath("fifo")
+
+	dst, err := Move(fifo, other, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	info, err := os.Lstat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode()).To(Equal(os.ModeNamedPipe | 0640))
+	_, err = os.Lstat(fifo)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestMoveCrossDeviceSocket(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	sock := makeTestPath("sock")
+	listener, err := net.Listen("unix", sock)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+
+	_, err = Move(sock, other, nil)
+	var crossErr *CrossDeviceError
+	g.Expect(errors.As(err, &crossErr)).To(BeTrue())
+	g.Expect(crossErr.Src).To(Equal(sock))
+
+	// The socket is left where it was
+	_, err = os.Lstat(sock)
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestMoveCrossDeviceRemoveFails(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	src := makeTestPath("testfile")
+	setImmutable(t, src, true)
+	t.Cleanup(func() { setImmutable(t, src, false) })
+
+	dst, err := Move(src, other, nil)
+	g.Expect(dst).To(Equal(filepath.Join(other, "testfile")))
+
+	var moveErr *MoveError
+	g.Expect(errors.As(err, &moveErr)).To(BeTrue())
+	g.Expect(moveErr.Op).To(Equal("remove"))
+	g.Expect(moveErr.Copied).To(BeTrue())
+
+	// It now exists in both places
+	g.Expect(FilesEqual(src, dst, nil)).To(BeTrue())
+}
+
+func TestPlanMoveCrossDevice(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+	other := crossDeviceDir(t)
+
+	src := makeTestPath("testdir")
+	plan, err := PlanMove(src, other)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(describePlan(plan)).To(Equal([]string{
+		"mkdir " + src + " -> " + filepath.Join(other, "testdir"),
+		"copy " + makeTestPath("testdir/file1") + " -> " + filepath.Join(other, "testdir/file1"),
+		"copy " + makeTestPath("testdir/file2") + " -> " + filepath.Join(other, "testdir/file2"),
+		"remove " + src + " -> ",
+	}))
+}
@@ -0,0 +1,24 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// ERROR_NOT_SAME_DEVICE on Windows
+const errNotSameDevice = syscall.Errno(17)
+
+// Report whether a rename failed because it would have crossed devices.
+func isCrossDevice(err error) bool {
+	return runtime.GOOS == "windows" && errors.Is(err, errNotSameDevice)
+}
+
+// Create a named pipe or device file at dst like the one described by fi.
+func mknodLike(dst string, fi os.FileInfo) error {
+	return fmt.Errorf("can't recreate a file with mode %s on this platform", fi.Mode())
+}
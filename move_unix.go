@@ -0,0 +1,48 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Report whether a rename failed because it would have crossed devices.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, unix.EXDEV)
+}
+
+// Create a named pipe or device file at dst like the one described by fi.
+func mknodLike(dst string, fi os.FileInfo) error {
+	perm := uint32(fi.Mode().Perm())
+	switch kind := KindOf(fi.Mode()); kind {
+	case KindNamedPipe:
+		err := unix.Mkfifo(dst, perm)
+		if err != nil {
+			return &os.PathError{Op: "mkfifo", Path: dst, Err: err}
+		}
+	case KindBlockDevice, KindCharDevice:
+		stat, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("`%s` has no device number", dst)
+		}
+		typ := uint32(unix.S_IFBLK)
+		if kind == KindCharDevice {
+			typ = unix.S_IFCHR
+		}
+		err := mknod(dst, typ|perm, uint64(stat.Rdev))
+		if err != nil {
+			return &os.PathError{Op: "mknod", Path: dst, Err: err}
+		}
+	case KindSocket:
+		return errors.New("sockets only exist while a process is bound to them")
+	default:
+		return fmt.Errorf("unsupported file mode %s", fi.Mode())
+	}
+	// Like open(2), mknod(2) applies the umask
+	return os.Chmod(dst, fi.Mode().Perm())
+}
@@ -0,0 +1,56 @@
+package shutil
+
+type MoveAllOptions struct {
+	MoveOptions
+
+	// Carry on moving the remaining sources after one fails.
+	ContinueOnError bool
+}
+
+// Move several files or directories into dstDir, like `mv a b c dir/`.
+// Return their destinations, in the same order as srcs.
+//
+// Each source is moved with Move(), stopping at the first one that fails.
+// See MoveAllWithOptions() to carry on instead.
+func MoveAll(dstDir string, srcs ...string) ([]string, error) {
+	return MoveAllWithOptions(dstDir, srcs, nil)
+}
+
+// Move several files or directories into dstDir, like MoveAll().
+//
+// dstDir must already be a directory, otherwise a NotADirectoryError is
+// returned before anything is moved. The errors from any sources that
+// fail are returned together in a MultiError, each as the MoveError for
+// that source. The destinations are returned for every source that was
+// attempted, including those that failed, as Move() reports them.
+func MoveAllWithOptions(dstDir string, srcs []string, options *MoveAllOptions) ([]string, error) {
+	if options == nil {
+		options = &MoveAllOptions{}
+	}
+
+	isDir, err := isDirectory(dstDir)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, &NotADirectoryError{dstDir}
+	}
+
+	dsts := make([]string, 0, len(srcs))
+	var errs []error
+	for _, src := range srcs {
+		dst, err := Move(src, dstDir, &options.MoveOptions)
+		dsts = append(dsts, dst)
+		if err != nil {
+			errs = append(errs, err)
+			if !options.ContinueOnError {
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return dsts, &MultiError{errs}
+	}
+	return dsts, nil
+}
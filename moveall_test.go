@@ -0,0 +1,58 @@
+package shutil
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMoveAll(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dir := makeTestPath("testdir")
+	g.Expect(MoveAll(dir, makeTestPath("testfile"), makeTestPath("testfile2"))).To(Equal([]string{
+		makeTestPath("testdir/testfile"),
+		makeTestPath("testdir/testfile2"),
+	}))
+	g.Expect(makeTestPath("testdir/testfile")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("testfile")).NotTo(BeAnExistingFile())
+}
+
+func TestMoveAllNotADirectory(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	file := makeTestPath("testfile2")
+	_, err := MoveAll(file, makeTestPath("testfile"))
+	g.Expect(err).Should(MatchError(&NotADirectoryError{file}))
+	g.Expect(makeTestPath("testfile")).To(BeAnExistingFile())
+}
+
+func TestMoveAllErrors(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dir := makeTestPath("testdir")
+	srcs := []string{makeTestPath("missing"), makeTestPath("testfile")}
+
+	// Stops at the first failure
+	dsts, err := MoveAll(dir, srcs...)
+	g.Expect(dsts).To(HaveLen(1))
+	var multi *MultiError
+	g.Expect(errors.As(err, &multi)).To(BeTrue())
+	g.Expect(multi.Errors).To(HaveLen(1))
+	g.Expect(makeTestPath("testfile")).To(BeAnExistingFile())
+
+	// Carries on
+	dsts, err = MoveAllWithOptions(dir, srcs, &MoveAllOptions{ContinueOnError: true})
+	g.Expect(dsts).To(HaveLen(2))
+	g.Expect(errors.As(err, &multi)).To(BeTrue())
+	g.Expect(multi.Errors).To(HaveLen(1))
+
+	var moveErr *MoveError
+	g.Expect(errors.As(multi.Errors[0], &moveErr)).To(BeTrue())
+	g.Expect(moveErr.Src).To(Equal(makeTestPath("missing")))
+	g.Expect(makeTestPath("testdir/testfile")).To(BeAnExistingFile())
+}
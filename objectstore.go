@@ -0,0 +1,208 @@
+package shutil
+
+import (
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Describes an object in an ObjectStore.
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// The minimal interface to an object store, such as S3 or GCS, needed to
+// copy trees to and from it with ObjectStoreFS. Implementations usually
+// wrap the store's client, along with the bucket and a context.
+type ObjectStore interface {
+	// Return the contents of the object with the key.
+	Get(key string) (io.ReadCloser, error)
+
+	// Store everything read from r as the object with the key, replacing
+	// any that exists.
+	Put(key string, r io.Reader) error
+
+	// Describe every object whose key starts with prefix.
+	List(prefix string) ([]ObjectInfo, error)
+
+	// Remove the object with the key.
+	Delete(key string) error
+}
+
+// Presents the objects of an ObjectStore as an FS, so that trees can be
+// copied to and from it, or synced to it with SyncTreeFS().
+//
+// Keys are the names of files below Prefix, which should usually end in a
+// slash. Object stores don't have directories, so directories exist
+// whenever there are objects inside them, and creating or removing one
+// does nothing. Modes and times can't be changed, and symbolic links
+// aren't supported.
+type ObjectStoreFS struct {
+	Store  ObjectStore
+	Prefix string
+}
+
+// Return the key of the named file.
+func (o ObjectStoreFS) key(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	return o.Prefix + name
+}
+
+// Return the prefix of the keys of the entries of the named directory.
+func (o ObjectStoreFS) dirPrefix(name string) string {
+	prefix := o.key(name)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+func (o ObjectStoreFS) Open(name string) (io.ReadCloser, error) {
+	return o.Store.Get(o.key(name))
+}
+
+func (o ObjectStoreFS) Create(name string, mode os.FileMode) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	w := &objectWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := o.Store.Put(o.key(name), pr)
+		// Stop any further writes if the object store gave up early
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+func (o ObjectStoreFS) Mkdir(name string, mode os.FileMode) error {
+	return nil
+}
+
+func (o ObjectStoreFS) Lstat(name string) (os.FileInfo, error) {
+	key := o.key(name)
+	if key == o.Prefix {
+		return objectFileInfo{name: path.Base(name), dir: true}, nil
+	}
+
+	objects, err := o.Store.List(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if object.Key == key {
+			return objectFileInfo{name: path.Base(key), size: object.Size, modTime: object.ModTime}, nil
+		}
+		if strings.HasPrefix(object.Key, key+"/") {
+			return objectFileInfo{name: path.Base(key), dir: true}, nil
+		}
+	}
+	return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+}
+
+func (o ObjectStoreFS) ReadDir(name string) ([]os.FileInfo, error) {
+	prefix := o.dirPrefix(name)
+	objects, err := o.Store.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[string]objectFileInfo{}
+	for _, object := range objects {
+		rest := strings.TrimPrefix(object.Key, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			children[rest[:i]] = objectFileInfo{name: rest[:i], dir: true}
+		} else if rest != "" {
+			if _, ok := children[rest]; !ok {
+				children[rest] = objectFileInfo{name: rest, size: object.Size, modTime: object.ModTime}
+			}
+		}
+	}
+
+	entries := make([]os.FileInfo, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, child)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (o ObjectStoreFS) Readlink(name string) (string, error) {
+	return "", &NotSupportedError{"readlink", name}
+}
+
+func (o ObjectStoreFS) Symlink(oldname, newname string) error {
+	return &NotSupportedError{"symlink", newname}
+}
+
+func (o ObjectStoreFS) Chmod(name string, mode os.FileMode) error {
+	return nil
+}
+
+func (o ObjectStoreFS) Chtimes(name string, atime, mtime time.Time) error {
+	return nil
+}
+
+func (o ObjectStoreFS) Remove(name string) error {
+	info, err := o.Lstat(name)
+	if err != nil || info.IsDir() {
+		return err
+	}
+	return o.Store.Delete(o.key(name))
+}
+
+// Streams what is written to it into ObjectStore.Put().
+type objectWriter struct {
+	pw     *io.PipeWriter
+	done   chan error
+	closed bool
+	err    error
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Finish the object, returning any error storing it.
+func (w *objectWriter) Close() error {
+	return w.finish(nil)
+}
+
+// Fail the upload with err, so that ObjectStore.Put() gives up rather than
+// storing what has been written so far over the object.
+func (w *objectWriter) Abort(err error) error {
+	return w.finish(err)
+}
+
+func (w *objectWriter) finish(err error) error {
+	if !w.closed {
+		w.closed = true
+		w.pw.CloseWithError(err)
+		w.err = <-w.done
+	}
+	return w.err
+}
+
+// Describes an object, or a directory implied by the keys of objects.
+type objectFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi objectFileInfo) Name() string       { return fi.name }
+func (fi objectFileInfo) Size() int64        { return fi.size }
+func (fi objectFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi objectFileInfo) IsDir() bool        { return fi.dir }
+func (fi objectFileInfo) Sys() interface{}   { return nil }
+
+func (fi objectFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
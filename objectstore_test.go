@@ -0,0 +1,162 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// An ObjectStore that keeps objects in memory.
+type memoryObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	putErr  error
+}
+
+func newMemoryObjectStore() *memoryObjectStore {
+	return &memoryObjectStore{objects: map[string][]byte{}}
+}
+
+func (s *memoryObjectStore) Get(key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, &os.PathError{Op: "get", Path: key, Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryObjectStore) Put(key string, r io.Reader) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryObjectStore) List(prefix string) ([]ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var objects []ObjectInfo
+	for key, data := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: int64(len(data)), ModTime: time.Unix(0, 0)})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *memoryObjectStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func TestCopyTreeToObjectStore(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	store := newMemoryObjectStore()
+	bucket := ObjectStoreFS{Store: store, Prefix: "backups/"}
+	result, err := CopyTreeFS(context.Background(), OSFS{}, testdir, bucket, "today", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Files).To(Equal(4))
+	g.Expect(store.objects).To(Equal(map[string][]byte{
+		"backups/today/testdir/file1": []byte("file1\n"),
+		"backups/today/testdir/file2": []byte("file2\n"),
+		"backups/today/testfile":      []byte("testfile\n"),
+		"backups/today/testfile2":     []byte("testfile2\n"),
+	}))
+
+	info, err := bucket.Lstat("today/testdir")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.IsDir()).To(BeTrue())
+	_, err = bucket.Lstat("today/test")
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	result, err = CopyTreeFS(context.Background(), bucket, "today", OSFS{}, makeTestPath("restored"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(TreeResult{Files: 4, Dirs: 2, Bytes: 31}))
+	g.Expect(FilesEqual(makeTestPath("testdir/file2"), makeTestPath("restored/testdir/file2"), nil)).To(BeTrue())
+}
+
+func TestCopyTreeToObjectStoreError(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	store := newMemoryObjectStore()
+	store.putErr = errors.New("bucket is full")
+	_, err := CopyTreeFS(context.Background(), OSFS{}, testdir, ObjectStoreFS{Store: store}, "", nil)
+	g.Expect(err).To(MatchError(store.putErr))
+}
+
+// An FS whose files fail to be read after their first few bytes.
+type failingReadFS struct {
+	OSFS
+	err error
+}
+
+func (f failingReadFS) Open(name string) (io.ReadCloser, error) {
+	r, err := f.OSFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(io.MultiReader(io.LimitReader(r, 2), iotest.ErrReader(f.err))), nil
+}
+
+func TestCopyTreeToObjectStoreKeepsOldObjects(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	store := newMemoryObjectStore()
+	store.objects["testdir/file1"] = []byte("GOOD OLD CONTENT")
+	srcFS := failingReadFS{err: errors.New("disk read error")}
+	_, err := CopyTreeFS(context.Background(), srcFS, makeTestPath("testdir"), ObjectStoreFS{Store: store}, "testdir", nil)
+	g.Expect(err).To(MatchError(srcFS.err))
+	g.Expect(store.objects).To(Equal(map[string][]byte{"testdir/file1": []byte("GOOD OLD CONTENT")}))
+}
+
+func TestObjectStoreFSUnsupported(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+	bucket := ObjectStoreFS{Store: newMemoryObjectStore()}
+
+	_, err := CopyTreeFS(context.Background(), OSFS{}, testdir, bucket, "tree", &CopyTreeFSOptions{Symlinks: true})
+	g.Expect(err).To(MatchError(&NotSupportedError{"symlink", "tree/link"}))
+}
+
+func TestObjectStoreFSRemove(t *testing.T) {
+	g := NewWithT(t)
+
+	store := newMemoryObjectStore()
+	store.objects["dir/file"] = []byte("data")
+	bucket := ObjectStoreFS{Store: store}
+
+	g.Expect(bucket.Remove("dir")).To(Succeed())
+	g.Expect(store.objects).To(HaveLen(1))
+	g.Expect(bucket.Remove("dir/file")).To(Succeed())
+	g.Expect(store.objects).To(BeEmpty())
+	g.Expect(os.IsNotExist(bucket.Remove("dir/file"))).To(BeTrue())
+}
@@ -0,0 +1,420 @@
+package shutil
+
+import (
+	"context"
+	"time"
+)
+
+// The kinds of operation an Op runs.
+type opKind int
+
+const (
+	opCopy opKind = iota
+	opMove
+	opSync
+)
+
+func (k opKind) String() string {
+	switch k {
+	case opCopy:
+		return "copy"
+	case opMove:
+		return "move"
+	}
+	return "sync"
+}
+
+// A copy, move or sync, set up step by step and then run, as a single
+// entry point to the options of Copy(), CopyTree(), Move() and SyncTree():
+//
+//	report, err := shutil.NewCopy(src, dst).
+//		Recursive().
+//		PreserveAll().
+//		Parallel(8).
+//		WithContext(ctx).
+//		Run()
+//
+// Each method changes the Op and returns it. Options that don't apply to
+// the kind of operation are ignored.
+type Op struct {
+	kind      opKind
+	src       string
+	dst       string
+	ctx       context.Context
+	recursive bool
+	symlinks  bool
+	linkStyle LinkStyle
+	parallel  int
+	order     CopyOrder
+	headroom  *Headroom
+	vanished  bool
+	ignore    IgnoreFunc
+	skipDir   SkipDirFunc
+	exclude   bool
+	existOK   bool
+	manifest  *string
+	progress  ProgressFunc
+	events    EventFunc
+	delete    bool
+	compare   CompareMode
+	lock      *LockOptions
+	options   CopyOptions
+}
+
+// What an Op did. It can be marshalled to JSON for other programs to read,
+// with the Duration in nanoseconds.
+type Report struct {
+	// "copy", "move" or "sync".
+	Op string `json:"op"`
+
+	Src string `json:"src"`
+
+	// Where src ended up, which is inside the destination that was passed
+	// in if that was a directory.
+	Dst string `json:"dst"`
+
+	// What was copied. Renames aren't counted, and for a sync, Files
+	// counts everything that was copied, as SyncResult.Copied does.
+	TreeResult
+
+	// What a sync left alone or removed, as in SyncResult.
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+
+	// Describes the error Run() returned, if any, as DescribeErrors()
+	// does.
+	Errors []ErrorInfo `json:"errors,omitempty"`
+
+	// The manifest that was written, if any.
+	Manifest string `json:"manifest,omitempty"`
+
+	// Describes the Warnings of what was copied, such as metadata that
+	// couldn't be preserved.
+	WarningInfo []ErrorInfo `json:"warnings,omitempty"`
+}
+
+// Set up a copy of src to dst, which by default is a single file copied
+// like Copy().
+func NewCopy(src, dst string) *Op {
+	return &Op{kind: opCopy, src: src, dst: dst}
+}
+
+// Set up a move of src to dst, like Move().
+func NewMove(src, dst string) *Op {
+	return &Op{kind: opMove, src: src, dst: dst}
+}
+
+// Set up a sync of the tree dst with src, like SyncTree().
+func NewSync(src, dst string) *Op {
+	return &Op{kind: opSync, src: src, dst: dst}
+}
+
+// Stop the operation with the context's error if it is cancelled.
+func (o *Op) WithContext(ctx context.Context) *Op {
+	o.ctx = ctx
+	return o
+}
+
+// Copy a whole directory tree, like CopyTree().
+func (o *Op) Recursive() *Op {
+	o.recursive = true
+	return o
+}
+
+// Copy symbolic links in a tree as links, rather than what they point to.
+func (o *Op) Symlinks() *Op {
+	o.symlinks = true
+	return o
+}
+
+// Copy symbolic links in a tree as links written in the given style, as
+// CopyTreeOptions.LinkStyle describes.
+func (o *Op) LinkStyle(style LinkStyle) *Op {
+	o.symlinks = true
+	o.linkStyle = style
+	return o
+}
+
+// Give copies the same times, owners and extended attributes as their
+// sources, as well as their modes. A sync compares and copies modes and
+// times.
+func (o *Op) PreserveAll() *Op {
+	o.options.PreserveTimes = true
+	o.options.PreserveOwner = true
+	o.options.PreserveXattrs = true
+	o.compare = CompareContentModeTimes
+	return o
+}
+
+// Give copies the same times as their sources.
+func (o *Op) PreserveTimes() *Op {
+	o.options.PreserveTimes = true
+	return o
+}
+
+// Report metadata that can't be given to copies in Report.Warnings,
+// rather than failing, as CopyOptions.DegradePreservation does.
+func (o *Op) DegradePreservation() *Op {
+	o.options.DegradePreservation = true
+	return o
+}
+
+// Read each copy back and compare it with its source.
+func (o *Op) Verify() *Op {
+	o.options.Verify = true
+	return o
+}
+
+// Copy up to n files of a tree at once.
+func (o *Op) Parallel(n int) *Op {
+	o.parallel = n
+	return o
+}
+
+// Start copying the files of a tree in the order that order gives, such
+// as LargestFirst.
+func (o *Op) Order(order CopyOrder) *Op {
+	o.order = order
+	return o
+}
+
+// Check that there is space for a copy of a tree and the headroom before
+// starting it, failing with an InsufficientSpaceError if there isn't, as
+// EnsurePlanSpace() does.
+func (o *Op) EnsureSpace(headroom Headroom) *Op {
+	o.headroom = &headroom
+	return o
+}
+
+// Skip the files of a tree that are removed while it's being copied,
+// listing them in Report.Vanished, rather than failing.
+func (o *Op) SkipVanished() *Op {
+	o.vanished = true
+	return o
+}
+
+// Leave out the entries of each directory of a tree that fn returns.
+func (o *Op) Ignore(fn IgnoreFunc) *Op {
+	o.ignore = fn
+	return o
+}
+
+// Skip the directories of a tree that fn returns true for, without
+// reading them.
+func (o *Op) SkipDir(fn SkipDirFunc) *Op {
+	o.skipDir = fn
+	return o
+}
+
+// Leave the destination of a tree out of the copy if it's inside the
+// source, rather than failing, as CopyTreeOptions.ExcludeDestination
+// does.
+func (o *Op) ExcludeDestination() *Op {
+	o.exclude = true
+	return o
+}
+
+// Copy a tree into the destination even if it exists, merging
+// directories and replacing files and links, as
+// CopyTreeOptions.DirsExistOK does.
+func (o *Op) DirsExistOK() *Op {
+	o.existOK = true
+	return o
+}
+
+// Once the operation succeeds, write a manifest of the destination to
+// path, as WriteManifest() does. An empty path is the destination with
+// ".sha256" added, so the manifest is next to it.
+func (o *Op) Manifest(path string) *Op {
+	o.manifest = &path
+	return o
+}
+
+// Apply the options of preset, such as Mirror. Options set before it may
+// be changed, and those set after it change it.
+func (o *Op) Preset(preset Preset) *Op {
+	preset.apply(o)
+	return o
+}
+
+// Copy file data no faster than limiter allows, as
+// CopyOptions.RateLimit describes.
+func (o *Op) RateLimit(limiter *RateLimiter) *Op {
+	o.options.RateLimit = limiter
+	return o
+}
+
+// Call fn after each file has been copied.
+func (o *Op) Progress(fn ProgressFunc) *Op {
+	o.progress = fn
+	return o
+}
+
+// Call fn with what happens to each file. A single copy or move is
+// reported as one event.
+func (o *Op) Events(fn EventFunc) *Op {
+	o.events = fn
+	return o
+}
+
+// Remove what isn't in the source from the destination of a sync.
+func (o *Op) Delete() *Op {
+	o.delete = true
+	return o
+}
+
+// Decide which files a sync copies by comparing them as mode describes.
+func (o *Op) Compare(mode CompareMode) *Op {
+	o.compare = mode
+	return o
+}
+
+// Hold a lock on the destination of a sync while it runs, as
+// SyncTreeOptions.Lock does.
+func (o *Op) Lock(options LockOptions) *Op {
+	o.lock = &options
+	return o
+}
+
+// Run the operation, reporting what it did, even if it failed part way
+// through.
+func (o *Op) Run() (Report, error) {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	report := Report{Op: o.kind.String(), Src: o.src, Dst: o.dst, Started: time.Now()}
+
+	var err error
+	switch o.kind {
+	case opCopy:
+		err = o.runCopy(ctx, &report)
+	case opMove:
+		report.Dst, err = Move(o.src, o.dst, o.moveOptions(ctx))
+		if err == nil {
+			o.events.send(Event{Kind: EventRenamed, Src: o.src, Dst: report.Dst})
+		} else {
+			o.events.send(Event{Kind: EventErrored, Src: o.src, Dst: o.dst, Err: err})
+		}
+	case opSync:
+		err = o.runSync(ctx, &report)
+	}
+	if err == nil && o.manifest != nil {
+		name := *o.manifest
+		if name == "" {
+			name = report.Dst + ".sha256"
+		}
+		err = writeManifestFile(ctx, report.Dst, name)
+		if err == nil {
+			report.Manifest = name
+		}
+	}
+	report.Duration = time.Since(report.Started)
+	report.Errors = DescribeErrors(err)
+	for _, warning := range report.Warnings {
+		report.WarningInfo = append(report.WarningInfo, DescribeErrors(warning)...)
+	}
+	return report, err
+}
+
+func (o *Op) runCopy(ctx context.Context, report *Report) error {
+	options := o.options
+	if !o.recursive {
+		result, err := CopyContext(ctx, o.src, o.dst, &options)
+		report.Dst = result.Dst
+		report.Bytes = result.Bytes
+		report.Warnings = result.Warnings
+		switch {
+		case err != nil:
+			o.events.send(Event{Kind: EventErrored, Src: o.src, Dst: result.Dst, Err: err})
+		case result.Skipped:
+			o.events.send(Event{Kind: EventSkipped, Src: o.src, Dst: result.Dst, Reason: SkipDanglingSymlink})
+		case result.Symlink:
+			report.Symlinks = 1
+			o.events.send(Event{Kind: EventSymlinkCreated, Src: o.src, Dst: result.Dst})
+		default:
+			report.Files = 1
+			o.events.send(Event{Kind: EventFileCopied, Src: o.src, Dst: result.Dst, Bytes: result.Bytes, Digests: result.Digests})
+		}
+		return err
+	}
+
+	treeOptions := &CopyTreeOptions{
+		Symlinks:           o.symlinks,
+		LinkStyle:          o.linkStyle,
+		Ignore:             o.ignore,
+		CopyOptions:        &options,
+		Events:             o.events,
+		SkipVanished:       o.vanished,
+		SkipDir:            o.skipDir,
+		ExcludeDestination: o.exclude,
+		DirsExistOK:        o.existOK,
+	}
+	if o.parallel <= 1 && o.progress == nil && o.order == nil && o.headroom == nil {
+		var err error
+		report.TreeResult, err = CopyTreeContext(ctx, o.src, o.dst, treeOptions)
+		return err
+	}
+
+	// Copying files in parallel or in order, reporting progress, or
+	// checking for space needs the whole tree to be known first
+	plan, err := PlanCopyTreeContext(ctx, o.src, o.dst, treeOptions)
+	if err != nil {
+		return err
+	}
+	if o.headroom != nil {
+		err = EnsurePlanSpace(o.dst, plan, *o.headroom)
+		if err != nil {
+			return err
+		}
+	}
+	result, err := Apply(ctx, plan, &ApplyOptions{
+		Parallel:     o.parallel,
+		Order:        o.order,
+		CopyOptions:  &options,
+		SkipVanished: o.vanished,
+		Progress:     o.progress,
+		Events:       o.events,
+	})
+	report.TreeResult = result.TreeResult
+	return err
+}
+
+// The options for a move, which only need to say how to copy when the
+// copies are rate limited.
+func (o *Op) moveOptions(ctx context.Context) *MoveOptions {
+	if o.options.RateLimit == nil {
+		return nil
+	}
+	return &MoveOptions{
+		CopyFunction: func(src, dst string, followSymlinks bool) (string, error) {
+			result, err := CopyContext(ctx, src, dst, &CopyOptions{
+				FollowSymlinks: followSymlinks,
+				RateLimit:      o.options.RateLimit,
+			})
+			return result.Dst, err
+		},
+	}
+}
+
+func (o *Op) runSync(ctx context.Context, report *Report) error {
+	result, err := SyncTree(ctx, o.src, o.dst, &SyncTreeOptions{
+		Compare:   o.compare,
+		Delete:    o.delete,
+		Ignore:    o.ignore,
+		Progress:  o.progress,
+		Events:    o.events,
+		RateLimit: o.options.RateLimit,
+		Lock:      o.lock,
+	})
+	report.Files = result.Copied
+	report.Bytes = result.Bytes
+	report.Updated = result.Updated
+	report.Unchanged = result.Unchanged
+	report.Deleted = result.Deleted
+	return err
+}
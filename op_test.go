@@ -0,0 +1,111 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOpCopy(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	report, err := NewCopy(makeTestPath("testfile"), makeTestPath("testdir")).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Op).To(Equal("copy"))
+	g.Expect(report.Dst).To(Equal(makeTestPath("testdir/testfile")))
+	g.Expect(report.TreeResult).To(Equal(TreeResult{Files: 1, Bytes: 9}))
+	g.Expect(report.Duration).To(BeNumerically(">", 0))
+	g.Expect(FilesEqual(makeTestPath("testfile"), makeTestPath("testdir/testfile"), nil)).To(BeTrue())
+}
+
+func TestOpCopyTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chtimes(makeTestPath("testdir/file1"), old, old)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir"), old, old)).To(Succeed())
+	g.Expect(os.Chmod(makeTestPath("testdir"), 0750)).To(Succeed())
+
+	for _, parallel := range []int{0, 4} {
+		dst := makeTestPath("out")
+		g.Expect(os.RemoveAll(dst)).To(Succeed())
+
+		var progress []string
+		op := NewCopy(makeTestPath("testdir"), dst).Recursive().Symlinks().PreserveAll().Parallel(parallel)
+		if parallel > 1 {
+			op.Progress(func(p Progress) {
+				g.Expect(p.FilesTotal).To(Equal(2))
+				progress = append(progress, p.Src)
+			})
+		}
+		report, err := op.WithContext(context.Background()).Run()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(report.TreeResult).To(Equal(TreeResult{Files: 2, Dirs: 1, Symlinks: 1, Bytes: 12}))
+
+		diff, err := TreesEqual(makeTestPath("testdir"), dst, &CompareOptions{Symlinks: true, Mode: true, ModTime: true})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(diff.Equal()).To(BeTrue())
+		info, err := os.Stat(dst)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.ModTime()).To(Equal(old))
+		g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+
+		if parallel > 1 {
+			sort.Strings(progress)
+			g.Expect(progress).To(Equal([]string{makeTestPath("testdir/file1"), makeTestPath("testdir/file2")}))
+		}
+	}
+
+	_, err := NewCopy(makeTestPath("testdir"), makeTestPath("out")).Recursive().Parallel(2).Run()
+	g.Expect(err).To(MatchError(&AlreadyExistsError{makeTestPath("out")}))
+}
+
+func TestOpCopyTreeCancelled(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewCopy(makeTestPath("testdir"), makeTestPath("out")).Recursive().Parallel(2).WithContext(ctx).Run()
+	g.Expect(err).To(MatchError(context.Canceled))
+}
+
+func TestOpMove(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	report, err := NewMove(makeTestPath("testfile"), makeTestPath("testdir")).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Op).To(Equal("move"))
+	g.Expect(report.Dst).To(Equal(makeTestPath("testdir/testfile")))
+	g.Expect(makeTestPath("testdir/testfile")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("testfile")).NotTo(BeAnExistingFile())
+}
+
+func TestOpSync(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	g.Expect(Copy(makeTestPath("testfile"), dst, false)).To(Equal(dst))
+	g.Expect(os.Remove(dst)).To(Succeed())
+	g.Expect(os.Mkdir(dst, 0755)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("out/extra"), nil, 0644)).To(Succeed())
+
+	report, err := NewSync(makeTestPath("testdir"), dst).Delete().Compare(CompareContentMode).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Op).To(Equal("sync"))
+	g.Expect(report.Files).To(Equal(2))
+	g.Expect(report.Deleted).To(Equal(1))
+
+	report, err = NewSync(makeTestPath("testdir"), dst).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Unchanged).To(Equal(2))
+}
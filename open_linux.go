@@ -0,0 +1,21 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Open a file for reading, without updating its access time if noAtime is
+// set. Only the owner of a file or root can do that, so it's opened as
+// usual otherwise.
+func openSource(name string, noAtime bool) (*os.File, error) {
+	if noAtime {
+		f, err := os.OpenFile(name, os.O_RDONLY|unix.O_NOATIME, 0)
+		if !errors.Is(err, unix.EPERM) {
+			return f, err
+		}
+	}
+	return os.Open(name)
+}
@@ -0,0 +1,152 @@
+package shutil
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Returned by ParseOwnerSpec() for an owner it can't parse or find.
+type OwnerSpecError struct {
+	Spec string
+	Err  error
+}
+
+func (e OwnerSpecError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid owner `%s`: %s", e.Spec, e.Err)
+	}
+	return fmt.Sprintf("invalid owner `%s`", e.Spec)
+}
+
+func (e OwnerSpecError) Code() ErrorCode {
+	return CodeInvalidSpec
+}
+
+func (e OwnerSpecError) Unwrap() error {
+	return e.Err
+}
+
+// The numeric owner and group to give a file. Either can be -1 to leave
+// it unchanged, as with os.Chown().
+type Owner struct {
+	UID int
+	GID int
+}
+
+// Parse an owner as chown(1) takes it: "user", "user:group", "user:" for
+// the user and their login group, or ":group" for just the group. Users
+// and groups can be names, which are looked up on this system, or
+// numeric ids, which are used as they are.
+func ParseOwnerSpec(spec string) (Owner, error) {
+	owner := Owner{UID: -1, GID: -1}
+	userPart, groupPart, hasGroup := spec, "", false
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		userPart, groupPart, hasGroup = spec[:i], spec[i+1:], true
+	}
+	if userPart == "" && groupPart == "" {
+		return owner, &OwnerSpecError{spec, nil}
+	}
+
+	var loginGroup string
+	if userPart != "" {
+		if uid, err := strconv.Atoi(userPart); err == nil && uid >= 0 {
+			owner.UID = uid
+		} else {
+			u, err := user.Lookup(userPart)
+			if err != nil {
+				return owner, &OwnerSpecError{spec, err}
+			}
+			owner.UID, err = strconv.Atoi(u.Uid)
+			if err != nil {
+				return owner, &OwnerSpecError{spec, err}
+			}
+			loginGroup = u.Gid
+		}
+	}
+
+	switch {
+	case groupPart != "":
+		if gid, err := strconv.Atoi(groupPart); err == nil && gid >= 0 {
+			owner.GID = gid
+			break
+		}
+		g, err := user.LookupGroup(groupPart)
+		if err != nil {
+			return owner, &OwnerSpecError{spec, err}
+		}
+		owner.GID, err = strconv.Atoi(g.Gid)
+		if err != nil {
+			return owner, &OwnerSpecError{spec, err}
+		}
+	case hasGroup:
+		// "user:" means the user's login group
+		if loginGroup == "" {
+			u, err := user.LookupId(userPart)
+			if err != nil {
+				return owner, &OwnerSpecError{spec, err}
+			}
+			loginGroup = u.Gid
+		}
+		gid, err := strconv.Atoi(loginGroup)
+		if err != nil {
+			return owner, &OwnerSpecError{spec, err}
+		}
+		owner.GID = gid
+	}
+	return owner, nil
+}
+
+// Change the owner and group of the file name, like Python's chown().
+// user and group are names, which are looked up on this system, or
+// numeric ids, and either can be empty to leave it unchanged, but not
+// both. Symbolic links are followed.
+func Chown(name, user, group string) error {
+	spec := user
+	if group != "" {
+		spec += ":" + group
+	}
+	owner, err := ParseOwnerSpec(spec)
+	if err != nil {
+		return err
+	}
+	return os.Chown(name, owner.UID, owner.GID)
+}
+
+// Change the owner and group of every file and directory in the tree
+// root, including root, like `chown -R`. Symbolic links themselves are
+// changed, rather than what they point to.
+func ChownTree(root string, owner Owner) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, owner.UID, owner.GID)
+	})
+}
+
+// Decides the owner and group to give a copy of a file owned by uid and
+// gid, when owners are preserved.
+type OwnerMapper func(uid, gid int) (int, int)
+
+// A translation table of numeric user and group ids, for copying trees
+// between systems that give the same users different ids, such as into a
+// container's user namespace. Ids that aren't in the table are kept.
+type IDMap struct {
+	UIDs map[int]int
+	GIDs map[int]int
+}
+
+// Translate uid and gid. This is an OwnerMapper.
+func (m IDMap) Map(uid, gid int) (int, int) {
+	if mapped, ok := m.UIDs[uid]; ok {
+		uid = mapped
+	}
+	if mapped, ok := m.GIDs[gid]; ok {
+		gid = mapped
+	}
+	return uid, gid
+}
@@ -0,0 +1,80 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseOwnerSpec(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ParseOwnerSpec("1000")).To(Equal(Owner{1000, -1}))
+	g.Expect(ParseOwnerSpec("1000:50")).To(Equal(Owner{1000, 50}))
+	g.Expect(ParseOwnerSpec(":50")).To(Equal(Owner{-1, 50}))
+
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("can't look up the current user: %s", err)
+	}
+	uid, err := strconv.Atoi(current.Uid)
+	if err != nil {
+		t.Skipf("user ids aren't numeric: %s", current.Uid)
+	}
+	gid, _ := strconv.Atoi(current.Gid)
+	g.Expect(ParseOwnerSpec(current.Username)).To(Equal(Owner{uid, -1}))
+	g.Expect(ParseOwnerSpec(current.Username + ":")).To(Equal(Owner{uid, gid}))
+	g.Expect(ParseOwnerSpec(current.Uid + ":")).To(Equal(Owner{uid, gid}))
+	if group, err := user.LookupGroupId(current.Gid); err == nil {
+		g.Expect(ParseOwnerSpec(current.Username + ":" + group.Name)).To(Equal(Owner{uid, gid}))
+	}
+
+	for _, spec := range []string{"", ":", "no-such-user-shutil", "1000:no-such-group-shutil"} {
+		_, err := ParseOwnerSpec(spec)
+		g.Expect(err).To(BeAssignableToTypeOf(&OwnerSpecError{}), spec)
+	}
+}
+
+func TestChownTreeAndIDMap(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	if err := ChownTree(makeTestPath("testdir"), Owner{1234, 5678}); err != nil {
+		t.Skipf("can't change ownership: %s", err)
+	}
+	info, err := os.Stat(makeTestPath("testdir/file1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	uid, gid, _ := fileOwner(info)
+	g.Expect([]int{uid, gid}).To(Equal([]int{1234, 5678}))
+
+	idMap := IDMap{UIDs: map[int]int{1234: 100}, GIDs: map[int]int{1: 2}}
+	_, err = CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath("out"), &CopyTreeOptions{
+		CopyOptions: &CopyOptions{PreserveOwner: true, OwnerMapper: idMap.Map},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, path := range []string{"out", "out/file1"} {
+		info, err := os.Stat(makeTestPath(path))
+		g.Expect(err).NotTo(HaveOccurred())
+		uid, gid, _ := fileOwner(info)
+		g.Expect([]int{uid, gid}).To(Equal([]int{100, 5678}), path)
+	}
+}
+
+func TestChown(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(Chown(makeTestPath("testfile"), "", "")).To(BeAssignableToTypeOf(&OwnerSpecError{}))
+	if err := Chown(makeTestPath("testfile"), "1234", "5678"); err != nil {
+		t.Skipf("can't change ownership: %s", err)
+	}
+	g.Expect(Chown(makeTestPath("testfile"), "", "910")).To(Succeed())
+	info, err := os.Stat(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	uid, gid, _ := fileOwner(info)
+	g.Expect([]int{uid, gid}).To(Equal([]int{1234, 910}))
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !plan9
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// Return the numeric owner and group of a file, if the platform has them.
+func fileOwner(fi os.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
@@ -0,0 +1,96 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Whether paths are compared ignoring case, as the filesystems of Windows
+// and macOS usually are case-insensitive.
+var foldCase = runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+
+// Return the absolute path of path with symbolic links resolved and "."
+// and ".." elements removed. If path doesn't exist, the part of it that
+// does is resolved and the rest is kept as it is, so where something is
+// about to be created can be made canonical too.
+func Canonical(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		parent := filepath.Dir(abs)
+		if !os.IsNotExist(err) || parent == abs {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(abs))
+		abs = parent
+	}
+}
+
+// Report whether child is parent or inside it, once both are made
+// Canonical(). Unlike comparing the paths as strings, "/a/bc" isn't
+// inside "/a/b", and a path reached through a symbolic link is inside
+// the directory the link points into. On Windows and macOS, whose
+// filesystems usually are case-insensitive, case is ignored.
+func IsSubpath(parent, child string) (bool, error) {
+	parent, err := Canonical(parent)
+	if err != nil {
+		return false, err
+	}
+	child, err = Canonical(child)
+	if err != nil {
+		return false, err
+	}
+	return pathWithin(parent, child), nil
+}
+
+// Return the deepest directory that all of paths are in, or are, once
+// they are made Canonical(). It's "" if there are no paths, or if they
+// are on different Windows volumes, so have nothing in common.
+func CommonRoot(paths ...string) (string, error) {
+	var root string
+	for i, path := range paths {
+		path, err := Canonical(path)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			root = path
+			continue
+		}
+		for !pathWithin(root, path) {
+			parent := filepath.Dir(root)
+			if parent == root {
+				return "", nil
+			}
+			root = parent
+		}
+	}
+	return root, nil
+}
+
+// Report whether child is parent or inside it, comparing the clean
+// absolute paths element by element.
+func pathWithin(parent, child string) bool {
+	if foldCase {
+		parent, child = strings.ToLower(parent), strings.ToLower(child)
+	}
+	if parent == child {
+		return true
+	}
+	if !strings.HasSuffix(parent, string(filepath.Separator)) {
+		parent += string(filepath.Separator)
+	}
+	return strings.HasPrefix(child, parent)
+}
@@ -0,0 +1,63 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCanonical(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dir, err := filepath.Abs(makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	dir, err = filepath.EvalSymlinks(dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.Symlink("testdir", makeTestPath("link"))).To(Succeed())
+
+	g.Expect(Canonical(makeTestPath("testdir"))).To(Equal(dir))
+	g.Expect(Canonical(makeTestPath("link/../testdir/./"))).To(Equal(dir))
+	g.Expect(Canonical(makeTestPath("link"))).To(Equal(dir))
+	// What doesn't exist is kept after what does, resolved
+	g.Expect(Canonical(makeTestPath("link/new/file"))).To(Equal(filepath.Join(dir, "new", "file")))
+}
+
+func TestIsSubpath(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testdir", makeTestPath("link"))).To(Succeed())
+	for _, test := range []struct {
+		parent, child string
+		within        bool
+	}{
+		{"testdir", "testdir", true},
+		{"testdir", "testdir/file1", true},
+		{"testdir", "testdir/new/file", true},
+		{"testdir", "testdir2", false},
+		{"testdir/file1", "testdir", false},
+		{"testdir", "link/file1", true},
+		{"link", "testdir/new", true},
+		{"testdir", "testdir/../testfile", false},
+	} {
+		g.Expect(IsSubpath(makeTestPath(test.parent), makeTestPath(test.child))).To(Equal(test.within), test.child)
+	}
+}
+
+func TestCommonRoot(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	root, err := Canonical(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.Symlink("testdir", makeTestPath("link"))).To(Succeed())
+
+	g.Expect(CommonRoot()).To(Equal(""))
+	g.Expect(CommonRoot(makeTestPath("testdir"))).To(Equal(filepath.Join(root, "testdir")))
+	g.Expect(CommonRoot(makeTestPath("testdir/file1"), makeTestPath("link/file2"))).To(Equal(filepath.Join(root, "testdir")))
+	g.Expect(CommonRoot(makeTestPath("testdir"), makeTestPath("testdir2"), makeTestPath("testfile"))).To(Equal(root))
+	g.Expect(CommonRoot(makeTestPath("testdir"), "/")).To(Equal(filepath.VolumeName(root) + string(filepath.Separator)))
+}
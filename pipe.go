@@ -0,0 +1,26 @@
+package shutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+)
+
+// Copy what is written to the named pipe src to dst until its writers
+// close it. A pipe can't be read again, so with verify set the data is
+// hashed as it's read, and the hash returned for verifyHash(). The data
+// is written to tee as well, if it's set.
+func copyPipe(ctx context.Context, dst, src *os.File, bufferSize int, verify bool, tee io.Writer) (int64, []byte, error) {
+	var r io.Reader = src
+	if tee != nil {
+		r = io.TeeReader(r, tee)
+	}
+	if !verify {
+		n, err := copyData(ctx, dst, r, bufferSize)
+		return n, nil, err
+	}
+	h := sha256.New()
+	n, err := copyData(ctx, dst, io.TeeReader(r, h), bufferSize)
+	return n, h.Sum(nil), err
+}
@@ -0,0 +1,44 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyReadNamedPipes(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	fifo := makeTestPath("fifo")
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{"fifo": {Mode: os.ModeNamedPipe | 0640}})
+	_, err := CopyContext(context.Background(), fifo, makeTestPath("out"), nil)
+	g.Expect(err).To(BeAssignableToTypeOf(&SpecialFileError{}))
+
+	done := make(chan error, 1)
+	go func() {
+		w, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+		if err == nil {
+			_, err = w.WriteString("from the pipe\n")
+			w.Close()
+		}
+		done <- err
+	}()
+	result, err := CopyContext(context.Background(), fifo, makeTestPath("out"), &CopyOptions{
+		ReadNamedPipes: true,
+		Verify:         true,
+	})
+	g.Expect(<-done).To(Succeed())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(14)))
+	g.Expect(os.ReadFile(makeTestPath("out"))).To(Equal([]byte("from the pipe\n")))
+
+	info, err := os.Stat(makeTestPath("out"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode()).To(Equal(os.FileMode(0640)))
+}
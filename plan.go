@@ -0,0 +1,300 @@
+package shutil
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The kinds of change an Action makes.
+type ActionKind int
+
+const (
+	// Create the directory Dst.
+	ActionMkdir ActionKind = iota
+	// Copy the file Src to Dst.
+	ActionCopy
+	// Create Dst as a symbolic link to Target.
+	ActionSymlink
+	// Rename Src to Dst.
+	ActionRename
+	// Remove Src, along with everything inside it.
+	ActionRemove
+)
+
+func (k ActionKind) String() string {
+	switch k {
+	case ActionMkdir:
+		return "mkdir"
+	case ActionCopy:
+		return "copy"
+	case ActionSymlink:
+		return "symlink"
+	case ActionRename:
+		return "rename"
+	case ActionRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// One change to the filesystem that an operation would make.
+type Action struct {
+	Kind ActionKind
+	Src  string
+	Dst  string
+
+	// The target of a symbolic link being created.
+	Target string
+
+	// Describes Src, or for symbolic links being followed, the file they
+	// point to.
+	Info os.FileInfo
+}
+
+// The changes an operation would make, in the order it would make them.
+type Plan struct {
+	Actions []Action
+
+	// Whether the files that are created are given the same owners as
+	// their sources, which usually needs root privileges.
+	PreserveOwner bool
+
+	// Whether the destination may already exist, as with
+	// CopyTreeOptions.DirsExistOK, so that existing directories are
+	// merged and existing symbolic links replaced.
+	DirsExistOK bool
+
+	// What the plan creates: the files copied and their total size, and
+	// the directories and symbolic links made. Renamed files aren't
+	// counted, as they need no space.
+	Totals TreeResult
+}
+
+// Count what the actions create.
+func (p Plan) count() TreeResult {
+	var totals TreeResult
+	for _, action := range p.Actions {
+		switch action.Kind {
+		case ActionMkdir:
+			totals.Dirs++
+		case ActionSymlink:
+			totals.Symlinks++
+		case ActionCopy:
+			totals.Files++
+			if action.Info != nil && action.Info.Mode().IsRegular() {
+				totals.Bytes += action.Info.Size()
+			}
+		}
+	}
+	return totals
+}
+
+// Plan the changes CopyTree() would make to copy src to dst with the same
+// options, without making them. The tree is read as it is now, so the
+// plan is only accurate until it changes.
+//
+// Custom CopyFunctions and Handlers can't be planned for, so every file
+// is planned as copied as CopyContext() would copy it.
+func PlanCopyTree(src, dst string, options *CopyTreeOptions) (Plan, error) {
+	return PlanCopyTreeContext(context.Background(), src, dst, options)
+}
+
+// Plan the changes CopyTree() would make like PlanCopyTree(), stopping
+// with the context's error if it is cancelled, as reading a large tree
+// can take a while.
+func PlanCopyTreeContext(ctx context.Context, src, dst string, options *CopyTreeOptions) (Plan, error) {
+	if options == nil {
+		options = &CopyTreeOptions{}
+	}
+	var plan Plan
+	if options.CopyOptions != nil {
+		plan.PreserveOwner = options.CopyOptions.PreserveOwner
+	}
+
+	plan.DirsExistOK = options.DirsExistOK
+
+	_, err := os.Lstat(dst)
+	if err == nil && !options.DirsExistOK {
+		return plan, &AlreadyExistsError{dst}
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return plan, err
+	}
+	options, err = excludeDestination(src, dst, options)
+	if err != nil {
+		return plan, err
+	}
+	err = planTree(ctx, &plan, src, dst, options)
+	plan.Totals = plan.count()
+	return plan, err
+}
+
+// Count what CopyTree() would copy from src with the same options: the
+// files and their total size, the directories, and the symbolic links it
+// would create, leaving out what the Ignore option leaves out. This is a
+// pre-scan, to know how much there is to copy before copying it, such
+// as to check there is space for it or to estimate how long it will take.
+// The context is checked as each directory is read.
+func ScanTree(ctx context.Context, src string, options *CopyTreeOptions) (TreeResult, error) {
+	if options == nil {
+		options = &CopyTreeOptions{}
+	}
+	var plan Plan
+	err := planTree(ctx, &plan, src, src, options)
+	return plan.count(), err
+}
+
+func planTree(ctx context.Context, plan *Plan, src, dst string, options *CopyTreeOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &NotADirectoryError{src}
+	}
+	plan.Actions = append(plan.Actions, Action{Kind: ActionMkdir, Src: src, Dst: dst, Info: info})
+
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	ignoredNames := []string{}
+	if options.Ignore != nil {
+		ignoredNames = options.Ignore(src, entries)
+	}
+
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			continue
+		}
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+
+		switch {
+		case entry.IsDir():
+			if options.SkipDir != nil && options.SkipDir(srcPath, entry) {
+				continue
+			}
+			err = planTree(ctx, plan, srcPath, dstPath, options)
+		case IsSymlink(entry) && options.Symlinks:
+			err = planSymlink(plan, srcPath, dstPath, entry, options.LinkStyle)
+		case IsSymlink(entry):
+			target, statErr := os.Stat(srcPath)
+			if os.IsNotExist(statErr) && options.IgnoreDanglingSymlinks {
+				continue
+			}
+			if statErr == nil {
+				entry = target
+			}
+			plan.Actions = append(plan.Actions, Action{Kind: ActionCopy, Src: srcPath, Dst: dstPath, Info: entry})
+		default:
+			plan.Actions = append(plan.Actions, Action{Kind: ActionCopy, Src: srcPath, Dst: dstPath, Info: entry})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func planSymlink(plan *Plan, src, dst string, info os.FileInfo, style LinkStyle) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if style != LinksAsIs {
+		// The plan starts with the root of the tree
+		root := plan.Actions[0]
+		target, err = copiedLinkTarget(target, src, root.Src, dst, root.Dst, style)
+		if err != nil {
+			return err
+		}
+	}
+	plan.Actions = append(plan.Actions, Action{Kind: ActionSymlink, Src: src, Dst: dst, Target: target, Info: info})
+	return nil
+}
+
+// Plan the changes Move() would make to move src to dst, without making
+// them. If dst is a directory, src is planned to move inside it. A rename
+// is planned when both are on the same device, and otherwise a copy of
+// src, keeping its symbolic links, followed by its removal.
+func PlanMove(src, dst string) (Plan, error) {
+	var plan Plan
+	info, err := os.Lstat(src)
+	if err != nil {
+		return plan, err
+	}
+
+	realDst := dst
+	if isDir, _ := isDirectory(dst); isDir {
+		realDst = filepath.Join(dst, filepath.Base(src))
+		if _, err := os.Lstat(realDst); err == nil {
+			return plan, &AlreadyExistsError{realDst}
+		}
+	}
+
+	sameDevice, err := onSameDevice(src, realDst)
+	if err != nil {
+		return plan, err
+	}
+	if sameDevice {
+		plan.Actions = []Action{{Kind: ActionRename, Src: src, Dst: realDst, Info: info}}
+		return plan, nil
+	}
+
+	switch {
+	case IsSymlink(info):
+		err = planSymlink(&plan, src, realDst, info, LinksAsIs)
+	case info.IsDir():
+		err = planTree(context.Background(), &plan, src, realDst, &CopyTreeOptions{Symlinks: true})
+	default:
+		plan.Actions = append(plan.Actions, Action{Kind: ActionCopy, Src: src, Dst: realDst, Info: info})
+	}
+	if err != nil {
+		return plan, err
+	}
+	plan.Actions = append(plan.Actions, Action{Kind: ActionRemove, Src: src, Info: info})
+	plan.Totals = plan.count()
+	return plan, nil
+}
+
+// Report whether src is on the same device as the nearest existing
+// directory that would hold dst, assuming it is if the platform doesn't
+// say.
+func onSameDevice(src, dst string) (bool, error) {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return false, err
+	}
+	dirInfo, _, err := existingAncestor(filepath.Dir(dst))
+	if err != nil {
+		return false, err
+	}
+	srcDev, ok := fileDevice(srcInfo)
+	dirDev, dirOK := fileDevice(dirInfo)
+	if !ok || !dirOK {
+		return true, nil
+	}
+	return srcDev == dirDev, nil
+}
+
+// Describe the nearest ancestor of path, or path itself, that exists,
+// returning its path too.
+func existingAncestor(path string) (os.FileInfo, string, error) {
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			return info, path, nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return nil, path, err
+		}
+		path = parent
+	}
+}
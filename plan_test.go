@@ -0,0 +1,94 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// Describe the actions of a plan as "kind src -> dst".
+func describePlan(plan Plan) []string {
+	var actions []string
+	for _, action := range plan.Actions {
+		actions = append(actions, action.Kind.String()+" "+action.Src+" -> "+action.Dst)
+	}
+	return actions
+}
+
+func TestPlanCopyTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Symlink("missing", makeTestPath("testdir/dangling"))).To(Succeed())
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+
+	plan, err := PlanCopyTree(src, dst, &CopyTreeOptions{IgnoreDanglingSymlinks: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(describePlan(plan)).To(Equal([]string{
+		"mkdir " + src + " -> " + dst,
+		"copy " + makeTestPath("testdir/file1") + " -> " + makeTestPath("out/file1"),
+		"copy " + makeTestPath("testdir/file2") + " -> " + makeTestPath("out/file2"),
+		"copy " + makeTestPath("testdir/link") + " -> " + makeTestPath("out/link"),
+	}))
+	g.Expect(plan.Actions[3].Info.Mode().IsRegular()).To(BeTrue())
+	g.Expect(plan.Totals).To(Equal(TreeResult{Files: 3, Dirs: 1, Bytes: 18}))
+
+	plan, err = PlanCopyTree(src, dst, &CopyTreeOptions{Symlinks: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan.Actions).To(HaveLen(5))
+	g.Expect(plan.Actions[1].Kind).To(Equal(ActionSymlink))
+	g.Expect(plan.Actions[1].Target).To(Equal("missing"))
+	g.Expect(plan.Totals).To(Equal(TreeResult{Files: 2, Dirs: 1, Symlinks: 2, Bytes: 12}))
+
+	// Nothing was changed
+	g.Expect(dst).NotTo(BeADirectory())
+
+	_, err = PlanCopyTree(src, makeTestPath("testfile"), nil)
+	g.Expect(err).To(MatchError(&AlreadyExistsError{makeTestPath("testfile")}))
+	_, err = PlanCopyTree(makeTestPath("testfile"), dst, nil)
+	g.Expect(err).To(MatchError(&NotADirectoryError{makeTestPath("testfile")}))
+}
+
+func TestScanTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Mkdir(makeTestPath("testdir/sub"), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("testdir/sub/file3"), []byte("file3\n"), 0644)).To(Succeed())
+
+	totals, err := ScanTree(context.Background(), makeTestPath("testdir"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(totals).To(Equal(TreeResult{Files: 4, Dirs: 2, Bytes: 24}))
+
+	totals, err = ScanTree(context.Background(), makeTestPath("testdir"), &CopyTreeOptions{
+		Symlinks: true,
+		Ignore:   IgnorePatterns("sub"),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(totals).To(Equal(TreeResult{Files: 2, Dirs: 1, Symlinks: 1, Bytes: 12}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ScanTree(ctx, makeTestPath("testdir"), nil)
+	g.Expect(err).To(MatchError(context.Canceled))
+}
+
+func TestPlanMove(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	plan, err := PlanMove(makeTestPath("testfile"), makeTestPath("testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(describePlan(plan)).To(Equal([]string{
+		"rename " + makeTestPath("testfile") + " -> " + makeTestPath("testdir/testfile"),
+	}))
+
+	g.Expect(os.WriteFile(makeTestPath("testdir/testfile2"), nil, 0644)).To(Succeed())
+	_, err = PlanMove(makeTestPath("testfile2"), makeTestPath("testdir"))
+	g.Expect(err).To(MatchError(&AlreadyExistsError{makeTestPath("testdir/testfile2")}))
+}
@@ -0,0 +1,27 @@
+package shutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Allocate size bytes of disk space for f, which is empty, and make it
+// that long. Contiguous space is asked for first, and then any.
+func preallocate(f *os.File, size int64) error {
+	store := unix.Fstore_t{
+		Flags:   unix.F_ALLOCATECONTIG | unix.F_ALLOCATEALL,
+		Posmode: unix.F_PEOFPOSMODE,
+		Length:  size,
+	}
+	err := unix.FcntlFstore(f.Fd(), unix.F_PREALLOCATE, &store)
+	if err != nil {
+		store.Flags = unix.F_ALLOCATEALL
+		err = unix.FcntlFstore(f.Fd(), unix.F_PREALLOCATE, &store)
+	}
+	if err != nil {
+		return &os.PathError{Op: "preallocate", Path: f.Name(), Err: err}
+	}
+	// Allocating space doesn't change the size
+	return f.Truncate(size)
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !linux
+
+package shutil
+
+import "os"
+
+// Make f, which is empty, size bytes long. Disk space can't be allocated
+// ahead of time on this platform.
+func preallocate(f *os.File, size int64) error {
+	return f.Truncate(size)
+}
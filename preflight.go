@@ -0,0 +1,235 @@
+package shutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Something that would stop an Action in a Plan from succeeding, as found
+// by CheckWritable().
+type WritableProblem struct {
+	// The action that would fail.
+	Action Action
+
+	// The file or directory that is the cause.
+	Path string
+
+	// Why the action would fail.
+	Reason string
+}
+
+func (p WritableProblem) String() string {
+	return fmt.Sprintf("%s `%s`: %s", p.Action.Kind, p.Path, p.Reason)
+}
+
+// What CheckWritable() found.
+type WritableReport struct {
+	Problems []WritableProblem
+}
+
+// Report whether no problems were found.
+func (r WritableReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Check, before anything is changed, that this process could make every
+// change in plan, which was made for an operation writing to dst. The
+// report lists each action that would fail: one creating something in a
+// directory that can't be written to, or that the plan itself creates
+// without write permission; one overwriting a file that is read-only or
+// immutable, or that already exists; one removing a tree that can't be
+// emptied; and when the plan preserves owners, one giving a file an owner
+// other than this process without root privileges.
+//
+// Only what can be known in advance is checked, so the operation can
+// still fail, for example by running out of space. An error is returned
+// only if the checks themselves fail.
+func CheckWritable(dst string, plan Plan) (WritableReport, error) {
+	c := &writableChecker{created: map[string]os.FileMode{}}
+	_, root, err := existingAncestor(dst)
+	if err != nil {
+		return c.report, err
+	}
+	if reason := checkDirWritable(root); reason != "" {
+		c.problem(Action{Kind: ActionMkdir, Dst: dst}, root, reason)
+	}
+
+	for _, action := range plan.Actions {
+		var err error
+		switch action.Kind {
+		case ActionMkdir, ActionCopy, ActionSymlink:
+			err = c.checkCreate(action)
+		case ActionRename:
+			err = c.checkRename(action)
+		case ActionRemove:
+			err = c.checkRemove(action, action.Src)
+		}
+		if err != nil {
+			return c.report, err
+		}
+		if plan.PreserveOwner && action.Kind != ActionRemove && action.Kind != ActionRename {
+			c.checkOwner(action)
+		}
+	}
+	return c.report, nil
+}
+
+// The state of a single CheckWritable() call.
+type writableChecker struct {
+	// The modes of the directories the plan creates.
+	created map[string]os.FileMode
+	report  WritableReport
+}
+
+func (c *writableChecker) problem(action Action, path, reason string) {
+	c.report.Problems = append(c.report.Problems, WritableProblem{action, path, reason})
+}
+
+// Check that the directory that would hold path can have entries added or
+// removed.
+func (c *writableChecker) checkParent(action Action, path string) error {
+	parent := filepath.Dir(path)
+	if mode, ok := c.created[parent]; ok {
+		if mode&0300 != 0300 && os.Geteuid() != 0 {
+			c.problem(action, parent, fmt.Sprintf("the directory is created with mode %s", mode))
+		}
+		return nil
+	}
+
+	_, dir, err := existingAncestor(parent)
+	if err != nil {
+		return err
+	}
+	if reason := checkDirWritable(dir); reason != "" {
+		c.problem(action, dir, reason)
+	}
+	return nil
+}
+
+func (c *writableChecker) checkCreate(action Action) error {
+	err := c.checkParent(action, action.Dst)
+	if err != nil {
+		return err
+	}
+	if action.Kind == ActionMkdir && action.Info != nil {
+		c.created[action.Dst] = action.Info.Mode().Perm()
+	}
+
+	info, err := os.Lstat(action.Dst)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	switch {
+	case action.Kind == ActionMkdir && info.IsDir():
+		if reason := checkDirWritable(action.Dst); reason != "" {
+			c.problem(action, action.Dst, reason)
+		}
+	case action.Kind == ActionCopy && info.Mode().IsRegular():
+		if reason := checkFileWritable(action.Dst); reason != "" {
+			c.problem(action, action.Dst, reason)
+		}
+	default:
+		c.problem(action, action.Dst, "it already exists")
+	}
+	return nil
+}
+
+func (c *writableChecker) checkRename(action Action) error {
+	err := c.checkParent(action, action.Src)
+	if err != nil {
+		return err
+	}
+	err = c.checkParent(action, action.Dst)
+	if err != nil {
+		return err
+	}
+	// A directory's entry for its parent changes when it moves to a new
+	// one
+	if action.Info != nil && action.Info.IsDir() && filepath.Dir(action.Src) != filepath.Dir(action.Dst) {
+		if reason := checkDirWritable(action.Src); reason != "" {
+			c.problem(action, action.Src, reason)
+		}
+	}
+	if reason := checkImmutable(action.Src); reason != "" {
+		c.problem(action, action.Src, reason)
+	}
+	return nil
+}
+
+// Check that path can be removed, along with everything inside it.
+func (c *writableChecker) checkRemove(action Action, path string) error {
+	err := c.checkParent(action, path)
+	if err != nil {
+		return err
+	}
+	if reason := checkImmutable(path); reason != "" {
+		c.problem(action, path, reason)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return err
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		if reason := checkDirWritable(path); reason != "" {
+			c.problem(action, path, reason)
+			return nil
+		}
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			err = c.checkRemove(action, filepath.Join(path, entry.Name()))
+		} else if reason := checkImmutable(filepath.Join(path, entry.Name())); reason != "" {
+			c.problem(action, filepath.Join(path, entry.Name()), reason)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *writableChecker) checkOwner(action Action) {
+	if action.Info == nil || os.Geteuid() == 0 {
+		return
+	}
+	uid, _, ok := fileOwner(action.Info)
+	if ok && uid != os.Geteuid() {
+		c.problem(action, action.Dst, fmt.Sprintf("only root can give it the owner %d", uid))
+	}
+}
+
+// Return why entries can't be added to or removed from a directory, or ""
+// if they can.
+func checkDirWritable(dir string) string {
+	if err := canWrite(dir, true); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// Return why a file can't be overwritten, or "" if it can.
+func checkFileWritable(path string) string {
+	if err := canWrite(path, false); err != nil {
+		return err.Error()
+	}
+	return checkImmutable(path)
+}
+
+// Return why a file can't be changed because of its attributes, or "" if
+// it can.
+func checkImmutable(path string) string {
+	immutable, err := isImmutable(path)
+	if err == nil && immutable {
+		return "it is immutable or append-only"
+	}
+	return ""
+}
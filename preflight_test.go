@@ -0,0 +1,55 @@
+package shutil
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCheckWritable(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	plan, err := PlanCopyTree(makeTestPath("testdir"), dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	report, err := CheckWritable(dst, plan)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.OK()).To(BeTrue())
+
+	// Things that appear after planning get in the way
+	g.Expect(os.Mkdir(dst, 0755)).To(Succeed())
+	g.Expect(os.Symlink("elsewhere", makeTestPath("out/file1"))).To(Succeed())
+	report, err = CheckWritable(dst, plan)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Problems).To(HaveLen(1))
+	g.Expect(report.Problems[0].Path).To(Equal(makeTestPath("out/file1")))
+	g.Expect(report.Problems[0].String()).To(Equal("copy `" + makeTestPath("out/file1") + "`: it already exists"))
+}
+
+func TestCheckWritablePermissions(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write anywhere")
+	}
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Chmod(makeTestPath("testdir"), 0555)).To(Succeed())
+	t.Cleanup(func() { os.Chmod(makeTestPath("testdir"), 0755) })
+
+	// The copy's directory is created read-only like the original, and
+	// the original can't be emptied
+	dst := makeTestPath("out")
+	plan, err := PlanCopyTree(makeTestPath("testdir"), dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	plan.Actions = append(plan.Actions, Action{Kind: ActionRemove, Src: makeTestPath("testdir")})
+	report, err := CheckWritable(dst, plan)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var paths []string
+	for _, problem := range report.Problems {
+		paths = append(paths, problem.Path)
+	}
+	g.Expect(paths).To(Equal([]string{dst, dst, makeTestPath("testdir")}))
+}
@@ -0,0 +1,115 @@
+package shutil
+
+import "os"
+
+// Give dst the ownership, extended attributes, capabilities, ACLs, SELinux
+// context, times and mapped mode of src, which srcInfo describes, as
+// requested by the options. If srcInfo is a symbolic link, dst is assumed
+// to be one too and isn't followed. What couldn't be preserved exactly,
+// such as times on filesystems that store them less precisely, is returned
+// as warnings, as is what can't be preserved at all with the
+// DegradePreservation option, or the Downgrade policies.
+func preserveMetadata(src, dst string, srcInfo os.FileInfo, options *CopyOptions) ([]error, error) {
+	link := IsSymlink(srcInfo)
+	var warnings []error
+	degrade := func(metadata string, err error) error {
+		if err == nil || !cannotPreserve(err) {
+			return err
+		}
+		if feature, ok := metadataFeatures[metadata]; ok {
+			if policy, ok := options.downgradePolicy(feature); ok {
+				switch policy {
+				case DowngradeWarn:
+					warnings = append(warnings, &PreservationWarning{dst, metadata, err})
+				case DowngradeFail:
+					return &UnsupportedFeatureError{dst, feature, err}
+				}
+				return nil
+			}
+		}
+		if options.DegradePreservation {
+			warnings = append(warnings, &PreservationWarning{dst, metadata, err})
+			return nil
+		}
+		return err
+	}
+
+	// Ownership has to come first, as changing it can clear the setuid
+	// and setgid bits
+	if options.PreserveOwner {
+		if uid, gid, ok := fileOwner(srcInfo); ok {
+			if options.OwnerMapper != nil {
+				uid, gid = options.OwnerMapper(uid, gid)
+			}
+			chown := os.Chown
+			if link {
+				chown = os.Lchown
+			}
+			if err := degrade(MetadataOwner, chown(dst, uid, gid)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if options.PreserveXattrs {
+		if err := degrade(MetadataXattrs, syncXattrs(src, dst)); err != nil {
+			return nil, err
+		}
+		if err := degrade(MetadataFileFlags, copyFileFlags(dst, srcInfo)); err != nil {
+			return nil, err
+		}
+	}
+
+	// After ownership, which clears them
+	if options.PreserveCapabilities && !link {
+		if err := degrade(MetadataCapabilities, copyCapabilities(src, dst)); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.PreserveACLs && !link {
+		if err := degrade(MetadataACLs, copyACL(src, dst)); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.PreserveSELinux || options.Relabel != nil {
+		warning, err := copySELinuxContext(src, dst, srcInfo, options)
+		if err != nil {
+			return nil, err
+		}
+		if warning != nil {
+			warnings = append(warnings, &PreservationWarning{dst, MetadataSELinux, warning})
+		}
+	}
+
+	if options.ModeMapper != nil && !link {
+		if err := os.Chmod(dst, chmodBits(options.ModeMapper(srcInfo.Mode()))); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.PreserveTimes {
+		atime, mtime := fileTimes(srcInfo)
+		var err error
+		if link {
+			err = lutimes(dst, atime, mtime)
+		} else {
+			err = chtimes(dst, atime, mtime)
+		}
+		if err != nil {
+			return warnings, degrade(MetadataTimes, err)
+		}
+		warning, err := checkTimePrecision(dst, mtime)
+		if err != nil {
+			return nil, err
+		}
+		if warning != nil {
+			warnings = append(warnings, warning)
+		}
+		// Setting the modification time can move the creation time back,
+		// so it goes last
+		return warnings, copyBirthtime(src, dst, srcInfo, options.Birthtime)
+	}
+	return warnings, nil
+}
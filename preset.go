@@ -0,0 +1,106 @@
+package shutil
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Returned by LookupPreset() for a name that isn't a preset.
+type UnknownPresetError struct {
+	Name string
+}
+
+func (e UnknownPresetError) Error() string {
+	return fmt.Sprintf("unknown preset `%s`", e.Name)
+}
+
+func (e UnknownPresetError) Code() ErrorCode {
+	return CodeUnknownPreset
+}
+
+// A named bundle of Op options for a common job, so the right combination
+// doesn't have to be worked out option by option. Apply one with
+// Op.Preset(), and then any options that should differ from it.
+type Preset struct {
+	Name        string
+	Description string
+	apply       func(o *Op)
+}
+
+var (
+	// Make the destination an exact copy of the source. Every kind of
+	// metadata is preserved, or reported in Report.Warnings where it
+	// can't be, symbolic links are copied as links, and a sync compares
+	// modes, times and extended attributes and removes what isn't in the
+	// source.
+	Mirror = Preset{
+		Name:        "mirror",
+		Description: "preserve everything and remove what isn't in the source",
+		apply: func(o *Op) {
+			o.symlinks = true
+			o.options.PreserveTimes = true
+			o.options.PreserveOwner = true
+			o.options.PreserveXattrs = true
+			o.options.PreserveACLs = true
+			o.options.PreserveInodeFlags = true
+			o.options.PreserveCapabilities = true
+			o.options.PreserveSELinux = true
+			o.options.DegradePreservation = true
+			o.compare = CompareContentXattrs
+			o.delete = true
+		},
+	}
+
+	// Keep a copy to be trusted later. Times, owners, extended attributes
+	// and ACLs are preserved, or reported in Report.Warnings where they
+	// can't be, each copy is read back and verified, and a manifest of
+	// the destination is written next to it, as Op.Manifest("") does.
+	Archival = Preset{
+		Name:        "archival",
+		Description: "preserve metadata, verify every copy and write a manifest",
+		apply: func(o *Op) {
+			o.symlinks = true
+			o.options.PreserveTimes = true
+			o.options.PreserveOwner = true
+			o.options.PreserveXattrs = true
+			o.options.PreserveACLs = true
+			o.options.DegradePreservation = true
+			o.options.Verify = true
+			o.compare = CompareContentModeTimes
+			if o.manifest == nil {
+				o.Manifest("")
+			}
+		},
+	}
+
+	// Get files into place quickly. A tree is copied a file per CPU at
+	// once, largest first, into the destination even if it exists,
+	// replacing what's there, and no metadata but modes is kept. A sync
+	// only compares contents.
+	FastDeploy = Preset{
+		Name:        "fast-deploy",
+		Description: "copy in parallel without metadata, overwriting the destination",
+		apply: func(o *Op) {
+			o.options = CopyOptions{}
+			o.parallel = runtime.NumCPU()
+			o.order = LargestFirst
+			o.existOK = true
+			o.compare = CompareContent
+		},
+	}
+)
+
+// Return the presets, in the order they are documented.
+func Presets() []Preset {
+	return []Preset{Mirror, Archival, FastDeploy}
+}
+
+// Return the preset called name, such as "mirror".
+func LookupPreset(name string) (Preset, error) {
+	for _, preset := range Presets() {
+		if preset.Name == name {
+			return preset, nil
+		}
+	}
+	return Preset{}, &UnknownPresetError{name}
+}
@@ -0,0 +1,75 @@
+package shutil
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+)
+
+func TestLookupPreset(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, preset := range Presets() {
+		found, err := LookupPreset(preset.Name)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(found.Name).To(Equal(preset.Name))
+		g.Expect(preset.Description).NotTo(BeEmpty())
+	}
+	_, err := LookupPreset("turbo")
+	g.Expect(err).To(MatchError(&UnknownPresetError{"turbo"}))
+}
+
+func TestPresetOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	op := NewSync("src", "dst").Preset(Mirror)
+	g.Expect(op.delete).To(BeTrue())
+	g.Expect(op.symlinks).To(BeTrue())
+	g.Expect(op.compare).To(Equal(CompareContentXattrs))
+	g.Expect(op.options.PreserveOwner).To(BeTrue())
+	g.Expect(op.options.DegradePreservation).To(BeTrue())
+
+	op = NewCopy("src", "dst").Manifest("sums").Preset(Archival)
+	g.Expect(op.options.Verify).To(BeTrue())
+	g.Expect(*op.manifest).To(Equal("sums"))
+
+	// Options after a preset change it
+	op = NewCopy("src", "dst").PreserveAll().Preset(FastDeploy).Parallel(3)
+	g.Expect(op.options).To(Equal(CopyOptions{}))
+	g.Expect(op.parallel).To(Equal(3))
+	g.Expect(op.existOK).To(BeTrue())
+	g.Expect(NewCopy("src", "dst").Preset(FastDeploy).parallel).To(Equal(runtime.NumCPU()))
+}
+
+func TestPresetRun(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("deploy")
+	shutiltest.CreateTree(t, dst, shutiltest.Tree{
+		"file1": shutiltest.File("stale\n"),
+		"extra": shutiltest.File("extra\n"),
+	})
+	_, err := NewCopy(makeTestPath("testdir"), dst).Recursive().Preset(FastDeploy).Parallel(2).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("deploy/file1"), nil)).To(BeTrue())
+	g.Expect(makeTestPath("deploy/extra")).To(BeAnExistingFile())
+
+	report, err := NewSync(makeTestPath("testdir"), dst).Preset(Mirror).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Deleted).To(Equal(1))
+	g.Expect(makeTestPath("deploy/extra")).NotTo(BeAnExistingFile())
+
+	report, err = NewCopy(makeTestPath("testdir"), makeTestPath("archive")).Recursive().Preset(Archival).Run()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Manifest).To(Equal(makeTestPath("archive.sha256")))
+	manifest, err := os.ReadFile(report.Manifest)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(manifest)).To(Equal(
+		sha256Hex("file1\n") + "  archive/file1\n" +
+			sha256Hex("file2\n") + "  archive/file2\n"))
+}
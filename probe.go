@@ -0,0 +1,173 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A capability of a destination that copies to it can need.
+type Feature string
+
+const (
+	FeatureSymlinks      Feature = "symbolic links"
+	FeatureHardlinks     Feature = "hard links"
+	FeatureXattrs        Feature = "extended attributes"
+	FeatureSparse        Feature = "sparse files"
+	FeatureCaseSensitive Feature = "case-sensitive names"
+	FeatureReflink       Feature = "reflinks"
+)
+
+// The size of the file ProbeDestination() writes to measure throughput.
+const probeThroughputSize = 4 << 20
+
+// What ProbeDestination() found out about a destination.
+type DestinationReport struct {
+	// The directory that was probed, which is the closest one to the
+	// destination that exists.
+	Dir string
+
+	// Whether files can be created in Dir. If they can't, only the Type
+	// and BlockSize of the filesystem are known, and none of the features
+	// are reported as supported.
+	Writable bool
+
+	// The filesystem's features, other than links.
+	FSInfo
+
+	Symlinks  bool
+	Hardlinks bool
+
+	// How long it took to create, sync and remove an empty file, which is
+	// the least each file copied there costs.
+	Latency time.Duration
+
+	// The rate a 4MiB file was written and synced at, in bytes per
+	// second.
+	Throughput float64
+}
+
+// Report whether the destination has the feature.
+func (r DestinationReport) Supports(feature Feature) bool {
+	switch feature {
+	case FeatureSymlinks:
+		return r.Symlinks
+	case FeatureHardlinks:
+		return r.Hardlinks
+	case FeatureXattrs:
+		return r.Xattrs
+	case FeatureSparse:
+		return r.Sparse
+	case FeatureCaseSensitive:
+		return r.CaseSensitive
+	case FeatureReflink:
+		return r.Reflink
+	}
+	return false
+}
+
+// Return the features that copying a tree with the options needs, but
+// the destination doesn't have: symbolic links when they're copied as
+// links, and extended attributes when they, or ACLs, capabilities or
+// SELinux contexts, which are kept in them, are preserved.
+func (r DestinationReport) Missing(options *CopyTreeOptions) []Feature {
+	var needs []Feature
+	if options.Symlinks {
+		needs = append(needs, FeatureSymlinks)
+	}
+	if o := options.CopyOptions; o != nil && (o.PreserveXattrs || o.PreserveACLs || o.PreserveCapabilities || o.PreserveSELinux) {
+		needs = append(needs, FeatureXattrs)
+	}
+	var missing []Feature
+	for _, feature := range needs {
+		if !r.Supports(feature) {
+			missing = append(missing, feature)
+		}
+	}
+	return missing
+}
+
+// Check the destination dst before copying to it: whether files can be
+// created there, which features its filesystem has, as FilesystemInfo()
+// finds them, whether it supports symbolic and hard links, and how fast
+// it is. Dst needn't exist, in which case the closest directory to it
+// that does is probed. Temporary files are created to find these out,
+// which are removed again, and a 4MiB file is written to measure the
+// throughput, so this isn't free on a slow destination.
+func ProbeDestination(dst string) (DestinationReport, error) {
+	var report DestinationReport
+	info, dir, err := existingAncestor(dst)
+	if err != nil {
+		return report, err
+	}
+	if !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	report.Dir = dir
+	report.Type, report.BlockSize, err = statfs(dir)
+	if err != nil {
+		return report, err
+	}
+
+	start := time.Now()
+	f, err := probeFile(dir, nil)
+	if os.IsPermission(err) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+	err = f.Sync()
+	closeAndRemove(f)
+	if err != nil {
+		return report, err
+	}
+	report.Latency = time.Since(start)
+	report.Writable = true
+
+	report.FSInfo, err = FilesystemInfo(dir)
+	if err != nil {
+		return report, err
+	}
+	if report.Symlinks, report.Hardlinks, err = probeLinks(dir); err != nil {
+		return report, err
+	}
+	report.Throughput, err = probeThroughput(dir)
+	return report, err
+}
+
+// Report whether symbolic and hard links can be made in dir.
+func probeLinks(dir string) (bool, bool, error) {
+	f, err := probeFile(dir, nil)
+	if err != nil {
+		return false, false, err
+	}
+	defer closeAndRemove(f)
+
+	symlink, hardlink := f.Name()+".symlink", f.Name()+".link"
+	symlinks := os.Symlink(filepath.Base(f.Name()), symlink) == nil
+	if symlinks {
+		os.Remove(symlink)
+	}
+	hardlinks := os.Link(f.Name(), hardlink) == nil
+	if hardlinks {
+		os.Remove(hardlink)
+	}
+	return symlinks, hardlinks, nil
+}
+
+// Return the rate in bytes per second that a file can be written to dir
+// and synced at.
+func probeThroughput(dir string) (float64, error) {
+	data := make([]byte, probeThroughputSize)
+	start := time.Now()
+	f, err := probeFile(dir, data)
+	if err != nil {
+		return 0, err
+	}
+	defer closeAndRemove(f)
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return float64(len(data)) / time.Since(start).Seconds(), nil
+}
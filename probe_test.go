@@ -0,0 +1,47 @@
+package shutil
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestProbeDestination(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	report, err := ProbeDestination(makeTestPath("new/dir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Dir).To(Equal(testdir))
+	g.Expect(report.Writable).To(BeTrue())
+	g.Expect(report.Symlinks).To(BeTrue())
+	g.Expect(report.Hardlinks).To(BeTrue())
+	g.Expect(report.Latency).To(BeNumerically(">", 0))
+	g.Expect(report.Throughput).To(BeNumerically(">", 0))
+	g.Expect(report.Supports(FeatureSymlinks)).To(BeTrue())
+	g.Expect(report.Supports(FeatureXattrs)).To(Equal(report.Xattrs))
+
+	// Nothing is left behind
+	entries, err := os.ReadDir(testdir)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, entry := range entries {
+		g.Expect(entry.Name()).NotTo(HavePrefix(".shutil-probe-"))
+	}
+
+	// A file is probed in its directory
+	report, err = ProbeDestination(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Dir).To(Equal(testdir))
+}
+
+func TestDestinationReportMissing(t *testing.T) {
+	g := NewWithT(t)
+
+	report := DestinationReport{Symlinks: true}
+	options := &CopyTreeOptions{Symlinks: true, CopyOptions: &CopyOptions{PreserveACLs: true}}
+	g.Expect(report.Missing(options)).To(Equal([]Feature{FeatureXattrs}))
+	report.Xattrs = true
+	g.Expect(report.Missing(options)).To(BeEmpty())
+	g.Expect(DestinationReport{}.Missing(&CopyTreeOptions{})).To(BeEmpty())
+}
@@ -0,0 +1,9 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+
+package shutil
+
+// Report whether the process pid is running on this host, which can't be
+// found out here, so it's assumed to be.
+func processRunning(pid int) bool {
+	return true
+}
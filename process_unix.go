@@ -0,0 +1,12 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import "golang.org/x/sys/unix"
+
+// Report whether the process pid is running on this host. One that can't
+// be signalled, because it's another user's, is running.
+func processRunning(pid int) bool {
+	err := unix.Kill(pid, 0)
+	return err == nil || err == unix.EPERM
+}
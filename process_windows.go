@@ -0,0 +1,22 @@
+package shutil
+
+import "golang.org/x/sys/windows"
+
+// The exit code GetExitCodeProcess() reports for a process that hasn't
+// exited.
+const stillActive = 259
+
+// Report whether the process pid is running on this host. One that can't
+// be opened, other than because there is no such process, is running.
+func processRunning(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return err != windows.ERROR_INVALID_PARAMETER
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if windows.GetExitCodeProcess(h, &code) != nil {
+		return true
+	}
+	return code == stillActive
+}
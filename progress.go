@@ -0,0 +1,104 @@
+package shutil
+
+import "time"
+
+// A snapshot of how far a multi-file operation has got. It is passed to
+// the ProgressFunc after each file has been handled.
+type Progress struct {
+	// The file that was just handled.
+	Src string
+	Dst string
+
+	// The error copying this file, if any.
+	Err error
+
+	FilesDone  int
+	FilesTotal int
+	BytesDone  int64
+
+	// The total size of the files, where it's known beforehand, such as
+	// when applying a Plan, or zero.
+	BytesTotal int64
+
+	// How long the operation has been running.
+	Elapsed time.Duration
+
+	// The rate data has been copied at over the last few seconds, in
+	// bytes per second.
+	Throughput float64
+
+	// An estimate of the time left, from the data left to copy and the
+	// throughput, or zero if BytesTotal isn't known.
+	Remaining time.Duration
+}
+
+// The fraction of the operation that is done, from 0 to 1, by size if the
+// total size is known and by the number of files otherwise. Zero if
+// neither total is known.
+func (p Progress) Fraction() float64 {
+	switch {
+	case p.BytesTotal > 0:
+		return float64(p.BytesDone) / float64(p.BytesTotal)
+	case p.FilesTotal > 0:
+		return float64(p.FilesDone) / float64(p.FilesTotal)
+	}
+	return 0
+}
+
+// Called after each file of a multi-file operation. Calls are never made
+// concurrently, even when the operation itself runs in parallel.
+type ProgressFunc func(Progress)
+
+// How far back Progress.Throughput looks, and how many samples of the
+// data copied it keeps over that time.
+const (
+	throughputWindow  = 10 * time.Second
+	throughputSamples = 20
+)
+
+// The clock progress is timed with, which tests can change.
+var progressNow = time.Now
+
+// Fills in the timing fields of each Progress of an operation.
+type progressMeter struct {
+	start time.Time
+
+	// The data copied at times over the last throughputWindow, oldest
+	// first, and at least one from before that once the operation has
+	// been running longer.
+	samples []progressSample
+}
+
+type progressSample struct {
+	at    time.Time
+	bytes int64
+}
+
+func newProgressMeter() *progressMeter {
+	now := progressNow()
+	return &progressMeter{start: now, samples: []progressSample{{at: now}}}
+}
+
+// Set the Elapsed, Throughput and Remaining fields of p, which follows the
+// Progress the meter was last given.
+func (m *progressMeter) update(p *Progress) {
+	now := progressNow()
+	p.Elapsed = now.Sub(m.start)
+
+	last := m.samples[len(m.samples)-1]
+	if now.Sub(last.at) >= throughputWindow/throughputSamples {
+		m.samples = append(m.samples, progressSample{now, p.BytesDone})
+	}
+	for len(m.samples) > 1 && now.Sub(m.samples[1].at) >= throughputWindow {
+		m.samples = m.samples[1:]
+	}
+
+	p.Throughput, p.Remaining = 0, 0
+	oldest := m.samples[0]
+	if seconds := now.Sub(oldest.at).Seconds(); seconds > 0 {
+		p.Throughput = float64(p.BytesDone-oldest.bytes) / seconds
+	}
+	if p.BytesTotal > 0 && p.Throughput > 0 && p.BytesDone < p.BytesTotal {
+		p.Remaining = time.Duration(float64(p.BytesTotal-p.BytesDone) / p.Throughput * float64(time.Second))
+	}
+}
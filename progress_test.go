@@ -0,0 +1,63 @@
+package shutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestProgressMeter(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	defer func() { progressNow = time.Now }()
+	progressNow = func() time.Time { return now }
+	m := newProgressMeter()
+
+	// 100 bytes a second for 20 seconds, then 1000 bytes a second
+	p := Progress{BytesTotal: 100000}
+	for i := 0; i < 40; i++ {
+		now = now.Add(time.Second)
+		if i < 20 {
+			p.BytesDone += 100
+		} else {
+			p.BytesDone += 1000
+		}
+		m.update(&p)
+		if i == 0 {
+			g.Expect(p.Throughput).To(Equal(100.0))
+			g.Expect(p.Remaining).To(Equal(999 * time.Second))
+		}
+	}
+	g.Expect(p.Elapsed).To(Equal(40 * time.Second))
+	// Only the recent rate counts
+	g.Expect(p.Throughput).To(Equal(1000.0))
+	g.Expect(p.Remaining).To(Equal(78 * time.Second))
+	g.Expect(p.Fraction()).To(Equal(0.22))
+}
+
+func TestProgressFraction(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Progress{FilesDone: 1, FilesTotal: 4}.Fraction()).To(Equal(0.25))
+	g.Expect(Progress{FilesDone: 1}.Fraction()).To(BeZero())
+}
+
+func TestApplyProgressTotals(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	plan, err := PlanCopyTree(makeTestPath("testdir"), makeTestPath("out"), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	var last Progress
+	_, err = Apply(context.Background(), plan, &ApplyOptions{
+		Progress: func(p Progress) { last = p },
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(last.BytesTotal).To(Equal(int64(12)))
+	g.Expect(last.BytesDone).To(Equal(int64(12)))
+	g.Expect(last.Fraction()).To(Equal(1.0))
+	g.Expect(last.Remaining).To(BeZero())
+}
@@ -0,0 +1,168 @@
+package shutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Which files Prune() removes. Files are removed if they are older than
+// MaxAge or larger than MaxSize, but the KeepNewest newest files of each
+// directory are kept regardless. Limits that are zero don't apply.
+type PrunePolicy struct {
+	// Remove files last modified longer ago than this.
+	MaxAge time.Duration
+
+	// Remove files larger than this many bytes.
+	MaxSize int64
+
+	// The number of newest files in each directory to keep, whatever the
+	// other limits say. If it's the only limit, every other file is
+	// removed.
+	KeepNewest int
+
+	// Also remove directories that pruning leaves empty, other than root.
+	RemoveEmptyDirs bool
+
+	// Called like CopyTreeOptions.Ignore for each directory. Ignored
+	// names are left alone, and ignored directories aren't looked into.
+	Ignore IgnoreFunc
+
+	// Work out what would be removed, without removing anything.
+	DryRun bool
+
+	// Called with each removal, or each that would be made in a dry run.
+	Events EventFunc
+}
+
+// What Prune() did.
+type PruneResult struct {
+	// The files and directories removed, or that would be in a dry run,
+	// in the order they were.
+	Removed []string `json:"removed"`
+
+	// The total size of the files removed.
+	Bytes int64 `json:"bytes"`
+}
+
+// The time Prune() measures ages from, which tests can change.
+var pruneNow = time.Now
+
+// Remove the files in the tree root that policy selects, such as logs
+// older than a week or cache entries over a size, for cleaning up trees
+// that grow without limit. Only regular files are removed, and symbolic
+// links aren't followed. Set the DryRun option to find out what would be
+// removed first.
+//
+// The result lists what was removed, even if Prune() failed part way
+// through.
+func Prune(root string, policy PrunePolicy) (PruneResult, error) {
+	p := &pruner{policy: policy, now: pruneNow()}
+	info, err := os.Stat(root)
+	if err != nil {
+		return p.result, err
+	}
+	if !info.IsDir() {
+		return p.result, &NotADirectoryError{root}
+	}
+	_, err = p.pruneDir(root)
+	return p.result, err
+}
+
+// The state of a single Prune() call.
+type pruner struct {
+	policy PrunePolicy
+	now    time.Time
+	result PruneResult
+}
+
+// Prune the directory dir, reporting whether it was left empty.
+func (p *pruner) pruneDir(dir string) (bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	ignoredNames := []string{}
+	if p.policy.Ignore != nil {
+		ignoredNames = p.policy.Ignore(dir, entries)
+	}
+
+	remaining := 0
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			remaining++
+			continue
+		}
+		switch {
+		case entry.IsDir():
+			path := filepath.Join(dir, entry.Name())
+			empty, err := p.pruneDir(path)
+			if err != nil {
+				return false, err
+			}
+			if empty && p.policy.RemoveEmptyDirs {
+				err = p.remove(path, entry)
+				if err != nil {
+					return false, err
+				}
+			} else {
+				remaining++
+			}
+		case entry.Mode().IsRegular():
+			files = append(files, entry)
+		default:
+			remaining++
+		}
+	}
+
+	// Newest first, so the ones to keep come first
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	for i, file := range files {
+		if !p.selects(file, i) {
+			remaining++
+			continue
+		}
+		err = p.remove(filepath.Join(dir, file.Name()), file)
+		if err != nil {
+			return false, err
+		}
+	}
+	return remaining == 0, nil
+}
+
+// Report whether the policy selects file, which is the nth newest file in
+// its directory, for removal.
+func (p *pruner) selects(file os.FileInfo, n int) bool {
+	policy := p.policy
+	if policy.KeepNewest > 0 && n < policy.KeepNewest {
+		return false
+	}
+	if policy.MaxAge <= 0 && policy.MaxSize <= 0 {
+		return policy.KeepNewest > 0
+	}
+	return (policy.MaxAge > 0 && p.now.Sub(file.ModTime()) > policy.MaxAge) ||
+		(policy.MaxSize > 0 && file.Size() > policy.MaxSize)
+}
+
+// Remove path, which info describes. Only files count towards the bytes
+// removed.
+func (p *pruner) remove(path string, info os.FileInfo) error {
+	if !p.policy.DryRun {
+		err := os.Remove(path)
+		if err != nil {
+			p.policy.Events.send(Event{Kind: EventErrored, Dst: path, Err: err})
+			return err
+		}
+	}
+	p.result.Removed = append(p.result.Removed, path)
+	if info.Mode().IsRegular() {
+		p.result.Bytes += info.Size()
+	}
+	p.policy.Events.send(Event{Kind: EventRemoved, Dst: path, Old: info})
+	return nil
+}
@@ -0,0 +1,85 @@
+package shutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestPrune(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	now := time.Now()
+	defer func() { pruneNow = time.Now }()
+	pruneNow = func() time.Time { return now }
+	day := 24 * time.Hour
+	aged := func(contents string, age time.Duration) shutiltest.Entry {
+		return shutiltest.Entry{Contents: contents, ModTime: now.Add(-age)}
+	}
+	shutiltest.CreateTree(t, makeTestPath("logs"), shutiltest.Tree{
+		"a.log":       aged("a", 1*day),
+		"b.log":       aged("b", 10*day),
+		"c.log":       aged("c", 20*day),
+		"big.log":     aged("0123456789", 2*day),
+		"old/d.log":   aged("d", 30*day),
+		"keep/e.log":  aged("e", 30*day),
+		"link":        shutiltest.Symlink("c.log"),
+		"empty/":      shutiltest.Dir(),
+		"keep/.stamp": aged("", 40*day),
+	})
+
+	policy := PrunePolicy{
+		MaxAge:          7 * day,
+		MaxSize:         5,
+		RemoveEmptyDirs: true,
+		Ignore:          IgnorePatterns("keep"),
+		DryRun:          true,
+	}
+	result, err := Prune(makeTestPath("logs"), policy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Removed).To(Equal([]string{
+		makeTestPath("logs/empty"),
+		makeTestPath("logs/old/d.log"),
+		makeTestPath("logs/old"),
+		makeTestPath("logs/big.log"),
+		makeTestPath("logs/b.log"),
+		makeTestPath("logs/c.log"),
+	}))
+	g.Expect(result.Bytes).To(Equal(int64(13)))
+	g.Expect(makeTestPath("logs/c.log")).To(BeAnExistingFile())
+
+	// Keeping the newest two of each directory saves big.log
+	policy.DryRun = false
+	policy.KeepNewest = 2
+	var events eventLog
+	policy.Events = events.record
+	result, err = Prune(makeTestPath("logs"), policy)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Removed).To(ConsistOf(
+		makeTestPath("logs/empty"),
+		makeTestPath("logs/b.log"),
+		makeTestPath("logs/c.log"),
+	))
+	g.Expect(events).To(HaveLen(3))
+	g.Expect(Glob(makeTestPath("logs/**"))).To(Equal([]string{
+		makeTestPath("logs/a.log"),
+		makeTestPath("logs/big.log"),
+		makeTestPath("logs/keep"),
+		makeTestPath("logs/keep/.stamp"),
+		makeTestPath("logs/keep/e.log"),
+		makeTestPath("logs/link"),
+		makeTestPath("logs/old"),
+		makeTestPath("logs/old/d.log"),
+	}))
+
+	// KeepNewest on its own removes everything else
+	result, err = Prune(makeTestPath("logs"), PrunePolicy{KeepNewest: 1})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Removed).To(Equal([]string{makeTestPath("logs/keep/.stamp"), makeTestPath("logs/big.log")}))
+
+	_, err = Prune(makeTestPath("testfile"), policy)
+	g.Expect(err).To(BeAssignableToTypeOf(&NotADirectoryError{}))
+}
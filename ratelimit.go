@@ -0,0 +1,87 @@
+package shutil
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// The most data a rate limited copy reads at once, so that copies sharing
+// a limiter take turns.
+const rateLimitChunk = 64 << 10
+
+// Limits the rate data is copied at, to leave bandwidth for other work.
+// A single limiter can be shared by any number of copies, such as those a
+// Scheduler runs, to limit them together. It lets a second's worth of
+// data through at once after being idle.
+type RateLimiter struct {
+	rate float64 // bytes per second
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Return a limiter that lets bytesPerSecond through, or nil, which doesn't
+// limit anything, if bytesPerSecond isn't positive.
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// Wait until n more bytes may be copied, or the context is done.
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	// Going into debt, rather than waiting for enough tokens, keeps
+	// waiters in the order they came
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reads from r no faster than the limiter allows.
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > rateLimitChunk {
+		p = p[:rateLimitChunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
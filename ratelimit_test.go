@@ -0,0 +1,50 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRateLimiter(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(NewRateLimiter(0)).To(BeNil())
+	g.Expect((*RateLimiter)(nil).WaitN(context.Background(), 1<<30)).To(Succeed())
+
+	// A second's worth goes straight through, and the rest waits
+	limiter := NewRateLimiter(1 << 20)
+	start := time.Now()
+	g.Expect(limiter.WaitN(context.Background(), 1<<20)).To(Succeed())
+	g.Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond))
+	g.Expect(limiter.WaitN(context.Background(), 256<<10)).To(Succeed())
+	g.Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.Expect(limiter.WaitN(ctx, 1<<20)).To(MatchError(context.Canceled))
+}
+
+func TestCopyRateLimit(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	data := bytes.Repeat([]byte("x"), 3<<20)
+	src := makeTestPath("big")
+	g.Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+
+	// 2MiB/s lets the first 2MiB through at once, and takes half a
+	// second over the rest
+	start := time.Now()
+	result, err := CopyContext(context.Background(), src, makeTestPath("copy"), &CopyOptions{
+		RateLimit: NewRateLimiter(2 << 20),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(len(data))))
+	g.Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+	g.Expect(FilesEqual(src, makeTestPath("copy"), nil)).To(BeTrue())
+}
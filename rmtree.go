@@ -0,0 +1,161 @@
+package shutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type ImmutableFileError struct {
+	Path string
+	Err  error
+}
+
+func (e ImmutableFileError) Error() string {
+	return fmt.Sprintf("`%s` is immutable or append-only (see chattr(1)): %s", e.Path, e.Err)
+}
+
+func (e ImmutableFileError) Code() ErrorCode {
+	return CodeImmutable
+}
+
+func (e ImmutableFileError) Unwrap() error {
+	return e.Err
+}
+
+type RmTreeOptions struct {
+	// Carry on past any errors, removing as much as possible, and don't
+	// return them.
+	IgnoreErrors bool
+
+	// Called with the path and error of each failed removal, unless
+	// IgnoreErrors is set. Returning nil carries on with the rest of the
+	// tree. Returning an error stops and returns it from RmTree().
+	OnError func(path string, err error) error
+
+	// Clear read-only permission bits (and FILE_ATTRIBUTE_READONLY on
+	// Windows) from entries which can't otherwise be removed.
+	ForceWritable bool
+}
+
+// Recursively delete a directory tree.
+//
+// By default the first error stops the removal and is returned. See
+// RmTreeOptions for how to carry on instead.
+//
+// Read-only trees, such as the Go module cache, can only be removed with
+// ForceWritable set. Files and directories that can't be removed because
+// their immutable or append-only attribute is set result in an
+// ImmutableFileError, as only `chattr -i` can remove those attributes.
+//
+// `path` must be a directory, and not a symbolic link to one.
+func RmTree(path string, options *RmTreeOptions) error {
+	if options == nil {
+		options = &RmTreeOptions{}
+	}
+	onError := func(p string, err error) error {
+		if options.IgnoreErrors {
+			return nil
+		}
+		if options.OnError != nil {
+			return options.OnError(p, err)
+		}
+		return err
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return onError(path, err)
+	}
+	if !info.IsDir() {
+		return onError(path, &NotADirectoryError{path})
+	}
+	return rmtree(path, options, onError)
+}
+
+func rmtree(path string, options *RmTreeOptions, onError func(string, error) error) error {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil && os.IsPermission(err) && options.ForceWritable {
+		if os.Chmod(path, 0700) == nil {
+			entries, err = ioutil.ReadDir(path)
+		}
+	}
+	if err != nil {
+		if err := onError(path, err); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		if entry.IsDir() {
+			err = rmtree(entryPath, options, onError)
+		} else {
+			err = removeEntry(entryPath, options.ForceWritable)
+			if err != nil {
+				err = onError(entryPath, err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = removeEntry(path, options.ForceWritable)
+	if err != nil {
+		return onError(path, err)
+	}
+	return nil
+}
+
+// Remove a file or empty directory, making it and its parent writable
+// first if that is what's stopping it. Something that has already gone,
+// perhaps removed by another process, isn't an error.
+func removeEntry(path string, forceWritable bool) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err == nil || !os.IsPermission(err) {
+		return err
+	}
+
+	if forceWritable {
+		if makeWritable(filepath.Dir(path)) == nil && makeWritable(path) == nil {
+			err = os.Remove(path)
+			if err == nil || !os.IsPermission(err) {
+				return err
+			}
+		}
+	}
+
+	for _, p := range []string{path, filepath.Dir(path)} {
+		if immutable, _ := isImmutable(p); immutable {
+			return &ImmutableFileError{p, err}
+		}
+	}
+	return err
+}
+
+// Add owner write permission to a file or directory, and owner search
+// permission to a directory. Symbolic links are left alone, as changing
+// their mode would change their target instead.
+func makeWritable(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if IsSymlink(info) {
+		return nil
+	}
+
+	mode := info.Mode() | 0200
+	if info.IsDir() {
+		mode |= 0100
+	}
+	if mode == info.Mode() {
+		return nil
+	}
+	return os.Chmod(path, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+}
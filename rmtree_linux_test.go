@@ -0,0 +1,46 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+func setImmutable(t *testing.T, path string, immutable bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	flags, err := getInodeFlags(path)
+	if err != nil {
+		t.Skipf("inode flags not supported: %s", err)
+	}
+	if immutable {
+		flags |= fsImmutableFlag
+	} else {
+		flags &^= fsImmutableFlag
+	}
+	err = unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, flags)
+	if err != nil {
+		t.Skipf("can't set the immutable attribute: %s", err)
+	}
+}
+
+func TestRmTreeImmutable(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	file := makeTestPath("testdir/file1")
+	setImmutable(t, file, true)
+	t.Cleanup(func() { setImmutable(t, file, false) })
+
+	err := RmTree(makeTestPath("testdir"), &RmTreeOptions{ForceWritable: true})
+	var immutableErr *ImmutableFileError
+	g.Expect(errors.As(err, &immutableErr)).To(BeTrue())
+	g.Expect(immutableErr.Path).To(Equal(file))
+}
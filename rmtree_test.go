@@ -0,0 +1,71 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRmTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(RmTree(testdir, nil)).To(Succeed())
+	_, err := os.Lstat(testdir)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestRmTreeReadOnly(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dir := makeTestPath("testdir")
+	g.Expect(os.Chmod(makeTestPath("testdir/file1"), 0444)).To(Succeed())
+	g.Expect(os.Chmod(dir, 0555)).To(Succeed())
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	g.Expect(RmTree(dir, &RmTreeOptions{ForceWritable: true})).To(Succeed())
+	_, err := os.Lstat(dir)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
+
+func TestRmTreeErrors(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	missing := makeTestPath("missing")
+	err := RmTree(missing, nil)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+
+	g.Expect(RmTree(missing, &RmTreeOptions{IgnoreErrors: true})).To(Succeed())
+
+	var failed []string
+	stop := errors.New("stop")
+	g.Expect(RmTree(missing, &RmTreeOptions{
+		OnError: func(path string, err error) error {
+			failed = append(failed, path)
+			return stop
+		},
+	})).To(MatchError(stop))
+	g.Expect(failed).To(Equal([]string{missing}))
+}
+
+func TestRemoveEntryMissing(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(removeEntry(makeTestPath("missing"), false)).To(Succeed())
+	g.Expect(removeEntry(makeTestPath("missing"), true)).To(Succeed())
+}
+
+func TestRmTreeSymlink(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	link := makeTestPath("link")
+	g.Expect(os.Symlink("testdir", link)).To(Succeed())
+	g.Expect(RmTree(link, nil)).Should(MatchError(&NotADirectoryError{link}))
+	g.Expect(makeTestPath("testdir/file1")).To(BeAnExistingFile())
+}
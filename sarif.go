@@ -0,0 +1,149 @@
+package shutil
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// The version of SARIF, the Static Analysis Results Interchange Format,
+// that WriteSARIF() writes.
+const sarifVersion = "2.1.0"
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// The parts of SARIF that WriteSARIF() uses.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Write the errors and warnings of reports to w as a SARIF log, which CI
+// systems such as GitHub code scanning read to annotate what failed. Each
+// report is a run, whose results are its Errors, at level "error", and its
+// WarningInfo, at level "warning", with the file each is about as its
+// location and the name of its ErrorInfo.Code as its rule. Errors with
+// CodeUnknown have the rule "error", and warnings "warning". The
+// operation, source and destination of the report are the properties of
+// each result.
+func WriteSARIF(w io.Writer, reports ...Report) error {
+	log := sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{}}
+	for _, report := range reports {
+		run := sarifRun{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "go-shutil",
+				InformationURI: "https://github.com/gocardless/go-shutil",
+			}},
+			Results: []sarifResult{},
+		}
+		rules := map[string]bool{}
+		add := func(info ErrorInfo, level string) {
+			rule := level
+			if info.Code != CodeUnknown {
+				rule = info.Code.String()
+			}
+			if !rules[rule] {
+				rules[rule] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{rule})
+			}
+			result := sarifResult{
+				RuleID:  rule,
+				Level:   level,
+				Message: sarifMessage{info.Message},
+				Properties: map[string]interface{}{
+					"op":  report.Op,
+					"src": report.Src,
+					"dst": report.Dst,
+				},
+			}
+			if path := problemPath(info); path != "" {
+				result.Locations = []sarifLocation{{sarifPhysicalLocation{sarifArtifactLocation{sarifURI(path)}}}}
+			}
+			run.Results = append(run.Results, result)
+		}
+		for _, info := range report.Errors {
+			add(info, "error")
+		}
+		for _, info := range report.WarningInfo {
+			add(info, "warning")
+		}
+		log.Runs = append(log.Runs, run)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+// Return the file a problem is about: the one a system call failed on,
+// or else the destination or source of the copy.
+func problemPath(info ErrorInfo) string {
+	switch {
+	case info.Path != "":
+		return info.Path
+	case info.Dst != "":
+		return info.Dst
+	}
+	return info.Src
+}
+
+// Return path as a SARIF artifact location, which is a URI: relative
+// paths stay relative, with forward slashes, and absolute ones are file
+// URIs.
+func sarifURI(path string) string {
+	uri := &url.URL{Path: filepath.ToSlash(path)}
+	if filepath.IsAbs(path) {
+		uri.Scheme = "file"
+		if !strings.HasPrefix(uri.Path, "/") {
+			// A Windows path with a drive letter
+			uri.Path = "/" + uri.Path
+		}
+	}
+	return uri.String()
+}
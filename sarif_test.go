@@ -0,0 +1,53 @@
+package shutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	report := Report{
+		Op:  "copy",
+		Src: "src",
+		Dst: "dst",
+		Errors: []ErrorInfo{
+			{Src: "src/a", Dst: "dst/a", Path: "/data/src/a b", Code: CodePermission, Message: "open: permission denied"},
+			{Message: "oops"},
+		},
+		WarningInfo: []ErrorInfo{
+			{Path: "dst/b", Code: CodeNotPreserved, Message: "owner not preserved"},
+			{Path: "dst/c", Code: CodeNotPreserved, Message: "owner not preserved"},
+		},
+	}
+	var buf bytes.Buffer
+	g.Expect(WriteSARIF(&buf, report, Report{Op: "sync"})).To(Succeed())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(buf.Bytes(), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Schema).NotTo(BeEmpty())
+	g.Expect(log.Runs).To(HaveLen(2))
+	g.Expect(log.Runs[1].Results).To(BeEmpty())
+
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Name).To(Equal("go-shutil"))
+	g.Expect(run.Tool.Driver.Rules).To(Equal([]sarifRule{{"permission"}, {"error"}, {"not-preserved"}}))
+	g.Expect(run.Results).To(HaveLen(4))
+
+	g.Expect(run.Results[0].RuleID).To(Equal("permission"))
+	g.Expect(run.Results[0].Level).To(Equal("error"))
+	g.Expect(run.Results[0].Message.Text).To(Equal("open: permission denied"))
+	g.Expect(run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI).To(Equal("file:///data/src/a%20b"))
+	g.Expect(run.Results[0].Properties).To(HaveKeyWithValue("op", "copy"))
+
+	g.Expect(run.Results[1].RuleID).To(Equal("error"))
+	g.Expect(run.Results[1].Locations).To(BeEmpty())
+
+	g.Expect(run.Results[2].Level).To(Equal("warning"))
+	g.Expect(run.Results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI).To(Equal("dst/b"))
+}
@@ -0,0 +1,186 @@
+package shutil
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Options for NewScheduler().
+type SchedulerOptions struct {
+	// The number of jobs run at once. Zero means one.
+	Concurrency int
+
+	// The most file data the jobs may copy per second between them, or
+	// zero for no limit.
+	BytesPerSecond int64
+
+	// Called with the progress of each job, after each file it handles.
+	// Calls are never made concurrently, even for different jobs.
+	Progress func(JobProgress)
+}
+
+// The progress of one of a Scheduler's jobs.
+type JobProgress struct {
+	Job *Job
+	Progress
+}
+
+// How far a Scheduler's job has got.
+type JobState int
+
+const (
+	// Waiting for one of the running jobs to finish.
+	JobQueued JobState = iota
+	JobRunning
+	// Finished, whether it succeeded or not.
+	JobDone
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobQueued:
+		return "queued"
+	case JobRunning:
+		return "running"
+	}
+	return "done"
+}
+
+// An operation submitted to a Scheduler.
+type Job struct {
+	Name string
+
+	scheduler *Scheduler
+	op        *Op
+	state     JobState
+	done      chan struct{}
+	report    Report
+	err       error
+}
+
+// Return how far the job has got.
+func (j *Job) State() JobState {
+	j.scheduler.mu.Lock()
+	defer j.scheduler.mu.Unlock()
+	return j.state
+}
+
+// Return a channel that is closed when the job is done.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait for the job to finish, and return what its Op's Run() did.
+func (j *Job) Wait() (Report, error) {
+	<-j.done
+	return j.report, j.err
+}
+
+// Runs many independent operations, such as syncing each of the
+// directories a backup agent looks after, a few at a time and within a
+// limit on the bandwidth they use together. Jobs are started in the
+// order they are submitted, as others finish.
+type Scheduler struct {
+	ctx        context.Context
+	options    SchedulerOptions
+	limiter    *RateLimiter
+	progressMu sync.Mutex
+
+	mu      sync.Mutex
+	jobs    []*Job
+	queue   []*Job
+	running int
+}
+
+// Return a Scheduler whose jobs stop with the context's error if it is
+// cancelled. Jobs that haven't started by then fail without running.
+func NewScheduler(ctx context.Context, options *SchedulerOptions) *Scheduler {
+	if options == nil {
+		options = &SchedulerOptions{}
+	}
+	s := &Scheduler{ctx: ctx, options: *options}
+	if s.options.Concurrency <= 0 {
+		s.options.Concurrency = 1
+	}
+	s.limiter = NewRateLimiter(options.BytesPerSecond)
+	return s
+}
+
+// Queue op to be run as a job called name. Unless the op was given its
+// own context, it is run with the scheduler's, its copies are limited by
+// the scheduler's rate in place of any limit it had, and its progress is
+// reported to the scheduler's Progress as well as its own. The op
+// shouldn't be changed once it has been submitted.
+func (s *Scheduler) Submit(name string, op *Op) *Job {
+	job := &Job{Name: name, scheduler: s, op: op, done: make(chan struct{})}
+	if op.ctx == nil {
+		op.ctx = s.ctx
+	}
+	if s.limiter != nil {
+		op.options.RateLimit = s.limiter
+	}
+	if s.options.Progress != nil {
+		own := op.progress
+		op.progress = func(p Progress) {
+			if own != nil {
+				own(p)
+			}
+			s.progressMu.Lock()
+			defer s.progressMu.Unlock()
+			s.options.Progress(JobProgress{Job: job, Progress: p})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+	s.queue = append(s.queue, job)
+	s.dispatch()
+	return job
+}
+
+// Start queued jobs while there is room for them. s.mu must be held.
+func (s *Scheduler) dispatch() {
+	for s.running < s.options.Concurrency && len(s.queue) > 0 {
+		job := s.queue[0]
+		s.queue = s.queue[1:]
+		job.state = JobRunning
+		s.running++
+		go s.run(job)
+	}
+}
+
+func (s *Scheduler) run(job *Job) {
+	if err := job.op.ctx.Err(); err != nil {
+		job.report = Report{Op: job.op.kind.String(), Src: job.op.src, Dst: job.op.dst, Started: time.Now()}
+		job.report.Errors = DescribeErrors(err)
+		job.err = err
+	} else {
+		job.report, job.err = job.op.Run()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.state = JobDone
+	close(job.done)
+	s.running--
+	s.dispatch()
+}
+
+// Return every job submitted, in the order they were.
+func (s *Scheduler) Jobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Job(nil), s.jobs...)
+}
+
+// Wait for every job submitted so far to finish, and return them in the
+// order they were submitted.
+func (s *Scheduler) Wait() []*Job {
+	jobs := s.Jobs()
+	for _, job := range jobs {
+		<-job.done
+	}
+	return jobs
+}
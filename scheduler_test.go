@@ -0,0 +1,65 @@
+package shutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestScheduler(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	var mu sync.Mutex
+	progress := map[string]int{}
+	s := NewScheduler(context.Background(), &SchedulerOptions{
+		Concurrency:    2,
+		BytesPerSecond: 1 << 30,
+		Progress: func(p JobProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress[p.Job.Name] = p.FilesDone
+		},
+	})
+	copyJob := s.Submit("copy", NewCopy(makeTestPath("testdir"), makeTestPath("copy")).Recursive())
+	syncJob := s.Submit("sync", NewSync(makeTestPath("testdir"), makeTestPath("sync")))
+	fileJob := s.Submit("file", NewCopy(makeTestPath("testfile"), makeTestPath("file")))
+
+	jobs := s.Wait()
+	g.Expect(jobs).To(Equal([]*Job{copyJob, syncJob, fileJob}))
+	for _, job := range jobs {
+		g.Expect(job.State()).To(Equal(JobDone))
+		report, err := job.Wait()
+		g.Expect(err).NotTo(HaveOccurred(), job.Name)
+		g.Expect(report.Files).To(BeNumerically(">", 0), job.Name)
+	}
+	g.Expect(progress).To(HaveKeyWithValue("copy", 2))
+	g.Expect(progress).To(HaveKey("sync"))
+	g.Expect(progress).NotTo(HaveKey("file"))
+	g.Expect(makeTestPath("sync/file2")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("file")).To(BeAnExistingFile())
+}
+
+func TestSchedulerCancelled(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := NewScheduler(ctx, nil)
+	job := s.Submit("move", NewMove(makeTestPath("testdir"), makeTestPath("moved")))
+	report, err := job.Wait()
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(report.Errors).NotTo(BeEmpty())
+	g.Expect(makeTestPath("testdir")).To(BeADirectory())
+}
+
+func TestJobState(t *testing.T) {
+	g := NewWithT(t)
+
+	for state, name := range map[JobState]string{JobQueued: "queued", JobRunning: "running", JobDone: "done"} {
+		g.Expect(state.String()).To(Equal(name))
+	}
+}
@@ -0,0 +1,58 @@
+package shutil
+
+import "os"
+
+// Decides the SELinux security context to give the copy dst, whose mode
+// is mode, of a file whose context is srcContext, or "" if it has none or
+// it wasn't preserved. It returns the context dst is given, or "" to
+// leave dst with the one it got when it was created. Like restorecon(8),
+// it can look up the context policy gives files at dst's path, so that
+// copies suit where they are rather than where they came from.
+type RelabelFunc func(dst string, mode os.FileMode, srcContext string) (string, error)
+
+// Return the SELinux security context of the named file, such as
+// "system_u:object_r:etc_t:s0", or "" if it doesn't have one, such as
+// when SELinux isn't in use. Symbolic links aren't followed. Only Linux
+// has SELinux, so a NotSupportedError is returned elsewhere.
+func SELinuxContext(name string) (string, error) {
+	return selinuxContext(name)
+}
+
+// Give the named file the SELinux security context ctx, which needs
+// permission to relabel it from its current context, without following
+// it if it is a symbolic link.
+func SetSELinuxContext(name, ctx string) error {
+	return setXattr(name, selinuxXattr, append([]byte(ctx), 0))
+}
+
+// The extended attribute SELinux keeps contexts in.
+const selinuxXattr = "security.selinux"
+
+// Give dst the security context the options ask for, given that src,
+// which srcInfo describes, has been copied to it. Failing to set it is
+// returned as a warning, as without privileges it's expected to.
+func copySELinuxContext(src, dst string, srcInfo os.FileInfo, options *CopyOptions) (error, error) {
+	var ctx string
+	if options.PreserveSELinux {
+		var err error
+		ctx, err = selinuxContext(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.Relabel != nil {
+		var err error
+		ctx, err = options.Relabel(dst, srcInfo.Mode(), ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ctx == "" {
+		return nil, nil
+	}
+	current, err := selinuxContext(dst)
+	if err != nil || current == ctx {
+		return nil, err
+	}
+	return SetSELinuxContext(dst, ctx), nil
+}
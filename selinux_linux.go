@@ -0,0 +1,19 @@
+package shutil
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func selinuxContext(name string) (string, error) {
+	value, err := getXattr(name, selinuxXattr)
+	if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.EOPNOTSUPP) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(value), "\x00"), nil
+}
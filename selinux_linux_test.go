@@ -0,0 +1,56 @@
+package shutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+func TestPreserveSELinux(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	const label = "system_u:object_r:user_home_t:s0"
+	src := makeTestPath("testfile")
+	err := SetSELinuxContext(src, label)
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EPERM) || errors.Is(err, unix.EINVAL) {
+		t.Skip("can't set SELinux contexts")
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(SELinuxContext(src)).To(Equal(label))
+	g.Expect(SELinuxContext(makeTestPath("testfile2"))).To(Equal(""))
+
+	dst := makeTestPath("copied")
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{PreserveSELinux: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(SELinuxContext(dst)).To(Equal(label))
+
+	// Relabelling is given the source's context, and decides the copy's
+	var relabelled []string
+	relabel := func(dst string, mode os.FileMode, srcContext string) (string, error) {
+		relabelled = append(relabelled, dst+" "+srcContext)
+		return "system_u:object_r:var_t:s0", nil
+	}
+	dst = makeTestPath("relabelled")
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{PreserveSELinux: true, Relabel: relabel})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(relabelled).To(Equal([]string{dst + " " + label}))
+	g.Expect(SELinuxContext(dst)).To(Equal("system_u:object_r:var_t:s0"))
+
+	// Without PreserveSELinux, it isn't
+	relabelled = nil
+	dst = makeTestPath("relabelled2")
+	_, err = CopyContext(context.Background(), src, dst, &CopyOptions{Relabel: relabel})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(relabelled).To(Equal([]string{dst + " "}))
+
+	// Errors deciding the context fail the copy
+	_, err = CopyContext(context.Background(), src, makeTestPath("relabelled3"), &CopyOptions{
+		Relabel: func(string, os.FileMode, string) (string, error) { return "", os.ErrInvalid },
+	})
+	g.Expect(err).To(MatchError(os.ErrInvalid))
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package shutil
+
+func selinuxContext(name string) (string, error) {
+	return "", &NotSupportedError{"read SELinux context", name}
+}
@@ -1,13 +1,15 @@
 package shutil
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type SameFileError struct {
@@ -19,6 +21,10 @@ func (e SameFileError) Error() string {
 	return fmt.Sprintf("%s and %s are the same file", e.Src, e.Dst)
 }
 
+func (e SameFileError) Code() ErrorCode {
+	return CodeSameFile
+}
+
 type SpecialFileError struct {
 	File     string
 	FileInfo os.FileInfo
@@ -28,6 +34,65 @@ func (e SpecialFileError) Error() string {
 	return fmt.Sprintf("`%s` is a named pipe", e.File)
 }
 
+func (e SpecialFileError) Code() ErrorCode {
+	return CodeSpecialFile
+}
+
+// Returned when a copy that was checked doesn't match its source.
+type VerifyError struct {
+	Src string
+	Dst string
+}
+
+func (e VerifyError) Error() string {
+	return fmt.Sprintf("`%s` does not match `%s` after copying", e.Dst, e.Src)
+}
+
+func (e VerifyError) Code() ErrorCode {
+	return CodeChecksumMismatch
+}
+
+// Returned when the data copied from a file doesn't match the size it had
+// when the copy began, as CopyOptions.SizeCheck requires, such as because
+// it was written to while it was copied.
+type ShortCopyError struct {
+	Src string
+	Dst string
+
+	// The size of the source when the copy began.
+	Size int64
+
+	// The amount of data copied, which can be more than Size if the source
+	// grew.
+	Copied int64
+}
+
+func (e ShortCopyError) Error() string {
+	return fmt.Sprintf("%s: %d/%d copied", e.Src, e.Copied, e.Size)
+}
+
+func (e ShortCopyError) Code() ErrorCode {
+	return CodeShortCopy
+}
+
+// Returned when following a symbolic link whose target doesn't exist.
+type DanglingSymlinkError struct {
+	Link   string
+	Target string
+}
+
+func (e DanglingSymlinkError) Error() string {
+	return fmt.Sprintf("`%s` is a dangling symbolic link to `%s`", e.Link, e.Target)
+}
+
+func (e DanglingSymlinkError) Code() ErrorCode {
+	return CodeDanglingSymlink
+}
+
+func (e DanglingSymlinkError) Unwrap() error {
+	return os.ErrNotExist
+}
+
 type NotADirectoryError struct {
 	Src string
 }
@@ -36,6 +101,10 @@ func (e NotADirectoryError) Error() string {
 	return fmt.Sprintf("`%s` is not a directory", e.Src)
 }
 
+func (e NotADirectoryError) Code() ErrorCode {
+	return CodeNotADirectory
+}
+
 type AlreadyExistsError struct {
 	Dst string
 }
@@ -44,6 +113,58 @@ func (e AlreadyExistsError) Error() string {
 	return fmt.Sprintf("`%s` already exists", e.Dst)
 }
 
+func (e AlreadyExistsError) Code() ErrorCode {
+	return CodeAlreadyExists
+}
+
+// Returned by Move() for any failure.
+type MoveError struct {
+	Src string
+	Dst string
+
+	// What Move() was doing when it failed: "rename", "copy" or "remove".
+	// A failed copy may leave a partial copy at Dst. A failed removal
+	// means Copied is set: Dst is complete, but some or all of Src still
+	// exists too.
+	Op     string
+	Copied bool
+
+	Err error
+}
+
+func (e MoveError) Error() string {
+	return fmt.Sprintf("Cannot move `%s` to `%s`: %s: %s", e.Src, e.Dst, e.Op, e.Err)
+}
+
+func (e MoveError) Code() ErrorCode {
+	return CodeOf(e.Err)
+}
+
+func (e MoveError) Unwrap() error {
+	return e.Err
+}
+
+// Returned by Move() when src and dst are on different devices, so src
+// can't simply be renamed, and it is a kind of file which can't be
+// recreated on the destination device.
+type CrossDeviceError struct {
+	Src string
+	Dst string
+	Err error
+}
+
+func (e CrossDeviceError) Error() string {
+	return fmt.Sprintf("Cannot move `%s` to `%s` on another device: %s", e.Src, e.Dst, e.Err)
+}
+
+func (e CrossDeviceError) Code() ErrorCode {
+	return CodeCrossDevice
+}
+
+func (e CrossDeviceError) Unwrap() error {
+	return e.Err
+}
+
 type MoveOntoSelfError struct {
 	Src string
 	Dst string
@@ -53,14 +174,71 @@ func (e MoveOntoSelfError) Error() string {
 	return fmt.Sprintf("Cannot move a directory `%s` into itself `%s` ", e.Src, e.Dst)
 }
 
-func samefile(src string, dst string) bool {
-	srcInfo, _ := os.Stat(src)
-	dstInfo, _ := os.Stat(dst)
-	return os.SameFile(srcInfo, dstInfo)
+func (e MoveOntoSelfError) Code() ErrorCode {
+	return CodeIntoSelf
+}
+
+// Returned by CopyTree() when the destination is inside the source, so
+// the copy would copy itself.
+type CopyIntoSelfError struct {
+	Src string
+	Dst string
+}
+
+func (e CopyIntoSelfError) Error() string {
+	return fmt.Sprintf("Cannot copy a directory `%s` into itself `%s`", e.Src, e.Dst)
+}
+
+func (e CopyIntoSelfError) Code() ErrorCode {
+	return CodeIntoSelf
+}
+
+// An error that occurred while operating on a single file as part of a
+// larger operation.
+type FileError struct {
+	Src string
+	Dst string
+	Err error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("`%s` -> `%s`: %s", e.Src, e.Dst, e.Err)
+}
+
+func (e FileError) Code() ErrorCode {
+	return CodeOf(e.Err)
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// The errors collected by an operation that carries on after individual
+// files fail, in the order they occurred.
+type MultiError struct {
+	Errors []error
+}
+
+func (e MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e MultiError) Code() ErrorCode {
+	if len(e.Errors) == 0 {
+		return CodeUnknown
+	}
+	return CodeOf(e.Errors[0])
 }
 
 func specialfile(fi os.FileInfo) bool {
-	return (fi.Mode() & os.ModeNamedPipe) == os.ModeNamedPipe
+	return IsFIFO(fi)
 }
 
 func stringInSlice(a string, list []string) bool {
@@ -73,107 +251,356 @@ func stringInSlice(a string, list []string) bool {
 }
 
 func IsSymlink(fi os.FileInfo) bool {
-	return (fi.Mode() & os.ModeSymlink) == os.ModeSymlink
+	return KindOf(fi.Mode()) == KindSymlink
 }
 
+// Look up files being copied, as variables so that benchmarks can count
+// the calls.
+var (
+	statFile  = os.Stat
+	lstatFile = os.Lstat
+)
+
 // Copy data from src to dst
 //
 // If followSymlinks is not set and src is a symbolic link, a
 // new symlink will be created instead of copying the file it points
 // to.
 func CopyFile(src, dst string, followSymlinks bool) error {
-	if samefile(src, dst) {
-		return &SameFileError{src, dst}
-	}
+	_, err := CopyFileContext(context.Background(), src, dst, &CopyOptions{FollowSymlinks: followSymlinks})
+	return err
+}
 
-	// Make sure src exists and neither are special files
-	srcStat, err := os.Lstat(src)
-	if err != nil {
-		return err
+// Copy data from src to dst like CopyFile(), with the given options.
+//
+// The copy stops with the context's error if it is cancelled. The
+// result's Bytes is the amount of data copied.
+//
+// If src is a symbolic link that is being followed but its target doesn't
+// exist, the DanglingSymlinks option decides what happens. By default a
+// DanglingSymlinkError is returned.
+func CopyFileContext(ctx context.Context, src, dst string, options *CopyOptions) (CopyResult, error) {
+	if options == nil {
+		options = &CopyOptions{}
 	}
-	if specialfile(srcStat) {
-		return &SpecialFileError{src, srcStat}
+	if err := ctx.Err(); err != nil {
+		return CopyResult{Dst: dst}, err
 	}
-
-	dstStat, err := os.Stat(dst)
+	dstStat, err := statFile(dst)
 	if err != nil && !os.IsNotExist(err) {
-		return err
-	} else if err == nil {
-		if specialfile(dstStat) {
-			return &SpecialFileError{dst, dstStat}
-		}
+		return CopyResult{Dst: dst}, err
 	}
+	return copyFile(ctx, src, dst, nil, dstStat, false, options)
+}
 
-	// If we don't follow symlinks and it's a symlink, just link it and be done
-	if !followSymlinks && IsSymlink(srcStat) {
-		return os.Symlink(src, dst)
+// Copy src to dst like CopyFileContext(). srcLstat is what os.Lstat()
+// returns for src, if the caller already has it, and dstStat what
+// os.Stat() returns for dst, or nil if it doesn't exist, so that neither
+// is looked up again. With copyMode set, the copy is given src's mode
+// bits too, as CopyMode() would.
+func copyFile(ctx context.Context, src, dst string, srcLstat, dstStat os.FileInfo, copyMode bool, options *CopyOptions) (CopyResult, error) {
+	result := CopyResult{Dst: dst}
+	if err := waitIfPaused(ctx); err != nil {
+		return result, err
 	}
+	// Once started, a file is only given up on if the operation is
+	// cancelled, rather than stopped
+	ctx = fileContext(ctx)
 
-	// If we are a symlink, follow it
-	if IsSymlink(srcStat) {
-		src, err = os.Readlink(src)
+	var err error
+	if srcLstat == nil {
+		srcLstat, err = lstatFile(src)
 		if err != nil {
-			return err
+			return result, err
+		}
+	}
+	// A link to nothing is left without a stat, as it's dangling
+	srcStat := srcLstat
+	if IsSymlink(srcLstat) {
+		srcStat, err = statFile(src)
+		if err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+	}
+
+	// Make sure they aren't the same file, and neither are special files
+	if dstStat != nil && srcStat != nil && os.SameFile(srcStat, dstStat) {
+		return result, &SameFileError{src, dst}
+	}
+	if specialfile(srcLstat) && !options.ReadNamedPipes {
+		return result, &SpecialFileError{src, srcLstat}
+	}
+	if dstStat != nil && specialfile(dstStat) {
+		return result, &SpecialFileError{dst, dstStat}
+	}
+
+	// If we don't follow symlinks and it's a symlink, just link it and be
+	// done, unless the destination can't have links and they're to be
+	// done without
+	var withoutSymlink *symlinksUnsupported
+	if !options.FollowSymlinks && IsSymlink(srcLstat) {
+		result, err := copySymlink(src, dst, srcLstat, options)
+		if !errors.As(err, &withoutSymlink) {
+			return result, err
+		}
+		if srcStat == nil {
+			result = CopyResult{Dst: dst, Skipped: true}
+			result.Warnings = withoutSymlink.warnings(dst, options)
+			return result, nil
+		}
+	}
+
+	// If we are a symlink, follow it. Metadata that isn't in the stat,
+	// such as extended attributes, is read from what it points to.
+	metaSrc := src
+	if IsSymlink(srcLstat) {
+		if srcStat == nil {
+			switch options.DanglingSymlinks {
+			case DanglingSymlinkCopyLink:
+				result, err := copySymlink(src, dst, srcLstat, options)
+				if unsupported := (*symlinksUnsupported)(nil); errors.As(err, &unsupported) {
+					result = CopyResult{Dst: dst, Skipped: true}
+					result.Warnings = unsupported.warnings(dst, options)
+					return result, nil
+				}
+				return result, err
+			case DanglingSymlinkSkip:
+				result.Skipped = true
+				return result, nil
+			default:
+				target, _ := os.Readlink(src)
+				return result, &DanglingSymlinkError{src, target}
+			}
 		}
-		srcStat, err = os.Stat(src)
+		metaSrc, err = filepath.EvalSymlinks(src)
 		if err != nil {
-			return err
+			return result, err
 		}
 	}
 
 	// Do the actual copy
-	fsrc, err := os.Open(src)
+	digester, err := newDigester(options.Hashes)
 	if err != nil {
-		return err
+		return result, err
+	}
+	// Whatever is read is written to tee, so it can be hashed and its
+	// progress watched
+	var tees []io.Writer
+	if digester != nil {
+		tees = append(tees, digester)
+	}
+	watch := newStallWatch(options)
+	if watch != nil {
+		tees = append(tees, watch)
+	}
+	var tee io.Writer
+	switch len(tees) {
+	case 0:
+	case 1:
+		tee = tees[0]
+	default:
+		tee = io.MultiWriter(tees...)
+	}
+	fsrc, err := openSource(src, options.NoAtime)
+	if err != nil {
+		return result, err
 	}
 	defer fsrc.Close()
+	if options.Cache != CacheDefault {
+		adviseSequential(fsrc)
+	}
 
 	fdst, err := os.Create(dst)
 	if err != nil {
-		return err
+		return result, err
 	}
 	defer fdst.Close()
+	if options.PreserveInodeFlags {
+		copyNoCOWFlag(fdst, fsrc)
+	}
 
-	size, err := io.Copy(fdst, fsrc)
+	// Clone the file where the filesystem can. If it can't, nothing has
+	// been written. A source of unknown size is read until it ends.
+	var size int64
+	var srcSum []byte
+	pipe := IsFIFO(srcStat)
+	sizeUnknown := options.SizeUnknown || pipe || (options.AllowSpecialSources && IsDevice(srcStat))
+	text := false
+	if options.Text != nil && srcStat.Mode().IsRegular() {
+		text, err = options.Text.isText(src, fsrc)
+		if err != nil {
+			return result, err
+		}
+	}
+	copyContents := func(ctx context.Context) (int64, []byte, error) {
+		switch {
+		case text:
+			n, err := copyText(ctx, fdst, fsrc, tee, options)
+			return n, nil, err
+		case pipe:
+			return copyPipe(ctx, fdst, fsrc, options.BufferSize, options.Verify, tee)
+		case !sizeUnknown && tee == nil && cloneFile(fdst, fsrc) == nil:
+			return srcStat.Size(), nil, nil
+		}
+		expected := srcStat.Size()
+		if sizeUnknown {
+			expected = -1
+		}
+		if options.Preallocate && expected > 0 {
+			if err := preallocate(fdst, expected); err != nil {
+				return 0, nil, err
+			}
+		}
+		return copyFileData(ctx, fdst, fsrc, expected, tee, options)
+	}
+	if watch != nil {
+		size, srcSum, err = watch.run(ctx, src, dst, copyContents)
+	} else {
+		size, srcSum, err = copyContents(ctx)
+	}
+	result.Bytes = size
+	if err == nil && !sizeUnknown && !text && !options.SizeCheck.ok(size, srcStat.Size()) {
+		err = &ShortCopyError{src, dst, srcStat.Size(), size}
+	}
 	if err != nil {
-		return err
+		fdst.Close()
+		cleanUpPartial(dst, options.PartialFiles)
+		return result, err
 	}
 
-	if size != srcStat.Size() {
-		return fmt.Errorf("%s: %d/%d copied", src, size, srcStat.Size())
+	if digester != nil {
+		result.Digests = digester.digests()
 	}
 
-	return nil
+	if options.Cache == CacheDrop && size >= largeFileSize {
+		dropCache(fsrc, fdst)
+	}
+
+	// Close first, so nothing written afterwards changes the times
+	err = fdst.Close()
+	if err != nil {
+		return result, err
+	}
+	result.Warnings, err = preserveMetadata(metaSrc, dst, srcStat, options)
+	if err != nil {
+		return result, err
+	}
+
+	if options.Verify && srcSum != nil {
+		err = verifyHash(src, dst, srcSum)
+		if err != nil {
+			return result, err
+		}
+	} else if options.Verify && !text {
+		same, err := SameContent(src, dst)
+		if err != nil {
+			return result, err
+		}
+		if !same {
+			return result, &VerifyError{src, dst}
+		}
+	}
+
+	// Verifying reads the source again, so this comes last
+	if options.RestoreSourceAtime {
+		atime, _ := fileTimes(srcStat)
+		if err := chtimes(metaSrc, atime, time.Time{}); err != nil {
+			result.Warnings = append(result.Warnings, err)
+		}
+	}
+
+	// Copy the mode bits, as CopyMode() would, unless they're mapped
+	if copyMode && options.ModeMapper == nil {
+		err = os.Chmod(dst, srcStat.Mode())
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// Immutable and append-only flags stop the copy being changed, so
+	// they go last
+	if options.PreserveInodeFlags {
+		warning, err := copyInodeFlags(metaSrc, dst)
+		if err != nil {
+			return result, err
+		}
+		if warning != nil {
+			result.Warnings = append(result.Warnings, warning)
+		}
+	}
+
+	if withoutSymlink != nil {
+		result.Warnings = append(result.Warnings, withoutSymlink.warnings(dst, options)...)
+	}
+	return result, nil
 }
 
 // Copy mode bits from src to dst.
 //
 // If followSymlinks is false, symlinks aren't followed if and only
-// if both `src` and `dst` are symlinks. If `lchmod` isn't available
-// and both are symlinks this does nothing. (I don't think lchmod is
-// available in Go)
+// if both `src` and `dst` are symlinks, and the link dst is given the
+// mode of the link src with Lchmod(). Where the platform can't change the
+// mode of a link this does nothing; use CopyModeResult() to get an
+// UnsupportedWarning when that happens.
 func CopyMode(src, dst string, followSymlinks bool) error {
-	srcStat, err := os.Lstat(src)
-	if err != nil {
-		return err
+	_, err := CopyModeResult(src, dst, followSymlinks)
+	return err
+}
+
+// Copy the mode bits from src to dst like CopyMode(), returning what
+// couldn't be copied as warnings in the result. If followSymlinks is false
+// and both are symbolic links, the link dst is given the mode of the link
+// src with Lchmod(), or where the platform can't, an UnsupportedWarning
+// is returned.
+func CopyModeResult(src, dst string, followSymlinks bool) (CopyResult, error) {
+	return CopyModeInfo(src, dst, nil, followSymlinks)
+}
+
+// Give the symbolic link dst the mode of the link srcStat describes, or
+// add an UnsupportedWarning to the result if the platform can't.
+func copyLinkMode(dst string, srcStat os.FileInfo, result *CopyResult) error {
+	err := Lchmod(dst, srcStat.Mode())
+	var notSupported *NotSupportedError
+	if errors.As(err, &notSupported) {
+		result.Warnings = append(result.Warnings, &UnsupportedWarning{"lchmod", dst})
+		return nil
 	}
+	return err
+}
 
-	dstStat, err := os.Lstat(dst)
+// Recreate the symbolic link src at dst.
+func copySymlink(src, dst string, srcStat os.FileInfo, options *CopyOptions) (CopyResult, error) {
+	result := CopyResult{Dst: dst, Symlink: true}
+	linkTo, err := os.Readlink(src)
 	if err != nil {
-		return err
+		return result, err
 	}
-
-	// They are both symlinks and we can't change mode on symlinks.
-	if !followSymlinks && IsSymlink(srcStat) && IsSymlink(dstStat) {
-		return nil
+	err = os.Symlink(linkTo, dst)
+	if err != nil {
+		return result, downgradeSymlink(dst, err, options)
 	}
+	result.Warnings, err = preserveMetadata(src, dst, srcStat, options)
+	return result, err
+}
 
-	// Atleast one is not a symlink, get the actual file stats
-	srcStat, _ = os.Stat(src)
-	err = os.Chmod(dst, srcStat.Mode())
+// Copy the mode bits, access time and modification time from src to dst.
+// The file contents and ownership are unaffected.
+//
+// If followSymlinks is false and both `src` and `dst` are symlinks, the
+// mode bits and times of the links themselves are copied, where the
+// platform allows, as with CopyMode().
+func CopyStat(src, dst string, followSymlinks bool) error {
+	_, err := CopyStatResult(src, dst, followSymlinks)
 	return err
 }
 
+// Copy the mode bits, access time and modification time from src to dst
+// like CopyStat(), returning what couldn't be copied exactly as warnings
+// in the result, as CopyModeResult() does.
+func CopyStatResult(src, dst string, followSymlinks bool) (CopyResult, error) {
+	return CopyStatInfo(src, dst, nil, followSymlinks)
+}
+
 // Copy data and mode bits ("cp src dst"). Return the file's destination.
 //
 // The destination may be a directory.
@@ -184,37 +611,152 @@ func CopyMode(src, dst string, followSymlinks bool) error {
 // If source and destination are the same file, a SameFileError will be
 // rased.
 func Copy(src, dst string, followSymlinks bool) (string, error) {
-	dstInfo, err := os.Stat(dst)
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{FollowSymlinks: followSymlinks})
+	return result.Dst, err
+}
 
-	if err == nil && dstInfo.Mode().IsDir() {
-		dst = filepath.Join(dst, filepath.Base(src))
-	}
+// Copy data and metadata ("cp -p src dst"), like Python's copy2(). Return
+// the file's destination.
+//
+// This is Copy() that also keeps the access and modification times, and
+// extended attributes where the filesystems support them. Metadata that
+// can't be kept for want of permission or support is left behind, as
+// with the DegradePreservation option.
+func Copy2(src, dst string, followSymlinks bool) (string, error) {
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{
+		FollowSymlinks:      followSymlinks,
+		PreserveTimes:       true,
+		PreserveXattrs:      true,
+		DegradePreservation: true,
+	})
+	return result.Dst, err
+}
 
-	if err != nil && !os.IsNotExist(err) {
-		return dst, err
-	}
+// Copy data and mode bits like Copy(), with the given options. This is
+// the default CopyFunc2.
+func CopyContext(ctx context.Context, src, dst string, options *CopyOptions) (CopyResult, error) {
+	return copyContext(ctx, src, dst, nil, options)
+}
 
-	err = CopyFile(src, dst, followSymlinks)
-	if err != nil {
-		return dst, err
+// Copy src to dst like CopyContext(), given what os.Lstat() returns for
+// src if the caller already has it.
+func copyContext(ctx context.Context, src, dst string, srcLstat os.FileInfo, options *CopyOptions) (CopyResult, error) {
+	if options == nil {
+		options = &CopyOptions{}
 	}
 
-	err = CopyMode(src, dst, followSymlinks)
-	if err != nil {
-		return dst, err
+	dstStat, err := statFile(dst)
+	if err == nil && dstStat.Mode().IsDir() {
+		dst = filepath.Join(dst, filepath.Base(src))
+		dstStat, err = statFile(dst)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return CopyResult{Dst: dst}, err
 	}
 
-	return dst, nil
+	return copyFile(ctx, src, dst, srcLstat, dstStat, true, options)
 }
 
 type CopyFunc func(string, string, bool) (string, error)
 type IgnoreFunc func(string, []os.FileInfo) []string
 
+// Decides whether to skip the directory at path, which info describes,
+// and everything in it. Unlike an IgnoreFunc, it's called before the
+// directory is read, so a large subtree costs nothing to skip.
+type SkipDirFunc func(path string, info os.FileInfo) bool
+
+// Return a SkipDirFunc that skips the directories with any of the given
+// names, such as ".git" or "node_modules".
+func SkipDirsNamed(names ...string) SkipDirFunc {
+	return func(path string, info os.FileInfo) bool {
+		return stringInSlice(info.Name(), names)
+	}
+}
+
+// Copies a single entry of a tree for CopyTree. `info` describes srcPath,
+// as returned by os.Lstat().
+type CopyHandler func(srcPath, dstPath string, info os.FileInfo) error
+
+// The CopyHandler to use for each kind of file. Any that are nil get
+// CopyTree's default behavior for that kind of file.
+type CopyHandlers struct {
+	Regular   CopyHandler
+	Dir       CopyHandler
+	Symlink   CopyHandler
+	NamedPipe CopyHandler
+	Socket    CopyHandler
+	Device    CopyHandler
+}
+
 type CopyTreeOptions struct {
 	Symlinks               bool
 	IgnoreDanglingSymlinks bool
 	CopyFunction           CopyFunc
 	Ignore                 IgnoreFunc
+	Handlers               CopyHandlers
+
+	// Used instead of CopyFunction when set, and passed CopyOptions.
+	CopyFunction2 CopyFunc2
+	CopyOptions   *CopyOptions
+
+	// Called with what happens to each entry of the tree. Entries handled
+	// by custom Handlers aren't reported.
+	Events EventFunc
+
+	// Skip entries that are removed from the source after their
+	// directory is read, as happens when copying a tree that is in use,
+	// rather than failing. They are listed in TreeResult.Vanished, so
+	// like rsync's exit status 24, the copy succeeds but is incomplete.
+	SkipVanished bool
+
+	// Called with each directory in the tree other than the root, which
+	// is skipped, along with what's in it, if it returns true.
+	SkipDir SkipDirFunc
+
+	// How the symbolic links copied as links with the Symlinks flag are
+	// written, so that a tree that has been relocated stays consistent.
+	LinkStyle LinkStyle
+
+	// If the destination is inside the source, such as when copying "."
+	// to "./backup", leave it out of the copy rather than failing with a
+	// CopyIntoSelfError.
+	ExcludeDestination bool
+
+	// Copy into the destination even if it already exists, like Python's
+	// dirs_exist_ok, rather than failing with an AlreadyExistsError.
+	// Directories are merged, and files and symbolic links of the same
+	// names are replaced.
+	DirsExistOK bool
+
+	// Allow the destination to exist only as an empty directory, and fail
+	// with a ConcurrentModificationError if anything but the copy creates
+	// an entry in it while it runs, so that a deploy doesn't mix its files
+	// with those of another writing to the same place.
+	ExpectEmptyDestination bool
+
+	// With DirsExistOK, fail with a ConcurrentModificationError rather
+	// than overwrite an entry of the destination that has been created,
+	// changed or removed since its directory was first read, and when
+	// anything but the copy changes a directory by the time it's been
+	// copied. Entries are compared by identity, type, size and
+	// modification time.
+	VerifyBeforeOverwrite bool
+}
+
+// What CopyTreeContext() did. Entries handled by custom Handlers aren't
+// counted.
+type TreeResult struct {
+	Files    int   `json:"files"`
+	Dirs     int   `json:"dirs"`
+	Symlinks int   `json:"symlinks"`
+	Bytes    int64 `json:"bytes"`
+
+	// The entries skipped with the SkipVanished option.
+	Vanished []string `json:"vanished,omitempty"`
+
+	// What couldn't be preserved, as in CopyResult.Warnings, for every
+	// entry.
+	Warnings []error `json:"-"`
 }
 
 // Recursively copy a directory tree.
@@ -246,93 +788,383 @@ type CopyTreeOptions struct {
 // The optional copyFunction argument is a callable that will be used
 // to copy each file. It will be called with the source path and the
 // destination path as arguments. By default, Copy() is used, but any
-// function that supports the same signature (like Copy2() when it
-// exists) can be used.
+// function that supports the same signature (like Copy2()) can be used.
+//
+// The optional Handlers replace how particular kinds of file are copied,
+// such as to recreate device files or skip sockets, without having to
+// reimplement the traversal. Each handler is called with the source and
+// destination paths of the entry instead of the default behavior: the
+// Dir handler in place of recursing into it, and the Symlink handler
+// regardless of the Symlinks and IgnoreDanglingSymlinks flags. By
+// default named pipes, sockets and devices are passed to copyFunction.
 func CopyTree(src, dst string, options *CopyTreeOptions) error {
+	_, err := CopyTreeContext(context.Background(), src, dst, options)
+	return err
+}
+
+// Recursively copy a directory tree like CopyTree(), stopping with the
+// context's error if it is cancelled. Return what was copied, even if
+// the copy failed part way through.
+//
+// Files are copied with the optional CopyFunction2, which is passed the
+// context and CopyOptions, in preference to copyFunction. If neither is
+// set CopyContext() is used. The PreserveTimes and PreserveOwner
+// CopyOptions are also applied to the directories and, when Symlinks is
+// set, to the symbolic links that are created, which are created with
+// CreateLink() so that unprivileged Windows users get junctions.
+func CopyTreeContext(ctx context.Context, src, dst string, options *CopyTreeOptions) (TreeResult, error) {
 	if options == nil {
 		options = &CopyTreeOptions{
 			Symlinks:               false,
 			Ignore:                 nil,
-			CopyFunction:           Copy,
 			IgnoreDanglingSymlinks: false}
 	}
 
+	// Without a copy function, CopyContext() is used, passing it what
+	// the traversal already knows about each file
+	t := &treeCopier{ctx: ctx, options: options, root: src, dstRoot: dst}
+	switch {
+	case options.CopyFunction2 != nil:
+		t.copyFunction = options.CopyFunction2
+	case options.CopyFunction != nil:
+		t.copyFunction = AdaptCopyFunc(options.CopyFunction)
+	}
+	if options.CopyOptions != nil {
+		t.copyOptions = *options.CopyOptions
+	}
+	// The tree decides whether symlinks are followed
+	t.copyOptions.FollowSymlinks = false
+
+	options, err := excludeDestination(src, dst, options)
+	if err != nil {
+		return t.result, t.fail(src, dst, err)
+	}
+	t.options = options
+	err = t.copyTree(src, dst)
+	return t.result, err
+}
+
+// The state of a single CopyTreeContext() call.
+type treeCopier struct {
+	ctx          context.Context
+	options      *CopyTreeOptions
+	root         string
+	dstRoot      string
+	copyFunction CopyFunc2 // nil for copyContext()
+	copyOptions  CopyOptions
+	result       TreeResult
+
+	// The entries last seen in each destination directory being copied
+	// into, when checking for other writers
+	seen map[string]map[string]os.FileInfo
+
+	// Reused for each entry, rather than allocated afresh: the buffer the
+	// paths of an entry are built in, and the options its copy is given,
+	// which the copy function may change
+	pathBuf      []byte
+	entryOptions CopyOptions
+}
+
+func (t *treeCopier) copyTree(src, dst string) error {
 	srcFileInfo, err := os.Stat(src)
 	if err != nil {
-		return err
+		return t.fail(src, dst, err)
 	}
 
 	if !srcFileInfo.IsDir() {
-		return &NotADirectoryError{src}
+		return t.fail(src, dst, &NotADirectoryError{src})
 	}
 
-	_, err = os.Open(dst)
-	if !os.IsNotExist(err) {
-		return &AlreadyExistsError{dst}
+	_, err = os.Lstat(dst)
+	switch {
+	case t.options.ExpectEmptyDestination && dst == t.dstRoot:
+		err = checkEmptyDestination(dst)
+	case !os.IsNotExist(err) && !t.options.DirsExistOK:
+		err = &AlreadyExistsError{dst}
+	default:
+		err = nil
+	}
+	if err != nil {
+		return t.fail(src, dst, err)
 	}
 
 	entries, err := ioutil.ReadDir(src)
 	if err != nil {
-		return err
+		return t.fail(src, dst, err)
 	}
 
 	err = os.MkdirAll(dst, srcFileInfo.Mode())
+	if err != nil {
+		return t.fail(src, dst, err)
+	}
+	t.result.Dirs++
+	t.options.Events.send(Event{Kind: EventDirCreated, Src: src, Dst: dst})
+
+	if t.options.checksDestination() {
+		if err := t.watchDestination(dst); err != nil {
+			return t.fail(src, dst, err)
+		}
+	}
+	err = t.copyEntries(src, dst, entries)
 	if err != nil {
 		return err
 	}
+	if t.options.checksDestination() {
+		if err := t.verifyDestination(dst); err != nil {
+			return t.fail(src, dst, err)
+		}
+	}
+
+	// Copying the entries changes the directory's times, so they can
+	// only be preserved at the end
+	warnings, err := preserveMetadata(src, dst, srcFileInfo, &t.copyOptions)
+	t.result.Warnings = append(t.result.Warnings, warnings...)
+	if err == nil && t.copyOptions.PreserveInodeFlags {
+		var warning *InodeFlagsWarning
+		warning, err = copyInodeFlags(src, dst)
+		if warning != nil {
+			t.result.Warnings = append(t.result.Warnings, warning)
+		}
+	}
+	if err != nil {
+		return t.fail(src, dst, err)
+	}
+	return nil
+}
+
+// Report that copying src to dst failed with err, and return it, unless
+// it failed because src vanished and the SkipVanished option is set.
+func (t *treeCopier) fail(src, dst string, err error) error {
+	if t.options.SkipVanished && src != t.root && sourceVanished(src, err) {
+		t.result.Vanished = append(t.result.Vanished, src)
+		t.options.Events.send(Event{Kind: EventSkipped, Src: src, Dst: dst, Reason: SkipVanished})
+		return nil
+	}
+	t.options.Events.send(Event{Kind: EventErrored, Src: src, Dst: dst, Err: err})
+	return err
+}
+
+// Report whether err is because src no longer exists, rather than
+// something in the destination not existing.
+func sourceVanished(src string, err error) bool {
+	if !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	_, statErr := os.Lstat(src)
+	return os.IsNotExist(statErr)
+}
 
+// Copy the entries of the src directory into dst.
+func (t *treeCopier) copyEntries(src, dst string, entries []os.FileInfo) error {
 	ignoredNames := []string{}
-	if options.Ignore != nil {
-		ignoredNames = options.Ignore(src, entries)
+	if t.options.Ignore != nil {
+		ignoredNames = t.options.Ignore(src, entries)
 	}
 
+	plain := joinsPlainly(src) && joinsPlainly(dst)
 	for _, entry := range entries {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+		var srcPath, dstPath string
+		if plain {
+			srcPath, dstPath = t.entryPaths(src, dst, entry.Name())
+		} else {
+			srcPath, dstPath = filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())
+		}
 		if stringInSlice(entry.Name(), ignoredNames) {
+			t.options.Events.send(Event{Kind: EventSkipped, Src: srcPath, Dst: dstPath, Reason: SkipIgnored})
 			continue
 		}
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
 
-		entryFileInfo, err := os.Lstat(srcPath)
+		if t.options.checksDestination() {
+			if err := t.checkEntry(dstPath); err != nil {
+				return t.fail(srcPath, dstPath, err)
+			}
+		}
+		// ReadDir() has already described the entry without following it
+		err := t.copyEntry(srcPath, dstPath, entry)
 		if err != nil {
 			return err
 		}
-
-		// Deal with symlinks
-		if IsSymlink(entryFileInfo) {
-			linkTo, err := os.Readlink(srcPath)
-			if err != nil {
-				return err
-			}
-			if options.Symlinks {
-				os.Symlink(linkTo, dstPath)
-				//CopyStat(srcPath, dstPath, false)
-			} else {
-				// ignore dangling symlink if flag is on
-				_, err = os.Stat(linkTo)
-				if os.IsNotExist(err) && options.IgnoreDanglingSymlinks {
-					continue
-				}
-				_, err = options.CopyFunction(srcPath, dstPath, false)
-				if err != nil {
-					return err
-				}
-			}
-		} else if entryFileInfo.IsDir() {
-			err = CopyTree(srcPath, dstPath, options)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err = options.CopyFunction(srcPath, dstPath, false)
-			if err != nil {
-				return err
+		if t.options.checksDestination() {
+			if err := t.recordEntry(dstPath); err != nil {
+				return t.fail(srcPath, dstPath, err)
 			}
 		}
 	}
 	return nil
 }
 
+// Return the paths of the entry name in src and dst, as filepath.Join()
+// would, building both in the copier's buffer so that there is a single
+// allocation for the pair. src and dst must join plainly.
+func (t *treeCopier) entryPaths(src, dst, name string) (string, string) {
+	buf := appendPath(t.pathBuf[:0], src, name)
+	n := len(buf)
+	buf = appendPath(buf, dst, name)
+	t.pathBuf = buf
+	paths := string(buf)
+	return paths[:n], paths[n:]
+}
+
+func appendPath(buf []byte, dir, name string) []byte {
+	buf = append(buf, dir...)
+	if !os.IsPathSeparator(dir[len(dir)-1]) {
+		buf = append(buf, filepath.Separator)
+	}
+	return append(buf, name...)
+}
+
+// Report whether joining a name to dir is only a matter of putting a
+// separator between them, as it is unless dir needs cleaning, is "." or is
+// a bare volume name.
+func joinsPlainly(dir string) bool {
+	return dir != "" && dir != "." && filepath.VolumeName(dir) != dir && filepath.Clean(dir) == dir
+}
+
+// Copy an entry of a tree with the handler from the dispatch table. The
+// default handlers are called directly, rather than as method values, so
+// there's nothing to allocate for each entry.
+func (t *treeCopier) copyEntry(srcPath, dstPath string, fi os.FileInfo) error {
+	handlers := t.options.Handlers
+	kind := KindOf(fi.Mode())
+	switch kind {
+	case KindSymlink:
+		if handlers.Symlink != nil {
+			return handlers.Symlink(srcPath, dstPath, fi)
+		}
+		return t.copySymlink(srcPath, dstPath, fi)
+	case KindDir:
+		if t.options.SkipDir != nil && t.options.SkipDir(srcPath, fi) {
+			t.options.Events.send(Event{Kind: EventSkipped, Src: srcPath, Dst: dstPath, Reason: SkipSubtree})
+			return nil
+		}
+		if handlers.Dir != nil {
+			return handlers.Dir(srcPath, dstPath, fi)
+		}
+		return t.copyTree(srcPath, dstPath)
+	}
+
+	var handler CopyHandler
+	switch kind {
+	case KindNamedPipe:
+		handler = handlers.NamedPipe
+	case KindSocket:
+		handler = handlers.Socket
+	case KindBlockDevice, KindCharDevice:
+		handler = handlers.Device
+	default:
+		handler = handlers.Regular
+	}
+	if handler != nil {
+		return handler(srcPath, dstPath, fi)
+	}
+	return t.copyRegular(srcPath, dstPath, fi)
+}
+
+func (t *treeCopier) copyRegular(srcPath, dstPath string, info os.FileInfo) error {
+	old := t.existing(dstPath)
+	t.entryOptions = t.copyOptions
+	var result CopyResult
+	var err error
+	if t.copyFunction != nil {
+		result, err = t.copyFunction(t.ctx, srcPath, dstPath, &t.entryOptions)
+	} else {
+		result, err = copyContext(t.ctx, srcPath, dstPath, info, &t.entryOptions)
+	}
+	t.result.Bytes += result.Bytes
+	t.result.Warnings = append(t.result.Warnings, result.Warnings...)
+	if err != nil {
+		return t.fail(srcPath, dstPath, err)
+	}
+	switch {
+	case result.Symlink:
+		t.result.Symlinks++
+		t.options.Events.send(Event{Kind: EventSymlinkCreated, Src: srcPath, Dst: result.Dst, Old: old})
+	case result.Skipped:
+		t.options.Events.send(Event{Kind: EventSkipped, Src: srcPath, Dst: dstPath, Reason: SkipDanglingSymlink})
+	default:
+		t.result.Files++
+		t.options.Events.send(Event{Kind: EventFileCopied, Src: srcPath, Dst: result.Dst, Bytes: result.Bytes, Digests: result.Digests, Old: old})
+	}
+	return nil
+}
+
+// Describe what is at dstPath before it's copied over, for its event,
+// which can only be something with the DirsExistOK option.
+func (t *treeCopier) existing(dstPath string) os.FileInfo {
+	if !t.options.DirsExistOK || t.options.Events == nil {
+		return nil
+	}
+	info, err := os.Lstat(dstPath)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+func (t *treeCopier) copySymlink(srcPath, dstPath string, info os.FileInfo) error {
+	linkTo, err := os.Readlink(srcPath)
+	if err != nil {
+		return t.fail(srcPath, dstPath, err)
+	}
+	if t.options.Symlinks {
+		old := t.existing(dstPath)
+		linkTo, err = copiedLinkTarget(linkTo, srcPath, t.root, dstPath, t.dstRoot, t.options.LinkStyle)
+		if err == nil && t.options.DirsExistOK {
+			err = replaceLink(linkTo, dstPath)
+		} else if err == nil {
+			err = CreateLink(linkTo, dstPath)
+		}
+		if err != nil {
+			err = downgradeSymlink(dstPath, err, &t.copyOptions)
+			if unsupported := (*symlinksUnsupported)(nil); errors.As(err, &unsupported) {
+				return t.copyLinkTarget(srcPath, dstPath, unsupported)
+			}
+		}
+		if err == nil {
+			t.result.Symlinks++
+			var warnings []error
+			warnings, err = preserveMetadata(srcPath, dstPath, info, &t.copyOptions)
+			t.result.Warnings = append(t.result.Warnings, warnings...)
+		}
+		if err != nil {
+			return t.fail(srcPath, dstPath, err)
+		}
+		t.options.Events.send(Event{Kind: EventSymlinkCreated, Src: srcPath, Dst: dstPath, Old: old})
+		return nil
+	}
+	// ignore dangling symlink if flag is on
+	_, err = os.Stat(srcPath)
+	if os.IsNotExist(err) && t.options.IgnoreDanglingSymlinks {
+		t.options.Events.send(Event{Kind: EventSkipped, Src: srcPath, Dst: dstPath, Reason: SkipDanglingSymlink})
+		return nil
+	}
+	return t.copyRegular(srcPath, dstPath, info)
+}
+
+// Copy what the link srcPath points to in its place, as the destination
+// can't have symbolic links and they're to be done without, or skip it if
+// it's dangling.
+func (t *treeCopier) copyLinkTarget(srcPath, dstPath string, unsupported *symlinksUnsupported) error {
+	info, err := os.Stat(srcPath)
+	switch {
+	case os.IsNotExist(err):
+		t.options.Events.send(Event{Kind: EventSkipped, Src: srcPath, Dst: dstPath, Reason: SkipDanglingSymlink})
+	case err != nil:
+		return t.fail(srcPath, dstPath, err)
+	case info.IsDir():
+		err = t.copyTree(srcPath, dstPath)
+	default:
+		err = t.copyRegular(srcPath, dstPath, info)
+	}
+	if err == nil {
+		t.result.Warnings = append(t.result.Warnings, unsupported.warnings(dstPath, &t.copyOptions)...)
+	}
+	return err
+}
+
 // Determines if a file represented
 // by `path` is a directory or not
 func isDirectory(path string) (bool, error) {
@@ -359,113 +1191,194 @@ type MoveOptions struct {
 //
 // If the destination is in our current file system, then rename() is used. Otherwise,
 // src is copied to the destination and then removed. Symlinks are recreated under the new
-// name if os.rename() fails because of cross filesystem renames.
+// name if os.rename() fails because of cross filesystem renames. So are named pipes and
+// device files where the platform supports it, otherwise a CrossDeviceError is returned,
+// as it is for sockets.
 //
 // The optional `copy_function` argument is a callable the will be used to copy the source
 // or it will be delegated to `copytree`. By default copy2() is used, but any function
 // that supports the same signature (like copy()) can be used.
 //
-
+// Every error is returned as a MoveError, wrapping the error that caused it, along with
+// the destination Move was using. The MoveError records whether the source had been
+// copied already, so callers can tell whether it now exists in both places.
 func Move(src, dst string, options *MoveOptions) (string, error) {
 	if options == nil {
-		options = &MoveOptions{
-			CopyFunction: Copy,
-		}
+		options = &MoveOptions{}
+	}
+	copyFunction := options.CopyFunction
+	if copyFunction == nil {
+		copyFunction = Copy2
 	}
 	real_dst := dst
 
+	fail := func(op string, copied bool, err error) (string, error) {
+		return real_dst, &MoveError{src, real_dst, op, copied, err}
+	}
+
 	// dst might not exist so ignore any errors
 	// (matching Pythons os.path.isdir())
 	isDirDst, _ := isDirectory(dst)
 
 	if isDirDst {
-		if samefile(src, dst) {
+		same, err := SamePath(src, dst)
+		if err != nil {
+			return fail("rename", false, err)
+		}
+		if same {
 			// We might be on a case insentive file system,
 			// perform the rename anyway
-			return dst, os.Rename(src, dst)
+			if err := os.Rename(src, dst); err != nil {
+				return fail("rename", false, err)
+			}
+			return dst, nil
 		}
-		real_dst = path.Join(dst, path.Base(src))
-		if _, err := os.Stat(real_dst); err == nil {
-			return "", &AlreadyExistsError{dst}
+		real_dst = filepath.Join(dst, filepath.Base(src))
+		if _, err := os.Lstat(real_dst); err == nil {
+			return fail("rename", false, &AlreadyExistsError{real_dst})
 		}
 	}
+	// Renaming a directory into itself fails anyway, but copying it would
+	// never finish
+	if srcStat, err := os.Lstat(src); err == nil && srcStat.IsDir() {
+		within, err := DestWithinSrc(src, real_dst)
+		if err != nil {
+			return fail("rename", false, err)
+		}
+		if within {
+			return fail("rename", false, &MoveOntoSelfError{src, dst})
+		}
+	}
+
 	// If a rename works, do that
-	if err := os.Rename(src, real_dst); err == nil {
+	err := os.Rename(src, real_dst)
+	if err == nil {
 		return real_dst, nil
 	}
+	// Copying only helps when src and dst are on different devices,
+	// anything else is a real failure
+	if !isCrossDevice(err) {
+		return fail("rename", false, err)
+	}
 
 	srcStat, err := os.Lstat(src)
 	if err != nil {
-		return "", err
+		return fail("copy", false, err)
 	}
 
-	// If the source is a symlink then handle that
-	if IsSymlink(srcStat) {
+	switch {
+	case IsSymlink(srcStat):
+		// If the source is a symlink then recreate it
 		linkto, err := os.Readlink(src)
 		if err != nil {
-			return "", err
+			return fail("copy", false, err)
 		}
 		err = os.Symlink(linkto, real_dst)
 		if err != nil {
-			return "", err
-		}
-		err = os.Remove(src)
-		if err != nil {
-			return "", err
-		}
-		return real_dst, nil
-	}
-
-	isSrcDir, _ := isDirectory(src)
-
-	if isSrcDir {
-		insrc, err := destinsrc(src, dst)
-		if err != nil {
-			return "", err
-		}
-		if insrc {
-			return "", &MoveOntoSelfError{src, dst}
+			return fail("copy", false, err)
 		}
+	case srcStat.IsDir():
 		// Skip the immutability checks for now
 		// These are hard in Golang
-		CopyTree(src, real_dst, &CopyTreeOptions{
+		err = CopyTree(src, real_dst, &CopyTreeOptions{
 			Symlinks:               true,
 			IgnoreDanglingSymlinks: false,
 			Ignore:                 nil,
-			CopyFunction:           Copy,
+			CopyFunction:           copyFunction,
 		})
-		os.RemoveAll(src)
-	} else {
-		_, err = options.CopyFunction(src, real_dst, true)
 		if err != nil {
-			return "", err
+			return fail("copy", false, err)
+		}
+		err = os.RemoveAll(src)
+		if err != nil {
+			return fail("remove", true, err)
+		}
+		return real_dst, nil
+	case srcStat.Mode().IsRegular():
+		_, err = copyFunction(src, real_dst, true)
+		if err != nil {
+			return fail("copy", false, err)
 		}
-		err = os.Remove(src)
+	default:
+		// Named pipes and devices have no contents to copy, but can be
+		// recreated if the platform (and our privileges) allow
+		err = mknodLike(real_dst, srcStat)
 		if err != nil {
-			return "", err
+			return fail("copy", false, &CrossDeviceError{src, real_dst, err})
 		}
 	}
-	return real_dst, nil
 
+	err = os.Remove(src)
+	if err != nil {
+		return fail("remove", true, err)
+	}
+	return real_dst, nil
 }
 
-func destinsrc(src, dst string) (bool, error) {
-	var err error
-	sep := string(os.PathSeparator)
+// Check that the tree copy of src to dst isn't into itself, or with the
+// ExcludeDestination option, return options that leave dst out of it.
+// Errors finding out are left to the copy to report.
+func excludeDestination(src, dst string, options *CopyTreeOptions) (*CopyTreeOptions, error) {
+	if within, _ := DestWithinSrc(src, dst); !within {
+		return options, nil
+	}
+	if !options.ExcludeDestination {
+		return options, &CopyIntoSelfError{src, dst}
+	}
+	// Where dst is in the tree, as it will be reached from src. It can
+	// only be left out if its path shows it's inside src.
+	canonicalSrc, err := Canonical(src)
+	if err != nil {
+		return options, err
+	}
+	canonicalDst, err := Canonical(dst)
+	if err != nil {
+		return options, err
+	}
+	rel, ok := relWithin(canonicalSrc, canonicalDst)
+	if !ok || rel == "." {
+		return options, &CopyIntoSelfError{src, dst}
+	}
+	excluded := filepath.Join(src, rel)
+	skipDir := options.SkipDir
+	copied := *options
+	copied.SkipDir = func(path string, info os.FileInfo) bool {
+		return path == excluded || (skipDir != nil && skipDir(path, info))
+	}
+	return &copied, nil
+}
 
-	src, err = filepath.Abs(src)
+// Report whether dst is src or inside it, so copying or moving src to dst
+// would copy it into itself. As well as comparing the paths once they are
+// made Canonical(), each directory dst is in is compared with src by
+// device and inode, which catches what the paths can't show, such as a
+// bind mount of src. Where dst doesn't exist, the part of it that does is
+// compared.
+func DestWithinSrc(src, dst string) (bool, error) {
+	within, err := IsSubpath(src, dst)
+	if err != nil || within {
+		return within, err
+	}
+	srcInfo, err := os.Stat(src)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
-	dst, err = filepath.Abs(dst)
+	dir, err := Canonical(dst)
 	if err != nil {
 		return false, err
 	}
-	if !strings.HasSuffix(src, sep) {
-		src += sep
-	}
-	if !strings.HasSuffix(src, sep) {
-		dst += sep
+	for {
+		info, err := os.Stat(dir)
+		if err == nil && os.SameFile(srcInfo, info) {
+			return true, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false, nil
+		}
+		dir = parent
 	}
-	return strings.HasPrefix(dst, src), nil
 }
@@ -1,13 +1,15 @@
 package shutil
 
 import (
-	"bytes"
-	"io/ioutil"
+	"context"
+	"errors"
 	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/gocardless/go-shutil/shutiltest"
 	. "github.com/onsi/gomega"
 )
 
@@ -15,31 +17,22 @@ const testdir = "_test"
 
 // Testing utility functions
 
-func filesMatch(src, dst string) (bool, error) {
-	srcContents, err := ioutil.ReadFile(src)
-	if err != nil {
-		return false, err
-	}
-
-	dstContents, err := ioutil.ReadFile(dst)
-	if err != nil {
-		return false, err
-	}
-
-	if !bytes.Equal(srcContents, dstContents) {
-		return false, nil
-	}
-	return true, nil
+// The tree each test starts with in testdir
+var fixture = shutiltest.Tree{
+	"testfile":      shutiltest.File("testfile\n"),
+	"testfile2":     shutiltest.File("testfile2\n"),
+	"testdir/file1": shutiltest.File("file1\n"),
+	"testdir/file2": shutiltest.File("file2\n"),
 }
 
-func setup() {
-	cmd := exec.Command("cp", "-a", "test", testdir)
-	cmd.Run()
+func setup(t *testing.T) {
+	teardown()
+	shutiltest.CreateTree(t, testdir, fixture)
+	t.Cleanup(teardown)
 }
 
 func teardown() {
-	cmd := exec.Command("rm", "-rf", testdir)
-	cmd.Run()
+	os.RemoveAll(testdir)
 }
 
 func makeTestPath(p string) string {
@@ -49,8 +42,7 @@ func makeTestPath(p string) string {
 // CopyFile Tests
 
 func TestCopyFile(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	src1 := makeTestPath("testfile")
@@ -58,17 +50,36 @@ func TestCopyFile(t *testing.T) {
 	dst := makeTestPath("testfile3")
 
 	g.Expect(CopyFile(src1, dst, false)).To(Succeed())
-	g.Expect(filesMatch(src1, dst)).To(BeTrue())
+	g.Expect(FilesEqual(src1, dst, nil)).To(BeTrue())
 
 	g.Expect(CopyFile(src2, dst, false)).To(Succeed())
-	g.Expect(filesMatch(src2, dst)).To(BeTrue())
+	g.Expect(FilesEqual(src2, dst, nil)).To(BeTrue())
+}
+
+// CopyStat Tests
+
+func TestCopyStat(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("testfile2")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chmod(src, 0604)).To(Succeed())
+	g.Expect(os.Chtimes(src, old, old)).To(Succeed())
+
+	g.Expect(CopyStat(src, dst, true)).To(Succeed())
+	info, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0604)))
+	g.Expect(info.ModTime()).To(Equal(old))
+	g.Expect(FilesEqual(src, dst, nil)).To(BeFalse())
 }
 
 // Copy Tests
 
 func TestCopySameFileError(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	src := makeTestPath("testfile")
@@ -77,8 +88,7 @@ func TestCopySameFileError(t *testing.T) {
 }
 
 func TestCopy(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	src1 := makeTestPath("testfile")
@@ -86,17 +96,35 @@ func TestCopy(t *testing.T) {
 	dst := makeTestPath("testfile3")
 
 	g.Expect(Copy(src1, dst, false)).To(Equal(dst))
-	g.Expect(filesMatch(src1, dst)).To(BeTrue())
+	g.Expect(FilesEqual(src1, dst, nil)).To(BeTrue())
 
 	g.Expect(Copy(src2, dst, false)).To(Equal(dst))
-	g.Expect(filesMatch(src2, dst)).To(BeTrue())
+	g.Expect(FilesEqual(src2, dst, nil)).To(BeTrue())
+}
+
+func TestCopy2(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chmod(src, 0604)).To(Succeed())
+	g.Expect(os.Chtimes(src, old, old)).To(Succeed())
+
+	dst, err := Copy2(src, makeTestPath("testdir"), true)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dst).To(Equal(makeTestPath("testdir/testfile")))
+	g.Expect(FilesEqual(src, dst, nil)).To(BeTrue())
+	info, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0604)))
+	g.Expect(info.ModTime()).To(Equal(old))
 }
 
 // CopyTree tests
 
 func TestCopyTree(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	src := makeTestPath("testdir")
@@ -106,30 +134,154 @@ func TestCopyTree(t *testing.T) {
 	dstFile := makeTestPath("testdir3/file1")
 
 	g.Expect(CopyTree(src, dst, nil)).To(Succeed())
-	g.Expect(filesMatch(srcFile, dstFile)).To(BeTrue())
+	g.Expect(FilesEqual(srcFile, dstFile, nil)).To(BeTrue())
 }
 
 func TestCopyTreeMissingSource(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	g.Expect(CopyTree(makeTestPath("testdir0"), makeTestPath("testdir3"), nil)).Should(HaveOccurred())
 }
 
 func TestCopyTreeSourceFile(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	g.Expect(CopyTree(makeTestPath("testfile"), makeTestPath("testdir3"), nil)).Should(HaveOccurred())
 }
 
+func TestCopyTreeHandlers(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	target, err := filepath.Abs(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.Symlink(target, makeTestPath("link"))).To(Succeed())
+
+	var regular, links []string
+	options := &CopyTreeOptions{
+		Handlers: CopyHandlers{
+			Regular: func(srcPath, dstPath string, info os.FileInfo) error {
+				regular = append(regular, srcPath)
+				return nil
+			},
+			Symlink: func(srcPath, dstPath string, info os.FileInfo) error {
+				links = append(links, srcPath)
+				_, err := Copy(srcPath, dstPath, true)
+				return err
+			},
+		},
+	}
+	// Outside the tree, as it can't be copied into itself
+	out := t.TempDir()
+	dst := filepath.Join(out, "testdir3")
+	g.Expect(CopyTree(testdir, dst, options)).To(Succeed())
+
+	g.Expect(regular).To(ConsistOf(
+		makeTestPath("testfile"),
+		makeTestPath("testfile2"),
+		makeTestPath("testdir/file1"),
+		makeTestPath("testdir/file2"),
+	))
+	g.Expect(links).To(Equal([]string{makeTestPath("link")}))
+	g.Expect(filepath.Join(dst, "testdir")).To(BeADirectory())
+	g.Expect(filepath.Join(dst, "testfile")).NotTo(BeAnExistingFile())
+	g.Expect(filepath.Join(dst, "link")).To(BeAnExistingFile())
+
+	// Skipping directories entirely
+	options.Handlers.Dir = func(srcPath, dstPath string, info os.FileInfo) error {
+		return nil
+	}
+	g.Expect(CopyTree(testdir, filepath.Join(out, "testdir4"), options)).To(Succeed())
+	g.Expect(filepath.Join(out, "testdir4", "testdir")).NotTo(BeADirectory())
+}
+
+func TestCopyTreeIntoSelf(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Symlink("testdir", makeTestPath("link"))).To(Succeed())
+	for _, dst := range []string{"testdir/backup", "link/backup", "testdir/sub/backup"} {
+		_, err := CopyTreeContext(context.Background(), makeTestPath("testdir"), makeTestPath(dst), nil)
+		var selfErr *CopyIntoSelfError
+		g.Expect(errors.As(err, &selfErr)).To(BeTrue(), dst)
+		_, err = PlanCopyTree(makeTestPath("testdir"), makeTestPath(dst), nil)
+		g.Expect(errors.As(err, &selfErr)).To(BeTrue(), dst)
+	}
+	g.Expect(makeTestPath("testdir/backup")).NotTo(BeADirectory())
+
+	_, err := Move(makeTestPath("testdir"), makeTestPath("link/moved"), nil)
+	var selfErr *MoveOntoSelfError
+	g.Expect(errors.As(err, &selfErr)).To(BeTrue())
+	g.Expect(makeTestPath("testdir/file1")).To(BeAnExistingFile())
+}
+
+func TestCopyTreeExcludeDestination(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	options := &CopyTreeOptions{ExcludeDestination: true}
+	for _, dst := range []string{"backup", "testdir/nested/backup"} {
+		_, err := CopyTreeContext(context.Background(), testdir, makeTestPath(dst), options)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(makeTestPath(dst + "/testdir/file1")).To(BeAnExistingFile())
+		g.Expect(makeTestPath(dst + "/" + dst)).NotTo(BeADirectory())
+	}
+
+	plan, err := PlanCopyTree(makeTestPath("testdir"), makeTestPath("testdir/nested/again"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, action := range plan.Actions {
+		g.Expect(action.Src).NotTo(HavePrefix(makeTestPath("testdir/nested/again")))
+	}
+
+	// Copying a tree onto itself can't leave anything out
+	_, err = CopyTreeContext(context.Background(), testdir, testdir, options)
+	var selfErr *CopyIntoSelfError
+	g.Expect(errors.As(err, &selfErr)).To(BeTrue())
+}
+
+func TestCopyTreeDirsExistOK(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	g.Expect(os.Symlink("file2", makeTestPath("testdir/link"))).To(Succeed())
+	dst := makeTestPath("out")
+	shutiltest.CreateTree(t, dst, shutiltest.Tree{
+		"file1": shutiltest.File("stale\n"),
+		"link":  shutiltest.Symlink("elsewhere"),
+		"extra": shutiltest.File("extra\n"),
+	})
+
+	_, err := CopyTreeContext(context.Background(), src, dst, &CopyTreeOptions{Symlinks: true})
+	g.Expect(err).To(MatchError(&AlreadyExistsError{dst}))
+	_, err = PlanCopyTree(src, dst, nil)
+	g.Expect(err).To(MatchError(&AlreadyExistsError{dst}))
+
+	// Both the direct copy and one applying a plan merge into dst
+	for _, parallel := range []bool{false, true} {
+		options := &CopyTreeOptions{Symlinks: true, DirsExistOK: true}
+		if parallel {
+			plan, err := PlanCopyTree(src, dst, options)
+			g.Expect(err).NotTo(HaveOccurred())
+			_, err = Apply(context.Background(), plan, &ApplyOptions{Parallel: 2})
+			g.Expect(err).NotTo(HaveOccurred())
+		} else {
+			_, err = CopyTreeContext(context.Background(), src, dst, options)
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+		g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("out/file1"), nil)).To(BeTrue())
+		g.Expect(os.Readlink(makeTestPath("out/link"))).To(Equal("file2"))
+		g.Expect(makeTestPath("out/extra")).To(BeAnExistingFile())
+		g.Expect(os.WriteFile(makeTestPath("out/file1"), []byte("stale\n"), 0644)).To(Succeed())
+	}
+}
+
 // Move tests
 
 func TestSimpleMove(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	src := makeTestPath("testdir")
@@ -145,32 +297,71 @@ func TestSimpleMove(t *testing.T) {
 }
 
 func TestMoveExisting(t *testing.T) {
-	setup()
-	t.Cleanup(teardown)
+	setup(t)
 	g := NewWithT(t)
 
 	src := makeTestPath("testdir")
 	dst := testdir
 
 	// Should fail because target exists already
-	_, err := Move(src, dst, nil)
-	g.Expect(err).Should(HaveOccurred())
+	realDst, err := Move(src, dst, nil)
+	g.Expect(realDst).To(Equal(src))
+
+	var moveErr *MoveError
+	g.Expect(errors.As(err, &moveErr)).To(BeTrue())
+	g.Expect(moveErr.Op).To(Equal("rename"))
+	g.Expect(moveErr.Copied).To(BeFalse())
+
+	var existsErr *AlreadyExistsError
+	g.Expect(errors.As(err, &existsErr)).To(BeTrue())
+	g.Expect(existsErr.Dst).To(Equal(src))
+}
+
+func TestMoveMissingSource(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("testdir2")
+	realDst, err := Move(makeTestPath("missing"), dst, nil)
+	g.Expect(realDst).To(Equal(dst))
+	g.Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
 }
 
 // Private function tests
 
-func TestDestInSrcTrue(t *testing.T) {
+func TestDestWithinSrcTrue(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(DestWithinSrc("_test", "_test/testdir/")).To(BeTrue())
+	g.Expect(DestWithinSrc("_test/", "_test/testdir")).To(BeTrue())
+	g.Expect(DestWithinSrc("_test/", "_test/testdir/")).To(BeTrue())
+	g.Expect(DestWithinSrc("_test/testdir", "_test/testdir")).To(BeTrue())
+}
+
+func TestDestWithinSrcFalse(t *testing.T) {
 	g := NewWithT(t)
 
-	g.Expect(destinsrc("_test", "_test/testdir/")).To(BeTrue())
-	g.Expect(destinsrc("_test/", "_test/testdir")).To(BeTrue())
-	g.Expect(destinsrc("_test/", "_test/testdir/")).To(BeTrue())
+	g.Expect(DestWithinSrc("_test/testdir", "_test/empty/")).To(BeFalse())
+	g.Expect(DestWithinSrc("_test/testdir/", "_test/empty")).To(BeFalse())
+	g.Expect(DestWithinSrc("_test/testdir/", "_test/empty/")).To(BeFalse())
+	g.Expect(DestWithinSrc("_test/testdir", "_test/testdir2")).To(BeFalse())
 }
 
-func TestDestInSrcFalse(t *testing.T) {
+func TestEntryPaths(t *testing.T) {
 	g := NewWithT(t)
 
-	g.Expect(destinsrc("_test/testdir", "_test/empty/")).To(BeFalse())
-	g.Expect(destinsrc("_test/testdir/", "_test/empty")).To(BeFalse())
-	g.Expect(destinsrc("_test/testdir/", "_test/empty/")).To(BeFalse())
+	var tc treeCopier
+	out := filepath.FromSlash("_test/out")
+	for _, dir := range []string{"_test", "_test/testdir", "/", "/tmp", "_test/", ".", "", "a/../b", "./a"} {
+		dir = filepath.FromSlash(dir)
+		if !joinsPlainly(dir) {
+			continue
+		}
+		src, dst := tc.entryPaths(dir, out, "file1")
+		g.Expect(src).To(Equal(filepath.Join(dir, "file1")), dir)
+		g.Expect(dst).To(Equal(filepath.Join(out, "file1")), dir)
+	}
+	g.Expect(joinsPlainly(".")).To(BeFalse())
+	g.Expect(joinsPlainly(filepath.FromSlash("_test/"))).To(BeFalse())
+	g.Expect(joinsPlainly(filepath.FromSlash("/"))).To(BeTrue())
 }
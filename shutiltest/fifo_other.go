@@ -0,0 +1,11 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutiltest
+
+import (
+	"os"
+)
+
+func mkfifo(p string, perm os.FileMode) error {
+	return errNoFifos
+}
@@ -0,0 +1,17 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutiltest
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func mkfifo(p string, perm os.FileMode) error {
+	err := unix.Mkfifo(p, uint32(perm))
+	if err != nil {
+		return &os.PathError{Op: "mkfifo", Path: p, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package shutiltest
+
+import (
+	"fmt"
+	"path"
+)
+
+// Describes a synthetic tree for GenerateTree(), so that benchmarks and
+// tests can be scaled without listing every file.
+type TreeSpec struct {
+	// The number of levels of directories below the root.
+	Depth int
+
+	// The number of subdirectories of each directory above the deepest
+	// level.
+	Fanout int
+
+	// The number of regular files in each directory, including the root.
+	Files int
+
+	// The size of each regular file, in bytes.
+	FileSize int64
+
+	// The number of symbolic links in each directory, each pointing to
+	// one of the files beside it, or to a missing file if there are none.
+	Symlinks int
+}
+
+// Return a tree of files laid out as described by spec, which can be
+// passed to CreateTree(). Files hold the same data, which doesn't repeat
+// within the first 251 bytes so that it doesn't compress trivially.
+func GenerateTree(spec TreeSpec) Tree {
+	data := make([]byte, spec.FileSize)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	g := &generator{spec: spec, contents: string(data), tree: Tree{}}
+	g.generate("", 0)
+	return g.tree
+}
+
+// Return the number of directories, regular files and symbolic links in
+// the tree described by spec, not counting the root.
+func (spec TreeSpec) Count() (dirs, files, symlinks int) {
+	levels := 1
+	for depth := 1; depth <= spec.Depth; depth++ {
+		levels *= spec.Fanout
+		dirs += levels
+	}
+	return dirs, (dirs + 1) * spec.Files, (dirs + 1) * spec.Symlinks
+}
+
+type generator struct {
+	spec     TreeSpec
+	contents string
+	tree     Tree
+}
+
+func (g *generator) generate(dir string, depth int) {
+	for i := 0; i < g.spec.Files; i++ {
+		g.tree[path.Join(dir, fmt.Sprintf("file%d", i))] = File(g.contents)
+	}
+	for i := 0; i < g.spec.Symlinks; i++ {
+		target := "missing"
+		if g.spec.Files > 0 {
+			target = fmt.Sprintf("file%d", i%g.spec.Files)
+		}
+		g.tree[path.Join(dir, fmt.Sprintf("link%d", i))] = Symlink(target)
+	}
+	if depth == g.spec.Depth {
+		if g.spec.Files == 0 && g.spec.Symlinks == 0 && dir != "" {
+			g.tree[dir] = Dir()
+		}
+		return
+	}
+	for i := 0; i < g.spec.Fanout; i++ {
+		g.generate(path.Join(dir, fmt.Sprintf("dir%d", i)), depth+1)
+	}
+}
@@ -0,0 +1,172 @@
+// Helpers for building trees of files to test code that copies, moves and
+// removes them, on any platform.
+package shutiltest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+var errNoFifos = errors.New("named pipes are not supported on this platform")
+
+// Describes a file to create with CreateTree().
+type Entry struct {
+	// The type and permission bits of the file. Only os.ModeDir,
+	// os.ModeSymlink and os.ModeNamedPipe are supported as types. If the
+	// permission bits are 0, directories get 0755 and files 0644.
+	Mode os.FileMode
+
+	// What a regular file holds.
+	Contents string
+
+	// If greater than the length of Contents, a regular file is extended
+	// to this size with a hole, making it sparse where the filesystem
+	// supports that.
+	Size int64
+
+	// The target of a symbolic link.
+	Target string
+
+	// If set, the modification time of the file. Times of symbolic links
+	// aren't set.
+	ModTime time.Time
+}
+
+// A tree of files, keyed by their paths relative to the root of the tree,
+// separated by slashes.
+type Tree map[string]Entry
+
+// A regular file holding contents.
+func File(contents string) Entry {
+	return Entry{Contents: contents}
+}
+
+// An empty directory.
+func Dir() Entry {
+	return Entry{Mode: os.ModeDir}
+}
+
+// A symbolic link to target.
+func Symlink(target string) Entry {
+	return Entry{Mode: os.ModeSymlink, Target: target}
+}
+
+// A named pipe.
+func Fifo() Entry {
+	return Entry{Mode: os.ModeNamedPipe}
+}
+
+// A file of size bytes that is all hole.
+func Sparse(size int64) Entry {
+	return Entry{Size: size}
+}
+
+// Create the tree below root, which is created if needed, failing the test
+// if anything can't be created. Missing parent directories are created
+// with mode 0755. The test is skipped if the tree has named pipes and the
+// platform doesn't support them.
+func CreateTree(t testing.TB, root string, tree Tree) {
+	t.Helper()
+
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dirs []string
+	for _, name := range names {
+		entry := tree[name]
+		p := filepath.Join(root, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(p), 0755)
+		if err == nil {
+			err = createEntry(p, entry)
+		}
+		if err == errNoFifos {
+			t.Skipf("can't create named pipe %s: %v", p, err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Mode.IsDir() {
+			dirs = append(dirs, name)
+		}
+	}
+
+	// Filling directories changes their times, so set them last, deepest
+	// first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		modTime := tree[dirs[i]].ModTime
+		if modTime.IsZero() {
+			continue
+		}
+		err = os.Chtimes(filepath.Join(root, filepath.FromSlash(dirs[i])), modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func createEntry(p string, entry Entry) error {
+	perm := entry.Mode.Perm()
+	switch {
+	case entry.Mode.IsDir():
+		if perm == 0 {
+			perm = 0755
+		}
+		err := os.Mkdir(p, perm)
+		if err != nil {
+			return err
+		}
+		// Mkdir is subject to the umask
+		return os.Chmod(p, perm)
+	case entry.Mode&os.ModeSymlink != 0:
+		return os.Symlink(entry.Target, p)
+	}
+
+	if perm == 0 {
+		perm = 0644
+	}
+	var err error
+	if entry.Mode&os.ModeNamedPipe != 0 {
+		err = mkfifo(p, perm)
+	} else {
+		err = writeFile(p, entry)
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(p, perm)
+	if err != nil {
+		return err
+	}
+	if !entry.ModTime.IsZero() {
+		return os.Chtimes(p, entry.ModTime, entry.ModTime)
+	}
+	return nil
+}
+
+func writeFile(p string, entry Entry) error {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(entry.Contents)
+	if err == nil && entry.Size > int64(len(entry.Contents)) {
+		err = f.Truncate(entry.Size)
+	}
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
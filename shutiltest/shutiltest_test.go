@@ -0,0 +1,86 @@
+package shutiltest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCreateTree(t *testing.T) {
+	g := NewWithT(t)
+	root := filepath.Join(t.TempDir(), "root")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	CreateTree(t, root, Tree{
+		"file":            File("contents"),
+		"private":         {Contents: "secret", Mode: 0600},
+		"empty":           Dir(),
+		"old":             {Mode: os.ModeDir, ModTime: old},
+		"old/file":        {Contents: "old", ModTime: old},
+		"nested/dir/file": File("nested"),
+		"sparse":          Sparse(1 << 20),
+	})
+
+	data, err := ioutil.ReadFile(filepath.Join(root, "nested", "dir", "file"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(data)).To(Equal("nested"))
+
+	info, err := os.Stat(filepath.Join(root, "empty"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.IsDir()).To(BeTrue())
+
+	info, err = os.Stat(filepath.Join(root, "sparse"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Size()).To(Equal(int64(1 << 20)))
+
+	for _, name := range []string{"old", "old/file"} {
+		info, err = os.Stat(filepath.Join(root, filepath.FromSlash(name)))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.ModTime()).To(Equal(old))
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err = os.Stat(filepath.Join(root, "private"))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.Mode()).To(Equal(os.FileMode(0600)))
+	}
+}
+
+func TestCreateTreeSymlink(t *testing.T) {
+	g := NewWithT(t)
+	root := t.TempDir()
+
+	CreateTree(t, root, Tree{"link": Symlink("missing")})
+	g.Expect(os.Readlink(filepath.Join(root, "link"))).To(Equal("missing"))
+}
+
+func TestCreateTreeFifo(t *testing.T) {
+	g := NewWithT(t)
+	root := t.TempDir()
+
+	CreateTree(t, root, Tree{"fifo": {Mode: os.ModeNamedPipe | 0640}})
+	info, err := os.Lstat(filepath.Join(root, "fifo"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode()).To(Equal(os.ModeNamedPipe | 0640))
+}
+
+func TestGenerateTree(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := TreeSpec{Depth: 2, Fanout: 2, Files: 3, FileSize: 300, Symlinks: 1}
+	tree := GenerateTree(spec)
+	g.Expect(tree).To(HaveLen(7*3 + 7))
+	g.Expect(tree).To(HaveKeyWithValue("dir1/dir0/link0", Symlink("file0")))
+	g.Expect(tree["dir0/file2"].Contents).To(HaveLen(300))
+
+	dirs, files, symlinks := spec.Count()
+	g.Expect([]int{dirs, files, symlinks}).To(Equal([]int{6, 21, 7}))
+
+	tree = GenerateTree(TreeSpec{Depth: 1, Fanout: 2})
+	g.Expect(tree).To(Equal(Tree{"dir0": Dir(), "dir1": Dir()}))
+}
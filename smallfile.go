@@ -0,0 +1,63 @@
+package shutil
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// The default for CopyOptions.SmallFileSize.
+const defaultSmallFileSize = 128 << 10
+
+// Buffers for copying small files, each a *[]byte, so that copying many of
+// them at once doesn't allocate one for each.
+var smallFileBuffers sync.Pool
+
+// The size under which the options have files copied by copySmall(), or
+// zero if they don't, as they ask for an engine other than the default.
+func smallFileSize(options *CopyOptions) int64 {
+	switch {
+	case options.Engine != EngineDefault || options.SmallFileSize < 0:
+		return 0
+	case options.SmallFileSize == 0:
+		return defaultSmallFileSize
+	}
+	return int64(options.SmallFileSize)
+}
+
+// Copy src, which is size bytes long, to dst by reading it whole into a
+// buffer and writing that with a single write, which takes fewer system
+// calls than copying it a chunk at a time. A byte more than size is asked
+// for, so that a source that has grown is noticed, and the rest of it is
+// copied too, as it would be otherwise.
+func copySmall(ctx context.Context, dst, src *os.File, size int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	bufp, _ := smallFileBuffers.Get().(*[]byte)
+	if bufp == nil || int64(cap(*bufp)) <= size {
+		buf := make([]byte, size+1)
+		bufp = &buf
+	}
+	defer smallFileBuffers.Put(bufp)
+
+	buf := (*bufp)[:size+1]
+	n, err := src.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	written, err := dst.WriteAt(buf[:n], 0)
+	if err != nil || int64(n) <= size {
+		return int64(written), err
+	}
+	_, err = src.Seek(int64(n), io.SeekStart)
+	if err == nil {
+		_, err = dst.Seek(int64(n), io.SeekStart)
+	}
+	if err != nil {
+		return int64(written), err
+	}
+	rest, err := copyData(ctx, dst, src, 0)
+	return int64(written) + rest, err
+}
@@ -0,0 +1,61 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopySmallFiles(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	// Larger files after smaller ones, so pooled buffers are too small
+	for _, size := range []int{1, 1000, defaultSmallFileSize - 1, defaultSmallFileSize, 10} {
+		data := bytes.Repeat([]byte("x"), size)
+		src := makeTestPath("small")
+		g.Expect(os.WriteFile(src, data, 0644)).To(Succeed())
+
+		for _, smallFileSize := range []int{0, -1, 2} {
+			dst := makeTestPath("small2")
+			result, err := CopyContext(context.Background(), src, dst, &CopyOptions{SmallFileSize: smallFileSize})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result.Bytes).To(Equal(int64(size)))
+			g.Expect(os.ReadFile(dst)).To(Equal(data))
+		}
+	}
+}
+
+func TestSmallFileSizeEngine(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(smallFileSize(&CopyOptions{})).To(Equal(int64(defaultSmallFileSize)))
+	g.Expect(smallFileSize(&CopyOptions{SmallFileSize: 10})).To(Equal(int64(10)))
+	g.Expect(smallFileSize(&CopyOptions{SmallFileSize: -1})).To(BeZero())
+
+	// Other engines copy small files too
+	g.Expect(smallFileSize(&CopyOptions{Engine: EngineIOUring})).To(BeZero())
+	g.Expect(smallFileSize(&CopyOptions{Engine: EngineMmap, SmallFileSize: 10})).To(BeZero())
+}
+
+func TestCopySmallGrown(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src, err := os.Open(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer src.Close()
+	dst, err := os.Create(makeTestPath("testfile3"))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer dst.Close()
+
+	// As if the file had grown since it was looked up, which is copied
+	// whole
+	n, err := copySmall(context.Background(), dst, src, 4)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(int64(9)))
+	g.Expect(FilesEqual(makeTestPath("testfile"), makeTestPath("testfile3"), nil)).To(BeTrue())
+}
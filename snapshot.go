@@ -0,0 +1,67 @@
+package shutil
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Takes filesystem-level snapshots of a directory before it is changed,
+// so the change can be undone, on filesystems such as btrfs and ZFS that
+// can snapshot instantly.
+type SnapshotProvider interface {
+	// Snapshot the directory dir, returning a name for the snapshot.
+	Snapshot(ctx context.Context, dir string) (string, error)
+
+	// Return dir to how it was when the named snapshot was taken.
+	Rollback(ctx context.Context, dir, snapshot string) error
+}
+
+// A SnapshotProvider that runs commands, such as the btrfs or zfs tools.
+// In each argument, "{dir}" is replaced by the directory and "{name}" by
+// the name of the snapshot, which is "shutil-" and the time it was taken.
+// For a btrfs subvolume:
+//
+//	CommandSnapshots{
+//		SnapshotArgs: []string{"btrfs", "subvolume", "snapshot", "-r", "{dir}", "/snapshots/{name}"},
+//	}
+//
+// or for a ZFS dataset mounted at the directory:
+//
+//	CommandSnapshots{
+//		SnapshotArgs: []string{"zfs", "snapshot", "pool/data@{name}"},
+//		RollbackArgs: []string{"zfs", "rollback", "-r", "pool/data@{name}"},
+//	}
+type CommandSnapshots struct {
+	SnapshotArgs []string
+
+	// If empty, Rollback() returns a NotSupportedError.
+	RollbackArgs []string
+}
+
+func (c CommandSnapshots) Snapshot(ctx context.Context, dir string) (string, error) {
+	name := "shutil-" + time.Now().UTC().Format("20060102T150405.000000000Z")
+	return name, runSnapshotCommand(ctx, "snapshot", c.SnapshotArgs, dir, name)
+}
+
+func (c CommandSnapshots) Rollback(ctx context.Context, dir, snapshot string) error {
+	return runSnapshotCommand(ctx, "rollback", c.RollbackArgs, dir, snapshot)
+}
+
+func runSnapshotCommand(ctx context.Context, op string, args []string, dir, name string) error {
+	if len(args) == 0 {
+		return &NotSupportedError{op, dir}
+	}
+	replacer := strings.NewReplacer("{dir}", dir, "{name}", name)
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = replacer.Replace(arg)
+	}
+	out, err := exec.CommandContext(ctx, expanded[0], expanded[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(expanded, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package shutil
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// Cancels a context once it has taken a snapshot, so the sync fails.
+type cancellingSnapshots struct {
+	SnapshotProvider
+	cancel context.CancelFunc
+}
+
+func (c cancellingSnapshots) Snapshot(ctx context.Context, dir string) (string, error) {
+	defer c.cancel()
+	return c.SnapshotProvider.Snapshot(ctx, dir)
+}
+
+func TestSyncTreeSnapshot(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs a shell")
+	}
+	// Snapshots are copies next to the directory
+	snapshots := CommandSnapshots{
+		SnapshotArgs: []string{"cp", "-R", "{dir}", "{dir}-{name}"},
+		RollbackArgs: []string{"sh", "-c", `rm -rf "$0" && mv "$0-$1" "$0"`, "{dir}", "{name}"},
+	}
+	src := makeTestPath("testdir")
+	dst := makeTestPath("dst")
+	g.Expect(MakeDirs(dst, 0755, false)).To(Succeed())
+	g.Expect(Copy(makeTestPath("testfile"), dst, false)).To(Equal(makeTestPath("dst/testfile")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := SyncTree(ctx, src, dst, &SyncTreeOptions{
+		Delete:          true,
+		Snapshot:        cancellingSnapshots{snapshots, cancel},
+		RollbackOnError: true,
+	})
+	g.Expect(err).To(MatchError(context.Canceled))
+	g.Expect(result.Snapshot).To(HavePrefix("shutil-"))
+	g.Expect(Glob(makeTestPath("dst*"))).To(Equal([]string{dst}))
+	g.Expect(Glob(makeTestPath("dst/*"))).To(Equal([]string{makeTestPath("dst/testfile")}))
+
+	result, err = SyncTree(context.Background(), src, dst, &SyncTreeOptions{Delete: true, Snapshot: snapshots})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(makeTestPath("dst-" + result.Snapshot + "/testfile")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("dst/testfile")).NotTo(BeAnExistingFile())
+
+	_, err = CommandSnapshots{}.Snapshot(context.Background(), dst)
+	g.Expect(err).To(MatchError(&NotSupportedError{"snapshot", dst}))
+}
@@ -0,0 +1,105 @@
+package shutil
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Returned when copying a file makes no progress for
+// CopyOptions.StallTimeout, or takes longer than FileTimeout.
+type StalledError struct {
+	Src string
+	Dst string
+
+	// The data read from the source before giving up.
+	Copied int64
+
+	// Set if the copy took longer than FileTimeout, rather than stalling.
+	TimedOut bool
+
+	// The timeout that ran out.
+	Timeout time.Duration
+}
+
+func (e StalledError) Error() string {
+	if e.TimedOut {
+		return fmt.Sprintf("copying `%s` to `%s` took longer than %s, with %d bytes copied", e.Src, e.Dst, e.Timeout, e.Copied)
+	}
+	return fmt.Sprintf("copying `%s` to `%s` stalled for %s after %d bytes", e.Src, e.Dst, e.Timeout, e.Copied)
+}
+
+func (e StalledError) Code() ErrorCode {
+	return CodeStalled
+}
+
+// Counts the data read from a file, which is written to it, to notice
+// when copying the file stalls.
+type stallWatch struct {
+	copied  int64 // accessed atomically
+	stall   time.Duration
+	timeout time.Duration
+}
+
+// Return a stallWatch for the timeouts of the options, or nil if there
+// are none.
+func newStallWatch(options *CopyOptions) *stallWatch {
+	if options.StallTimeout <= 0 && options.FileTimeout <= 0 {
+		return nil
+	}
+	return &stallWatch{stall: options.StallTimeout, timeout: options.FileTimeout}
+}
+
+func (w *stallWatch) Write(p []byte) (int, error) {
+	atomic.AddInt64(&w.copied, int64(len(p)))
+	return len(p), nil
+}
+
+// Call copyContents, which copies src to dst and writes what it reads to
+// w, and return what it does, unless it stalls or takes too long. Then
+// its context is cancelled and a StalledError returned straight away,
+// leaving it running in case it's blocked in a read that never returns.
+func (w *stallWatch) run(ctx context.Context, src, dst string, copyContents func(context.Context) (int64, []byte, error)) (int64, []byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type outcome struct {
+		n   int64
+		sum []byte
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		n, sum, err := copyContents(ctx)
+		done <- outcome{n, sum, err}
+	}()
+
+	var deadline, tick <-chan time.Time
+	if w.timeout > 0 {
+		timer := time.NewTimer(w.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	if w.stall > 0 {
+		ticker := time.NewTicker(w.stall / 4)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	last, moved := int64(0), time.Now()
+	for {
+		select {
+		case o := <-done:
+			return o.n, o.sum, o.err
+		case <-deadline:
+			copied := atomic.LoadInt64(&w.copied)
+			return copied, nil, &StalledError{src, dst, copied, true, w.timeout}
+		case now := <-tick:
+			copied := atomic.LoadInt64(&w.copied)
+			if copied != last {
+				last, moved = copied, now
+			} else if now.Sub(moved) >= w.stall {
+				return copied, nil, &StalledError{src, dst, copied, false, w.stall}
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+// Open the named pipe for writing, write data to it, and leave it open
+// until the test ends, so that reading it stalls.
+func stallPipe(t *testing.T, fifo, data string) {
+	opened := make(chan *os.File, 1)
+	go func() {
+		w, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+		if err == nil {
+			w.WriteString(data)
+		}
+		opened <- w
+	}()
+	t.Cleanup(func() {
+		if w := <-opened; w != nil {
+			w.Close()
+		}
+	})
+}
+
+func TestCopyStallTimeout(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	fifo := makeTestPath("fifo")
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{"fifo": shutiltest.Fifo()})
+	stallPipe(t, fifo, "some data")
+
+	start := time.Now()
+	_, err := CopyContext(context.Background(), fifo, makeTestPath("out"), &CopyOptions{
+		ReadNamedPipes: true,
+		StallTimeout:   100 * time.Millisecond,
+	})
+	var stalled *StalledError
+	g.Expect(errors.As(err, &stalled)).To(BeTrue())
+	g.Expect(stalled.TimedOut).To(BeFalse())
+	g.Expect(stalled.Copied).To(Equal(int64(9)))
+	g.Expect(stalled.Timeout).To(Equal(100 * time.Millisecond))
+	g.Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	g.Expect(makeTestPath("out")).NotTo(BeAnExistingFile())
+}
+
+func TestCopyFileTimeout(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	fifo := makeTestPath("fifo")
+	shutiltest.CreateTree(t, testdir, shutiltest.Tree{"fifo": shutiltest.Fifo()})
+	stallPipe(t, fifo, "")
+
+	_, err := CopyContext(context.Background(), fifo, makeTestPath("out"), &CopyOptions{
+		ReadNamedPipes: true,
+		FileTimeout:    100 * time.Millisecond,
+		PartialFiles:   PartialLeave,
+	})
+	var stalled *StalledError
+	g.Expect(errors.As(err, &stalled)).To(BeTrue())
+	g.Expect(stalled.TimedOut).To(BeTrue())
+	g.Expect(stalled.Copied).To(BeZero())
+	g.Expect(err).To(MatchError(ContainSubstring("took longer than 100ms")))
+	g.Expect(makeTestPath("out")).To(BeAnExistingFile())
+
+	// Files that don't stall are copied as usual
+	result, err := CopyContext(context.Background(), makeTestPath("testfile"), makeTestPath("copy"), &CopyOptions{
+		StallTimeout: time.Second,
+		FileTimeout:  time.Minute,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Bytes).To(Equal(int64(9)))
+}
@@ -0,0 +1,16 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package shutil
+
+import "os"
+
+// Return how much space is allocated to a file, which isn't known on this
+// platform.
+func allocatedBytes(fi os.FileInfo) (int64, bool) {
+	return 0, false
+}
+
+// Return the device a file is on, which isn't known on this platform.
+func fileDevice(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,29 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// Return how much space is allocated to a file, which is less than its
+// size if it has holes, if the platform reports it.
+func allocatedBytes(fi os.FileInfo) (int64, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	// Blocks are always counted in 512 byte units, whatever the block
+	// size of the filesystem
+	return int64(stat.Blocks) * 512, true
+}
+
+// Return the device a file is on, if the platform reports it.
+func fileDevice(fi os.FileInfo) (uint64, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
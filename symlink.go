@@ -0,0 +1,105 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// How the symbolic links of a tree are written when it's copied.
+type LinkStyle int
+
+const (
+	// Copy links' targets as they are.
+	LinksAsIs LinkStyle = iota
+	// Make links to inside the tree relative, so the copy's links point
+	// inside the copy wherever it's moved to, and make links to outside
+	// the tree absolute, so they still point at the same place.
+	LinksRelative
+	// Make every link absolute, with links to inside the tree pointing
+	// inside the copy.
+	LinksAbsolute
+)
+
+// Create linkPath as a symbolic link to target, written relative to the
+// directory linkPath is in, so the link keeps working if the two are
+// moved together. Relative paths are relative to the current directory,
+// as with os.Symlink()'s linkPath.
+func SymlinkRel(target, linkPath string) error {
+	rel, err := RelativeLink(target, linkPath)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(rel, linkPath)
+}
+
+// Return target, the target of the symbolic link at linkPath, written
+// relative to the directory linkPath is in. An absolute target is made
+// relative, and a relative one is taken to be relative to the current
+// directory, as SymlinkRel() takes it.
+func RelativeLink(target, linkPath string) (string, error) {
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	absLink, err := filepath.Abs(linkPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(filepath.Dir(absLink), absTarget)
+}
+
+// Return target, the target of the symbolic link at linkPath, as an
+// absolute path. A relative target is relative to the directory linkPath
+// is in, as it is when the link is followed.
+func AbsoluteLink(target, linkPath string) (string, error) {
+	if filepath.IsAbs(target) {
+		return filepath.Clean(target), nil
+	}
+	absLink, err := filepath.Abs(linkPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(absLink), target), nil
+}
+
+// Return the target to give dstLink, the copy in the tree dstRoot of the
+// symbolic link srcLink in the tree srcRoot, whose target is target. The
+// paths are compared as they are written, without resolving links in
+// them.
+func copiedLinkTarget(target, srcLink, srcRoot, dstLink, dstRoot string, style LinkStyle) (string, error) {
+	if style == LinksAsIs {
+		return target, nil
+	}
+	abs, err := AbsoluteLink(target, srcLink)
+	if err != nil {
+		return "", err
+	}
+	absRoot, err := filepath.Abs(srcRoot)
+	if err != nil {
+		return "", err
+	}
+	rel, inside := relWithin(absRoot, abs)
+	if !inside {
+		return abs, nil
+	}
+	absDstRoot, err := filepath.Abs(dstRoot)
+	if err != nil {
+		return "", err
+	}
+	abs = filepath.Join(absDstRoot, rel)
+	if style == LinksAbsolute {
+		return abs, nil
+	}
+	return RelativeLink(abs, dstLink)
+}
+
+// Return path relative to root, reporting whether it is root or inside
+// it. Both are absolute and clean.
+func relWithin(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,87 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestSymlinkRel(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(SymlinkRel(makeTestPath("testdir/file1"), makeTestPath("link"))).To(Succeed())
+	g.Expect(os.Readlink(makeTestPath("link"))).To(Equal(filepath.Join("testdir", "file1")))
+	g.Expect(os.ReadFile(makeTestPath("link"))).NotTo(BeEmpty())
+
+	abs, err := filepath.Abs(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(SymlinkRel(abs, makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Readlink(makeTestPath("testdir/link"))).To(Equal(filepath.Join("..", "testfile")))
+
+	g.Expect(AbsoluteLink(filepath.Join("..", "testfile"), makeTestPath("testdir/link"))).To(Equal(abs))
+	g.Expect(AbsoluteLink(abs, "anywhere")).To(Equal(abs))
+	g.Expect(RelativeLink(abs, makeTestPath("link"))).To(Equal("testfile"))
+}
+
+func TestCopyTreeLinkStyle(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src, err := filepath.Abs(makeTestPath("src"))
+	g.Expect(err).NotTo(HaveOccurred())
+	outside, err := filepath.Abs(makeTestPath("testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	shutiltest.CreateTree(t, src, shutiltest.Tree{"a/file": shutiltest.File("x")})
+	g.Expect(os.Symlink(filepath.Join(src, "a", "file"), filepath.Join(src, "abs"))).To(Succeed())
+	g.Expect(os.Symlink(filepath.Join("..", "a", "file"), filepath.Join(src, "a", "rel"))).To(Succeed())
+	g.Expect(os.Symlink(filepath.Join("..", "..", "testfile"), filepath.Join(src, "a", "out"))).To(Succeed())
+
+	for _, test := range []struct {
+		style    LinkStyle
+		abs, rel func(dst string) string
+		out      string
+	}{
+		{
+			LinksAsIs,
+			func(string) string { return filepath.Join(src, "a", "file") },
+			func(string) string { return filepath.Join("..", "a", "file") },
+			filepath.Join("..", "..", "testfile"),
+		},
+		{
+			LinksRelative,
+			func(string) string { return filepath.Join("a", "file") },
+			func(string) string { return "file" },
+			outside,
+		},
+		{
+			LinksAbsolute,
+			func(dst string) string { return filepath.Join(dst, "a", "file") },
+			func(dst string) string { return filepath.Join(dst, "a", "file") },
+			outside,
+		},
+	} {
+		dst, err := filepath.Abs(makeTestPath("dst"))
+		g.Expect(err).NotTo(HaveOccurred())
+		options := &CopyTreeOptions{Symlinks: true, LinkStyle: test.style}
+		_, err = CopyTreeContext(context.Background(), src, dst, options)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(os.Readlink(filepath.Join(dst, "abs"))).To(Equal(test.abs(dst)))
+		g.Expect(os.Readlink(filepath.Join(dst, "a", "rel"))).To(Equal(test.rel(dst)))
+		g.Expect(os.Readlink(filepath.Join(dst, "a", "out"))).To(Equal(test.out))
+
+		// Plans write links the same way
+		plan, err := PlanCopyTree(src, makeTestPath("dst2"), options)
+		g.Expect(err).NotTo(HaveOccurred())
+		for _, action := range plan.Actions {
+			if action.Kind == ActionSymlink && action.Src == filepath.Join(src, "a", "out") {
+				g.Expect(action.Target).To(Equal(test.out))
+			}
+		}
+		g.Expect(os.RemoveAll(dst)).To(Succeed())
+	}
+}
@@ -0,0 +1,372 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Options for SyncTree().
+type SyncTreeOptions struct {
+	// How files in src are compared with those already in dst to decide
+	// whether they need copying. Metadata that is compared is also
+	// copied.
+	Compare CompareMode
+
+	// How far apart modification times can be and still match, for
+	// filesystems that store them less precisely than src's, such as FAT
+	// with its two seconds.
+	ModifyWindow time.Duration
+
+	// Remove files and directories from dst that aren't in src.
+	Delete bool
+
+	// Called like CopyTreeOptions.Ignore for each directory of src and
+	// dst. Ignored names are neither copied nor removed.
+	Ignore IgnoreFunc
+
+	// Called after each file has been copied or updated.
+	Progress ProgressFunc
+
+	// Called with what happens to each entry of the tree.
+	Events EventFunc
+
+	// Snapshots dst, if it exists, before anything in it is changed.
+	Snapshot SnapshotProvider
+
+	// Roll dst back to the snapshot if the sync fails.
+	RollbackOnError bool
+
+	// Limits the rate files are copied at, as CopyOptions.RateLimit does.
+	RateLimit *RateLimiter
+
+	// Hold a lock on dst while syncing it, as AcquireLock() takes one, so
+	// that two syncs to the same destination can't interleave. dst is
+	// created first if need be, and the lock file is left out of the sync,
+	// as is any of the same name at the top of src.
+	Lock *LockOptions
+}
+
+// What SyncTree() did.
+type SyncResult struct {
+	// The number of files, directories and symbolic links that were
+	// created or replaced in dst.
+	Copied int `json:"copied"`
+
+	// The number of files whose contents matched, but whose compared
+	// metadata was updated.
+	Updated int `json:"updated"`
+
+	// The number of files that already matched.
+	Unchanged int `json:"unchanged"`
+
+	// The number of entries removed from dst, not counting the contents
+	// of removed directories.
+	Deleted int `json:"deleted"`
+
+	// The amount of data copied.
+	Bytes int64 `json:"bytes"`
+
+	// The name of the snapshot taken of dst before it was changed, if
+	// any.
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// Make the directory tree dst match src, creating dst if needed. Unlike
+// CopyTree(), files that already match, as CmpFiles() decides with the
+// Compare option, are left alone, so syncing a tree again only copies
+// what has changed since.
+//
+// A file whose contents differ is copied again, a file whose compared
+// metadata only differs has that metadata updated, and anything of a
+// different kind in dst is replaced. Symbolic links are copied as links
+// and other special files are left out. Unless the Delete option is set,
+// files in dst that aren't in src are left alone.
+//
+// With the Snapshot option, dst is snapshotted before it is changed, and
+// with RollbackOnError it is rolled back to the snapshot if the sync
+// fails, so that on filesystems that support it, dst is either synced or
+// left as it was.
+//
+// With the Lock option, a sync that finds another holding the lock on dst
+// fails with a LockedError, once it has waited for as long as the option
+// says, without changing anything.
+func SyncTree(ctx context.Context, src, dst string, options *SyncTreeOptions) (result SyncResult, err error) {
+	if options == nil {
+		options = &SyncTreeOptions{}
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if !info.IsDir() {
+		return SyncResult{}, &NotADirectoryError{src}
+	}
+
+	s := &treeSyncer{ctx: ctx, options: options, meter: newProgressMeter(), dstRoot: dst}
+	_, statErr := os.Lstat(dst)
+	existed := statErr == nil
+	if options.Lock != nil {
+		if !existed {
+			mkdirErr := os.Mkdir(dst, 0700)
+			if mkdirErr != nil && !os.IsExist(mkdirErr) {
+				return s.result, mkdirErr
+			}
+			s.rootCreated = mkdirErr == nil
+		}
+		lock, lockErr := AcquireLock(dst, options.Lock)
+		if lockErr != nil {
+			return s.result, lockErr
+		}
+		defer func() {
+			if releaseErr := lock.Release(); err == nil {
+				err = releaseErr
+			}
+		}()
+	}
+	if options.Snapshot != nil {
+		if existed {
+			s.result.Snapshot, err = options.Snapshot.Snapshot(ctx, dst)
+			if err != nil {
+				return s.result, err
+			}
+		}
+	}
+
+	err = s.syncDir(src, dst, info)
+	if err != nil && s.result.Snapshot != "" && options.RollbackOnError {
+		// The context may be why the sync failed, but the rollback
+		// shouldn't be cancelled too
+		rollbackErr := options.Snapshot.Rollback(context.Background(), dst, s.result.Snapshot)
+		if rollbackErr != nil {
+			return s.result, &MultiError{[]error{err, rollbackErr}}
+		}
+	}
+	return s.result, err
+}
+
+// The state of a single SyncTree() call.
+type treeSyncer struct {
+	ctx     context.Context
+	options *SyncTreeOptions
+	result  SyncResult
+	meter   *progressMeter
+
+	// The root of dst, which was created to be locked if rootCreated is
+	// set, so the sync still has to give it src's mode
+	dstRoot     string
+	rootCreated bool
+}
+
+func (s *treeSyncer) syncDir(src, dst string, info os.FileInfo) error {
+	err := os.Mkdir(dst, 0700)
+	if s.rootCreated && dst == s.dstRoot {
+		s.rootCreated, err = false, nil
+	}
+	created := err == nil
+	if created {
+		s.result.Copied++
+		s.options.Events.send(Event{Kind: EventDirCreated, Src: src, Dst: dst})
+	} else if !os.IsExist(err) {
+		return s.fail(src, dst, err)
+	}
+
+	srcEntries, err := s.entries(src)
+	if err != nil {
+		return s.fail(src, dst, err)
+	}
+	dstEntries, err := s.entries(dst)
+	if err != nil {
+		return s.fail(src, dst, err)
+	}
+	// The lock file is neither copied over nor deleted
+	if s.options.Lock != nil && dst == s.dstRoot {
+		delete(srcEntries, LockFileName)
+		delete(dstEntries, LockFileName)
+	}
+
+	for _, name := range sortedNames(srcEntries) {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		err = s.syncEntry(filepath.Join(src, name), filepath.Join(dst, name), srcEntries[name], dstEntries[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.options.Delete {
+		for _, name := range sortedNames(dstEntries) {
+			if _, ok := srcEntries[name]; ok {
+				continue
+			}
+			path := filepath.Join(dst, name)
+			err = os.RemoveAll(path)
+			if err != nil {
+				return s.fail("", path, err)
+			}
+			s.result.Deleted++
+			s.options.Events.send(Event{Kind: EventRemoved, Dst: path, Old: dstEntries[name]})
+		}
+	}
+
+	// Syncing the entries changes the directory's times, so its metadata
+	// is synced last. New directories always get the same permission bits
+	// as in src, as new files do.
+	if created {
+		err = os.Chmod(dst, info.Mode().Perm())
+	}
+	if err == nil {
+		err = s.syncMetadata(src, dst, info)
+	}
+	if err != nil {
+		return s.fail(src, dst, err)
+	}
+	return nil
+}
+
+// Report that syncing src to dst failed with err, and return it.
+func (s *treeSyncer) fail(src, dst string, err error) error {
+	s.options.Events.send(Event{Kind: EventErrored, Src: src, Dst: dst, Err: err})
+	return err
+}
+
+// Make dst match src. dstInfo describes dst, or is nil if it doesn't
+// exist.
+func (s *treeSyncer) syncEntry(src, dst string, srcInfo, dstInfo os.FileInfo) error {
+	if !srcInfo.IsDir() && !srcInfo.Mode().IsRegular() && !IsSymlink(srcInfo) {
+		s.options.Events.send(Event{Kind: EventSkipped, Src: src, Dst: dst, Reason: SkipSpecialFile})
+		return nil
+	}
+
+	if dstInfo != nil {
+		diff, err := cmpFiles(src, dst, srcInfo, dstInfo, s.options.Compare, s.options.ModifyWindow)
+		if err != nil {
+			return s.fail(src, dst, err)
+		}
+		switch {
+		case srcInfo.IsDir() && !diff.Kind:
+			return s.syncDir(src, dst, srcInfo)
+		case diff.Equal():
+			s.result.Unchanged++
+			s.options.Events.send(Event{Kind: EventSkipped, Src: src, Dst: dst, Reason: SkipUnchanged})
+			return nil
+		case !diff.Kind && !diff.Content:
+			err = s.syncMetadata(src, dst, srcInfo)
+			if err == nil {
+				s.result.Updated++
+				s.options.Events.send(Event{Kind: EventUpdated, Src: src, Dst: dst, Old: dstInfo})
+			} else {
+				s.fail(src, dst, err)
+			}
+			s.progress(src, dst, err)
+			return err
+		}
+		// Replace dst, as it's a different kind of file or a link that
+		// would be followed by copying over it
+		if diff.Kind || IsSymlink(dstInfo) {
+			err = os.RemoveAll(dst)
+			if err != nil {
+				return s.fail(src, dst, err)
+			}
+		}
+	}
+
+	if srcInfo.IsDir() {
+		return s.syncDir(src, dst, srcInfo)
+	}
+	result, err := CopyContext(s.ctx, src, dst, &CopyOptions{RateLimit: s.options.RateLimit})
+	s.result.Bytes += result.Bytes
+	if err == nil {
+		err = s.syncMetadata(src, dst, srcInfo)
+	}
+	if err == nil {
+		s.result.Copied++
+		if result.Symlink {
+			s.options.Events.send(Event{Kind: EventSymlinkCreated, Src: src, Dst: dst, Old: dstInfo})
+		} else {
+			s.options.Events.send(Event{Kind: EventFileCopied, Src: src, Dst: dst, Bytes: result.Bytes, Digests: result.Digests, Old: dstInfo})
+		}
+	} else {
+		s.fail(src, dst, err)
+	}
+	s.progress(src, dst, err)
+	return err
+}
+
+// Give dst the metadata of src that the Compare option compares.
+func (s *treeSyncer) syncMetadata(src, dst string, srcInfo os.FileInfo) error {
+	if IsSymlink(srcInfo) {
+		if s.options.Compare == CompareContentModeTimes {
+			atime, mtime := fileTimes(srcInfo)
+			return lutimes(dst, atime, mtime)
+		}
+		return nil
+	}
+
+	switch s.options.Compare {
+	case CompareContentMode, CompareContentModeTimes:
+		err := os.Chmod(dst, srcInfo.Mode().Perm())
+		if err != nil || s.options.Compare != CompareContentModeTimes {
+			return err
+		}
+		atime, mtime := fileTimes(srcInfo)
+		return os.Chtimes(dst, atime, mtime)
+	case CompareContentXattrs:
+		return syncXattrs(src, dst)
+	}
+	return nil
+}
+
+// Give dst the same extended attributes as src, and no others.
+func syncXattrs(src, dst string) error {
+	xattrs, err := readXattrs(src)
+	if err != nil {
+		return err
+	}
+	names, err := diffXattrs(src, dst)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if value, ok := xattrs[name]; ok {
+			err = setXattr(dst, name, value)
+		} else {
+			err = removeXattr(dst, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *treeSyncer) progress(src, dst string, err error) {
+	if s.options.Progress != nil {
+		progress := Progress{
+			Src:       src,
+			Dst:       dst,
+			Err:       err,
+			FilesDone: s.result.Copied + s.result.Updated,
+			BytesDone: s.result.Bytes,
+		}
+		s.meter.update(&progress)
+		s.options.Progress(progress)
+	}
+}
+
+func sortedNames(infos map[string]os.FileInfo) []string {
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe the entries of dir that aren't ignored, by name.
+func (s *treeSyncer) entries(dir string) (map[string]os.FileInfo, error) {
+	return compareEntries(dir, &CompareOptions{Symlinks: true, Ignore: s.options.Ignore})
+}
@@ -0,0 +1,95 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestSyncTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+
+	result, err := SyncTree(context.Background(), src, dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 4, Bytes: 12}))
+	diff, err := TreesEqual(src, dst, &CompareOptions{Symlinks: true, Mode: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diff.Equal()).To(BeTrue())
+
+	result, err = SyncTree(context.Background(), src, dst, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Unchanged: 3}))
+
+	g.Expect(os.WriteFile(makeTestPath("testdir/file1"), []byte("changed"), 0644)).To(Succeed())
+	g.Expect(os.Remove(makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Mkdir(makeTestPath("testdir/link"), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(makeTestPath("out/extra"), nil, 0644)).To(Succeed())
+
+	var progress []string
+	result, err = SyncTree(context.Background(), src, dst, &SyncTreeOptions{
+		Progress: func(p Progress) { progress = append(progress, p.Src) },
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 2, Unchanged: 1, Bytes: 7}))
+	g.Expect(progress).To(Equal([]string{makeTestPath("testdir/file1")}))
+	g.Expect(makeTestPath("out/link")).To(BeADirectory())
+	g.Expect(makeTestPath("out/extra")).To(BeAnExistingFile())
+
+	result, err = SyncTree(context.Background(), src, dst, &SyncTreeOptions{Delete: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Unchanged: 2, Deleted: 1}))
+	g.Expect(makeTestPath("out/extra")).NotTo(BeAnExistingFile())
+}
+
+func TestSyncTreeMetadata(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	options := &SyncTreeOptions{Compare: CompareContentModeTimes}
+	_, err := SyncTree(context.Background(), src, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(os.Chmod(makeTestPath("testdir/file1"), 0600)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir/file2"), old, old)).To(Succeed())
+	g.Expect(os.Chtimes(src, old, old)).To(Succeed())
+
+	result, err := SyncTree(context.Background(), src, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Updated: 2}))
+	diff, err := TreesEqual(src, dst, &CompareOptions{Mode: true, ModTime: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(diff.Equal()).To(BeTrue())
+	info, err := os.Stat(dst)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.ModTime()).To(Equal(old))
+}
+
+func TestSyncTreeIgnore(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("out")
+	shutiltest.CreateTree(t, dst, shutiltest.Tree{"keep.tmp": shutiltest.File("")})
+	g.Expect(os.WriteFile(makeTestPath("testdir/skip.tmp"), nil, 0644)).To(Succeed())
+
+	ignore := func(dir string, entries []os.FileInfo) []string {
+		return []string{"skip.tmp", "keep.tmp"}
+	}
+	result, err := SyncTree(context.Background(), makeTestPath("testdir"), dst, &SyncTreeOptions{Ignore: ignore, Delete: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Copied).To(Equal(2))
+	g.Expect(makeTestPath("out/keep.tmp")).To(BeAnExistingFile())
+	g.Expect(makeTestPath("out/skip.tmp")).NotTo(BeAnExistingFile())
+}
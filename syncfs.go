@@ -0,0 +1,263 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"path"
+	"time"
+)
+
+// Options for SyncTreeFS().
+type SyncTreeFSOptions struct {
+	// Take files of the same size to match if the one in dst was modified
+	// no earlier than the one in src, rather than reading both to compare
+	// their contents, which for an object store means downloading them.
+	// This is how tools such as "aws s3 sync" decide what to upload, as
+	// object stores don't keep the modification times of what's copied.
+	QuickCheck bool
+
+	// How far apart modification times can be and still match with
+	// QuickCheck, as SyncTreeOptions.ModifyWindow.
+	ModifyWindow time.Duration
+
+	// Remove files and directories from dst that aren't in src.
+	Delete bool
+
+	// Called like CopyTreeOptions.Ignore for each directory of src and
+	// dst. Ignored names are neither copied nor removed.
+	Ignore IgnoreFunc
+
+	// Called after each file has been copied.
+	Progress ProgressFunc
+
+	// Sync symbolic links as links, rather than leaving them out.
+	Symlinks bool
+
+	// Give files and directories that are copied the same modification
+	// times as in src.
+	PreserveTimes bool
+}
+
+// Make the directory tree dst of dstFS match src of srcFS, like SyncTree(),
+// so that a directory can be synced to a bucket with ObjectStoreFS.
+//
+// Files that match are left alone: by default that's when their contents
+// are the same, and with the QuickCheck option when their sizes are and
+// dst's is no older. Anything else is copied as CopyTreeFS() would copy
+// it, replacing what's in dst if it's a different kind of file. Unless the
+// Delete option is set, files in dst that aren't in src are left alone.
+// SyncResult.Updated is always zero, as metadata isn't compared.
+func SyncTreeFS(ctx context.Context, srcFS FS, src string, dstFS FS, dst string, options *SyncTreeFSOptions) (SyncResult, error) {
+	if options == nil {
+		options = &SyncTreeFSOptions{}
+	}
+	info, err := srcFS.Lstat(src)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if !info.IsDir() {
+		return SyncResult{}, &NotADirectoryError{src}
+	}
+
+	s := &fsTreeSyncer{
+		fsTreeCopier: fsTreeCopier{
+			ctx:   ctx,
+			srcFS: srcFS,
+			dstFS: dstFS,
+			options: &CopyTreeFSOptions{
+				Symlinks:      options.Symlinks,
+				PreserveTimes: options.PreserveTimes,
+			},
+			meter: newProgressMeter(),
+		},
+		syncOptions: options,
+	}
+	dstInfo, err := dstFS.Lstat(dst)
+	if err != nil && !os.IsNotExist(err) {
+		return SyncResult{}, err
+	}
+	if err == nil && !dstInfo.IsDir() {
+		return SyncResult{}, &NotADirectoryError{dst}
+	}
+	err = s.syncDir(src, dst, info, dstInfo)
+	s.synced.Bytes = s.result.Bytes
+	return s.synced, err
+}
+
+// The state of a single SyncTreeFS() call, which copies what doesn't match
+// with its fsTreeCopier, whose result only counts the bytes copied.
+type fsTreeSyncer struct {
+	fsTreeCopier
+	syncOptions *SyncTreeFSOptions
+	synced      SyncResult
+}
+
+// Sync the directory src to dst, which dstInfo describes, or is nil if it
+// doesn't exist.
+func (s *fsTreeSyncer) syncDir(src, dst string, info, dstInfo os.FileInfo) error {
+	if dstInfo == nil {
+		err := s.dstFS.Mkdir(dst, 0700)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+		s.synced.Copied++
+	}
+
+	srcEntries, err := s.entries(s.srcFS, src)
+	if err != nil {
+		return err
+	}
+	var dstEntries map[string]os.FileInfo
+	if dstInfo != nil {
+		dstEntries, err = s.entries(s.dstFS, dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedNames(srcEntries) {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		err = s.syncEntry(path.Join(src, name), path.Join(dst, name), srcEntries[name], dstEntries[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.syncOptions.Delete {
+		for _, name := range sortedNames(dstEntries) {
+			if _, ok := srcEntries[name]; ok {
+				continue
+			}
+			err = removeAllFS(s.dstFS, path.Join(dst, name))
+			if err != nil {
+				return err
+			}
+			s.synced.Deleted++
+		}
+	}
+
+	// Syncing the entries changes the directory's times, so its metadata
+	// is synced last
+	return s.copyMetadata(dst, info)
+}
+
+// Make dst match src. dstInfo describes dst, or is nil if it doesn't
+// exist.
+func (s *fsTreeSyncer) syncEntry(src, dst string, srcInfo, dstInfo os.FileInfo) error {
+	link := IsSymlink(srcInfo)
+	if !srcInfo.IsDir() && !srcInfo.Mode().IsRegular() && !(link && s.syncOptions.Symlinks) {
+		return nil
+	}
+
+	if dstInfo != nil {
+		sameKind := srcInfo.Mode().Type() == dstInfo.Mode().Type()
+		if sameKind && srcInfo.IsDir() {
+			return s.syncDir(src, dst, srcInfo, dstInfo)
+		}
+		if sameKind {
+			same, err := s.sameFile(src, dst, srcInfo, dstInfo)
+			if err != nil {
+				return err
+			}
+			if same {
+				s.synced.Unchanged++
+				return nil
+			}
+		}
+		// Replace dst, as it's a different kind of file or a link that
+		// can only be created where there's nothing
+		if !sameKind || link {
+			err := removeAllFS(s.dstFS, dst)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var err error
+	switch {
+	case srcInfo.IsDir():
+		return s.syncDir(src, dst, srcInfo, nil)
+	case link:
+		err = s.copySymlink(src, dst)
+	default:
+		var n int64
+		n, err = s.copyData(src, dst, srcInfo)
+		s.result.Bytes += n
+		if err == nil {
+			err = s.copyMetadata(dst, srcInfo)
+		}
+	}
+	if err == nil {
+		s.synced.Copied++
+	}
+	s.progress(src, dst, err)
+	return err
+}
+
+// Report whether the files src and dst, which are the same kind of file,
+// match.
+func (s *fsTreeSyncer) sameFile(src, dst string, srcInfo, dstInfo os.FileInfo) (bool, error) {
+	if IsSymlink(srcInfo) {
+		srcTarget, err := s.srcFS.Readlink(src)
+		if err != nil {
+			return false, err
+		}
+		dstTarget, err := s.dstFS.Readlink(dst)
+		return srcTarget == dstTarget, err
+	}
+
+	if srcInfo.Size() != dstInfo.Size() {
+		return false, nil
+	}
+	if s.syncOptions.QuickCheck {
+		return !dstInfo.ModTime().Before(srcInfo.ModTime().Add(-s.syncOptions.ModifyWindow)), nil
+	}
+
+	r, err := s.srcFS.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	dstR, err := s.dstFS.Open(dst)
+	if err != nil {
+		return false, err
+	}
+	defer dstR.Close()
+	return readersEqual(r, dstR)
+}
+
+// Describe the entries of dir in fsys that aren't ignored, by name.
+func (s *fsTreeSyncer) entries(fsys FS, dir string) (map[string]os.FileInfo, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ignoredNames []string
+	if s.syncOptions.Ignore != nil {
+		ignoredNames = s.syncOptions.Ignore(dir, entries)
+	}
+	infos := make(map[string]os.FileInfo, len(entries))
+	for _, entry := range entries {
+		if !stringInSlice(entry.Name(), ignoredNames) {
+			infos[entry.Name()] = entry
+		}
+	}
+	return infos, nil
+}
+
+func (s *fsTreeSyncer) progress(src, dst string, err error) {
+	if s.syncOptions.Progress != nil {
+		progress := Progress{
+			Src:       src,
+			Dst:       dst,
+			Err:       err,
+			FilesDone: s.synced.Copied,
+			BytesDone: s.result.Bytes,
+		}
+		s.meter.update(&progress)
+		s.syncOptions.Progress(progress)
+	}
+}
@@ -0,0 +1,97 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSyncTreeFSToObjectStore(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	store := newMemoryObjectStore()
+	bucket := ObjectStoreFS{Store: store, Prefix: "backups/"}
+	src := makeTestPath("testdir")
+
+	result, err := SyncTreeFS(context.Background(), OSFS{}, src, bucket, "today", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 3, Bytes: 12}))
+	g.Expect(store.objects).To(Equal(map[string][]byte{
+		"backups/today/file1": []byte("file1\n"),
+		"backups/today/file2": []byte("file2\n"),
+	}))
+
+	result, err = SyncTreeFS(context.Background(), OSFS{}, src, bucket, "today", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Unchanged: 2}))
+
+	g.Expect(os.WriteFile(makeTestPath("testdir/file1"), []byte("file9\n"), 0644)).To(Succeed())
+	store.objects["backups/today/extra"] = []byte("extra\n")
+	var progress []string
+	result, err = SyncTreeFS(context.Background(), OSFS{}, src, bucket, "today", &SyncTreeFSOptions{
+		Progress: func(p Progress) { progress = append(progress, p.Src) },
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 1, Unchanged: 1, Bytes: 6}))
+	g.Expect(progress).To(Equal([]string{makeTestPath("testdir/file1")}))
+	g.Expect(store.objects).To(HaveKeyWithValue("backups/today/file1", []byte("file9\n")))
+	g.Expect(store.objects).To(HaveKey("backups/today/extra"))
+
+	result, err = SyncTreeFS(context.Background(), OSFS{}, src, bucket, "today", &SyncTreeFSOptions{Delete: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Unchanged: 2, Deleted: 1}))
+	g.Expect(store.objects).NotTo(HaveKey("backups/today/extra"))
+}
+
+func TestSyncTreeFSQuickCheck(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	dst := makeTestPath("out")
+	g.Expect(os.Symlink("file1", makeTestPath("testdir/link"))).To(Succeed())
+	options := &SyncTreeFSOptions{QuickCheck: true, Symlinks: true, PreserveTimes: true}
+
+	result, err := SyncTreeFS(context.Background(), OSFS{}, src, OSFS{}, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 4, Bytes: 12}))
+	g.Expect(os.Readlink(makeTestPath("out/link"))).To(Equal("file1"))
+
+	// A change that keeps the size and time isn't noticed, but one that
+	// makes src newer is
+	info, err := os.Stat(makeTestPath("testdir/file1"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.WriteFile(makeTestPath("testdir/file1"), []byte("file9\n"), 0644)).To(Succeed())
+	g.Expect(os.Chtimes(makeTestPath("testdir/file1"), info.ModTime(), info.ModTime())).To(Succeed())
+	result, err = SyncTreeFS(context.Background(), OSFS{}, src, OSFS{}, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Unchanged: 3}))
+
+	later := info.ModTime().Add(time.Hour)
+	g.Expect(os.Chtimes(makeTestPath("testdir/file1"), later, later)).To(Succeed())
+	result, err = SyncTreeFS(context.Background(), OSFS{}, src, OSFS{}, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 1, Unchanged: 2, Bytes: 6}))
+	g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("out/file1"), nil)).To(BeTrue())
+
+	// Something of a different kind is replaced
+	g.Expect(os.Remove(makeTestPath("testdir/link"))).To(Succeed())
+	g.Expect(os.Mkdir(makeTestPath("testdir/link"), 0755)).To(Succeed())
+	result, err = SyncTreeFS(context.Background(), OSFS{}, src, OSFS{}, dst, options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(SyncResult{Copied: 1, Unchanged: 2}))
+	g.Expect(makeTestPath("out/link")).To(BeADirectory())
+}
+
+func TestSyncTreeFSNotADirectory(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	_, err := SyncTreeFS(context.Background(), OSFS{}, src, OSFS{}, makeTestPath("out"), nil)
+	g.Expect(err).To(MatchError(&NotADirectoryError{src}))
+}
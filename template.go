@@ -0,0 +1,214 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// The default for InstantiateOptions.MaxContentSize.
+const defaultMaxContentSize = 1 << 20
+
+// A placeholder in a template, such as "{{name}}" or "{{ name }}".
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// Returned by InstantiateTree() for a placeholder whose variable has no
+// value.
+type MissingVariableError struct {
+	// The file in the template the placeholder is in, or is the name of.
+	Path string
+	Name string
+}
+
+func (e MissingVariableError) Error() string {
+	return fmt.Sprintf("`%s` has no value for {{%s}}", e.Path, e.Name)
+}
+
+func (e MissingVariableError) Code() ErrorCode {
+	return CodeMissingVariable
+}
+
+// Returned by InstantiateTree() when substituting variables in a name
+// doesn't leave a name that can be given to a file, such as because it's
+// empty or has a path separator in it.
+type TemplateNameError struct {
+	Path string
+	Name string
+}
+
+func (e TemplateNameError) Error() string {
+	return fmt.Sprintf("`%s` would be named `%s`", e.Path, e.Name)
+}
+
+func (e TemplateNameError) Code() ErrorCode {
+	return CodeInvalidTemplateName
+}
+
+// Options for InstantiateTree().
+type InstantiateOptions struct {
+	// Substitute variables in the contents of text files, as well as in
+	// names. A file is taken to be text if it has no NUL bytes in it.
+	Contents bool
+
+	// The largest file whose contents are substituted, with larger files
+	// being copied as they are. Zero means 1MiB.
+	MaxContentSize int64
+
+	// Leave placeholders whose variables have no value as they are, rather
+	// than returning a MissingVariableError.
+	KeepMissing bool
+
+	// Called like CopyTreeOptions.Ignore with the names in the template,
+	// before any are substituted.
+	Ignore IgnoreFunc
+
+	// How files whose contents aren't substituted are copied. Symbolic
+	// links are always copied as links.
+	CopyOptions *CopyOptions
+}
+
+// Copy the tree templateDir to dst, which mustn't exist, replacing the
+// placeholders in the names of its files and directories, such as
+// "{{name}}", with the values of the variables in vars, as scaffolding
+// generators do. With the Contents option, placeholders in the contents
+// of text files are replaced too, and their modes are kept. Names of
+// variables start with a letter or underscore, and can have letters,
+// digits, underscores, dots and dashes in them.
+func InstantiateTree(templateDir, dst string, vars map[string]string, options *InstantiateOptions) (TreeResult, error) {
+	if options == nil {
+		options = &InstantiateOptions{}
+	}
+	in := &instantiator{vars: vars, options: options}
+	if options.CopyOptions != nil {
+		in.copyOptions = *options.CopyOptions
+	}
+	in.maxSize = options.MaxContentSize
+	if in.maxSize == 0 {
+		in.maxSize = defaultMaxContentSize
+	}
+	err := in.instantiateDir(templateDir, dst)
+	return in.result, err
+}
+
+type instantiator struct {
+	vars        map[string]string
+	options     *InstantiateOptions
+	copyOptions CopyOptions
+	maxSize     int64
+	result      TreeResult
+}
+
+// Replace the placeholders in data, which is from the template file path.
+func (in *instantiator) substitute(path string, data []byte) ([]byte, error) {
+	var err error
+	data = placeholder.ReplaceAllFunc(data, func(match []byte) []byte {
+		name := string(placeholder.FindSubmatch(match)[1])
+		value, ok := in.vars[name]
+		switch {
+		case ok:
+			return []byte(value)
+		case err == nil && !in.options.KeepMissing:
+			err = &MissingVariableError{path, name}
+		}
+		return match
+	})
+	return data, err
+}
+
+func (in *instantiator) instantiateDir(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &NotADirectoryError{src}
+	}
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	var ignoredNames []string
+	if in.options.Ignore != nil {
+		ignoredNames = in.options.Ignore(src, entries)
+	}
+	err = os.Mkdir(dst, 0700)
+	if err != nil {
+		return err
+	}
+	in.result.Dirs++
+
+	for _, entry := range entries {
+		if stringInSlice(entry.Name(), ignoredNames) {
+			continue
+		}
+		srcPath := filepath.Join(src, entry.Name())
+		name, err := in.substitute(srcPath, []byte(entry.Name()))
+		if err != nil {
+			return err
+		}
+		if len(name) == 0 || string(name) == "." || string(name) == ".." || bytes.ContainsAny(name, `/`+string(filepath.Separator)) {
+			return &TemplateNameError{srcPath, string(name)}
+		}
+		dstPath := filepath.Join(dst, string(name))
+
+		switch {
+		case entry.IsDir():
+			err = in.instantiateDir(srcPath, dstPath)
+		case IsSymlink(entry):
+			var target string
+			target, err = os.Readlink(srcPath)
+			if err == nil {
+				err = os.Symlink(target, dstPath)
+			}
+			if err == nil {
+				in.result.Symlinks++
+			}
+		default:
+			err = in.instantiateFile(srcPath, dstPath, entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Done last, in case the template's directory is read-only
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// Copy the file src, which info describes, to dst, replacing the
+// placeholders in it if it's text that isn't too large.
+func (in *instantiator) instantiateFile(src, dst string, info os.FileInfo) error {
+	if in.options.Contents && info.Mode().IsRegular() && info.Size() <= in.maxSize {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data, 0) < 0 {
+			data, err = in.substitute(src, data)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(dst, data, 0600)
+			if err == nil {
+				err = os.Chmod(dst, info.Mode().Perm())
+			}
+			if err != nil {
+				return err
+			}
+			in.result.Files++
+			in.result.Bytes += int64(len(data))
+			return nil
+		}
+	}
+	result, err := CopyContext(context.Background(), src, dst, &in.copyOptions)
+	if err != nil {
+		return err
+	}
+	in.result.Files++
+	in.result.Bytes += result.Bytes
+	in.result.Warnings = append(in.result.Warnings, result.Warnings...)
+	return nil
+}
@@ -0,0 +1,69 @@
+package shutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/gocardless/go-shutil/shutiltest"
+	. "github.com/onsi/gomega"
+)
+
+func TestInstantiateTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	template := makeTestPath("template")
+	shutiltest.CreateTree(t, template, shutiltest.Tree{
+		"{{name}}/main.go":          shutiltest.File("package {{ name }}\n// {{unknown}}\n"),
+		"{{name}}/{{name}}_test.go": shutiltest.File("package {{name}}\n"),
+		"README.md":                 {Mode: 0600, Contents: "# {{title}}\n"},
+		"logo.png":                  shutiltest.File("\x89PNG\x00{{name}}"),
+	})
+	vars := map[string]string{"name": "widget", "title": "Widgets"}
+
+	// Without Contents, only names are substituted
+	result, err := InstantiateTree(template, makeTestPath("names"), vars, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Files).To(Equal(4))
+	g.Expect(os.ReadFile(makeTestPath("names/widget/main.go"))).To(Equal([]byte("package {{ name }}\n// {{unknown}}\n")))
+	g.Expect(makeTestPath("names/widget/widget_test.go")).To(BeAnExistingFile())
+
+	_, err = InstantiateTree(template, makeTestPath("strict"), vars, &InstantiateOptions{Contents: true})
+	var missing *MissingVariableError
+	g.Expect(errors.As(err, &missing)).To(BeTrue())
+	g.Expect(missing.Name).To(Equal("unknown"))
+	g.Expect(missing.Path).To(Equal(makeTestPath("template/{{name}}/main.go")))
+
+	_, err = InstantiateTree(template, makeTestPath("out"), vars, &InstantiateOptions{Contents: true, KeepMissing: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("out/widget/main.go"))).To(Equal([]byte("package widget\n// {{unknown}}\n")))
+	g.Expect(os.ReadFile(makeTestPath("out/README.md"))).To(Equal([]byte("# Widgets\n")))
+	info, err := os.Stat(makeTestPath("out/README.md"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	// Binary files are copied as they are
+	g.Expect(os.ReadFile(makeTestPath("out/logo.png"))).To(Equal([]byte("\x89PNG\x00{{name}}")))
+
+	// Files over the limit are copied as they are
+	_, err = InstantiateTree(template, makeTestPath("small"), vars, &InstantiateOptions{Contents: true, MaxContentSize: 4, KeepMissing: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("small/README.md"))).To(Equal([]byte("# {{title}}\n")))
+}
+
+func TestInstantiateTreeBadName(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	template := makeTestPath("template")
+	shutiltest.CreateTree(t, template, shutiltest.Tree{"{{name}}": shutiltest.File("")})
+
+	_, err := InstantiateTree(template, makeTestPath("out"), map[string]string{"name": "../escape"}, nil)
+	var nameErr *TemplateNameError
+	g.Expect(errors.As(err, &nameErr)).To(BeTrue())
+	g.Expect(nameErr.Name).To(Equal("../escape"))
+	g.Expect(makeTestPath("escape")).NotTo(BeAnExistingFile())
+
+	_, err = InstantiateTree(template, makeTestPath("empty"), map[string]string{"name": ""}, nil)
+	g.Expect(errors.As(err, &nameErr)).To(BeTrue())
+}
@@ -0,0 +1,51 @@
+package shutil
+
+import (
+	"os"
+	"strconv"
+)
+
+// The size of a terminal window in characters, like the result of Python's
+// shutil.get_terminal_size().
+type TerminalSize struct {
+	Columns int
+	Lines   int
+}
+
+// The size GetTerminalSize() falls back to.
+var DefaultTerminalSize = TerminalSize{Columns: 80, Lines: 24}
+
+// Return the size of the terminal standard output is connected to, like
+// Python's get_terminal_size(). The COLUMNS and LINES environment
+// variables take precedence when they are positive numbers, and where the
+// size can't be found, such as when standard output isn't a terminal,
+// DefaultTerminalSize is used instead.
+func GetTerminalSize() TerminalSize {
+	size := TerminalSize{
+		Columns: positiveEnv("COLUMNS"),
+		Lines:   positiveEnv("LINES"),
+	}
+	if size.Columns > 0 && size.Lines > 0 {
+		return size
+	}
+
+	actual, err := terminalSize(os.Stdout)
+	if err != nil || actual.Columns <= 0 || actual.Lines <= 0 {
+		actual = DefaultTerminalSize
+	}
+	if size.Columns <= 0 {
+		size.Columns = actual.Columns
+	}
+	if size.Lines <= 0 {
+		size.Lines = actual.Lines
+	}
+	return size
+}
+
+func positiveEnv(name string) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,9 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+
+package shutil
+
+import "os"
+
+func terminalSize(f *os.File) (TerminalSize, error) {
+	return TerminalSize{}, &NotSupportedError{"terminal size", f.Name()}
+}
@@ -0,0 +1,23 @@
+package shutil
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGetTerminalSize(t *testing.T) {
+	g := NewWithT(t)
+
+	t.Setenv("COLUMNS", "123")
+	t.Setenv("LINES", "45")
+	g.Expect(GetTerminalSize()).To(Equal(TerminalSize{123, 45}))
+
+	// Test output isn't a terminal, so the rest comes from the default
+	t.Setenv("LINES", "-1")
+	if _, err := terminalSize(os.Stdout); err != nil {
+		g.Expect(GetTerminalSize()).To(Equal(TerminalSize{123, DefaultTerminalSize.Lines}))
+	}
+	g.Expect(GetTerminalSize().Columns).To(Equal(123))
+}
@@ -0,0 +1,17 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package shutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func terminalSize(f *os.File) (TerminalSize, error) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return TerminalSize{}, &os.SyscallError{Syscall: "ioctl", Err: err}
+	}
+	return TerminalSize{Columns: int(ws.Col), Lines: int(ws.Row)}, nil
+}
@@ -0,0 +1,19 @@
+package shutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func terminalSize(f *os.File) (TerminalSize, error) {
+	var info windows.ConsoleScreenBufferInfo
+	err := windows.GetConsoleScreenBufferInfo(windows.Handle(f.Fd()), &info)
+	if err != nil {
+		return TerminalSize{}, &os.SyscallError{Syscall: "GetConsoleScreenBufferInfo", Err: err}
+	}
+	return TerminalSize{
+		Columns: int(info.Window.Right-info.Window.Left) + 1,
+		Lines:   int(info.Window.Bottom-info.Window.Top) + 1,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The line endings text files are given by TextOptions.
+type LineEnding int
+
+const (
+	// Leave line endings as they are.
+	LineEndingAsIs LineEnding = iota
+	// End lines with a line feed, as Unix does, replacing each CRLF.
+	LineEndingLF
+	// End lines with a carriage return and a line feed, as Windows does.
+	LineEndingCRLF
+)
+
+// How much of a file is looked at to decide whether it's text.
+const textSniffSize = 8000
+
+// Options for copying text files between systems with different
+// conventions, such as from Windows to Unix.
+type TextOptions struct {
+	// The line endings to give text files.
+	LineEndings LineEnding
+
+	// The extensions of the files that are text, such as ".txt", matched
+	// regardless of case. If there are none, a file is taken to be text
+	// if there are no NUL bytes in its first 8000 bytes, as git decides,
+	// which UTF-16 text fails.
+	Extensions []string
+
+	// Converts the data of text files from their encoding to the one the
+	// copies should have, before line endings are changed, such as a
+	// reader from golang.org/x/text/transform. The result must be ASCII
+	// compatible for line endings to be changed.
+	Transcode func(io.Reader) io.Reader
+}
+
+// Report whether the file name, which is open as f, is text.
+func (o *TextOptions) isText(name string, f *os.File) (bool, error) {
+	if len(o.Extensions) > 0 {
+		ext := filepath.Ext(name)
+		for _, textExt := range o.Extensions {
+			if strings.EqualFold(ext, textExt) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	buf := make([]byte, textSniffSize)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) < 0, nil
+}
+
+// Copy the text file src to dst, converting it as the options say, and
+// return the size of the copy. The data read from src is written to tee
+// as well, if it's set.
+func copyText(ctx context.Context, dst, src *os.File, tee io.Writer, options *CopyOptions) (int64, error) {
+	var r io.Reader = src
+	if options.RateLimit != nil {
+		r = &rateLimitedReader{ctx, r, options.RateLimit}
+	}
+	if tee != nil {
+		r = io.TeeReader(r, tee)
+	}
+	if options.Text.Transcode != nil {
+		r = options.Text.Transcode(r)
+	}
+	w := &lineEndingWriter{w: dst, ending: options.Text.LineEndings}
+	_, err := copyData(ctx, w, r, options.BufferSize)
+	if err == nil {
+		err = w.flush()
+	}
+	return w.n, err
+}
+
+// Changes the line endings of what is written to it.
+type lineEndingWriter struct {
+	w      io.Writer
+	ending LineEnding
+	n      int64 // written to w
+	lastCR bool  // the last byte written to it was a carriage return
+	out    []byte
+}
+
+func (l *lineEndingWriter) Write(p []byte) (int, error) {
+	if l.ending == LineEndingAsIs {
+		n, err := l.w.Write(p)
+		l.n += int64(n)
+		return n, err
+	}
+	out := l.out[:0]
+	for _, b := range p {
+		switch l.ending {
+		case LineEndingLF:
+			// A carriage return is held back until it's known not to
+			// be part of a CRLF
+			if l.lastCR && b != '\n' {
+				out = append(out, '\r')
+			}
+			if b != '\r' {
+				out = append(out, b)
+			}
+		case LineEndingCRLF:
+			if b == '\n' && !l.lastCR {
+				out = append(out, '\r')
+			}
+			out = append(out, b)
+		}
+		l.lastCR = b == '\r'
+	}
+	l.out = out
+	n, err := l.w.Write(out)
+	l.n += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Write a carriage return that was held back at the end of the data.
+func (l *lineEndingWriter) flush() error {
+	if l.ending != LineEndingLF || !l.lastCR {
+		return nil
+	}
+	l.lastCR = false
+	n, err := l.w.Write([]byte{'\r'})
+	l.n += int64(n)
+	return err
+}
@@ -0,0 +1,84 @@
+package shutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyText(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("crlf.txt")
+	g.Expect(os.WriteFile(src, []byte("one\r\ntwo\nthree\r\r\nfour\r"), 0644)).To(Succeed())
+	dst := makeTestPath("lf.txt")
+
+	result, err := CopyContext(context.Background(), src, dst, &CopyOptions{
+		Text:       &TextOptions{LineEndings: LineEndingLF},
+		BufferSize: 5,
+		Verify:     true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(dst)).To(Equal([]byte("one\ntwo\nthree\r\nfour\r")))
+	g.Expect(result.Bytes).To(Equal(int64(len("one\ntwo\nthree\r\nfour\r"))))
+
+	_, err = CopyContext(context.Background(), dst, makeTestPath("crlf2.txt"), &CopyOptions{
+		Text:       &TextOptions{LineEndings: LineEndingCRLF},
+		BufferSize: 4,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("crlf2.txt"))).To(Equal([]byte("one\r\ntwo\r\nthree\r\nfour\r")))
+}
+
+func TestCopyTextDetection(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	binary := makeTestPath("data.bin")
+	g.Expect(os.WriteFile(binary, []byte("a\x00b\r\n"), 0644)).To(Succeed())
+	options := &CopyOptions{Text: &TextOptions{LineEndings: LineEndingLF}}
+	_, err := CopyContext(context.Background(), binary, makeTestPath("copy.bin"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(FilesEqual(binary, makeTestPath("copy.bin"), nil)).To(BeTrue())
+
+	// Only files with the extensions are text, if there are any
+	text := makeTestPath("notes.TXT")
+	g.Expect(os.WriteFile(text, []byte("a\r\n"), 0644)).To(Succeed())
+	options.Text.Extensions = []string{".md"}
+	_, err = CopyContext(context.Background(), text, makeTestPath("copy1.txt"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("copy1.txt"))).To(Equal([]byte("a\r\n")))
+
+	options.Text.Extensions = []string{".txt"}
+	_, err = CopyContext(context.Background(), text, makeTestPath("copy2.txt"), options)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("copy2.txt"))).To(Equal([]byte("a\n")))
+}
+
+func TestCopyTextTranscode(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("shout.txt")
+	g.Expect(os.WriteFile(src, []byte("hi\r\n"), 0644)).To(Succeed())
+	_, err := CopyContext(context.Background(), src, makeTestPath("copy.txt"), &CopyOptions{
+		Text: &TextOptions{
+			LineEndings: LineEndingLF,
+			Transcode: func(r io.Reader) io.Reader {
+				data, err := io.ReadAll(r)
+				if err != nil {
+					return r
+				}
+				return bytes.NewReader([]byte(strings.ToUpper(string(data))))
+			},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("copy.txt"))).To(Equal([]byte("HI\n")))
+}
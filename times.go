@@ -0,0 +1,85 @@
+package shutil
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Reports that a file was given a less precise modification time than the
+// one asked for, as its filesystem stores times less precisely, such as
+// FAT's two seconds. Comparisons can allow for it with a ModifyWindow of
+// Precision.
+type TimePrecisionWarning struct {
+	Path string
+
+	// The modification time asked for, and the one the file was given.
+	Wanted time.Time
+	Got    time.Time
+
+	// How precisely the filesystem appears to store times.
+	Precision time.Duration
+}
+
+func (w TimePrecisionWarning) Error() string {
+	return fmt.Sprintf("modification time of `%s` stored to %s: %s instead of %s",
+		w.Path, w.Precision, w.Got.Format(time.RFC3339Nano), w.Wanted.Format(time.RFC3339Nano))
+}
+
+func (w TimePrecisionWarning) Code() ErrorCode {
+	return CodeTimePrecision
+}
+
+// Set the access and modification times of the named file, like
+// os.Chtimes(), but leaving either as it is if it's the zero time, so that
+// only one of them can be changed. Times are set to the nanosecond, and
+// can be before 1970.
+func Chtimes(name string, atime, mtime time.Time) error {
+	return chtimes(name, atime, mtime)
+}
+
+// Set the times of the named file like Chtimes(), but without following
+// it if it is a symbolic link, where the platform allows.
+func Lchtimes(name string, atime, mtime time.Time) error {
+	return lutimes(name, atime, mtime)
+}
+
+// Report whether times a and b are no more than window apart. A window of
+// zero means they must be equal.
+func TimesEqual(a, b time.Time, window time.Duration) bool {
+	d := a.Sub(b)
+	if d < 0 {
+		d = -d
+	}
+	return d <= window
+}
+
+// Check that the file at path, which was just given the modification time
+// mtime, has it, returning a TimePrecisionWarning if it doesn't.
+func checkTimePrecision(path string, mtime time.Time) (*TimePrecisionWarning, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	got := info.ModTime()
+	if got.Equal(mtime) {
+		return nil, nil
+	}
+	return &TimePrecisionWarning{
+		Path:      path,
+		Wanted:    mtime,
+		Got:       got,
+		Precision: timePrecision(got),
+	}, nil
+}
+
+// Return the coarsest of the precisions filesystems commonly store times
+// with that t is a multiple of.
+func timePrecision(t time.Time) time.Duration {
+	for _, precision := range []time.Duration{2 * time.Second, time.Second, time.Millisecond, time.Microsecond} {
+		if t.Truncate(precision).Equal(t) {
+			return precision
+		}
+	}
+	return time.Nanosecond
+}
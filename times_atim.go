@@ -0,0 +1,18 @@
+//go:build aix || dragonfly || linux || openbsd || solaris
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Return the access and modification times of a file.
+func fileTimes(fi os.FileInfo) (atime, mtime time.Time) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.ModTime(), fi.ModTime()
+	}
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)), fi.ModTime()
+}
@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+
+package shutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Return the access and modification times of a file.
+func fileTimes(fi os.FileInfo) (atime, mtime time.Time) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.ModTime(), fi.ModTime()
+	}
+	return time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec)), fi.ModTime()
+}
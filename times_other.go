@@ -0,0 +1,14 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+
+package shutil
+
+import (
+	"os"
+	"time"
+)
+
+// Return the access and modification times of a file. Only the
+// modification time is known on this platform.
+func fileTimes(fi os.FileInfo) (atime, mtime time.Time) {
+	return fi.ModTime(), fi.ModTime()
+}
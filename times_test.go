@@ -0,0 +1,145 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyPreserveTimesPrecise(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	dst := makeTestPath("testfile3")
+	for _, mtime := range []time.Time{
+		time.Date(1950, 6, 1, 12, 0, 0, 123456789, time.UTC),
+		time.Date(2020, 1, 2, 3, 4, 5, 987654321, time.UTC),
+	} {
+		g.Expect(os.Chtimes(src, mtime, mtime)).To(Succeed())
+		result, err := CopyContext(context.Background(), src, dst, &CopyOptions{PreserveTimes: true})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		info, err := os.Stat(dst)
+		g.Expect(err).NotTo(HaveOccurred())
+		if len(result.Warnings) > 0 {
+			t.Skipf("times stored imprecisely: %v", result.Warnings[0])
+		}
+		g.Expect(info.ModTime()).To(BeTemporally("==", mtime))
+	}
+}
+
+func TestTimePrecision(t *testing.T) {
+	g := NewWithT(t)
+
+	base := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+	g.Expect(timePrecision(base)).To(Equal(2 * time.Second))
+	g.Expect(timePrecision(base.Add(time.Second))).To(Equal(time.Second))
+	g.Expect(timePrecision(base.Add(5 * time.Millisecond))).To(Equal(time.Millisecond))
+	g.Expect(timePrecision(base.Add(5 * time.Microsecond))).To(Equal(time.Microsecond))
+	g.Expect(timePrecision(base.Add(5))).To(Equal(time.Nanosecond))
+	g.Expect(timePrecision(time.Date(1950, 1, 1, 0, 0, 1, 0, time.UTC))).To(Equal(time.Second))
+}
+
+func TestCheckTimePrecision(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	path := makeTestPath("testfile")
+	mtime := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+	g.Expect(os.Chtimes(path, mtime, mtime)).To(Succeed())
+
+	g.Expect(checkTimePrecision(path, mtime)).To(BeNil())
+
+	warning, err := checkTimePrecision(path, mtime.Add(time.Second+5))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(warning.Got).To(BeTemporally("==", mtime))
+	g.Expect(warning.Precision).To(Equal(2 * time.Second))
+	g.Expect(warning.Error()).To(ContainSubstring("stored to 2s"))
+}
+
+func TestFilesEqualModifyWindow(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	a := makeTestPath("testdir/file1")
+	b := makeTestPath("testdir/file2")
+	g.Expect(os.WriteFile(b, []byte("file1\n"), 0644)).To(Succeed())
+	mtime := time.Now().Truncate(time.Second)
+	g.Expect(os.Chtimes(a, mtime, mtime.Add(1500*time.Millisecond))).To(Succeed())
+	g.Expect(os.Chtimes(b, mtime, mtime)).To(Succeed())
+
+	g.Expect(FilesEqual(a, b, &CompareOptions{ModTime: true})).To(BeFalse())
+	g.Expect(FilesEqual(a, b, &CompareOptions{ModTime: true, ModifyWindow: 2 * time.Second})).To(BeTrue())
+	g.Expect(TimesEqual(mtime, mtime.Add(-time.Second), time.Second)).To(BeTrue())
+}
+
+func TestChtimesOmit(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	path := makeTestPath("testfile")
+	atime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	mtime := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.Expect(os.Chtimes(path, atime, mtime)).To(Succeed())
+
+	newTime := time.Date(2003, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.Expect(Chtimes(path, time.Time{}, newTime)).To(Succeed())
+	info, err := os.Stat(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	gotAtime, gotMtime := fileTimes(info)
+	g.Expect(gotAtime).To(BeTemporally("==", atime))
+	g.Expect(gotMtime).To(BeTemporally("==", newTime))
+
+	g.Expect(Chtimes(path, newTime, time.Time{})).To(Succeed())
+	info, err = os.Stat(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	gotAtime, gotMtime = fileTimes(info)
+	g.Expect(gotAtime).To(BeTemporally("==", newTime))
+	g.Expect(gotMtime).To(BeTemporally("==", newTime))
+}
+
+func TestCopyRestoreSourceAtime(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	atime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	mtime := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.Expect(os.Chtimes(src, atime, mtime)).To(Succeed())
+
+	result, err := CopyContext(context.Background(), src, makeTestPath("testfile3"), &CopyOptions{
+		RestoreSourceAtime: true,
+		Verify:             true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Warnings).To(BeEmpty())
+
+	info, err := os.Stat(src)
+	g.Expect(err).NotTo(HaveOccurred())
+	gotAtime, gotMtime := fileTimes(info)
+	g.Expect(gotAtime).To(BeTemporally("==", atime))
+	g.Expect(gotMtime).To(BeTemporally("==", mtime))
+}
+
+func TestCopyNoAtime(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testfile")
+	atime := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	mtime := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.Expect(os.Chtimes(src, atime, mtime)).To(Succeed())
+
+	_, err := CopyContext(context.Background(), src, makeTestPath("testfile3"), &CopyOptions{NoAtime: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(os.ReadFile(makeTestPath("testfile3"))).To(Equal([]byte("testfile\n")))
+
+	info, err := os.Stat(src)
+	g.Expect(err).NotTo(HaveOccurred())
+	gotAtime, _ := fileTimes(info)
+	g.Expect(gotAtime).To(BeTemporally("==", atime))
+}
@@ -0,0 +1,16 @@
+package shutil
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Return the access and modification times of a file.
+func fileTimes(fi os.FileInfo) (atime, mtime time.Time) {
+	data, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return fi.ModTime(), fi.ModTime()
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds()), fi.ModTime()
+}
@@ -0,0 +1,182 @@
+package shutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Return the name to try for the nth collision with an existing file
+// called `name`, starting from one.
+type UniqueNameFunc func(name string, n int) string
+
+type UniqueOptions struct {
+	// Defaults to DefaultUniqueName.
+	NameFunc UniqueNameFunc
+
+	// How many alternative names to try before giving up. Defaults to
+	// 1000.
+	MaxAttempts int
+
+	// Used by CopyUnique() only.
+	FollowSymlinks bool
+
+	// Used by MoveUnique() only.
+	MoveOptions *MoveOptions
+}
+
+// Insert " (n)" before the extension, so the first alternative to
+// "file.txt" is "file (1).txt". This is what most download managers do.
+func DefaultUniqueName(name string, n int) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if stem == "" {
+		// A dotfile, such as ".bashrc", has no extension
+		stem, ext = name, ""
+	}
+	return fmt.Sprintf("%s (%d)%s", stem, n, ext)
+}
+
+// Copy a file like Copy(), but if the destination already exists pick a
+// name that doesn't instead. Return the file's destination.
+//
+// The destination may be a directory, in which case the file is copied
+// inside it under its own name, or an alternative to it. Names are
+// claimed by creating the file exclusively, so concurrent callers never
+// pick the same one.
+func CopyUnique(src, dst string, options *UniqueOptions) (string, error) {
+	if options == nil {
+		options = &UniqueOptions{}
+	}
+
+	dst, err := claimUniqueName(src, dst, options)
+	if err != nil {
+		return dst, err
+	}
+
+	if info, statErr := os.Lstat(src); statErr == nil && IsSymlink(info) && !options.FollowSymlinks {
+		err = replaceClaimed(dst, func(tmp string) error {
+			_, err := Copy(src, tmp, false)
+			return err
+		})
+	} else {
+		_, err = Copy(src, dst, options.FollowSymlinks)
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return dst, err
+}
+
+// Move a file or directory like Move(), but if the destination already
+// exists pick a name that doesn't instead. Return the source's new path.
+//
+// Names are claimed in the same way as CopyUnique(), except for
+// directories, where another process could create a file with the chosen
+// name between it being picked and the directory being moved there.
+func MoveUnique(src, dst string, options *UniqueOptions) (string, error) {
+	if options == nil {
+		options = &UniqueOptions{}
+	}
+
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return dst, err
+	}
+
+	if srcInfo.IsDir() {
+		dst, err = pickUniqueName(src, dst, options, func(candidate string) (bool, error) {
+			_, err := os.Lstat(candidate)
+			if os.IsNotExist(err) {
+				return true, nil
+			}
+			return false, err
+		})
+	} else {
+		dst, err = claimUniqueName(src, dst, options)
+	}
+	if err != nil {
+		return dst, err
+	}
+
+	var realDst string
+	if srcInfo.IsDir() || srcInfo.Mode().IsRegular() {
+		realDst, err = Move(src, dst, options.MoveOptions)
+	} else {
+		// Moving across devices would create the symbolic link or
+		// special file where there's already the placeholder
+		realDst = dst
+		err = replaceClaimed(dst, func(tmp string) error {
+			_, err := Move(src, tmp, options.MoveOptions)
+			return err
+		})
+	}
+	if err != nil && !srcInfo.IsDir() {
+		os.Remove(dst)
+	}
+	return realDst, err
+}
+
+// Pick a unique name by exclusively creating an empty file with it.
+func claimUniqueName(src, dst string, options *UniqueOptions) (string, error) {
+	return pickUniqueName(src, dst, options, func(candidate string) (bool, error) {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return true, f.Close()
+	})
+}
+
+// Put something that can only be created where nothing exists, such as a
+// symbolic link, at dst, a name claimUniqueName() has claimed. create
+// makes it at a temporary path in a directory beside dst, from which it's
+// renamed over the placeholder, so that nothing but the placeholder is
+// ever replaced.
+func replaceClaimed(dst string, create func(tmp string) error) error {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp")
+	if err != nil {
+		return err
+	}
+	// Only removed if empty, so that what was moved there isn't lost if
+	// the rename fails
+	defer os.Remove(tmpDir)
+
+	tmp := filepath.Join(tmpDir, filepath.Base(dst))
+	if err := create(tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// Try candidate names in turn, returning the first that claim accepts.
+func pickUniqueName(src, dst string, options *UniqueOptions, claim func(string) (bool, error)) (string, error) {
+	nameFunc := options.NameFunc
+	if nameFunc == nil {
+		nameFunc = DefaultUniqueName
+	}
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1000
+	}
+
+	if isDir, _ := isDirectory(dst); isDir {
+		dst = filepath.Join(dst, filepath.Base(src))
+	}
+	dir, name := filepath.Split(dst)
+
+	candidate := dst
+	for n := 1; ; n++ {
+		ok, err := claim(candidate)
+		if err != nil || ok {
+			return candidate, err
+		}
+		if n > maxAttempts {
+			return dst, &AlreadyExistsError{dst}
+		}
+		candidate = filepath.Join(dir, nameFunc(name, n))
+	}
+}
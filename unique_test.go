@@ -0,0 +1,100 @@
+package shutil
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDefaultUniqueName(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(DefaultUniqueName("file.txt", 1)).To(Equal("file (1).txt"))
+	g.Expect(DefaultUniqueName("file", 2)).To(Equal("file (2)"))
+	g.Expect(DefaultUniqueName(".bashrc", 3)).To(Equal(".bashrc (3)"))
+}
+
+func TestCopyUnique(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir/file1")
+	g.Expect(CopyUnique(src, testdir, nil)).To(Equal(makeTestPath("file1")))
+	g.Expect(CopyUnique(src, testdir, nil)).To(Equal(makeTestPath("file1 (1)")))
+	g.Expect(CopyUnique(src, makeTestPath("file1"), nil)).To(Equal(makeTestPath("file1 (2)")))
+	g.Expect(FilesEqual(src, makeTestPath("file1 (2)"), nil)).To(BeTrue())
+
+	// Original is untouched
+	g.Expect(FilesEqual(src, makeTestPath("file1"), nil)).To(BeTrue())
+}
+
+func TestCopyUniqueSymlink(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	link := makeTestPath("link")
+	g.Expect(os.Symlink("testfile", link)).To(Succeed())
+	g.Expect(os.Mkdir(makeTestPath("out"), 0755)).To(Succeed())
+
+	g.Expect(CopyUnique(link, makeTestPath("out"), nil)).To(Equal(makeTestPath("out/link")))
+	g.Expect(os.Readlink(makeTestPath("out/link"))).To(Equal("testfile"))
+	g.Expect(CopyUnique(link, makeTestPath("out"), nil)).To(Equal(makeTestPath("out/link (1)")))
+	g.Expect(os.Readlink(makeTestPath("out/link (1)"))).To(Equal("testfile"))
+
+	// Nothing is left behind from creating the links
+	entries, err := os.ReadDir(makeTestPath("out"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+
+	// Following the link copies the file it points to
+	dst, err := CopyUnique(link, makeTestPath("out"), &UniqueOptions{FollowSymlinks: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dst).To(Equal(makeTestPath("out/link (2)")))
+	g.Expect(dst).To(BeARegularFile())
+}
+
+func TestCopyUniqueNameFunc(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	options := &UniqueOptions{
+		NameFunc:    func(name string, n int) string { return fmt.Sprintf("%s.%d", name, n) },
+		MaxAttempts: 2,
+	}
+	src := makeTestPath("testfile")
+	dst := makeTestPath("testfile2")
+	g.Expect(CopyUnique(src, dst, options)).To(Equal(makeTestPath("testfile2.1")))
+	g.Expect(CopyUnique(src, dst, options)).To(Equal(makeTestPath("testfile2.2")))
+	_, err := CopyUnique(src, dst, options)
+	g.Expect(err).Should(MatchError(&AlreadyExistsError{dst}))
+}
+
+func TestMoveUnique(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(MoveUnique(makeTestPath("testfile"), makeTestPath("testfile2"), nil)).
+		To(Equal(makeTestPath("testfile2 (1)")))
+	g.Expect(makeTestPath("testfile")).NotTo(BeAnExistingFile())
+
+	g.Expect(CopyTree(makeTestPath("testdir"), makeTestPath("other/testdir"), nil)).To(Succeed())
+	g.Expect(MoveUnique(makeTestPath("testdir"), makeTestPath("other"), nil)).
+		To(Equal(makeTestPath("other/testdir (1)")))
+	g.Expect(makeTestPath("other/testdir (1)/file1")).To(BeAnExistingFile())
+}
+
+func TestMoveUniqueSymlink(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	link := makeTestPath("link")
+	g.Expect(os.Symlink("testfile", link)).To(Succeed())
+	g.Expect(os.Symlink("testfile", makeTestPath("testdir/link"))).To(Succeed())
+
+	g.Expect(MoveUnique(link, makeTestPath("testdir"), nil)).To(Equal(makeTestPath("testdir/link (1)")))
+	g.Expect(os.Readlink(makeTestPath("testdir/link (1)"))).To(Equal("testfile"))
+	_, err := os.Lstat(link)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
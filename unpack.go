@@ -0,0 +1,473 @@
+package shutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// What to do when something being created already exists.
+type OverwritePolicy int
+
+const (
+	// Fail with an AlreadyExistsError.
+	OverwriteNever OverwritePolicy = iota
+	// Leave the existing file alone and carry on.
+	OverwriteSkip
+	// Replace the existing file.
+	OverwriteAlways
+	// Replace the existing file only if it is older than the new one.
+	OverwriteIfNewer
+)
+
+// Returned when an archive entry would be written outside the destination
+// directory, or through a symbolic link.
+type UnsafePathError struct {
+	Name string
+}
+
+func (e UnsafePathError) Error() string {
+	return fmt.Sprintf("`%s` is not a safe path to extract", e.Name)
+}
+
+func (e UnsafePathError) Code() ErrorCode {
+	return CodeUnsafePath
+}
+
+// Options for the functions that unpack an archive into a directory.
+type UnpackOptions struct {
+	// What to do with entries that already exist in the destination.
+	// Directories are always merged.
+	Overwrite OverwritePolicy
+
+	// Give entries the owner and group recorded in the archive, which
+	// usually needs privileges. Zip archives don't record them.
+	PreserveOwner bool
+
+	// Restore the extended attributes recorded in a tar archive.
+	PreserveXattrs bool
+
+	// Called after each entry has been handled. FilesTotal is only known
+	// for zip archives.
+	Progress ProgressFunc
+}
+
+// Populate the directory dst, creating it if needed, from the tar archive
+// read from r.
+//
+// Entry names must be relative and stay inside dst, and no entry is
+// written through a symbolic link, even one created by the archive
+// itself; an UnsafePathError is returned otherwise. Regular files are
+// written atomically. Modes and modification times are restored, but
+// devices and named pipes are left out.
+func UntarTree(r io.Reader, dst string, options *UnpackOptions) error {
+	u, err := newUnpacker(dst, options)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := unpackEntry{
+			name:     header.Name,
+			mode:     header.FileInfo().Mode(),
+			modTime:  header.ModTime,
+			uid:      header.Uid,
+			gid:      header.Gid,
+			linkname: header.Linkname,
+			body:     tr,
+		}
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeRegA, tar.TypeDir, tar.TypeSymlink:
+		case tar.TypeLink:
+			entry.hardlink = true
+		default:
+			continue
+		}
+		for key, value := range header.PAXRecords {
+			if strings.HasPrefix(key, paxXattrPrefix) {
+				if entry.xattrs == nil {
+					entry.xattrs = map[string]string{}
+				}
+				entry.xattrs[strings.TrimPrefix(key, paxXattrPrefix)] = value
+			}
+		}
+
+		err = u.unpack(&entry)
+		if err != nil {
+			return err
+		}
+	}
+	return u.finish()
+}
+
+// Unpack the archive file filename into the directory extractDir, like
+// Python's unpack_archive(). The format is one that MakeArchive() takes,
+// or empty to guess it from the file name's extension: ".zip", ".tar", or
+// ".tar" followed by a registered compressor's extension, such as
+// ".tar.gz". An UnknownFormatError is returned if it can't be guessed.
+func UnpackArchive(filename, extractDir, format string, options *UnpackOptions) error {
+	if format == "" {
+		format = guessArchiveFormat(filename)
+		if format == "" {
+			return &UnknownFormatError{filepath.Ext(filename)}
+		}
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch {
+	case format == "zip":
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return UnzipTree(f, info.Size(), extractDir, options)
+	case format == "tar":
+		return UntarTree(f, extractDir, options)
+	case strings.HasSuffix(format, "tar"):
+		compressor, err := LookupCompressor(strings.TrimSuffix(format, "tar"))
+		if err != nil {
+			return &UnknownFormatError{format}
+		}
+		r, err := compressor.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return UntarTree(r, extractDir, options)
+	default:
+		return &UnknownFormatError{format}
+	}
+}
+
+// Return the format of the archive filename from its extension, or ""
+// if it's not one ArchiveFormats() has.
+func guessArchiveFormat(filename string) string {
+	switch {
+	case strings.HasSuffix(filename, ".zip"):
+		return "zip"
+	case strings.HasSuffix(filename, ".tar"):
+		return "tar"
+	}
+	i := strings.LastIndex(filename, ".tar.")
+	if i < 0 {
+		return ""
+	}
+	ext := filename[i+len(".tar"):]
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	for name, c := range compressors {
+		if c.Extension() == ext {
+			return name + "tar"
+		}
+	}
+	return ""
+}
+
+// Populate the directory dst from the zip archive read from r, which is
+// size bytes long, like UntarTree().
+func UnzipTree(r io.ReaderAt, size int64, dst string, options *UnpackOptions) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	u, err := newUnpacker(dst, options)
+	if err != nil {
+		return err
+	}
+	u.filesTotal = len(zr.File)
+
+	for _, f := range zr.File {
+		err = u.unpackZipFile(f)
+		if err != nil {
+			return err
+		}
+	}
+	return u.finish()
+}
+
+func (u *unpacker) unpackZipFile(f *zip.File) error {
+	entry := unpackEntry{
+		name:    f.Name,
+		mode:    f.Mode(),
+		modTime: f.Modified,
+		uid:     -1,
+		gid:     -1,
+	}
+	switch KindOf(entry.mode) {
+	case KindRegular, KindDir, KindSymlink:
+	default:
+		return nil
+	}
+
+	body, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	entry.body = body
+
+	if KindOf(entry.mode) == KindSymlink {
+		target, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		entry.linkname = string(target)
+	}
+	return u.unpack(&entry)
+}
+
+// An entry of an archive, however it was stored.
+type unpackEntry struct {
+	name     string
+	mode     os.FileMode
+	modTime  time.Time
+	uid, gid int
+	// The target of a symbolic link, or the entry a hard link is to.
+	linkname string
+	hardlink bool
+	xattrs   map[string]string
+	body     io.Reader
+	// Where a directory entry was written.
+	path string
+}
+
+// The state of a single UntarTree() or UnzipTree() call.
+type unpacker struct {
+	dst     string
+	options *UnpackOptions
+
+	// Directories get their final mode and times once everything inside
+	// them has been written.
+	dirs []unpackEntry
+
+	filesDone  int
+	filesTotal int
+	bytesDone  int64
+	meter      *progressMeter
+}
+
+func newUnpacker(dst string, options *UnpackOptions) (*unpacker, error) {
+	if options == nil {
+		options = &UnpackOptions{}
+	}
+	err := os.MkdirAll(dst, 0777)
+	if err != nil {
+		return nil, err
+	}
+	return &unpacker{dst: dst, options: options, meter: newProgressMeter()}, nil
+}
+
+func (u *unpacker) unpack(entry *unpackEntry) error {
+	dst, err := u.unpackEntry(entry)
+	if u.options.Progress != nil {
+		u.filesDone++
+		progress := Progress{
+			Src:        entry.name,
+			Dst:        dst,
+			Err:        err,
+			FilesDone:  u.filesDone,
+			FilesTotal: u.filesTotal,
+			BytesDone:  u.bytesDone,
+		}
+		u.meter.update(&progress)
+		u.options.Progress(progress)
+	}
+	return err
+}
+
+// Write a single entry, returning where it was written.
+func (u *unpacker) unpackEntry(entry *unpackEntry) (string, error) {
+	dst, err := u.target(entry.name)
+	if err != nil || dst == u.dst {
+		return dst, err
+	}
+
+	ok, err := u.makeWay(dst, entry)
+	if err != nil || !ok {
+		return dst, err
+	}
+
+	switch {
+	case entry.mode.IsDir():
+		err = os.Mkdir(dst, 0700)
+		if os.IsExist(err) {
+			err = nil
+		}
+		if err == nil {
+			entry.path = dst
+			u.dirs = append(u.dirs, *entry)
+		}
+		return dst, err
+	case entry.hardlink:
+		var target string
+		target, err = u.target(entry.linkname)
+		if err == nil {
+			err = os.Link(target, dst)
+		}
+		return dst, err
+	case KindOf(entry.mode) == KindSymlink:
+		err = os.Symlink(entry.linkname, dst)
+	default:
+		err = replaceAtomic(dst, func(tmp string) error {
+			return u.writeFile(tmp, entry)
+		})
+	}
+	if err != nil {
+		return dst, err
+	}
+	return dst, u.setMetadata(dst, entry)
+}
+
+// Return where the entry name should be written, after checking it would
+// stay inside the destination and not pass through a symbolic link. Any
+// missing parent directories are created.
+func (u *unpacker) target(name string) (string, error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+	if clean == "." {
+		return u.dst, nil
+	}
+	if clean == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") ||
+		strings.Contains(clean, `\`) || filepath.VolumeName(filepath.FromSlash(clean)) != "" {
+		return "", &UnsafePathError{name}
+	}
+
+	parts := strings.Split(clean, "/")
+	dir := u.dst
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			err = os.MkdirAll(filepath.Join(u.dst, filepath.FromSlash(path.Dir(clean))), 0777)
+			if err != nil {
+				return "", err
+			}
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if IsSymlink(fi) {
+			return "", &UnsafePathError{name}
+		}
+		if !fi.IsDir() {
+			return "", &NotADirectoryError{dir}
+		}
+	}
+	return filepath.Join(u.dst, filepath.FromSlash(clean)), nil
+}
+
+// Decide whether an entry should be written to dst, according to the
+// overwrite policy, and make way for it if so.
+func (u *unpacker) makeWay(dst string, entry *unpackEntry) (bool, error) {
+	fi, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if fi.IsDir() && entry.mode.IsDir() {
+		return true, nil
+	}
+
+	switch u.options.Overwrite {
+	case OverwriteSkip:
+		return false, nil
+	case OverwriteIfNewer:
+		if !entry.modTime.After(fi.ModTime()) {
+			return false, nil
+		}
+	case OverwriteAlways:
+	default:
+		return false, &AlreadyExistsError{dst}
+	}
+
+	// Regular files are replaced atomically when they are written
+	if fi.Mode().IsRegular() && entry.mode.IsRegular() && !entry.hardlink {
+		return true, nil
+	}
+	return true, os.Remove(dst)
+}
+
+func (u *unpacker) writeFile(tmp string, entry *unpackEntry) error {
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, entry.body)
+	u.bytesDone += n
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return u.setMetadata(tmp, entry)
+}
+
+// Give a file written from an entry the mode, owner, extended attributes
+// and times recorded in the archive.
+func (u *unpacker) setMetadata(dst string, entry *unpackEntry) error {
+	symlink := KindOf(entry.mode) == KindSymlink
+
+	if u.options.PreserveOwner && entry.uid >= 0 && entry.gid >= 0 {
+		err := os.Lchown(dst, entry.uid, entry.gid)
+		if err != nil {
+			return err
+		}
+	}
+	if !symlink {
+		err := os.Chmod(dst, entry.mode.Perm())
+		if err != nil {
+			return err
+		}
+	}
+	if u.options.PreserveXattrs {
+		for name, value := range entry.xattrs {
+			err := setXattr(dst, name, []byte(value))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if symlink {
+		return lutimes(dst, entry.modTime, entry.modTime)
+	}
+	return os.Chtimes(dst, entry.modTime, entry.modTime)
+}
+
+// Set the metadata of the directories, deepest first so that setting
+// their times isn't undone by changes to their contents.
+func (u *unpacker) finish() error {
+	for i := len(u.dirs) - 1; i >= 0; i-- {
+		dir := &u.dirs[i]
+		err := u.setMetadata(dir.path, dir)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
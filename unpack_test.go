@@ -0,0 +1,180 @@
+package shutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// Build a tar archive from headers, giving regular files their names as
+// contents.
+func makeTar(g *WithT, headers ...*tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(header.Name))
+		}
+		g.Expect(tw.WriteHeader(header)).To(Succeed())
+		if header.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(header.Name))
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+	}
+	g.Expect(tw.Close()).To(Succeed())
+	return &buf
+}
+
+func TestUntarTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	g.Expect(os.Chtimes(makeTestPath("testdir"), old, old)).To(Succeed())
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+
+	var buf bytes.Buffer
+	g.Expect(TarTree(testdir, &buf, &ArchiveOptions{Symlinks: true})).To(Succeed())
+
+	dst := makeTestPath("out")
+	var progress []string
+	err := UntarTree(&buf, dst, &UnpackOptions{
+		Progress: func(p Progress) {
+			g.Expect(p.Err).NotTo(HaveOccurred())
+			progress = append(progress, p.Src)
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(progress).To(Equal([]string{"link", "testdir/", "testdir/file1", "testdir/file2", "testfile", "testfile2"}))
+
+	g.Expect(FilesEqual(makeTestPath("testdir/file1"), makeTestPath("out/testdir/file1"), nil)).To(BeTrue())
+	g.Expect(FilesEqual(makeTestPath("testfile2"), makeTestPath("out/testfile2"), nil)).To(BeTrue())
+	g.Expect(os.Readlink(makeTestPath("out/link"))).To(Equal("testfile"))
+
+	info, err := os.Stat(makeTestPath("out/testdir"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.ModTime()).To(Equal(old))
+}
+
+func TestUntarTreeUnsafePaths(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	for _, name := range []string{"../evil", "/evil", "a/../../evil"} {
+		archive := makeTar(g, &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644})
+		err := UntarTree(archive, makeTestPath("out"), nil)
+		g.Expect(err).To(MatchError(&UnsafePathError{name}))
+	}
+
+	archive := makeTar(g,
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: ".."},
+		&tar.Header{Name: "link/evil", Typeflag: tar.TypeReg, Mode: 0644},
+	)
+	err := UntarTree(archive, makeTestPath("out"), nil)
+	g.Expect(err).To(MatchError(&UnsafePathError{"link/evil"}))
+	g.Expect(makeTestPath("evil")).NotTo(BeAnExistingFile())
+}
+
+func TestUntarTreeOverwrite(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	dst := makeTestPath("testdir")
+	newer := time.Now().Add(time.Hour)
+	archive := func(modTime time.Time) *bytes.Buffer {
+		return makeTar(g, &tar.Header{Name: "file1", Typeflag: tar.TypeReg, Mode: 0644, ModTime: modTime})
+	}
+	contents := func() string {
+		data, err := ioutil.ReadFile(makeTestPath("testdir/file1"))
+		g.Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	err := UntarTree(archive(newer), dst, nil)
+	g.Expect(err).To(MatchError(&AlreadyExistsError{makeTestPath("testdir/file1")}))
+
+	g.Expect(UntarTree(archive(newer), dst, &UnpackOptions{Overwrite: OverwriteSkip})).To(Succeed())
+	g.Expect(contents()).To(Equal("file1\n"))
+
+	g.Expect(UntarTree(archive(newer), dst, &UnpackOptions{Overwrite: OverwriteIfNewer})).To(Succeed())
+	g.Expect(contents()).To(Equal("file1"))
+
+	g.Expect(os.WriteFile(makeTestPath("testdir/file1"), []byte("changed"), 0644)).To(Succeed())
+	old := time.Now().Add(-time.Hour)
+	g.Expect(UntarTree(archive(old), dst, &UnpackOptions{Overwrite: OverwriteIfNewer})).To(Succeed())
+	g.Expect(contents()).To(Equal("changed"))
+
+	g.Expect(UntarTree(archive(old), dst, &UnpackOptions{Overwrite: OverwriteAlways})).To(Succeed())
+	g.Expect(contents()).To(Equal("file1"))
+}
+
+func TestUntarTreeXattrs(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir/file1")
+	if err := setXattr(src, "user.shutil", []byte("value")); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+
+	var buf bytes.Buffer
+	g.Expect(TarTree(makeTestPath("testdir"), &buf, &ArchiveOptions{Xattrs: true})).To(Succeed())
+	g.Expect(UntarTree(&buf, makeTestPath("out"), &UnpackOptions{PreserveXattrs: true})).To(Succeed())
+	g.Expect(getXattr(makeTestPath("out/file1"), "user.shutil")).To(Equal([]byte("value")))
+}
+
+func TestUnzipTree(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(os.Chmod(makeTestPath("testfile"), 0600)).To(Succeed())
+	g.Expect(os.Symlink("testfile", makeTestPath("link"))).To(Succeed())
+
+	var buf bytes.Buffer
+	g.Expect(ZipTree(testdir, &buf, &ArchiveOptions{Symlinks: true})).To(Succeed())
+
+	var last Progress
+	err := UnzipTree(bytes.NewReader(buf.Bytes()), int64(buf.Len()), makeTestPath("out"), &UnpackOptions{
+		Progress: func(p Progress) { last = p },
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(last.FilesDone).To(Equal(6))
+	g.Expect(last.FilesTotal).To(Equal(6))
+	g.Expect(last.BytesDone).To(Equal(int64(31)))
+
+	g.Expect(FilesEqual(makeTestPath("testdir/file2"), makeTestPath("out/testdir/file2"), nil)).To(BeTrue())
+	g.Expect(os.Readlink(makeTestPath("out/link"))).To(Equal("testfile"))
+	info, err := os.Stat(makeTestPath("out/testfile"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+}
+
+func TestUnpackArchive(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	g.Expect(ArchiveFormats()).To(ContainElements("gztar", "tar", "zip"))
+	for _, format := range []string{"gztar", "tar", "zip"} {
+		name, err := MakeArchive(makeTestPath("archive"), format, makeTestPath("testdir"), nil)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		dst := makeTestPath("out-" + format)
+		g.Expect(UnpackArchive(name, dst, "", nil)).To(Succeed(), format)
+		diff, err := TreesEqual(makeTestPath("testdir"), dst, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(diff.Equal()).To(BeTrue(), format)
+	}
+
+	// The format given takes precedence over the extension
+	g.Expect(os.Rename(makeTestPath("archive.tar"), makeTestPath("archive.bin"))).To(Succeed())
+	g.Expect(UnpackArchive(makeTestPath("archive.bin"), makeTestPath("out-bin"), "tar", nil)).To(Succeed())
+	g.Expect(UnpackArchive(makeTestPath("archive.bin"), makeTestPath("out-bin"), "", nil)).
+		To(MatchError(&UnknownFormatError{".bin"}))
+	g.Expect(UnpackArchive(makeTestPath("archive.bin"), makeTestPath("out-bin"), "rar", nil)).
+		To(MatchError(&UnknownFormatError{"rar"}))
+}
@@ -0,0 +1,4 @@
+package shutil
+
+// The nanoseconds of a time utimensat(2) leaves alone, from <sys/stat.h>.
+const utimeOmit = -2
@@ -0,0 +1,8 @@
+//go:build aix || dragonfly || freebsd || linux || openbsd || solaris
+
+package shutil
+
+import "golang.org/x/sys/unix"
+
+// The nanoseconds of a time utimensat(2) leaves alone.
+const utimeOmit = unix.UTIME_OMIT
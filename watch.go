@@ -0,0 +1,192 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Reports changes to a directory tree, for WatchAndSync(). The operating
+// system's notifications, such as inotify or FSEvents, can be used by
+// implementing this with a package that provides them.
+type Watcher interface {
+	// Call changed with the path of each file or directory under root
+	// that is created, changed or removed, until ctx is done, when the
+	// context's error is returned. Calls may be made concurrently, and
+	// may include paths that haven't really changed.
+	Watch(ctx context.Context, root string, changed func(path string)) error
+}
+
+// A Watcher that finds changes by walking the tree at intervals and
+// comparing each file's size, mode and modification time. It works
+// anywhere, but is slow to notice changes in large trees.
+type PollWatcher struct {
+	// How long to wait between walks of the tree. Zero means a second.
+	Interval time.Duration
+}
+
+// A file as PollWatcher last saw it.
+type polledFile struct {
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (w *PollWatcher) Watch(ctx context.Context, root string, changed func(path string)) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	before, err := pollTree(root)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		after, err := pollTree(root)
+		if err != nil {
+			return err
+		}
+		for path, file := range after {
+			if old, ok := before[path]; !ok || old != file {
+				changed(path)
+			}
+		}
+		for path := range before {
+			if _, ok := after[path]; !ok {
+				changed(path)
+			}
+		}
+		before = after
+	}
+}
+
+// Describe every file and directory under root, by path.
+func pollTree(root string) (map[string]polledFile, error) {
+	files := map[string]polledFile{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// It was removed between being listed and looked at
+			if os.IsNotExist(err) && path != root {
+				return nil
+			}
+			return err
+		}
+		files[path] = polledFile{info.Size(), info.Mode(), info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// Options for WatchAndSync().
+type WatchOptions struct {
+	// How each sync is done.
+	Sync *SyncTreeOptions
+
+	// Reports changes to the source. Nil uses a PollWatcher.
+	Watcher Watcher
+
+	// How long the source has to stay unchanged before it is synced
+	// again, so a burst of changes leads to a single sync. Zero means
+	// half a second.
+	Debounce time.Duration
+
+	// Called after each sync, including the first, with what it did. If
+	// it's set, a failed sync doesn't stop WatchAndSync(), which tries
+	// again after the next change.
+	OnSync func(SyncResult, error)
+}
+
+// Sync dst with src like SyncTree(), then keep watching src and syncing
+// it again whenever it changes, until ctx is done, when the context's
+// error is returned. This mirrors src to dst continuously.
+//
+// Each sync is of the whole tree, but as SyncTree() only copies what has
+// changed, that is no more than the changes themselves, plus the cost of
+// comparing the rest. Unless the OnSync option is set, the first sync
+// that fails is returned, as is any error from the Watcher.
+func WatchAndSync(ctx context.Context, src, dst string, options *WatchOptions) error {
+	if options == nil {
+		options = &WatchOptions{}
+	}
+	watcher := options.Watcher
+	if watcher == nil {
+		watcher = &PollWatcher{}
+	}
+	debounce := options.Debounce
+	if debounce <= 0 {
+		debounce = 500 * time.Millisecond
+	}
+
+	sync := func() error {
+		result, err := SyncTree(ctx, src, dst, options.Sync)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if options.OnSync != nil {
+			options.OnSync(result, err)
+			return nil
+		}
+		return err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changes := make(chan struct{}, 1)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- watcher.Watch(watchCtx, src, func(string) {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		})
+	}()
+
+	if err := sync(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-watchErr:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		case <-changes:
+		}
+
+		// Wait for the changes to settle
+		timer := time.NewTimer(debounce)
+	settle:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-changes:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(debounce)
+			case <-timer.C:
+				break settle
+			}
+		}
+
+		if err := sync(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package shutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWatchAndSync(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	src := makeTestPath("testdir")
+	dst := makeTestPath("mirror")
+	ctx, cancel := context.WithCancel(context.Background())
+	syncs := make(chan SyncResult, 100)
+	done := make(chan error)
+	go func() {
+		done <- WatchAndSync(ctx, src, dst, &WatchOptions{
+			Sync:     &SyncTreeOptions{Delete: true},
+			Watcher:  &PollWatcher{Interval: 10 * time.Millisecond},
+			Debounce: 10 * time.Millisecond,
+			OnSync:   func(result SyncResult, err error) { syncs <- result },
+		})
+	}()
+
+	g.Eventually(syncs).Should(Receive(Equal(SyncResult{Copied: 3, Bytes: 12})))
+	g.Expect(os.WriteFile(makeTestPath("testdir/file3"), []byte("file3\n"), 0644)).To(Succeed())
+	g.Expect(os.Remove(makeTestPath("testdir/file1"))).To(Succeed())
+	g.Eventually(func() string { return makeTestPath("mirror/file3") }).Should(BeAnExistingFile())
+	g.Eventually(func() string { return makeTestPath("mirror/file1") }).ShouldNot(BeAnExistingFile())
+
+	cancel()
+	g.Eventually(done).Should(Receive(MatchError(context.Canceled)))
+}
+
+func TestWatchAndSyncFails(t *testing.T) {
+	setup(t)
+	g := NewWithT(t)
+
+	err := WatchAndSync(context.Background(), makeTestPath("testfile"), makeTestPath("mirror"), nil)
+	g.Expect(err).To(BeAssignableToTypeOf(&NotADirectoryError{}))
+}
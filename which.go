@@ -0,0 +1,11 @@
+package shutil
+
+import "os/exec"
+
+// Return the path of the executable cmd, like Python's which(). Names
+// with no separator are looked for in the directories of the PATH
+// environment variable, as exec.LookPath() does, trying the extensions of
+// PATHEXT on Windows.
+func Which(cmd string) (string, error) {
+	return exec.LookPath(cmd)
+}
@@ -0,0 +1,26 @@
+package shutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWhich(t *testing.T) {
+	g := NewWithT(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("executables need extensions on Windows")
+	}
+
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "shutil-which-test")
+	g.Expect(os.WriteFile(exe, []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+	t.Setenv("PATH", dir)
+	g.Expect(Which("shutil-which-test")).To(Equal(exe))
+
+	_, err := Which("shutil-no-such-command")
+	g.Expect(err).To(HaveOccurred())
+}
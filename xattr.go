@@ -0,0 +1,6 @@
+package shutil
+
+import "errors"
+
+// Returned where the platform doesn't have extended attributes.
+var errNoXattrs = errors.New("extended attributes are not supported on this platform")
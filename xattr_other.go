@@ -0,0 +1,21 @@
+//go:build !darwin && !freebsd && !linux && !netbsd
+
+package shutil
+
+import "os"
+
+func listXattrs(path string) ([]string, error) {
+	return nil, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	return nil, &os.PathError{Op: "getxattr", Path: path, Err: errNoXattrs}
+}
+
+func setXattr(path, name string, value []byte) error {
+	return &os.PathError{Op: "setxattr", Path: path, Err: errNoXattrs}
+}
+
+func removeXattr(path, name string) error {
+	return &os.PathError{Op: "removexattr", Path: path, Err: errNoXattrs}
+}
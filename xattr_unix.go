@@ -0,0 +1,78 @@
+//go:build darwin || freebsd || linux || netbsd
+
+package shutil
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// List the names of the extended attributes of a file, without following
+// it if it is a symbolic link.
+func listXattrs(path string) ([]string, error) {
+	for {
+		size, err := unix.Llistxattr(path, nil)
+		if err != nil {
+			return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		size, err = unix.Llistxattr(path, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// The attributes changed between the calls
+			continue
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "listxattr", Path: path, Err: err}
+		}
+		return strings.FieldsFunc(string(buf[:size]), func(r rune) bool { return r == 0 }), nil
+	}
+}
+
+// Return the value of an extended attribute of a file, without following
+// it if it is a symbolic link.
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Lgetxattr(path, name, nil)
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		buf := make([]byte, size)
+		if size == 0 {
+			return buf, nil
+		}
+		size, err = unix.Lgetxattr(path, name, buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		return buf[:size], nil
+	}
+}
+
+// Set an extended attribute of a file, without following it if it is a
+// symbolic link.
+func setXattr(path, name string, value []byte) error {
+	err := unix.Lsetxattr(path, name, value, 0)
+	if err != nil {
+		return &os.PathError{Op: "setxattr", Path: path, Err: err}
+	}
+	return nil
+}
+
+// Remove an extended attribute of a file, without following it if it is
+// a symbolic link.
+func removeXattr(path, name string) error {
+	err := unix.Lremovexattr(path, name)
+	if err != nil {
+		return &os.PathError{Op: "removexattr", Path: path, Err: err}
+	}
+	return nil
+}